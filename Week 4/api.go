@@ -0,0 +1,183 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package consensus
+
+import (
+	"errors"
+	"math/big"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+var (
+	errNoMiningWork    = errors.New("no mining work available yet")
+	errNoHashrate      = errors.New("hashrate not measured yet")
+	errNoCurrentHeader = errors.New("no current header available")
+	errNoTargetTime    = errors.New("no target block time configured")
+)
+
+// remoteWork is the block currently being sealed, made available to external
+// miners along with the channel to hand their solution to the local miner on.
+type remoteWork struct {
+	block  *types.Block        // Pending block awaiting a seal (nil = no work)
+	solved chan<- *types.Block // Channel feeding external solutions to the miner
+	stop   <-chan struct{}     // Channel signalling the sealing was abandoned
+	done   <-chan struct{}     // Channel signalling the miner stopped reading solutions
+	lock   sync.Mutex          // Protects the fields above
+}
+
+// set replaces the current work with the given pending block.
+func (w *remoteWork) set(block *types.Block, solved chan<- *types.Block, stop, done <-chan struct{}) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	w.block, w.solved, w.stop, w.done = block, solved, stop, done
+}
+
+// current returns the pending block, or nil if there is none, its sealing was
+// abandoned or the miner already sealed it.
+func (w *remoteWork) current() *types.Block {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if w.block == nil {
+		return nil
+	}
+	select {
+	case <-w.stop:
+		w.block = nil
+	case <-w.done:
+		w.block = nil
+	default:
+	}
+	return w.block
+}
+
+// API exposes proof-of-work related methods for the RPC interface, allowing
+// external miners to fetch the pending work and submit solutions for it.
+// API menyediakan metoda proof-of-work untuk antarmuka RPC, sehingga miner eksternal dapat mengambil pekerjaan
+// dan mengirimkan solusinya.
+type API struct {
+	pow   *powEngine
+	chain ChainHeaderReader
+}
+
+// GetWork returns the work package for external miners.
+//
+// The work package consists of 3 strings:
+//
+//	result[0] - 32 bytes hex encoded current block header pow-hash
+//	result[1] - 32 bytes hex encoded seed hash, always zero as no dataset is used
+//	result[2] - 32 bytes hex encoded boundary condition ("target"), 2^256/difficulty
+//
+// metoda 'get work' akan mengembalikan paket pekerjaan untuk miner eksternal.
+func (api *API) GetWork() ([3]string, error) {
+	block := api.pow.work.current()
+	if block == nil {
+		return [3]string{}, errNoMiningWork
+	}
+	header := block.Header()
+	target := TargetFromDifficulty(api.pow.sealDifficulty(header.Difficulty))
+
+	return [3]string{
+		api.pow.SealHash(header).Hex(),
+		common.Hash{}.Hex(),
+		common.BytesToHash(target.Bytes()).Hex(),
+	}, nil
+}
+
+// SubmitWork can be used by external miners to submit their proof-of-work
+// solution. It returns whether the solution was valid for the pending block and
+// accepted, in which case the local miner stops and delivers the sealed block.
+// Solutions for work the local miner already sealed, or gave up on, are stale
+// and rejected.
+// metoda 'submit work' digunakan miner eksternal untuk mengirimkan solusi proof-of-work, dan mengembalikan
+// apakah solusi tersebut valid dan diterima.
+func (api *API) SubmitWork(nonce types.BlockNonce, hash, digest common.Hash) bool {
+	work := &api.pow.work
+
+	work.lock.Lock()
+	defer work.lock.Unlock()
+
+	if work.block == nil {
+		return false
+	}
+	// Make sure the work submitted is present
+	header := work.block.Header()
+	if api.pow.SealHash(header) != hash {
+		return false
+	}
+	// Verify the correctness of submitted result
+	header.Nonce = nonce
+	header.MixDigest = digest
+	if err := api.pow.VerifySeal(nil, header); err != nil {
+		return false
+	}
+	// Solution seems to be valid, return to the miner and notify acceptance
+	// unless the sealing was abandoned or the miner already sealed the block
+	block := work.block.WithSeal(header)
+	work.block = nil
+
+	select {
+	case work.solved <- block:
+		return true
+	case <-work.done:
+		return false
+	case <-work.stop:
+		return false
+	}
+}
+
+// Hashrate returns the combined hash attempts per second of the local mining
+// workers, averaged over the last ten seconds.
+// metoda 'hashrate' akan mengembalikan gabungan jumlah percobaan hash per detik dari worker mining lokal.
+func (api *API) Hashrate() uint64 {
+	return uint64(api.pow.Hashrate())
+}
+
+// ExpectedSealTime estimates the number of seconds the local miner needs on
+// average to seal a block at the difficulty of the current chain head, which
+// is the number of hashes expected per seal divided by the measured hashrate.
+// It helps calibrating the difficulty against a desired block time.
+// metoda 'expected seal time' akan memperkirakan rata-rata detik yang dibutuhkan miner lokal untuk menyegel
+// block pada tingkat kesulitan head chain saat ini.
+func (api *API) ExpectedSealTime() (float64, error) {
+	hashrate := api.pow.Hashrate()
+	if hashrate == 0 {
+		return 0, errNoHashrate
+	}
+	header := api.chain.CurrentHeader()
+	if header == nil || header.Difficulty == nil {
+		return 0, errNoCurrentHeader
+	}
+	difficulty, _ := new(big.Float).SetInt(api.pow.sealDifficulty(header.Difficulty)).Float64()
+	return difficulty / hashrate, nil
+}
+
+// TargetBlockTime returns the block time in seconds the difficulty retargets
+// towards, as configured along with the retargeting window or the target block
+// time controller. Engines adjusting the difficulty from the parent alone have
+// no target and return an error.
+// metoda 'target block time' akan mengembalikan waktu block dalam detik yang dituju oleh penyesuaian tingkat kesulitan.
+func (api *API) TargetBlockTime() (float64, error) {
+	if api.pow.window == 0 && api.pow.intervals == 0 {
+		return 0, errNoTargetTime
+	}
+	return api.pow.target.Seconds(), nil
+}
@@ -0,0 +1,209 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package consensus
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// trapdoorNonce is the only nonce satisfying any difficulty under trapdoorAlgo.
+const trapdoorNonce = 0x1337
+
+// trapdoorAlgo is a hash function digesting to zero for trapdoorNonce and to the
+// maximum for every other nonce, so that local mining never succeeds and only
+// an external miner knowing the trapdoor can seal.
+type trapdoorAlgo struct{}
+
+func (trapdoorAlgo) Sum(data []byte) []byte {
+	if binary.BigEndian.Uint64(data[len(data)-8:]) == trapdoorNonce {
+		return make([]byte, common.HashLength)
+	}
+	return bytes.Repeat([]byte{0xff}, common.HashLength)
+}
+
+// Tests a full get-work/submit-work cycle: the block being sealed is handed out
+// to external miners, bad solutions are rejected, and a valid one is delivered
+// as the sealed block.
+func TestRemoteSealing(t *testing.T) {
+	engine := NewPoW(WithHashAlgo(trapdoorAlgo{}), WithThreads(1))
+	genesis := testGenesis(131072)
+	chain := newTestChain(frontierConfig, genesis)
+	api := engine.APIs(chain)[0].Service.(*API)
+
+	if _, err := api.GetWork(); err != errNoMiningWork {
+		t.Fatalf("work before sealing: have %v, want %v", err, errNoMiningWork)
+	}
+	header := &types.Header{
+		ParentHash: genesis.Hash(),
+		UncleHash:  types.EmptyUncleHash,
+		Number:     big.NewInt(1),
+		GasLimit:   genesis.GasLimit,
+		Time:       genesis.Time + 1,
+	}
+	if err := engine.Prepare(chain, header); err != nil {
+		t.Fatalf("failed to prepare header: %v", err)
+	}
+	results, stop := make(chan *types.Block, 1), make(chan struct{})
+	defer close(stop)
+
+	if err := engine.Seal(chain, types.NewBlockWithHeader(header), results, stop); err != nil {
+		t.Fatalf("failed to seal block: %v", err)
+	}
+	work, err := api.GetWork()
+	if err != nil {
+		t.Fatalf("failed to get work: %v", err)
+	}
+	hash := engine.SealHash(header)
+	if want := hash.Hex(); work[0] != want {
+		t.Errorf("seal hash mismatch: have %s, want %s", work[0], want)
+	}
+	if want := common.BytesToHash(TargetFromDifficulty(header.Difficulty).Bytes()).Hex(); work[2] != want {
+		t.Errorf("target mismatch: have %s, want %s", work[2], want)
+	}
+	// Solutions for other work or with a wrong nonce must be rejected
+	if api.SubmitWork(types.EncodeNonce(trapdoorNonce), common.Hash{0x01}, common.Hash{}) {
+		t.Errorf("solution for unknown work accepted")
+	}
+	if api.SubmitWork(types.EncodeNonce(trapdoorNonce+1), hash, common.Hash{}) {
+		t.Errorf("invalid solution accepted")
+	}
+	// A valid solution must be delivered as the sealed block
+	if !api.SubmitWork(types.EncodeNonce(trapdoorNonce), hash, common.Hash{}) {
+		t.Fatalf("valid solution rejected")
+	}
+	select {
+	case block := <-results:
+		if nonce := block.Nonce(); nonce != trapdoorNonce {
+			t.Errorf("sealed nonce mismatch: have %#x, want %#x", nonce, trapdoorNonce)
+		}
+		if err := engine.VerifySeal(chain, block.Header()); err != nil {
+			t.Errorf("sealed block failed verification: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("sealed block not delivered")
+	}
+	// The work is consumed, so neither fetching nor resubmitting it works
+	if _, err := api.GetWork(); err != errNoMiningWork {
+		t.Errorf("work after sealing: have %v, want %v", err, errNoMiningWork)
+	}
+	if api.SubmitWork(types.EncodeNonce(trapdoorNonce), hash, common.Hash{}) {
+		t.Errorf("solution accepted twice")
+	}
+}
+
+// Tests that a solution submitted for work the local miner already sealed is
+// rejected as stale rather than silently dropped, and the work withdrawn.
+func TestRemoteSealingStale(t *testing.T) {
+	engine := NewPoW(WithThreads(1))
+	genesis := testGenesis(131072)
+	chain := newTestChain(frontierConfig, genesis)
+	api := engine.APIs(chain)[0].Service.(*API)
+
+	// Any nonce seals a block of difficulty 1, so the local miner wins at once
+	header := &types.Header{
+		ParentHash: genesis.Hash(),
+		UncleHash:  types.EmptyUncleHash,
+		Number:     big.NewInt(1),
+		GasLimit:   genesis.GasLimit,
+		Time:       genesis.Time + 1,
+		Difficulty: common.Big1,
+	}
+	results, stop := make(chan *types.Block, 1), make(chan struct{})
+	defer close(stop)
+
+	if err := engine.Seal(chain, types.NewBlockWithHeader(header), results, stop); err != nil {
+		t.Fatalf("failed to seal block: %v", err)
+	}
+	select {
+	case <-results:
+	case <-time.After(10 * time.Second):
+		t.Fatalf("sealed block not delivered")
+	}
+	if api.SubmitWork(types.EncodeNonce(trapdoorNonce), engine.SealHash(header), common.Hash{}) {
+		t.Errorf("stale solution accepted")
+	}
+	if _, err := api.GetWork(); err != errNoMiningWork {
+		t.Errorf("work after local sealing: have %v, want %v", err, errNoMiningWork)
+	}
+}
+
+// Tests that the expected seal time divides the head difficulty by the measured
+// hashrate, capped for the tester, and fails before any hashrate is measured.
+func TestExpectedSealTime(t *testing.T) {
+	chain := newTestChain(frontierConfig, testGenesis(5000))
+
+	for _, tt := range []struct {
+		name   string
+		engine *powEngine
+		want   float64
+	}{
+		{"pow", NewPoW(), 5},
+		{"tester", NewTester(), 0.128},
+	} {
+		api := &API{pow: tt.engine, chain: chain}
+		if _, err := api.ExpectedSealTime(); !errors.Is(err, errNoHashrate) {
+			t.Errorf("%s: unmeasured error mismatch: have %v, want %v", tt.name, err, errNoHashrate)
+		}
+		// Fake 1000 hashes per second over the hashrate window
+		tt.engine.meter(0).mark(1000 * hashrateWindow)
+		if have := api.Hashrate(); have != 1000 {
+			t.Errorf("%s: hashrate mismatch: have %d, want 1000", tt.name, have)
+		}
+		have, err := api.ExpectedSealTime()
+		if err != nil {
+			t.Fatalf("%s: failed to estimate seal time: %v", tt.name, err)
+		}
+		if have != tt.want {
+			t.Errorf("%s: seal time mismatch: have %v, want %v", tt.name, have, tt.want)
+		}
+	}
+}
+
+// Tests that the target block time is reported for both the windowed difficulty
+// and the target block time controller, and fails for parent-only retargeting.
+func TestTargetBlockTime(t *testing.T) {
+	chain := newTestChain(frontierConfig, testGenesis(5000))
+
+	for _, tt := range []struct {
+		name   string
+		engine *powEngine
+		want   float64
+		err    error
+	}{
+		{"parent", NewPoW(), 0, errNoTargetTime},
+		{"windowed", NewPoW(WithWindowedDifficulty(10, 15*time.Second)), 15, nil},
+		{"controller", NewPoW(WithTargetBlockTime(1500*time.Millisecond, 4)), 1.5, nil},
+		{"disabled", NewPoW(WithTargetBlockTime(0, 4)), 0, errNoTargetTime},
+	} {
+		api := &API{pow: tt.engine, chain: chain}
+		have, err := api.TargetBlockTime()
+		if !errors.Is(err, tt.err) {
+			t.Errorf("%s: error mismatch: have %v, want %v", tt.name, err, tt.err)
+		}
+		if have != tt.want {
+			t.Errorf("%s: target mismatch: have %v, want %v", tt.name, have, tt.want)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package consensus
+
+import (
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// TxApplier executes a transaction on top of the state as part of the block
+// with the given header, adding the gas it used to usedGas and returning its
+// receipt. It is usually backed by the state processor of the core package,
+// which can't be imported from here as it depends on the consensus engines.
+// TxApplier mengeksekusi transaksi pada state sebagai bagian dari block dengan header tertentu, menambahkan gas yang
+// terpakai ke usedGas, dan mengembalikan receipt-nya.
+type TxApplier func(state *state.StateDB, header *types.Header, tx *types.Transaction, usedGas *uint64) (*types.Receipt, error)
+
+// BlockAssembler builds pending blocks, executing their transactions through a
+// TxApplier before handing them to the engine's FinalizeAndAssemble.
+// BlockAssembler membangun block tertunda dengan mengeksekusi transaksinya, lalu menyerahkannya ke
+// 'finalize and assemble' dari engine.
+type BlockAssembler struct {
+	engine Engine            // Consensus engine finalizing the assembled blocks
+	chain  ChainHeaderReader // Chain the assembled blocks extend
+	apply  TxApplier         // Executor of the transactions included in the blocks
+}
+
+// NewBlockAssembler creates a block assembler finalizing blocks with the given
+// engine on top of the chain, executing their transactions with apply.
+// metoda 'new block assembler' akan membuat block assembler yang memfinalisasi block dengan engine tertentu.
+func NewBlockAssembler(engine Engine, chain ChainHeaderReader, apply TxApplier) *BlockAssembler {
+	return &BlockAssembler{engine: engine, chain: chain, apply: apply}
+}
+
+// Assemble executes the transactions in the given order on top of the state and
+// assembles the block out of the ones included. The header has to be prepared
+// by the engine already, its gas used is set to the gas of the included
+// transactions. A transaction which doesn't fit into the remaining gas of the
+// block, or fails to execute, is rolled back and skipped. The receipts of the
+// included transactions are returned along with the block.
+// metoda 'assemble' akan mengeksekusi transaksi secara berurutan pada state lalu merakit block dari transaksi yang
+// berhasil. Transaksi yang melebihi sisa gas block atau gagal dieksekusi akan dibatalkan dan dilewati.
+func (a *BlockAssembler) Assemble(header *types.Header, state *state.StateDB, txs []*types.Transaction, uncles []*types.Header, withdrawals []*types.Withdrawal) (*types.Block, []*types.Receipt, error) {
+	var (
+		included []*types.Transaction
+		receipts []*types.Receipt
+		gasUsed  uint64
+	)
+	for _, tx := range txs {
+		// Skip the transaction if it can't possibly fit into the block
+		if header.GasLimit-gasUsed < tx.Gas() {
+			continue
+		}
+		// Execute the transaction, rolling it back if it fails or overflows
+		var (
+			snap = state.Snapshot()
+			used = gasUsed
+		)
+		receipt, err := a.apply(state, header, tx, &used)
+		if err != nil || used > header.GasLimit {
+			state.RevertToSnapshot(snap)
+			continue
+		}
+		gasUsed = used
+		included = append(included, tx)
+		receipts = append(receipts, receipt)
+	}
+	header.GasUsed = gasUsed
+
+	block, err := a.engine.FinalizeAndAssemble(a.chain, header, state, included, uncles, receipts, withdrawals)
+	if err != nil {
+		return nil, nil, err
+	}
+	return block, receipts, nil
+}
@@ -0,0 +1,103 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package consensus
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// errTestApply is returned by transferApplier for transfers to failingAccount.
+var errTestApply = errors.New("test transaction failed")
+
+// failingAccount is an account every transfer to fails, after crediting it.
+var failingAccount = common.Address{0xff}
+
+// transferApplier executes transactions as plain transfers minting the value to
+// the recipient, each using all of its gas.
+func transferApplier(state *state.StateDB, header *types.Header, tx *types.Transaction, usedGas *uint64) (*types.Receipt, error) {
+	state.AddBalance(*tx.To(), tx.Value())
+	if *tx.To() == failingAccount {
+		return nil, errTestApply
+	}
+	*usedGas += tx.Gas()
+	return &types.Receipt{
+		Status:            types.ReceiptStatusSuccessful,
+		CumulativeGasUsed: *usedGas,
+		GasUsed:           tx.Gas(),
+		TxHash:            tx.Hash(),
+	}, nil
+}
+
+// Tests that the assembler includes the transactions fitting into the block,
+// rolling back and skipping the ones failing or exceeding the gas limit.
+func TestBlockAssembler(t *testing.T) {
+	var (
+		engine  = NewFaker()
+		genesis = testGenesis(params.MinimumDifficulty.Int64())
+		chain   = newTestChain(frontierConfig, genesis)
+		statedb = newTestState(t)
+		first   = common.Address{0x01}
+		second  = common.Address{0x02}
+	)
+	header := &types.Header{
+		ParentHash: genesis.Hash(),
+		Number:     big.NewInt(1),
+		GasLimit:   50_000,
+		Time:       genesis.Time + 1,
+	}
+	if err := engine.Prepare(chain, header); err != nil {
+		t.Fatalf("failed to prepare header: %v", err)
+	}
+	txs := []*types.Transaction{
+		types.NewTransaction(0, first, big.NewInt(1), params.TxGas, big.NewInt(1), nil),
+		types.NewTransaction(1, failingAccount, big.NewInt(2), params.TxGas, big.NewInt(1), nil),
+		types.NewTransaction(1, second, big.NewInt(3), params.TxGas, big.NewInt(1), nil),
+		types.NewTransaction(2, second, big.NewInt(4), params.TxGas, big.NewInt(1), nil),
+	}
+	block, receipts, err := NewBlockAssembler(engine, chain, transferApplier).Assemble(header, statedb, txs, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to assemble block: %v", err)
+	}
+	if len(receipts) != 2 || len(block.Transactions()) != 2 {
+		t.Fatalf("included transactions mismatch: have %d receipts and %d transactions, want 2", len(receipts), len(block.Transactions()))
+	}
+	if have, want := block.GasUsed(), 2*params.TxGas; have != want {
+		t.Errorf("gas used mismatch: have %d, want %d", have, want)
+	}
+	if have, want := receipts[1].CumulativeGasUsed, 2*params.TxGas; have != want {
+		t.Errorf("cumulative gas mismatch: have %d, want %d", have, want)
+	}
+	for i, tx := range block.Transactions() {
+		if tx.Hash() != txs[2*i].Hash() {
+			t.Errorf("transaction %d mismatch: have %x, want %x", i, tx.Hash(), txs[2*i].Hash())
+		}
+	}
+	// The failed transfer is rolled back, the one over the gas limit never ran
+	balances := map[common.Address]int64{first: 1, second: 3, failingAccount: 0}
+	for addr, want := range balances {
+		if have := statedb.GetBalance(addr); have.Cmp(big.NewInt(want)) != 0 {
+			t.Errorf("balance of %x mismatch: have %v, want %v", addr, have, want)
+		}
+	}
+}
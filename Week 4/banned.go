@@ -0,0 +1,62 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package consensus
+
+import (
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// BadHashes represent a set of manually tracked bad hashes (usually hard forks),
+// which every engine rejects with ErrBannedHash before doing any other work.
+// The map may only be modified directly before verification starts, use
+// BanHash to extend it while engines are running.
+// BadHashes adalah kumpulan hash block buruk yang dicatat secara manual, yang ditolak oleh setiap engine dengan
+// ErrBannedHash sebelum melakukan pekerjaan lainnya.
+var BadHashes = map[common.Hash]bool{}
+
+// badHashesLock protects BadHashes from concurrent modification.
+var badHashesLock sync.RWMutex
+
+// BanHash adds the given block hash to BadHashes, safe to call while headers
+// are being verified.
+// metoda 'ban hash' akan menambahkan hash block ke BadHashes, aman dipanggil saat header sedang diverifikasi.
+func BanHash(hash common.Hash) {
+	badHashesLock.Lock()
+	defer badHashesLock.Unlock()
+
+	BadHashes[hash] = true
+}
+
+// verifyNotBanned checks that a header is not one of the BadHashes.
+func verifyNotBanned(header *types.Header) error {
+	return verifyHashNotBanned(header.Hash())
+}
+
+// verifyHashNotBanned checks that the hash of a header is not one of the
+// BadHashes.
+func verifyHashNotBanned(hash common.Hash) error {
+	badHashesLock.RLock()
+	defer badHashesLock.RUnlock()
+
+	if BadHashes[hash] {
+		return ErrBannedHash
+	}
+	return nil
+}
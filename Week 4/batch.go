@@ -0,0 +1,808 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package consensus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"runtime"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// HeaderVerifyError is the error delivered on the results channel of a batch
+// verification when a header fails, identifying the offending header.
+// HeaderVerifyError adalah error yang dikirim pada channel hasil verifikasi batch ketika sebuah header gagal,
+// berisi identitas header yang bermasalah.
+type HeaderVerifyError struct {
+	Index  int         // Position of the header within the verified batch
+	Number uint64      // Number of the header failing verification
+	Hash   common.Hash // Hash of the header failing verification
+	Err    error       // Reason the header failed verification, wrapped with the header's identity
+}
+
+// newHeaderVerifyError wraps err, if any, with the identity of the header at
+// the given index of the batch.
+func newHeaderVerifyError(hashes *headerHashes, index int, err error) error {
+	if err == nil {
+		return nil
+	}
+	return hashedVerifyError(hashes.headers[index], hashes.hash(index), index, err)
+}
+
+// headerVerifyError wraps err, if any, with the identity of the header found at
+// the given index of a batch or stream.
+func headerVerifyError(header *types.Header, index int, err error) error {
+	if err == nil {
+		return nil
+	}
+	return hashedVerifyError(header, header.Hash(), index, err)
+}
+
+// hashedVerifyError wraps err with the identity of a header whose hash is
+// already known.
+func hashedVerifyError(header *types.Header, hash common.Hash, index int, err error) error {
+	return &HeaderVerifyError{
+		Index:  index,
+		Number: header.Number.Uint64(),
+		Hash:   hash,
+		Err:    wrapHashedError(header, hash, err),
+	}
+}
+
+// Error implements error.
+func (e *HeaderVerifyError) Error() string {
+	return fmt.Sprintf("batch index %d: %v", e.Index, e.Err)
+}
+
+// Unwrap returns the underlying verification error.
+func (e *HeaderVerifyError) Unwrap() error {
+	return e.Err
+}
+
+// wrapHeaderError wraps err, if any, with the number and hash of the header
+// failing verification, so the offending block is identifiable while errors.Is
+// still matches the underlying sentinel.
+func wrapHeaderError(header *types.Header, err error) error {
+	if err == nil {
+		return nil
+	}
+	return wrapHashedError(header, header.Hash(), err)
+}
+
+// wrapHashedError is wrapHeaderError for a header whose hash is already known.
+func wrapHashedError(header *types.Header, hash common.Hash, err error) error {
+	return fmt.Errorf("header %d (%s): %w", header.Number, hash.TerminalString(), err)
+}
+
+// batchConfig holds the engine settings a batch verification runs with.
+type batchConfig struct {
+	workers      int     // Number of goroutines verifying the batch (0 = GOMAXPROCS)
+	abortOnError bool    // Whether the first failure aborts the rest of the batch
+	metrics      Metrics // Receiver of the verification failures
+
+	progress batchProgress   // Reporting of the batch progress
+	closed   <-chan struct{} // Channel signalling the engine was closed (nil = never)
+	window   int             // Number of headers in flight at once (0 = four per worker)
+}
+
+// verifyHeadersConcurrently runs verify for every index of the batch on a pool
+// of the configured number of workers, one per allowed thread if zero, but
+// never more than there are headers. Headers among the BadHashes fail without
+// running verify. The results are delivered in input order on the returned
+// error channel, failures wrapped into a HeaderVerifyError and reported to the
+// metrics, and the quit channel aborts the operation. With abortOnError set, no
+// more headers are handed out after the first failure and every header after
+// the first failure in input order is reported with ErrAborted. The results
+// channel is closed after the last result, or right away once aborted, so
+// ranging over it always terminates. Progress is reported as results are
+// delivered, if configured.
+func verifyHeadersConcurrently(hashes *headerHashes, config batchConfig, verify func(index int) error) (chan<- struct{}, <-chan error) {
+	return verifyHeadersPipelined(hashes, config, func(index int) (bool, error) { return true, nil }, verify)
+}
+
+// verifyHeadersPipelined is verifyHeadersConcurrently split into two stages.
+// The first stage runs cheap for every header sequentially in input order,
+// rejecting bad headers right away. Cheap reports whether the header still
+// needs its seal verified, and only those headers are fanned out to the pool
+// of workers running seal. Both stages are torn down by the quit channel, or
+// once the first failure is seen with abortOnError set. The results of both
+// stages are merged back into input order.
+//
+// Only a window of headers, four per worker by default, is in flight at any
+// time: the first stage doesn't move past the window until the oldest result
+// in it was read, and the results are reassembled in a ring of the window's
+// size. Memory thus stays proportional to the window, not the batch. A caller
+// abandoning the results channel midway has to close the quit channel, which
+// never blocks on a pending result and releases every goroutine. Closing the
+// engine tears down both stages and reports every header not yet verified with
+// errEngineClosed.
+//
+// The hash of every header is memoized in hashes, so the stages and the result
+// reassembly hash each header at most once, shared with the callbacks.
+func verifyHeadersPipelined(hashes *headerHashes, config batchConfig, cheap func(index int) (bool, error), seal func(index int) error) (chan<- struct{}, <-chan error) {
+	headers := hashes.headers
+
+	// Nothing to verify for an empty batch
+	if len(headers) == 0 {
+		abort, results := make(chan struct{}), make(chan error)
+		close(results)
+		return abort, results
+	}
+	// Spawn as many workers as requested, or allowed threads by default
+	workers := config.workers
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	if len(headers) < workers {
+		workers = len(headers)
+	}
+	window := config.window
+	if window <= 0 {
+		window = 4 * workers
+	}
+	if window < workers {
+		window = workers
+	}
+	if len(headers) < window {
+		window = len(headers)
+	}
+	// Create a task channel and spawn the seal verifiers. At most a window of
+	// headers is done but not delivered, so the done channel never blocks.
+	var (
+		inputs = make(chan int)
+		done   = make(chan int, window)
+		slots  = make(chan struct{}, window)
+		halt   = make(chan struct{})
+		errs   = make([]error, window)
+		abort  = make(chan struct{})
+	)
+	for i := 0; i < workers; i++ {
+		go func() {
+			for index := range inputs {
+				errs[index%window] = verifySafely(hashes.hash(index), index, seal)
+				done <- index
+			}
+		}()
+	}
+	// Run the cheap checks in order, handing the headers left to seal to the workers
+	go func() {
+		defer close(inputs)
+
+		for index := range headers {
+			// Wait for the window to have room for the header
+			select {
+			case slots <- struct{}{}:
+			case <-halt:
+				return
+			case <-abort:
+				return
+			case <-config.closed:
+				return
+			}
+			var pending bool
+			errs[index%window] = verifySafely(hashes.hash(index), index, func(index int) (err error) {
+				pending, err = cheap(index)
+				return err
+			})
+			if !pending || errs[index%window] != nil {
+				done <- index
+				continue
+			}
+			select {
+			case inputs <- index:
+			case <-halt:
+				return
+			case <-abort:
+				return
+			case <-config.closed:
+				return
+			}
+		}
+	}()
+
+	errorsOut := make(chan error, workers)
+	go func() {
+		defer close(errorsOut)
+
+		var halted bool
+		stop := func() {
+			if !halted {
+				halted = true
+				close(halt)
+			}
+		}
+		defer stop()
+
+		progress := newProgressTracker(config.progress, len(headers))
+		defer progress.finish()
+
+		// deliver hands a result to the consumer, unless the batch is aborted first
+		deliver := func(err error) bool {
+			select {
+			case errorsOut <- err:
+				return true
+			case <-abort:
+				return false
+			}
+		}
+		var (
+			out     = 0
+			checked = make([]bool, window)
+		)
+		for {
+			select {
+			case index := <-done:
+				slot := index % window
+				errs[slot] = observeFailure(config.metrics, newHeaderVerifyError(hashes, index, errs[slot]))
+
+				// Stop handing out headers once any of them failed, if requested
+				if config.abortOnError && errs[slot] != nil {
+					stop()
+				}
+				for checked[slot] = true; checked[out%window]; out++ {
+					err := errs[out%window]
+					checked[out%window], errs[out%window] = false, nil
+					if !deliver(err) {
+						return
+					}
+					<-slots
+					progress.update(out + 1)
+
+					if out == len(headers)-1 {
+						return
+					}
+					if config.abortOnError && err != nil {
+						for out++; out < len(headers); out++ {
+							if !deliver(newHeaderVerifyError(hashes, out, ErrAborted)) {
+								return
+							}
+						}
+						return
+					}
+				}
+			case <-abort:
+				return
+
+			case <-config.closed:
+				// Headers past the window weren't even checked, nor in the ring
+				for inflight := out + window; out < len(headers); out++ {
+					err := newHeaderVerifyError(hashes, out, errEngineClosed)
+					if out < inflight && checked[out%window] {
+						err = errs[out%window]
+					}
+					if !deliver(err) {
+						return
+					}
+				}
+				return
+			}
+		}
+	}()
+	return abort, errorsOut
+}
+
+// Errors returned for malformed batches before any header is verified.
+var (
+	errUnorderedBatch   = errors.New("batch headers not consecutive")
+	errNilBatchHeader   = errors.New("nil header in batch")
+	errSealsLenMismatch = errors.New("seals length mismatches headers")
+)
+
+// validateBatch checks the shape of a batch before any header is verified: a
+// seal flag must be given for every header, no header may be nil, and the
+// numbers have to be consecutive. A batch is ascending as imported forward, or
+// descending as handed out by a backwards sync, newest first, decided by its
+// first two headers. It returns whether the batch is descending.
+func validateBatch(headers []*types.Header, seals []bool) (bool, error) {
+	if err := validateBatchEntries(headers, seals); err != nil {
+		return false, err
+	}
+	if len(headers) < 2 {
+		return false, nil
+	}
+	step := common.Big1
+	descending := headers[0].Number.Cmp(headers[1].Number) > 0
+	if descending {
+		step = big.NewInt(-1)
+	}
+	for i := 1; i < len(headers); i++ {
+		if want := new(big.Int).Add(headers[i-1].Number, step); headers[i].Number.Cmp(want) != 0 {
+			return false, fmt.Errorf("%w: index %d is #%v, want #%v", errUnorderedBatch, i, headers[i].Number, want)
+		}
+	}
+	return descending, nil
+}
+
+// ValidateBatch checks the shape of a batch handed to VerifyHeaders before any
+// header is verified, so engines outside this package can reject a malformed
+// batch as a whole instead of failing, or panicking, on its headers one by one.
+// It returns whether the batch is descending.
+// metoda 'validate batch' akan mengecek bentuk batch sebelum header apapun diverifikasi, dan mengembalikan apakah
+// batch tersebut menurun.
+func ValidateBatch(headers []*types.Header, seals []bool) (bool, error) {
+	return validateBatch(headers, seals)
+}
+
+// validateBatchEntries checks the entries of a batch regardless of their order:
+// a seal flag must be given for every header and no header may be nil.
+func validateBatchEntries(headers []*types.Header, seals []bool) error {
+	if len(seals) != len(headers) {
+		return fmt.Errorf("%w: have %d seals for %d headers", errSealsLenMismatch, len(seals), len(headers))
+	}
+	for i, header := range headers {
+		if header == nil || header.Number == nil {
+			return fmt.Errorf("%w: index %d", errNilBatchHeader, i)
+		}
+	}
+	return nil
+}
+
+// failedBatch returns the channels of a batch verification rejected as a whole,
+// delivering the single given error before the results channel is closed.
+func failedBatch(err error) (chan<- struct{}, <-chan error) {
+	abort, results := make(chan struct{}), make(chan error, 1)
+	results <- err
+	close(results)
+	return abort, results
+}
+
+// failRemaining delivers an error for every header of the batch from index
+// start on, used when a run of the batch stopped delivering results early. The
+// headers left are descendants of one that wasn't verified, so they fail with
+// ErrUnknownAncestor. The results channel must have room for all of them.
+func failRemaining(results chan<- error, headers []*types.Header, start int) {
+	for i := start; i < len(headers); i++ {
+		results <- headerVerifyError(headers[i], i, ErrUnknownAncestor)
+	}
+}
+
+// verifySafely runs verify for the header at the given index of a batch,
+// rejecting denylisted headers before doing any work on them. A panic of
+// verify is recovered and returned as the error of the header, so a single bad
+// input can't bring down the process from within a worker goroutine.
+func verifySafely(hash common.Hash, index int, verify func(index int) error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("verification panicked: %v", r)
+		}
+	}()
+	if err := verifyHashNotBanned(hash); err != nil {
+		return err
+	}
+	return verify(index)
+}
+
+// streamTask is a header of a stream handed to a verification worker, along
+// with the header preceding it in the stream and where to deliver the result.
+type streamTask struct {
+	index  int
+	header *types.Header
+	prev   *types.Header
+	result chan error
+}
+
+// verifyHeaderStream runs verify for every header pulled from next, on a pool
+// of the given number of workers, one per allowed thread if zero. The header
+// preceding each one in the stream is passed along, nil for the first header.
+// At most four headers per worker are in flight: once that many results wait
+// for the consumer, the iterator is not called again until the consumer reads
+// on. Results are delivered in input order, failures wrapped into a
+// HeaderVerifyError and reported to the metrics, and the results channel is
+// closed after the last one. A consumer abandoning the results midway has to
+// close the quit channel, which stops pulling headers and releases every
+// goroutine.
+func verifyHeaderStream(next func() (*types.Header, bool), workers int, metrics Metrics, verify func(header, prev *types.Header) error) (chan<- struct{}, <-chan error) {
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	var (
+		window = 4 * workers
+		tasks  = make(chan streamTask)
+		queue  = make(chan chan error, window)
+		out    = make(chan error)
+		abort  = make(chan struct{})
+	)
+	for i := 0; i < workers; i++ {
+		go func() {
+			for task := range tasks {
+				err := verifySafely(task.header.Hash(), task.index, func(int) error {
+					return verify(task.header, task.prev)
+				})
+				task.result <- observeFailure(metrics, headerVerifyError(task.header, task.index, err))
+			}
+		}()
+	}
+	// Pull headers while there is room in the window, queueing their results
+	go func() {
+		defer close(tasks)
+		defer close(queue)
+
+		var prev *types.Header
+		for index := 0; ; index++ {
+			result := make(chan error, 1)
+			select {
+			case queue <- result: // Blocks while the window is full
+			case <-abort:
+				return
+			}
+			header, ok := next()
+			if !ok {
+				close(result)
+				return
+			}
+			if header == nil {
+				result <- fmt.Errorf("%w: index %d", errNilBatchHeader, index)
+				continue
+			}
+			select {
+			case tasks <- streamTask{index: index, header: header, prev: prev, result: result}:
+			case <-abort:
+				return
+			}
+			prev = header
+		}
+	}()
+	// Deliver the results in input order, freeing up the window as they're read
+	go func() {
+		defer close(out)
+
+		for result := range queue {
+			var (
+				err error
+				ok  bool
+			)
+			select {
+			case err, ok = <-result:
+				if !ok {
+					return
+				}
+			case <-abort:
+				return
+			}
+			select {
+			case out <- err:
+			case <-abort:
+				return
+			}
+		}
+	}()
+	return abort, out
+}
+
+// batchLinks reports for every header of a batch whether its parent is its
+// older neighbour in the batch: the preceding header of an ascending batch, or
+// the following header of a descending one. The hashes are memoized for the
+// later stages of the verification.
+func batchLinks(hashes *headerHashes, descending bool) []bool {
+	links := make([]bool, len(hashes.headers))
+	for i := 1; i < len(hashes.headers); i++ {
+		older, newer := i-1, i
+		if descending {
+			older, newer = i, i-1
+		}
+		links[newer] = hashes.hash(older) == hashes.headers[newer].ParentHash
+	}
+	return links
+}
+
+// batchOlder returns the index of the older neighbour of a header in a batch.
+func batchOlder(index int, descending bool) int {
+	if descending {
+		return index + 1
+	}
+	return index - 1
+}
+
+// batchParent returns the parent of the header at the given index of a batch.
+// A header linked to its older neighbour takes the neighbour as its parent, so
+// only the oldest header and those after a discontinuity, e.g. of a side branch,
+// have their parent looked up in the chain. Nil is returned if the parent is
+// unknown.
+func batchParent(chain ChainHeaderReader, headers []*types.Header, links []bool, index int, descending bool) *types.Header {
+	if links[index] {
+		return headers[batchOlder(index, descending)]
+	}
+	return chain.GetHeader(headers[index].ParentHash, headers[index].Number.Uint64()-1)
+}
+
+// batchAncestors returns the headers of a batch the one at the given index
+// descends from, up to the nearest discontinuity, in ascending order whatever
+// the order of the batch.
+func batchAncestors(headers []*types.Header, links []bool, index int, descending bool) []*types.Header {
+	oldest := index
+	for links[oldest] {
+		oldest = batchOlder(oldest, descending)
+	}
+	if !descending {
+		return headers[oldest:index]
+	}
+	ancestors := make([]*types.Header, 0, oldest-index)
+	for i := oldest; i > index; i-- {
+		ancestors = append(ancestors, headers[i])
+	}
+	return ancestors
+}
+
+// HeaderVerifyResult is the outcome of verifying a single header of a batch,
+// identifying the header it refers to, so results can be consumed in any order.
+// HeaderVerifyResult adalah hasil verifikasi satu header dari batch, berisi identitas header yang dimaksud.
+type HeaderVerifyResult struct {
+	Index int         // Position of the header within the verified batch
+	Hash  common.Hash // Hash of the verified header
+	Err   error       // Reason the header failed verification, nil if it passed
+}
+
+// VerifyHeadersTyped verifies a batch of headers through the engine's
+// VerifyHeaders, delivering a HeaderVerifyResult for every header instead of a
+// bare error, in input order. If the engine rejects the batch as a whole, every
+// header is reported with the batch's error. The quit channel aborts the
+// operation.
+// metoda 'verify headers typed' akan memverifikasi batch header melalui 'verify headers' dari engine, dan
+// mengirimkan hasil bertipe untuk setiap header yang berisi index, hash, dan error.
+func VerifyHeadersTyped(engine Engine, chain ChainHeaderReader, headers []*types.Header, seals []bool) (chan<- struct{}, <-chan HeaderVerifyResult) {
+	abort, results := engine.VerifyHeaders(chain, headers, seals)
+
+	var (
+		quit = make(chan struct{})
+		out  = make(chan HeaderVerifyResult, len(headers))
+	)
+	go func() {
+		defer close(out)
+
+		var last error
+		for i := 0; i < len(headers); i++ {
+			var (
+				err error
+				ok  bool
+			)
+			select {
+			case err, ok = <-results:
+			case <-quit:
+				close(abort)
+				return
+			}
+			if !ok {
+				// The batch was rejected as a whole
+				if last == nil {
+					return
+				}
+				err = last
+			}
+			last = err
+
+			result := HeaderVerifyResult{Index: i, Err: err}
+			if headers[i] != nil {
+				result.Hash = headers[i].Hash()
+			}
+			out <- result
+		}
+	}()
+	return quit, out
+}
+
+// hashedVerifier is implemented by engines able to verify a batch whose header
+// hashes are memoized by the caller, sharing the memo instead of hashing every
+// header of the batch once more.
+type hashedVerifier interface {
+	verifyHeadersHashed(chain ChainHeaderReader, hashes *headerHashes, seals []bool) (chan<- struct{}, <-chan error)
+}
+
+// verifyHeadersContext verifies a batch of headers through the engine's
+// VerifyHeaders, aborting the operation once the context is done. Results are
+// delivered in input order, and every header not verified by the time of the
+// cancellation is reported with the context's error, wrapped into a
+// HeaderVerifyError. If the engine rejects the batch as a whole, its error is
+// delivered once and the results channel closed. The results channel is always
+// closed once every result is delivered, and no goroutine is left behind.
+func verifyHeadersContext(ctx context.Context, engine Engine, chain ChainHeaderReader, headers []*types.Header, seals []bool) <-chan error {
+	var (
+		hashes  = newHeaderHashes(headers)
+		out     = make(chan error, len(headers))
+		abort   chan<- struct{}
+		results <-chan error
+	)
+	// Share the hashes with the engine if it can, so the headers failed by the
+	// context don't need hashing again
+	if verifier, ok := engine.(hashedVerifier); ok {
+		abort, results = verifier.verifyHeadersHashed(chain, hashes, seals)
+	} else {
+		abort, results = engine.VerifyHeaders(chain, headers, seals)
+	}
+	go func() {
+		defer close(out)
+
+		for i := 0; i < len(headers); i++ {
+			select {
+			case err, ok := <-results:
+				if !ok {
+					// The batch was rejected as a whole, nothing left to wait for
+					return
+				}
+				out <- err
+			case <-ctx.Done():
+				close(abort)
+				for ; i < len(headers); i++ {
+					out <- newHeaderVerifyError(hashes, i, ctx.Err())
+				}
+			}
+		}
+	}()
+	return out
+}
+
+// batchChain is a ChainHeaderReader which additionally knows the headers of a
+// batch that have already been verified but are not yet part of the chain.
+type batchChain struct {
+	ChainHeaderReader
+	headers map[common.Hash]*types.Header
+	numbers map[uint64]*types.Header
+}
+
+// newBatchChain creates a header reader overlaying verified batch headers on
+// top of the chain.
+func newBatchChain(chain ChainHeaderReader) *batchChain {
+	return &batchChain{
+		ChainHeaderReader: chain,
+		headers:           make(map[common.Hash]*types.Header),
+		numbers:           make(map[uint64]*types.Header),
+	}
+}
+
+// add makes the given headers visible through the reader. It must not be
+// called concurrently with any lookups.
+func (c *batchChain) add(headers []*types.Header) {
+	for _, header := range headers {
+		c.headers[header.Hash()] = header
+		c.numbers[header.Number.Uint64()] = header
+	}
+}
+
+// addHashed is add for the headers of a batch whose hashes are memoized.
+func (c *batchChain) addHashed(hashes *headerHashes) {
+	for i, header := range hashes.headers {
+		c.headers[hashes.hash(i)] = header
+		c.numbers[header.Number.Uint64()] = header
+	}
+}
+
+// GetHeader retrieves a block header by hash and number, from the batch or the
+// underlying chain.
+func (c *batchChain) GetHeader(hash common.Hash, number uint64) *types.Header {
+	if header, ok := c.headers[hash]; ok && header.Number.Uint64() == number {
+		return header
+	}
+	return c.ChainHeaderReader.GetHeader(hash, number)
+}
+
+// GetHeaderByHash retrieves a block header by hash, from the batch or the
+// underlying chain.
+func (c *batchChain) GetHeaderByHash(hash common.Hash) *types.Header {
+	if header, ok := c.headers[hash]; ok {
+		return header
+	}
+	return c.ChainHeaderReader.GetHeaderByHash(hash)
+}
+
+// GetHeaderByNumber retrieves a block header by number, from the batch or the
+// underlying chain.
+func (c *batchChain) GetHeaderByNumber(number uint64) *types.Header {
+	if header, ok := c.numbers[number]; ok {
+		return header
+	}
+	return c.ChainHeaderReader.GetHeaderByNumber(number)
+}
+
+// VerifyHeadersUnordered is similar to the VerifyHeaders method of the engine,
+// but accepts the headers of the batch in arbitrary order, e.g. as gathered from
+// multiple peers. Before any consensus rule is checked, the parent links within
+// the batch are resolved: every header whose parent is neither in the chain nor
+// in the batch, directly or through other batch headers, fails with
+// ErrUnknownAncestor. The rest are verified by the engine one by one, with the
+// batch headers they descend from visible as if they were in the chain already.
+// Results are delivered in input order. A batch with a nil header or without a
+// seal flag for every header is rejected as a whole with a single error.
+// metoda 'verify headers unordered' sama dengan 'verify headers', namun menerima header batch dengan urutan acak.
+// Header yang parent-nya tidak ada di chain maupun di batch akan gagal dengan ErrUnknownAncestor.
+func VerifyHeadersUnordered(engine Engine, chain ChainHeaderReader, headers []*types.Header, seals []bool) (chan<- struct{}, <-chan error) {
+	if err := validateBatchEntries(headers, seals); err != nil {
+		return failedBatch(err)
+	}
+	// Index the batch and resolve which headers link up to the chain
+	var (
+		indices = make(map[common.Hash]int, len(headers))
+		linked  = make(map[int]bool, len(headers))
+	)
+	for i, header := range headers {
+		indices[header.Hash()] = i
+	}
+	var link func(index int) bool
+	link = func(index int) bool {
+		if ok, done := linked[index]; done {
+			return ok
+		}
+		// Guard against revisiting the header while its ancestry is resolved
+		linked[index] = false
+
+		header := headers[index]
+		number := header.Number.Uint64()
+		if parent, ok := indices[header.ParentHash]; ok && headers[parent].Number.Uint64()+1 == number {
+			linked[index] = link(parent)
+		} else {
+			linked[index] = number > 0 && chain.GetHeader(header.ParentHash, number-1) != nil
+		}
+		return linked[index]
+	}
+	overlay := newBatchChain(chain)
+	for i, header := range headers {
+		if link(i) {
+			overlay.add([]*types.Header{header})
+		}
+	}
+	// Verify every linked header against its ancestry, in input order
+	abort, results := make(chan struct{}), make(chan error, len(headers))
+	go func() {
+		defer close(results)
+
+		for i, header := range headers {
+			select {
+			case <-abort:
+				return
+			default:
+			}
+			var err error
+			if !linked[i] {
+				err = wrapHeaderError(header, ErrUnknownAncestor)
+			} else {
+				err = engine.VerifyHeader(&ancestryChain{overlay, header.Hash()}, header, seals[i])
+			}
+			if err != nil {
+				err = &HeaderVerifyError{Index: i, Number: header.Number.Uint64(), Hash: header.Hash(), Err: err}
+			}
+			results <- err
+		}
+	}()
+	return abort, results
+}
+
+// ancestryChain is a batchChain hiding the batch header being verified, so that
+// the engine doesn't consider it known already and skip its verification.
+type ancestryChain struct {
+	*batchChain
+	self common.Hash
+}
+
+// GetHeader retrieves a block header by hash and number, unless it's the header
+// being verified.
+func (c *ancestryChain) GetHeader(hash common.Hash, number uint64) *types.Header {
+	if hash == c.self {
+		return c.ChainHeaderReader.GetHeader(hash, number)
+	}
+	return c.batchChain.GetHeader(hash, number)
+}
+
+// GetHeaderByHash retrieves a block header by hash, unless it's the header
+// being verified.
+func (c *ancestryChain) GetHeaderByHash(hash common.Hash) *types.Header {
+	if hash == c.self {
+		return c.ChainHeaderReader.GetHeaderByHash(hash)
+	}
+	return c.batchChain.GetHeaderByHash(hash)
+}
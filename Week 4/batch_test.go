@@ -0,0 +1,1137 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package consensus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"math/rand"
+	"reflect"
+	"runtime"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that a batch verified under a context delivers one result per header in
+// input order, and that once the context is done every header not verified by
+// then reports the context's error.
+func TestVerifyHeadersContext(t *testing.T) {
+	var (
+		engine  = NewFaker()
+		genesis = testGenesis(1)
+		chain   = newTestChain(frontierConfig, genesis)
+		headers = makeHeaders(engine, chain, genesis, 64, 1)
+		seals   = make([]bool, len(headers))
+	)
+	// A live context verifies every header
+	var verified int
+	for err := range engine.VerifyHeadersContext(context.Background(), chain, headers, seals) {
+		if err != nil {
+			t.Fatalf("header %d: verification failed: %v", verified, err)
+		}
+		verified++
+	}
+	if verified != len(headers) {
+		t.Fatalf("result count mismatch: have %d, want %d", verified, len(headers))
+	}
+	// An expired context leaves the tail of the batch unverified
+	ctx, cancel := context.WithTimeout(context.Background(), time.Nanosecond)
+	defer cancel()
+	<-ctx.Done()
+
+	var (
+		results int
+		expired bool
+	)
+	for err := range engine.VerifyHeadersContext(ctx, chain, headers, seals) {
+		var verr *HeaderVerifyError
+		switch {
+		case err == nil && expired:
+			t.Fatalf("header %d: verified after the context expired", results)
+		case err != nil && (!errors.Is(err, context.DeadlineExceeded) || !errors.As(err, &verr) || verr.Index != results):
+			t.Fatalf("header %d: error mismatch: have %v, want %v", results, err, context.DeadlineExceeded)
+		}
+		expired = expired || err != nil
+		results++
+	}
+	if results != len(headers) {
+		t.Fatalf("result count mismatch: have %d, want %d", results, len(headers))
+	}
+}
+
+// Tests that a failure on the results channel of a batch identifies the index,
+// number and hash of the offending header, while successes are reported as nil.
+func TestHeaderVerifyError(t *testing.T) {
+	genesis := testGenesis(params.MinimumDifficulty.Int64())
+
+	// A fake engine failing a header in the middle of the batch
+	failer := NewFakeFailer(5)
+	headers := makeHeaders(failer, newTestChain(frontierConfig, genesis), genesis, 10, 1)
+	checkHeaderVerifyErrors(t, failer, newTestChain(frontierConfig, genesis), headers, 4, errInvalidPoW)
+
+	// A real engine failing the header rules of the batch tip
+	engine := NewPoW()
+	headers = makeHeaders(engine, newTestChain(frontierConfig, genesis), genesis, 10, 1)
+	headers[9].Difficulty = new(big.Int).Add(headers[9].Difficulty, common.Big1)
+	checkHeaderVerifyErrors(t, engine, newTestChain(frontierConfig, genesis), headers, 9, ErrInvalidDifficulty)
+}
+
+// Tests that a banned header in the middle of a batch is rejected with
+// ErrBannedHash, while the headers after it are still verified on their own.
+func TestVerifyHeadersBanned(t *testing.T) {
+	var (
+		genesis = testGenesis(params.MinimumDifficulty.Int64())
+		headers = makeHeaders(NewPoW(), newTestChain(frontierConfig, genesis), genesis, 5, 1)
+	)
+	BanHash(headers[2].Hash())
+	defer func() {
+		badHashesLock.Lock()
+		delete(BadHashes, headers[2].Hash())
+		badHashesLock.Unlock()
+	}()
+	engines := map[string]Engine{
+		"pow":    NewPoW(),
+		"beacon": NewBeacon(NewPoW()),
+	}
+	for name, engine := range engines {
+		t.Run(name, func(t *testing.T) {
+			checkHeaderVerifyErrors(t, engine, newTestChain(frontierConfig, genesis), headers, 2, ErrBannedHash)
+		})
+	}
+	// A header breaking the rules past the banned one is still caught
+	tampered := append([]*types.Header{}, headers...)
+	tampered[4] = types.CopyHeader(headers[4])
+	tampered[4].Difficulty = new(big.Int).Add(tampered[4].Difficulty, common.Big1)
+
+	abort, results := NewPoW().VerifyHeaders(newTestChain(frontierConfig, genesis), tampered, make([]bool, len(tampered)))
+	defer close(abort)
+
+	want := []error{nil, nil, ErrBannedHash, nil, ErrInvalidDifficulty}
+	for i := range tampered {
+		if err := <-results; !errors.Is(err, want[i]) {
+			t.Errorf("header %d: error mismatch: have %v, want %v", i, err, want[i])
+		}
+	}
+}
+
+// checkHeaderVerifyErrors verifies the batch without seals and checks that only
+// the header at index fails, with the given error and its identity attached.
+func checkHeaderVerifyErrors(t *testing.T, engine Engine, chain ChainHeaderReader, headers []*types.Header, index int, want error) {
+	t.Helper()
+
+	abort, results := engine.VerifyHeaders(chain, headers, make([]bool, len(headers)))
+	defer close(abort)
+
+	for i, header := range headers {
+		err := <-results
+		if i != index {
+			if err != nil {
+				t.Errorf("header %d: verification failed: %v", i, err)
+			}
+			continue
+		}
+		var verr *HeaderVerifyError
+		if !errors.As(err, &verr) {
+			t.Fatalf("header %d: error type mismatch: have %T, want %T", i, err, verr)
+		}
+		if verr.Index != i || verr.Number != header.Number.Uint64() || verr.Hash != header.Hash() {
+			t.Errorf("header %d: identity mismatch: have index %d number %d hash %x, want index %d number %d hash %x",
+				i, verr.Index, verr.Number, verr.Hash, i, header.Number, header.Hash())
+		}
+		if !errors.Is(err, want) {
+			t.Errorf("header %d: error mismatch: have %v, want %v", i, err, want)
+		}
+		if prefix := fmt.Sprintf("batch index %d: header %d ", i, header.Number); !strings.HasPrefix(err.Error(), prefix) {
+			t.Errorf("header %d: error message %q missing prefix %q", i, err, prefix)
+		}
+	}
+}
+
+// Tests that every engine wraps a single header's verification error with the
+// header's number and hash, keeping the sentinel reachable via errors.Is.
+func TestVerifyHeaderErrorContext(t *testing.T) {
+	var (
+		genesis = testGenesis(params.MinimumDifficulty.Int64())
+		chain   = newTestChain(frontierConfig, genesis)
+	)
+	// A dangling header far from the chain, recognisable by its number
+	header := &types.Header{
+		ParentHash: common.Hash{0x01},
+		UncleHash:  types.EmptyUncleHash,
+		Number:     big.NewInt(1234),
+		Difficulty: big.NewInt(1),
+		GasLimit:   genesis.GasLimit,
+		Time:       1,
+	}
+	engines := map[string]Engine{
+		"pow":    NewPoW(),
+		"dev":    NewDev(0),
+		"beacon": NewBeacon(NewPoW()),
+		"poa":    NewPoA(testCliqueConfig, nil),
+	}
+	for name, engine := range engines {
+		err := engine.VerifyHeader(chain, header, false)
+		if !errors.Is(err, ErrUnknownAncestor) {
+			t.Errorf("%s: error mismatch: have %v, want %v", name, err, ErrUnknownAncestor)
+			continue
+		}
+		var number uint64
+		if _, serr := fmt.Sscanf(err.Error(), "header %d", &number); serr != nil || number != header.Number.Uint64() {
+			t.Errorf("%s: header number not recoverable from %q: have %d, want %d", name, err, number, header.Number)
+		}
+	}
+}
+
+// Tests that cancelling a slow batch midway promptly fails every header still
+// outstanding with context.Canceled, rather than waiting for their verification.
+func TestVerifyHeadersContextCancel(t *testing.T) {
+	const delay = 50 * time.Millisecond
+
+	var (
+		engine  = NewFakeDelayer(delay)
+		genesis = testGenesis(params.MinimumDifficulty.Int64())
+		headers = makeHeaders(engine, newTestChain(frontierConfig, genesis), genesis, 40, 1)
+	)
+	engine.workers = 2 // 20 rounds of delay to get through the whole batch
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	results := engine.VerifyHeadersContext(ctx, newTestChain(frontierConfig, genesis), headers, make([]bool, len(headers)))
+	for i := 0; i < 2; i++ {
+		if err := <-results; err != nil {
+			t.Fatalf("header %d: verification failed: %v", i, err)
+		}
+	}
+	cancel()
+	start := time.Now()
+
+	var canceled int
+	for i := 2; i < len(headers); i++ {
+		err, ok := <-results
+		if !ok {
+			t.Fatalf("results closed after %d headers", i)
+		}
+		switch {
+		case errors.Is(err, context.Canceled):
+			canceled++
+		case err != nil:
+			t.Errorf("header %d: error mismatch: have %v, want %v", i, err, context.Canceled)
+		case canceled > 0:
+			t.Errorf("header %d: verified after an earlier header was canceled", i)
+		}
+	}
+	if elapsed := time.Since(start); elapsed > 5*delay {
+		t.Errorf("remaining headers took %v to fail, want under %v", elapsed, 5*delay)
+	}
+	// Only the headers in flight on the two workers may have completed
+	if canceled < len(headers)-2-2*2 {
+		t.Errorf("canceled headers mismatch: have %d, want at least %d", canceled, len(headers)-6)
+	}
+}
+
+// Tests that an unordered batch verifies whatever order its headers come in,
+// that only headers not linking up to the chain fail, and that malformed
+// batches are rejected as a whole.
+func TestVerifyHeadersUnordered(t *testing.T) {
+	var (
+		engine  = NewPoW()
+		genesis = testGenesis(params.MinimumDifficulty.Int64())
+		headers = makeHeaders(engine, newTestChain(frontierConfig, genesis), genesis, 10, 1)
+	)
+	// A fork whose first header is missing leaves the rest dangling
+	forked := makeHeaders(engine, newTestChain(frontierConfig, genesis), headers[4], 3, 2)[1:]
+
+	batch := append(append([]*types.Header{}, headers...), forked...)
+	rand.New(rand.NewSource(1)).Shuffle(len(batch), func(i, j int) { batch[i], batch[j] = batch[j], batch[i] })
+
+	abort, results := VerifyHeadersUnordered(engine, newTestChain(frontierConfig, genesis), batch, make([]bool, len(batch)))
+	defer close(abort)
+
+	dangling := map[common.Hash]bool{forked[0].Hash(): true, forked[1].Hash(): true}
+	for i, header := range batch {
+		err := <-results
+		if dangling[header.Hash()] {
+			var verr *HeaderVerifyError
+			if !errors.Is(err, ErrUnknownAncestor) || !errors.As(err, &verr) || verr.Index != i {
+				t.Errorf("dangling header %d at index %d: error mismatch: have %v, want %v", header.Number, i, err, ErrUnknownAncestor)
+			}
+		} else if err != nil {
+			t.Errorf("header %d at index %d: verification failed: %v", header.Number, i, err)
+		}
+	}
+	if _, ok := <-results; ok {
+		t.Errorf("results not closed after the batch")
+	}
+	// Malformed batches fail once, before any header is verified
+	for name, tt := range map[string]struct {
+		headers []*types.Header
+		seals   []bool
+		want    error
+	}{
+		"nil header":    {[]*types.Header{headers[1], nil, headers[0]}, make([]bool, 3), errNilBatchHeader},
+		"missing seals": {[]*types.Header{headers[1], headers[0]}, make([]bool, 1), errSealsLenMismatch},
+	} {
+		_, results := VerifyHeadersUnordered(engine, newTestChain(frontierConfig, genesis), tt.headers, tt.seals)
+		if err := <-results; !errors.Is(err, tt.want) {
+			t.Errorf("%s: error mismatch: have %v, want %v", name, err, tt.want)
+		}
+		if _, ok := <-results; ok {
+			t.Errorf("%s: results not closed after the rejection", name)
+		}
+	}
+}
+
+// Tests that the same headers verify alike whether the batch is ascending or
+// descending, that a bad tip is reported at its index in either direction, and
+// that batches in neither order are rejected as a whole.
+func TestVerifyHeadersDescending(t *testing.T) {
+	tests := []struct {
+		name   string
+		engine Engine
+		tamper func(tip, parent *types.Header)
+		want   error
+	}{
+		{"pow", NewPoW(), func(tip, parent *types.Header) { tip.Difficulty = new(big.Int).Add(tip.Difficulty, common.Big1) }, ErrInvalidDifficulty},
+		{"dev", NewDev(0), func(tip, parent *types.Header) { tip.Time = parent.Time - 1 }, errInvalidTimestamp},
+	}
+	for _, tt := range tests {
+		genesis := testGenesis(params.MinimumDifficulty.Int64())
+		genesis.Time = 100
+		headers := makeHeaders(tt.engine, newTestChain(frontierConfig, genesis), genesis, 8, 1)
+
+		reversed := make([]*types.Header, len(headers))
+		for i, header := range headers {
+			reversed[len(headers)-1-i] = header
+		}
+		t.Run(tt.name, func(t *testing.T) {
+			chain := newTestChain(frontierConfig, genesis)
+			checkHeaderVerifyErrors(t, tt.engine, chain, headers, -1, nil)
+			checkHeaderVerifyErrors(t, tt.engine, chain, reversed, -1, nil)
+
+			// A bad tip is the last header ascending, but the first descending
+			tip := types.CopyHeader(headers[len(headers)-1])
+			tt.tamper(tip, headers[len(headers)-2])
+
+			ascending := append(append([]*types.Header{}, headers[:len(headers)-1]...), tip)
+			checkHeaderVerifyErrors(t, tt.engine, chain, ascending, len(headers)-1, tt.want)
+
+			descending := append([]*types.Header{tip}, reversed[1:]...)
+			checkHeaderVerifyErrors(t, tt.engine, chain, descending, 0, tt.want)
+
+			// Swapping two headers in the middle breaks either order
+			mixed := append([]*types.Header{}, headers...)
+			mixed[3], mixed[4] = mixed[4], mixed[3]
+
+			_, results := tt.engine.VerifyHeaders(chain, mixed, make([]bool, len(mixed)))
+			if err := <-results; !errors.Is(err, errUnorderedBatch) {
+				t.Errorf("mixed order error mismatch: have %v, want %v", err, errUnorderedBatch)
+			}
+			if _, ok := <-results; ok {
+				t.Errorf("results not closed after the rejection")
+			}
+		})
+	}
+}
+
+// panickingChain is a chain whose header lookups panic.
+type panickingChain struct {
+	*testChain
+}
+
+func (c panickingChain) GetHeader(hash common.Hash, number uint64) *types.Header {
+	panic("header lookup failed")
+}
+
+// Tests that every engine rejects a batch with missing seals or a nil header
+// with a single error on a closed results channel, and that a panic while
+// verifying a header is reported as the error of that header.
+func TestVerifyHeadersMalformed(t *testing.T) {
+	var (
+		genesis = testGenesis(params.MinimumDifficulty.Int64())
+		headers = makeHeaders(NewPoW(), newTestChain(frontierConfig, genesis), genesis, 3, 1)
+	)
+	engines := map[string]Engine{
+		"pow":    NewPoW(),
+		"dev":    NewDev(0),
+		"beacon": NewBeacon(NewPoW()),
+		"poa":    NewPoA(testCliqueConfig, nil),
+	}
+	tests := map[string]struct {
+		headers []*types.Header
+		seals   []bool
+		want    error
+	}{
+		"short seals": {headers, make([]bool, len(headers)-1), errSealsLenMismatch},
+		"nil header":  {[]*types.Header{headers[0], nil, headers[2]}, make([]bool, 3), errNilBatchHeader},
+	}
+	for name, engine := range engines {
+		for test, tt := range tests {
+			_, results := engine.VerifyHeaders(newTestChain(frontierConfig, genesis), tt.headers, tt.seals)
+			if err := <-results; !errors.Is(err, tt.want) {
+				t.Errorf("%s: %s: error mismatch: have %v, want %v", name, test, err, tt.want)
+			}
+			if _, ok := <-results; ok {
+				t.Errorf("%s: %s: results not closed after the rejection", name, test)
+			}
+		}
+		abort, results := engine.VerifyHeaders(panickingChain{newTestChain(frontierConfig, genesis)}, headers[:1], []bool{false})
+		var verr *HeaderVerifyError
+		if err := <-results; !errors.As(err, &verr) || verr.Index != 0 || !strings.Contains(err.Error(), "panicked") {
+			t.Errorf("%s: panic not reported as the header's error: %v", name, err)
+		}
+		close(abort)
+	}
+}
+
+// Tests that typed results identify their headers well enough to reconstruct the
+// set of failed headers, however the results are consumed.
+func TestVerifyHeadersTyped(t *testing.T) {
+	var (
+		engine  = NewPoW()
+		genesis = testGenesis(params.MinimumDifficulty.Int64())
+		chain   = newTestChain(frontierConfig, genesis)
+		headers []*types.Header
+		bad     = map[common.Hash]int{}
+	)
+	// Break the difficulty of a few headers, keeping their children valid
+	parent := genesis
+	for i := 0; i < 16; i++ {
+		header := &types.Header{
+			ParentHash: parent.Hash(),
+			UncleHash:  types.EmptyUncleHash,
+			Number:     new(big.Int).Add(parent.Number, common.Big1),
+			GasLimit:   parent.GasLimit,
+			Time:       parent.Time + 1,
+		}
+		header.Difficulty = engine.CalcDifficulty(chain, header.Time, parent)
+		if i%5 == 3 {
+			header.Difficulty.Add(header.Difficulty, common.Big1)
+			bad[header.Hash()] = i
+		}
+		headers = append(headers, header)
+		parent = header
+	}
+	abort, results := VerifyHeadersTyped(engine, chain, headers, make([]bool, len(headers)))
+	defer close(abort)
+
+	var collected []HeaderVerifyResult
+	for result := range results {
+		collected = append(collected, result)
+	}
+	if len(collected) != len(headers) {
+		t.Fatalf("result count mismatch: have %d, want %d", len(collected), len(headers))
+	}
+	rand.New(rand.NewSource(1)).Shuffle(len(collected), func(i, j int) { collected[i], collected[j] = collected[j], collected[i] })
+
+	failed := make(map[common.Hash]int)
+	for _, result := range collected {
+		if result.Hash != headers[result.Index].Hash() {
+			t.Errorf("result %d: hash mismatch: have %x, want %x", result.Index, result.Hash, headers[result.Index].Hash())
+		}
+		if result.Err != nil {
+			if !errors.Is(result.Err, ErrInvalidDifficulty) {
+				t.Errorf("result %d: error mismatch: have %v, want %v", result.Index, result.Err, ErrInvalidDifficulty)
+			}
+			failed[result.Hash] = result.Index
+		}
+	}
+	if !reflect.DeepEqual(failed, bad) {
+		t.Errorf("failure set mismatch: have %v, want %v", failed, bad)
+	}
+}
+
+// Tests that a 10ms deadline against a 10k header batch closes the results
+// sooner than verifying the whole batch takes, with every header past the
+// deadline failing with its error, and that no goroutine outlives the batch.
+func TestVerifyHeadersContextDeadline(t *testing.T) {
+	var (
+		engine  = NewPoW()
+		genesis = testGenesis(params.MinimumDifficulty.Int64())
+		headers = makeHeaders(engine, newTestChain(frontierConfig, genesis), genesis, 10_000, 10)
+		before  = runtime.NumGoroutine()
+	)
+	// Time a full verification on a fresh engine, nothing cached
+	start := time.Now()
+	for err := range NewPoW().VerifyHeadersContext(context.Background(), newTestChain(frontierConfig, genesis), headers, make([]bool, len(headers))) {
+		if err != nil {
+			t.Fatalf("verification failed: %v", err)
+		}
+	}
+	full := time.Since(start)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	start = time.Now()
+	var (
+		results int
+		expired int
+	)
+	for err := range engine.VerifyHeadersContext(ctx, newTestChain(frontierConfig, genesis), headers, make([]bool, len(headers))) {
+		switch {
+		case errors.Is(err, context.DeadlineExceeded):
+			expired++
+		case err != nil:
+			t.Fatalf("header %d: verification failed: %v", results, err)
+		}
+		results++
+	}
+	if elapsed := time.Since(start); elapsed >= full {
+		t.Errorf("batch took %v past the deadline, want under the %v of a full verification", elapsed, full)
+	}
+	if results != len(headers) {
+		t.Errorf("result count mismatch: have %d, want %d", results, len(headers))
+	}
+	if expired == 0 {
+		t.Errorf("no header failed with the deadline")
+	}
+	waitGoroutines(t, before)
+}
+
+// Tests that every engine offers VerifyHeadersWithContext, and that a 10ms
+// deadline against a 10k header batch still delivers a result for every header
+// and closes the results channel, with no goroutine outliving the batch.
+func TestVerifyHeadersWithContext(t *testing.T) {
+	key, _ := newTestKey(t)
+	var (
+		genesis = testGenesis(params.MinimumDifficulty.Int64())
+		headers = makeHeaders(NewPoW(), newTestChain(frontierConfig, genesis), genesis, 10_000, 10)
+		signed  = makeSignedHeaders(t, key, 10_000)
+		seals   = make([]bool, 10_000)
+	)
+	switched, err := NewSwitchEngine(EngineSwitch{Block: 0, Engine: NewPoW()}, EngineSwitch{Block: 5000, Engine: NewPoW()})
+	if err != nil {
+		t.Fatalf("failed to create switch engine: %v", err)
+	}
+	type contextVerifier interface {
+		VerifyHeadersWithContext(ctx context.Context, chain ChainHeaderReader, headers []*types.Header, seals []bool) <-chan error
+	}
+	tests := []struct {
+		name    string
+		engine  Engine
+		headers []*types.Header
+	}{
+		{"pow", NewPoW(), headers},
+		{"dev", NewDev(0), headers},
+		{"beacon", NewBeacon(NewPoW()), headers},
+		{"switch", switched, headers},
+		{"logging", WrapWithLogging(NewPoW(), log.Root()), headers},
+		{"poa", NewPoA(testCliqueConfig, nil), signed},
+	}
+	for _, tt := range tests {
+		engine, ok := tt.engine.(contextVerifier)
+		if !ok {
+			t.Errorf("%s: no VerifyHeadersWithContext", tt.name)
+			continue
+		}
+		before := runtime.NumGoroutine()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		var results int
+		for err := range engine.VerifyHeadersWithContext(ctx, newTestChain(frontierConfig, genesis), tt.headers, seals) {
+			if err != nil && !errors.Is(err, context.DeadlineExceeded) {
+				t.Fatalf("%s, header %d: verification failed: %v", tt.name, results, err)
+			}
+			results++
+		}
+		cancel()
+
+		if results != len(tt.headers) {
+			t.Errorf("%s: result count mismatch: have %d, want %d", tt.name, results, len(tt.headers))
+		}
+		waitGoroutines(t, before)
+	}
+}
+
+// concurrencyMeter tracks the number of calls in flight, remembering the most
+// ever seen at once.
+type concurrencyMeter struct {
+	current atomic.Int32
+	peak    atomic.Int32
+}
+
+// enter marks a call in flight for the duration of the delay.
+func (m *concurrencyMeter) enter(delay time.Duration) {
+	n := m.current.Add(1)
+	for {
+		peak := m.peak.Load()
+		if n <= peak || m.peak.CompareAndSwap(peak, n) {
+			break
+		}
+	}
+	time.Sleep(delay)
+	m.current.Add(-1)
+}
+
+// meteredAlgo is the Keccak-256 hash, slowed down and metered for concurrency.
+type meteredAlgo struct {
+	meter *concurrencyMeter
+}
+
+func (a meteredAlgo) Sum(data []byte) []byte {
+	a.meter.enter(time.Millisecond)
+	return Keccak256.Sum(data)
+}
+
+// Tests that batches are verified on at most the configured number of workers,
+// and never on more workers than there are headers.
+func TestVerifyWorkersCap(t *testing.T) {
+	var (
+		genesis = testGenesis(params.MinimumDifficulty.Int64())
+		headers = makeHeaders(NewPoW(), newTestChain(frontierConfig, genesis), genesis, 16, 1)
+	)
+	tests := []struct {
+		workers int
+		headers int
+		want    int32
+	}{
+		{1, 16, 1},
+		{2, 16, 2},
+		{4, 16, 4},
+		{8, 3, 3},
+	}
+	for _, tt := range tests {
+		meter := new(concurrencyMeter)
+		abort, results := verifyHeadersConcurrently(newHeaderHashes(headers[:tt.headers]), batchConfig{workers: tt.workers, metrics: NoopMetrics{}}, func(int) error {
+			meter.enter(5 * time.Millisecond)
+			return nil
+		})
+		for range results {
+		}
+		close(abort)
+		if peak := meter.peak.Load(); peak != tt.want {
+			t.Errorf("%d workers, %d headers: peak concurrency mismatch: have %d, want %d", tt.workers, tt.headers, peak, tt.want)
+		}
+	}
+	// The engine option reaches the seal verification of a real batch
+	meter := new(concurrencyMeter)
+	engine := NewTester(WithHashAlgo(meteredAlgo{meter}), WithVerifyWorkers(2))
+	seals := make([]bool, len(headers))
+	for i := range seals {
+		seals[i] = true
+	}
+	abort, results := engine.VerifyHeaders(newTestChain(frontierConfig, genesis), headers, seals)
+	for range results {
+	}
+	close(abort)
+	if peak := meter.peak.Load(); peak != 2 {
+		t.Errorf("engine peak concurrency mismatch: have %d, want 2", peak)
+	}
+	if workers := NewPoA(testCliqueConfig, nil, WithPoAVerifyWorkers(3)).workers; workers != 3 {
+		t.Errorf("poa workers mismatch: have %d, want 3", workers)
+	}
+}
+
+// countingAlgo is trapdoorAlgo, counting the number of hashes computed.
+type countingAlgo struct {
+	count *atomic.Int32
+}
+
+func (a countingAlgo) Sum(data []byte) []byte {
+	a.count.Add(1)
+	return trapdoorAlgo{}.Sum(data)
+}
+
+// makeTrapdoorHeaders creates a frontier chain of n headers on top of a fresh
+// genesis, all sealed for trapdoorAlgo except the one at index bad, if any, and
+// returns the genesis along with the headers and their seal flags.
+func makeTrapdoorHeaders(t *testing.T, n int, bad int) (*types.Header, []*types.Header, []bool) {
+	t.Helper()
+
+	var (
+		genesis = testGenesis(params.MinimumDifficulty.Int64())
+		full    = newTestChain(frontierConfig, genesis)
+		headers = make([]*types.Header, n)
+		seals   = make([]bool, n)
+		parent  = genesis
+	)
+	for i := range headers {
+		header := &types.Header{
+			ParentHash: parent.Hash(),
+			UncleHash:  types.EmptyUncleHash,
+			Number:     new(big.Int).Add(parent.Number, common.Big1),
+			GasLimit:   parent.GasLimit,
+			Time:       parent.Time + 1,
+		}
+		if err := NewPoW().Prepare(full, header); err != nil {
+			t.Fatalf("header %d: failed to prepare: %v", i, err)
+		}
+		header.Nonce = types.EncodeNonce(trapdoorNonce)
+		if i == bad {
+			header.Nonce = types.EncodeNonce(trapdoorNonce + 1)
+		}
+		full.insert(header)
+		headers[i], seals[i], parent = header, true, header
+	}
+	return genesis, headers, seals
+}
+
+// Tests that with abort on error, an invalid header at index 3 of 1000 gives up
+// the batch after roughly 3 headers' worth of work: the remaining headers are
+// reported with ErrAborted without having their seals verified, and the results
+// channel closes. Without the option, every header is still verified.
+func TestVerifyHeadersAbortOnError(t *testing.T) {
+	genesis, headers, seals := makeTrapdoorHeaders(t, 1000, 3)
+
+	for _, abort := range []bool{false, true} {
+		var (
+			count  = new(atomic.Int32)
+			engine = NewPoW(WithHashAlgo(countingAlgo{count}), WithVerifyWorkers(1), WithAbortOnError(abort))
+		)
+		quit, results := engine.VerifyHeaders(newTestChain(frontierConfig, genesis), headers, seals)
+		for i := range headers {
+			err := <-results
+			switch {
+			case i < 3:
+				if err != nil {
+					t.Errorf("abort %v, header %d: verification failed: %v", abort, i, err)
+				}
+			case i == 3:
+				if !errors.Is(err, errInvalidPoW) {
+					t.Errorf("abort %v, header %d: error mismatch: have %v, want %v", abort, i, err, errInvalidPoW)
+				}
+			case abort:
+				if !errors.Is(err, ErrAborted) {
+					t.Fatalf("abort %v, header %d: error mismatch: have %v, want %v", abort, i, err, ErrAborted)
+				}
+			default:
+				if err != nil {
+					t.Fatalf("abort %v, header %d: verification failed: %v", abort, i, err)
+				}
+			}
+		}
+		if _, ok := <-results; ok {
+			t.Errorf("abort %v: results channel not closed", abort)
+		}
+		close(quit)
+
+		// Past the failure, at most the in-flight window of 4 headers was hashed
+		if have := count.Load(); abort && have > 4+4 {
+			t.Errorf("aborted batch hashed %d headers, want at most 8", have)
+		} else if !abort && have != int32(len(headers)) {
+			t.Errorf("full batch hashed %d headers, want %d", have, len(headers))
+		}
+	}
+}
+
+// syntheticStream returns an iterator over n synthetic headers on top of the
+// genesis, built on demand, along with the number of headers pulled so far. The
+// headers aren't linked, so they're only fit for a faking engine.
+func syntheticStream(n int) (func() (*types.Header, bool), *atomic.Int32) {
+	pulled := new(atomic.Int32)
+	return func() (*types.Header, bool) {
+		number := pulled.Load()
+		if int(number) == n {
+			return nil, false
+		}
+		pulled.Add(1)
+		return &types.Header{
+			UncleHash:  types.EmptyUncleHash,
+			Number:     big.NewInt(int64(number) + 1),
+			Difficulty: big.NewInt(1),
+			GasLimit:   params.GenesisGasLimit,
+			Time:       uint64(number) + 1,
+			Extra:      make([]byte, 32),
+		}, true
+	}, pulled
+}
+
+// Tests that streaming 100k headers delivers every result in order with the
+// memory in use staying constant, as only a bounded window is in flight.
+func TestVerifyHeaderStream(t *testing.T) {
+	n := 100000
+	if testing.Short() {
+		n = 10000
+	}
+	var (
+		engine     = NewFaker()
+		chain      = newTestChain(frontierConfig, testGenesis(1))
+		next, _    = syntheticStream(n)
+		stats      runtime.MemStats
+		base, peak uint64
+	)
+	runtime.GC()
+	runtime.ReadMemStats(&stats)
+	base = stats.HeapAlloc
+
+	quit, results := engine.VerifyHeaderStream(chain, next, true)
+	defer close(quit)
+
+	var count int
+	for err := range results {
+		if err != nil {
+			t.Fatalf("header %d: verification failed: %v", count, err)
+		}
+		if count++; count%(n/10) == 0 {
+			runtime.GC()
+			runtime.ReadMemStats(&stats)
+			if stats.HeapAlloc > peak {
+				peak = stats.HeapAlloc
+			}
+		}
+	}
+	if count != n {
+		t.Fatalf("result count mismatch: have %d, want %d", count, n)
+	}
+	// Holding all headers would take tens of megabytes
+	if peak > base && peak-base > 4*1024*1024 {
+		t.Errorf("memory grew while streaming: have %d bytes, want at most 4MiB", peak-base)
+	}
+}
+
+// Tests that a consumer not reading the results stops the iterator from being
+// called once the window is full, and that closing the quit channel midway
+// stops the stream and releases every goroutine.
+func TestVerifyHeaderStreamAbort(t *testing.T) {
+	var (
+		before       = runtime.NumGoroutine()
+		engine       = NewFaker()
+		chain        = newTestChain(frontierConfig, testGenesis(1))
+		next, pulled = syntheticStream(1000)
+	)
+	engine.workers = 2
+	quit, results := engine.VerifyHeaderStream(chain, next, false)
+
+	for i := 0; i < 10; i++ {
+		if err := <-results; err != nil {
+			t.Fatalf("header %d: verification failed: %v", i, err)
+		}
+	}
+	// With the consumer stalled, no more than the window beyond the read results
+	// may be pulled: 4 per worker queued up and one being delivered
+	time.Sleep(50 * time.Millisecond)
+	if have, limit := pulled.Load(), int32(10+4*2+1); have > limit {
+		t.Errorf("stalled stream pulled %d headers, want at most %d", have, limit)
+	}
+	stalled := pulled.Load()
+	close(quit)
+	waitGoroutines(t, before)
+
+	if have := pulled.Load(); have > stalled+1 {
+		t.Errorf("aborted stream kept pulling: have %d headers, want at most %d", have, stalled+1)
+	}
+}
+
+// countingChain is a chain counting its header lookups.
+type countingChain struct {
+	*testChain
+	lookups atomic.Int32
+}
+
+func (c *countingChain) GetHeader(hash common.Hash, number uint64) *types.Header {
+	c.lookups.Add(1)
+	return c.testChain.GetHeader(hash, number)
+}
+
+// Tests that a contiguous batch takes the parents of its headers from the batch
+// itself, looking up only the parent of the first header in the chain, while a
+// discontinuity falls back to the chain once more.
+func TestVerifyHeadersBatchParents(t *testing.T) {
+	var (
+		engine  = NewPoW()
+		genesis = testGenesis(params.MinimumDifficulty.Int64())
+		full    = newTestChain(frontierConfig, genesis)
+		headers = makeHeaders(engine, full, genesis, 64, 1)
+	)
+	// Fork a side branch off the chain at header 32, only known to the chain
+	sibling := types.CopyHeader(headers[31])
+	sibling.Extra = []byte("side")
+	full.insert(sibling)
+	side := makeHeaders(engine, full, sibling, 8, 1)
+
+	tests := []struct {
+		name    string
+		chain   *testChain
+		headers []*types.Header
+		want    int32
+	}{
+		{"contiguous", newTestChain(frontierConfig, genesis), headers, 1},
+		{"side branch", newTestChain(frontierConfig, genesis, sibling), append(append([]*types.Header{}, headers[:32]...), side...), 2},
+	}
+	for _, tt := range tests {
+		chain := &countingChain{testChain: tt.chain}
+		abort, results := engine.VerifyHeaders(chain, tt.headers, make([]bool, len(tt.headers)))
+		for i := range tt.headers {
+			if err := <-results; err != nil {
+				t.Errorf("%s: header %d: verification failed: %v", tt.name, i, err)
+			}
+		}
+		close(abort)
+		if have := chain.lookups.Load(); have != tt.want {
+			t.Errorf("%s: header lookup count mismatch: have %d, want %d", tt.name, have, tt.want)
+		}
+	}
+}
+
+// Benchmarks the two-stage pipeline against running the cheap checks and the
+// seal checks on the same pool of workers, over a batch of which every tenth
+// header fails the cheap checks. The seal checks are made to cost a few dozen
+// Keccak-256 hashes.
+func BenchmarkVerifyHeadersPipeline(b *testing.B) {
+	var (
+		genesis = testGenesis(params.MinimumDifficulty.Int64())
+		headers = makeHeaders(NewPoW(), newTestChain(frontierConfig, genesis), genesis, 1000, 1)
+		config  = batchConfig{metrics: NoopMetrics{}}
+	)
+	cheap := func(index int) (bool, error) {
+		if index%10 == 5 {
+			return false, ErrInvalidNumber
+		}
+		return true, nil
+	}
+	seal := func(index int) error {
+		digest := headers[index].ParentHash.Bytes()
+		for i := 0; i < 50; i++ {
+			digest = Keccak256.Sum(digest)
+		}
+		return nil
+	}
+	run := func(b *testing.B, verify func(hashes *headerHashes) (chan<- struct{}, <-chan error)) {
+		for i := 0; i < b.N; i++ {
+			abort, results := verify(newHeaderHashes(headers))
+			for range results {
+			}
+			close(abort)
+		}
+	}
+	b.Run("pooled", func(b *testing.B) {
+		run(b, func(hashes *headerHashes) (chan<- struct{}, <-chan error) {
+			return verifyHeadersConcurrently(hashes, config, func(index int) error {
+				if _, err := cheap(index); err != nil {
+					return err
+				}
+				return seal(index)
+			})
+		})
+	})
+	b.Run("pipelined", func(b *testing.B) {
+		run(b, func(hashes *headerHashes) (chan<- struct{}, <-chan error) {
+			return verifyHeadersPipelined(hashes, config, cheap, seal)
+		})
+	})
+}
+
+// Tests that a consumer abandoning the results midway through a 1000-header
+// batch and closing the quit channel leaks none of the verification goroutines
+// of any engine, and that closing the proof-of-authority engine ends a batch
+// still being read with every remaining header reported.
+func TestVerifyHeadersAbandoned(t *testing.T) {
+	key, _ := newTestKey(t)
+	var (
+		genesis = testGenesis(params.MinimumDifficulty.Int64())
+		headers = makeHeaders(NewPoW(), newTestChain(frontierConfig, genesis), genesis, 1000, 1)
+		signed  = makeSignedHeaders(t, key, 1000)
+		seals   = make([]bool, 1000)
+	)
+	tests := []struct {
+		name    string
+		engine  Engine
+		headers []*types.Header
+	}{
+		{"pow", NewPoW(), headers},
+		{"dev", NewDev(0), headers},
+		{"beacon", NewBeacon(NewPoW()), headers},
+		{"poa", NewPoA(testCliqueConfig, nil), signed},
+	}
+	for _, tt := range tests {
+		before := runtime.NumGoroutine()
+
+		abort, results := tt.engine.VerifyHeaders(newTestChain(frontierConfig, genesis), tt.headers, seals)
+		for i := 0; i < 10; i++ {
+			<-results
+		}
+		close(abort)
+		waitGoroutines(t, before)
+	}
+	// Closing the engine drains the batch without the quit channel
+	engine := NewPoA(testCliqueConfig, nil)
+	before := runtime.NumGoroutine()
+
+	abort, results := engine.VerifyHeaders(newTestChain(frontierConfig, genesis), signed, seals)
+	defer close(abort)
+
+	<-results
+	engine.Close()
+
+	delivered := 1
+	for range results {
+		delivered++
+	}
+	if delivered != len(signed) {
+		t.Errorf("closed engine result count mismatch: have %d, want %d", delivered, len(signed))
+	}
+	waitGoroutines(t, before)
+}
+
+// Tests that aborting batches at random points, while the workers may be in the
+// middle of delivering results, never deadlocks: the results channel of every
+// engine is closed soon after the abort and no goroutine is left behind. Meant
+// to be run with -race.
+func TestVerifyHeadersAbortStress(t *testing.T) {
+	key, _ := newTestKey(t)
+	var (
+		genesis = testGenesis(params.MinimumDifficulty.Int64())
+		headers = makeHeaders(NewPoW(), newTestChain(frontierConfig, genesis), genesis, 64, 1)
+		signed  = makeSignedHeaders(t, key, 64)
+		seals   = make([]bool, 64)
+		rng     = rand.New(rand.NewSource(1))
+	)
+	tests := []struct {
+		name    string
+		engine  Engine
+		headers []*types.Header
+	}{
+		{"pow", NewPoW(WithVerifyWorkers(4)), headers},
+		{"dev", NewDev(0), headers},
+		{"beacon", NewBeacon(NewPoW()), headers},
+		{"poa", NewPoA(testCliqueConfig, nil, WithPoAVerifyWorkers(4)), signed},
+	}
+	for _, tt := range tests {
+		before := runtime.NumGoroutine()
+		for i := 0; i < 100; i++ {
+			chain := newTestChain(frontierConfig, genesis)
+			abort, results := tt.engine.VerifyHeaders(chain, tt.headers, seals)
+			for n := rng.Intn(len(tt.headers)); n > 0; n-- {
+				<-results
+			}
+			close(abort)
+
+			timeout := time.After(5 * time.Second)
+		drain:
+			for {
+				select {
+				case _, ok := <-results:
+					if !ok {
+						break drain
+					}
+				case <-timeout:
+					t.Fatalf("%s: run %d: results not closed after the abort", tt.name, i)
+				}
+			}
+		}
+		waitGoroutines(t, before)
+	}
+}
+
+// Tests that with a consumer stalled, VerifyHeaders verifies no more headers
+// than the configured window ahead of the results delivered, the default being
+// four per worker, while a full read still yields every result in order.
+func TestVerifyWindow(t *testing.T) {
+	genesis, headers, seals := makeTrapdoorHeaders(t, 200, -1)
+
+	for _, window := range []int{0, 1, 3, 16} {
+		var (
+			count  = new(atomic.Int32)
+			engine = NewPoW(WithHashAlgo(countingAlgo{count}), WithVerifyWorkers(2), WithVerifyWindow(window))
+			chain  = newTestChain(frontierConfig, genesis)
+			before = runtime.NumGoroutine()
+		)
+		quit, results := engine.VerifyHeaders(chain, headers, seals)
+		for i := 0; i < 10; i++ {
+			if err := <-results; err != nil {
+				t.Fatalf("window %d, header %d: verification failed: %v", window, i, err)
+			}
+		}
+		// Beyond the read results, the window may be filled and as many results
+		// as there are workers buffered for delivery
+		time.Sleep(50 * time.Millisecond)
+
+		limit := window
+		if limit == 0 {
+			limit = 4 * 2
+		}
+		if limit < 2 {
+			limit = 2
+		}
+		if have, want := count.Load(), int32(10+limit+2); have > want {
+			t.Errorf("window %d: stalled batch hashed %d headers, want at most %d", window, have, want)
+		}
+		for i := 10; i < len(headers); i++ {
+			if err := <-results; err != nil {
+				t.Fatalf("window %d, header %d: verification failed: %v", window, i, err)
+			}
+		}
+		if _, ok := <-results; ok {
+			t.Errorf("window %d: results channel not closed", window)
+		}
+		close(quit)
+		waitGoroutines(t, before)
+
+		if have := count.Load(); have != int32(len(headers)) {
+			t.Errorf("window %d: batch hashed %d headers, want %d", window, have, len(headers))
+		}
+	}
+}
+
+// Tests that verifying a batch of 100k headers keeps the memory in use within a
+// few megabytes on top of the batch itself, as only a window of results is held
+// in flight. The per-header hash memo accounts for about 4.4MB of it.
+func TestVerifyHeadersMemory(t *testing.T) {
+	n := 100000
+	if testing.Short() {
+		n = 10000
+	}
+	var (
+		engine     = NewFaker()
+		chain      = newTestChain(frontierConfig, testGenesis(1))
+		next, _    = syntheticStream(n)
+		headers    = make([]*types.Header, 0, n)
+		stats      runtime.MemStats
+		base, peak uint64
+	)
+	for header, ok := next(); ok; header, ok = next() {
+		headers = append(headers, header)
+	}
+	runtime.GC()
+	runtime.ReadMemStats(&stats)
+	base = stats.HeapAlloc
+
+	quit, results := engine.VerifyHeaders(chain, headers, make([]bool, n))
+	defer close(quit)
+
+	var count int
+	for err := range results {
+		if err != nil {
+			t.Fatalf("header %d: verification failed: %v", count, err)
+		}
+		if count++; count%(n/10) == 0 {
+			runtime.GC()
+			runtime.ReadMemStats(&stats)
+			if stats.HeapAlloc > peak {
+				peak = stats.HeapAlloc
+			}
+		}
+	}
+	if count != n {
+		t.Fatalf("result count mismatch: have %d, want %d", count, n)
+	}
+	runtime.KeepAlive(headers)
+	if peak > base && peak-base > 8*1024*1024 {
+		t.Errorf("memory grew while verifying: have %d bytes, want at most 8MiB", peak-base)
+	}
+	t.Logf("memory in use grew by %d bytes verifying %d headers", peak-base, n)
+}
@@ -0,0 +1,397 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package consensus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+// beaconEngine is a consensus engine combining the legacy engine of a chain
+// with the proof-of-stake rules of the merge. Headers up to the terminal total
+// difficulty are handed to the legacy engine, every header after it has to
+// follow the post-merge rules: zero difficulty, an empty nonce and no uncles,
+// while the mix-digest carries the randomness of the beacon chain. The beacon
+// chain itself is out of reach, so the seal of post-merge headers is left to
+// it and the randomness is taken as given.
+// beaconEngine adalah consensus engine yang menggabungkan engine lama dari chain dengan aturan proof-of-stake
+// setelah merge. Header sampai terminal total difficulty diteruskan ke engine lama, header setelahnya harus
+// mengikuti aturan setelah merge.
+type beaconEngine struct {
+	ethone Engine // Legacy engine verifying the headers up to the merge
+}
+
+// NewBeacon creates a consensus engine delegating to the given legacy engine
+// below the terminal total difficulty and enforcing the post-merge rules above
+// it. Chains without a terminal total difficulty never leave the legacy engine.
+// metoda 'new beacon' akan membuat consensus engine yang meneruskan ke engine lama di bawah terminal total
+// difficulty dan menerapkan aturan setelah merge di atasnya.
+func NewBeacon(ethone Engine) *beaconEngine {
+	return &beaconEngine{ethone: ethone}
+}
+
+// isPoSHeader reports whether the header claims to be past the merge, which
+// post-merge headers announce with their zero difficulty.
+func isPoSHeader(header *types.Header) bool {
+	return header.Difficulty != nil && header.Difficulty.Sign() == 0
+}
+
+// Author implements Engine, returning the fee recipient of post-merge headers
+// and delegating to the legacy engine otherwise.
+// metoda 'author' akan mengembalikan penerima fee dari header setelah merge, atau meneruskan ke engine lama.
+func (b *beaconEngine) Author(header *types.Header) (common.Address, error) {
+	if !isPoSHeader(header) {
+		return b.ethone.Author(header)
+	}
+	return header.Coinbase, nil
+}
+
+// VerifyHeader implements Engine, delegating to the legacy engine unless the
+// parent of the header reached the terminal total difficulty, in which case
+// the post-merge rules are enforced.
+// metoda 'verify header' akan meneruskan ke engine lama, kecuali parent header sudah mencapai terminal total
+// difficulty, sehingga aturan setelah merge yang diterapkan.
+func (b *beaconEngine) VerifyHeader(chain ChainHeaderReader, header *types.Header, seal bool) error {
+	if err := verifyNotBanned(header); err != nil {
+		return wrapHeaderError(header, err)
+	}
+	number := header.Number.Uint64()
+	if number == 0 {
+		return b.ethone.VerifyHeader(chain, header, seal)
+	}
+	parent := chain.GetHeader(header.ParentHash, number-1)
+	if parent == nil {
+		return wrapHeaderError(header, ErrUnknownAncestor)
+	}
+	reached, err := ReachedTTD(chain, parent)
+	if err != nil {
+		return wrapHeaderError(header, err)
+	}
+	if !reached {
+		// A proof-of-stake header can't precede the terminal proof-of-work block
+		if isPoSHeader(header) {
+			return wrapHeaderError(header, ErrInvalidTerminalBlock)
+		}
+		return b.ethone.VerifyHeader(chain, header, seal)
+	}
+	return wrapHeaderError(header, b.verifyHeader(chain, header, parent))
+}
+
+// verifyHeader checks whether a header past the merge conforms to the
+// consensus rules, given its already resolved parent.
+func (b *beaconEngine) verifyHeader(chain ChainHeaderReader, header, parent *types.Header) error {
+	if err := verifyNotBanned(header); err != nil {
+		return err
+	}
+	if err := verifyHeaderBasics(chain, header, parent); err != nil {
+		return err
+	}
+	if uint64(len(header.Extra)) > params.MaximumExtraDataSize {
+		return fmt.Errorf("%w: %d > %d", ErrExtraDataTooLong, len(header.Extra), params.MaximumExtraDataSize)
+	}
+	if header.Time <= parent.Time {
+		return ErrTimestampTooOld
+	}
+	if err := verifyGasLimit(chain.Config(), GasLimitBounds{}, parent, header); err != nil {
+		return err
+	}
+	if err := verifyBaseFee(chain.Config(), parent, header); err != nil {
+		return err
+	}
+	if err := verifyWithdrawals(chain.Config(), header); err != nil {
+		return err
+	}
+	if err := verifyBlobGas(chain.Config(), parent, header); err != nil {
+		return err
+	}
+	if err := verifyParentBeaconRoot(chain.Config(), header); err != nil {
+		return err
+	}
+	return verifyPostMerge(header)
+}
+
+// VerifyHeaders implements Engine, splitting the batch at the merge. The total
+// difficulty is accumulated across the batch from the parent of its oldest
+// header, so headers up to the terminal total difficulty are verified by the
+// legacy engine and every header after it against the post-merge rules, even
+// if none of the batch is part of the chain yet. The results are delivered in
+// input order.
+// metoda 'verify headers' akan membagi batch pada titik merge: header sebelum merge diverifikasi engine lama,
+// header setelahnya dengan aturan setelah merge.
+func (b *beaconEngine) VerifyHeaders(chain ChainHeaderReader, headers []*types.Header, seals []bool) (chan<- struct{}, <-chan error) {
+	descending, err := validateBatch(headers, seals)
+	if err != nil {
+		return failedBatch(err)
+	}
+	split, err := b.splitBatch(chain, headers, descending)
+	if err != nil {
+		return failedBatch(err)
+	}
+	// Hand over to a single ruleset if the batch doesn't cross the merge
+	var (
+		hashes = newHeaderHashes(headers)
+		links  = batchLinks(hashes, descending)
+		verify = func(start, end int) (chan<- struct{}, <-chan error) {
+			return verifyHeadersConcurrently(hashes.slice(start, end), batchConfig{metrics: NoopMetrics{}}, func(index int) error {
+				parent := batchParent(chain, headers, links, start+index, descending)
+				if parent == nil {
+					return ErrUnknownAncestor
+				}
+				return b.verifyHeader(chain, headers[start+index], parent)
+			})
+		}
+	)
+	switch {
+	case split == len(headers) && !descending, split == 0 && descending:
+		return b.ethone.VerifyHeaders(chain, headers, seals)
+	case split == 0 && !descending, split == len(headers) && descending:
+		return verify(0, len(headers))
+	}
+	// The batch crosses the merge, verify both runs and merge their results
+	var (
+		abort   = make(chan struct{})
+		results = make(chan error, len(headers))
+		runs    = []struct {
+			start, end int
+			abort      chan<- struct{}
+			results    <-chan error
+		}{{start: 0, end: split}, {start: split, end: len(headers)}}
+	)
+	for i := range runs {
+		start, end := runs[i].start, runs[i].end
+		if (i == 0) == descending {
+			runs[i].abort, runs[i].results = verify(start, end)
+		} else {
+			runs[i].abort, runs[i].results = b.ethone.VerifyHeaders(chain, headers[start:end], seals[start:end])
+		}
+	}
+	go func() {
+		defer close(results)
+		defer func() {
+			for _, run := range runs {
+				close(run.abort)
+			}
+		}()
+		for _, run := range runs {
+			for i := run.start; i < run.end; i++ {
+				select {
+				case err, ok := <-run.results:
+					if !ok {
+						// The run stopped early, fail the headers it left behind
+						failRemaining(results, headers, i)
+						return
+					}
+					// Translate the failing index from the run to the whole batch
+					var verr *HeaderVerifyError
+					if errors.As(err, &verr) {
+						err = &HeaderVerifyError{Index: verr.Index + run.start, Number: verr.Number, Hash: verr.Hash, Err: verr.Err}
+					}
+					results <- err
+				case <-abort:
+					return
+				}
+			}
+		}
+	}()
+	return abort, results
+}
+
+// splitBatch returns the index splitting a batch into the headers up to the
+// merge and those past it: in an ascending batch the headers before the index
+// precede the merge, in a descending one the headers from the index on do. The
+// total difficulty of the oldest header's parent is retrieved via GetTd.
+func (b *beaconEngine) splitBatch(chain ChainHeaderReader, headers []*types.Header, descending bool) (int, error) {
+	config := chain.Config()
+	if config == nil || config.TerminalTotalDifficulty == nil || len(headers) == 0 {
+		if descending {
+			return 0, nil
+		}
+		return len(headers), nil
+	}
+	// Walk the batch from its oldest header, accumulating the total difficulty
+	oldest, step := 0, 1
+	if descending {
+		oldest, step = len(headers)-1, -1
+	}
+	td := new(big.Int)
+	if number := headers[oldest].Number.Uint64(); number > 0 {
+		parentTd := chain.GetTd(headers[oldest].ParentHash, number-1)
+		if parentTd == nil {
+			return 0, fmt.Errorf("%w: total difficulty of parent %d unknown", ErrUnknownAncestor, number-1)
+		}
+		td.Set(parentTd)
+	}
+	i := oldest
+	for ; i >= 0 && i < len(headers); i += step {
+		if headers[i].Number.Sign() > 0 && td.Cmp(config.TerminalTotalDifficulty) >= 0 {
+			break
+		}
+		if headers[i].Difficulty != nil {
+			td.Add(td, headers[i].Difficulty)
+		}
+	}
+	// The first post-merge header splits an ascending batch, the one after it
+	// in input order a descending one
+	if descending {
+		return i + 1, nil
+	}
+	return i, nil
+}
+
+// VerifyHeadersContext is similar to VerifyHeaders, but aborts verification
+// once the context is cancelled or its deadline elapses. Results are still
+// delivered in input order, headers left unverified report the context error.
+// metoda 'verify headers context' sama dengan 'verify headers', namun verifikasi dibatalkan ketika context dibatalkan atau melewati tenggat waktu.
+func (b *beaconEngine) VerifyHeadersContext(ctx context.Context, chain ChainHeaderReader, headers []*types.Header, seals []bool) <-chan error {
+	return verifyHeadersContext(ctx, b, chain, headers, seals)
+}
+
+// VerifyHeadersWithContext is VerifyHeadersContext, under the name callers built
+// around contexts look for.
+// metoda 'verify headers with context' sama dengan 'verify headers context'.
+func (b *beaconEngine) VerifyHeadersWithContext(ctx context.Context, chain ChainHeaderReader, headers []*types.Header, seals []bool) <-chan error {
+	return verifyHeadersContext(ctx, b, chain, headers, seals)
+}
+
+// VerifyUncles implements Engine, rejecting any uncles past the merge and
+// delegating to the legacy engine otherwise.
+// metoda 'verify uncles' akan menolak uncle setelah merge, atau meneruskan ke engine lama.
+func (b *beaconEngine) VerifyUncles(chain ChainReader, block *types.Block) error {
+	if !isPoSHeader(block.Header()) {
+		return b.ethone.VerifyUncles(chain, block)
+	}
+	if len(block.Uncles()) > 0 {
+		return fmt.Errorf("%w: have %d after the merge, want 0", ErrTooManyUncles, len(block.Uncles()))
+	}
+	return nil
+}
+
+// VerifySeal implements Engine, delegating to the legacy engine before the
+// merge. Post-merge headers are sealed by the beacon chain, which is trusted.
+// metoda 'verify seal' akan meneruskan ke engine lama sebelum merge. Header setelah merge disegel oleh beacon chain.
+func (b *beaconEngine) VerifySeal(chain ChainHeaderReader, header *types.Header) error {
+	if !isPoSHeader(header) {
+		return b.ethone.VerifySeal(chain, header)
+	}
+	return nil
+}
+
+// Prepare implements Engine, zeroing the difficulty and nonce of headers past
+// the merge and delegating to the legacy engine otherwise. The mix-digest is
+// left to the caller, who fills in the randomness of the beacon chain.
+// metoda 'prepare' akan mengosongkan difficulty dan nonce header setelah merge, atau meneruskan ke engine lama.
+func (b *beaconEngine) Prepare(chain ChainHeaderReader, header *types.Header) error {
+	parent := chain.GetHeader(header.ParentHash, header.Number.Uint64()-1)
+	if parent == nil {
+		return ErrUnknownAncestor
+	}
+	reached, err := ReachedTTD(chain, parent)
+	if err != nil {
+		return err
+	}
+	if !reached {
+		return b.ethone.Prepare(chain, header)
+	}
+	header.Difficulty = new(big.Int)
+	header.Nonce = types.BlockNonce{}
+	prepareBaseFee(chain.Config(), parent, header)
+	return nil
+}
+
+// Finalize implements Engine, crediting the withdrawals of headers past the
+// merge, which carry no block rewards, and delegating to the legacy engine
+// otherwise.
+// metoda 'finalize' akan menambahkan withdrawal untuk header setelah merge tanpa block reward, atau meneruskan ke
+// engine lama.
+func (b *beaconEngine) Finalize(chain ChainHeaderReader, header *types.Header, state *state.StateDB, txs []*types.Transaction, uncles []*types.Header, withdrawals []*types.Withdrawal) {
+	if !isPoSHeader(header) {
+		b.ethone.Finalize(chain, header, state, txs, uncles, withdrawals)
+		return
+	}
+	ProcessWithdrawals(state, withdrawals)
+}
+
+// FinalizeAndAssemble implements Engine, finalizing and assembling blocks past
+// the merge and delegating to the legacy engine otherwise.
+// metoda 'finalize and assemble' akan memfinalisasi dan membangun block setelah merge, atau meneruskan ke engine lama.
+func (b *beaconEngine) FinalizeAndAssemble(chain ChainHeaderReader, header *types.Header, state *state.StateDB, txs []*types.Transaction, uncles []*types.Header, receipts []*types.Receipt, withdrawals []*types.Withdrawal) (*types.Block, error) {
+	if !isPoSHeader(header) {
+		return b.ethone.FinalizeAndAssemble(chain, header, state, txs, uncles, receipts, withdrawals)
+	}
+	if len(uncles) > 0 {
+		return nil, fmt.Errorf("%w: have %d after the merge, want 0", ErrTooManyUncles, len(uncles))
+	}
+	if err := assembleWithdrawals(chain.Config(), header, withdrawals); err != nil {
+		return nil, err
+	}
+	b.Finalize(chain, header, state, txs, nil, withdrawals)
+
+	// Assign the final state root to header.
+	header.Root = state.IntermediateRoot(true)
+
+	// Header seems complete, assemble into a block and return
+	return types.NewBlockWithWithdrawals(header, txs, nil, receipts, withdrawals, trie.NewStackTrie(nil)), nil
+}
+
+// Seal implements Engine, delegating to the legacy engine before the merge.
+// Blocks past the merge are sealed by the beacon chain, so nothing is done.
+// metoda 'seal' akan meneruskan ke engine lama sebelum merge. Block setelah merge disegel oleh beacon chain.
+func (b *beaconEngine) Seal(chain ChainHeaderReader, block *types.Block, results chan<- *types.Block, stop <-chan struct{}) error {
+	if !isPoSHeader(block.Header()) {
+		return b.ethone.Seal(chain, block, results, stop)
+	}
+	return nil
+}
+
+// SealHash implements Engine, delegating to the legacy engine.
+// metoda 'seal hash' akan meneruskan ke engine lama.
+func (b *beaconEngine) SealHash(header *types.Header) common.Hash {
+	return b.ethone.SealHash(header)
+}
+
+// CalcDifficulty implements Engine, returning zero for the block following a
+// parent that reached the terminal total difficulty and delegating to the
+// legacy engine otherwise.
+// metoda 'calc difficulty' akan mengembalikan nol untuk block setelah parent yang mencapai terminal total difficulty,
+// atau meneruskan ke engine lama.
+func (b *beaconEngine) CalcDifficulty(chain ChainHeaderReader, time uint64, parent *types.Header) *big.Int {
+	if reached, err := ReachedTTD(chain, parent); err == nil && reached {
+		return new(big.Int)
+	}
+	return b.ethone.CalcDifficulty(chain, time, parent)
+}
+
+// APIs implements Engine, returning the RPC APIs of the legacy engine.
+// metoda 'apis' akan mengembalikan RPC API dari engine lama.
+func (b *beaconEngine) APIs(chain ChainHeaderReader) []rpc.API {
+	return b.ethone.APIs(chain)
+}
+
+// Close implements Engine, closing the legacy engine.
+// metoda 'close' akan menutup engine lama.
+func (b *beaconEngine) Close() error {
+	return b.ethone.Close()
+}
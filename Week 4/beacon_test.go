@@ -0,0 +1,159 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package consensus
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// makeMergeChain creates a chain crossing the merge: pre proof-of-work headers,
+// the last of which reaches the terminal total difficulty, followed by post
+// proof-of-stake headers carrying their randomness in the mix-digest. The
+// returned chain only holds the genesis.
+func makeMergeChain(pre, post int) (*testChain, []*types.Header) {
+	var (
+		genesis = testGenesis(params.MinimumDifficulty.Int64())
+		config  = *frontierConfig
+		full    = newTestChain(&config, genesis)
+		headers = makeHeaders(NewPoW(), full, genesis, pre, 1)
+	)
+	config.TerminalTotalDifficulty = full.GetTd(headers[pre-1].Hash(), uint64(pre))
+
+	parent := headers[pre-1]
+	for i := 0; i < post; i++ {
+		header := &types.Header{
+			ParentHash: parent.Hash(),
+			UncleHash:  types.EmptyUncleHash,
+			Number:     new(big.Int).Add(parent.Number, common.Big1),
+			GasLimit:   parent.GasLimit,
+			Time:       parent.Time + 12,
+			Difficulty: new(big.Int),
+			MixDigest:  common.Hash{byte(i + 1)},
+		}
+		headers, parent = append(headers, header), header
+	}
+	return newTestChain(&config, genesis), headers
+}
+
+// Tests that the beacon engine routes the headers of a chain crossing the merge
+// to the right rules, one by one and batched in either order, and enforces the
+// post-merge rules past it.
+func TestBeaconVerifyMerge(t *testing.T) {
+	engine := NewBeacon(NewPoW())
+
+	chain, headers := makeMergeChain(4, 4)
+	for i, header := range headers {
+		if err := engine.VerifyHeader(chain, header, false); err != nil {
+			t.Fatalf("header %d: verification failed: %v", i, err)
+		}
+		chain.insert(header)
+	}
+	chain, _ = makeMergeChain(4, 4)
+	checkHeaderVerifyErrors(t, engine, chain, headers, -1, nil)
+
+	reversed := make([]*types.Header, len(headers))
+	for i, header := range headers {
+		reversed[len(headers)-1-i] = header
+	}
+	checkHeaderVerifyErrors(t, engine, chain, reversed, -1, nil)
+
+	// A tip breaking either ruleset fails on its own
+	tests := []struct {
+		name  string
+		index int
+		edit  func(*types.Header)
+		want  error
+	}{
+		{"proof-of-stake difficulty", 7, func(h *types.Header) { h.Difficulty = common.Big1 }, errInvalidPoSDifficulty},
+		{"proof-of-stake nonce", 7, func(h *types.Header) { h.Nonce = types.EncodeNonce(1) }, errInvalidPoSNonce},
+		{"proof-of-stake uncles", 7, func(h *types.Header) { h.UncleHash = common.Hash{0x01} }, errInvalidPoSUncleHash},
+	}
+	for _, tt := range tests {
+		tampered := append([]*types.Header{}, headers[:tt.index+1]...)
+		tampered[tt.index] = types.CopyHeader(headers[tt.index])
+		tt.edit(tampered[tt.index])
+
+		t.Run(tt.name, func(t *testing.T) {
+			checkHeaderVerifyErrors(t, engine, chain, tampered, tt.index, tt.want)
+		})
+	}
+	// A proof-of-stake header can't stand in for the terminal block
+	early := types.CopyHeader(headers[3])
+	early.Difficulty = new(big.Int)
+
+	chain.insert(headers[:3]...)
+	if err := engine.VerifyHeader(chain, early, false); !errors.Is(err, ErrInvalidTerminalBlock) {
+		t.Errorf("early proof-of-stake error mismatch: have %v, want %v", err, ErrInvalidTerminalBlock)
+	}
+}
+
+// Tests that a banned proof-of-stake header is rejected by the beacon engine
+// whether verified on its own, in a batch, or with its parent resolved.
+func TestBeaconVerifyBanned(t *testing.T) {
+	engine := NewBeacon(NewPoW())
+	chain, headers := makeMergeChain(2, 2)
+
+	banned := headers[3]
+	BanHash(banned.Hash())
+	defer func() {
+		badHashesLock.Lock()
+		delete(BadHashes, banned.Hash())
+		badHashesLock.Unlock()
+	}()
+	checkHeaderVerifyErrors(t, engine, chain, headers, 3, ErrBannedHash)
+
+	chain.insert(headers[:3]...)
+	if err := engine.VerifyHeader(chain, banned, false); !errors.Is(err, ErrBannedHash) {
+		t.Errorf("single header error mismatch: have %v, want %v", err, ErrBannedHash)
+	}
+	if err := engine.verifyHeader(chain, banned, headers[2]); !errors.Is(err, ErrBannedHash) {
+		t.Errorf("resolved parent error mismatch: have %v, want %v", err, ErrBannedHash)
+	}
+}
+
+// Tests that a batch crossing the merge whose legacy run is rejected as a whole
+// still delivers a result for every header, failing the ones left behind, and
+// closes its results channel.
+func TestBeaconVerifyTruncatedRun(t *testing.T) {
+	engine := NewBeacon(truncatingEngine{NewPoW()})
+	chain, headers := makeMergeChain(4, 2)
+
+	abort, results := engine.VerifyHeaders(chain, headers, make([]bool, len(headers)))
+	defer close(abort)
+
+	for i := range headers {
+		err, ok := <-results
+		if !ok {
+			t.Fatalf("results closed after %d of %d headers", i, len(headers))
+		}
+		if i == 0 && !errors.Is(err, errInvalidPoW) {
+			t.Errorf("header %d: error mismatch: have %v, want %v", i, err, errInvalidPoW)
+		}
+		if i > 0 && !errors.Is(err, ErrUnknownAncestor) {
+			t.Errorf("header %d: error mismatch: have %v, want %v", i, err, ErrUnknownAncestor)
+		}
+	}
+	if _, ok := <-results; ok {
+		t.Errorf("results not closed after the last header")
+	}
+}
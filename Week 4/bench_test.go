@@ -0,0 +1,50 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package consensus_test
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/consensus/consensustest"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Benchmarks the throughput of the batch verification pipeline against the
+// sequential path, verifying 1000 headers costing a millisecond each.
+func BenchmarkVerifyHeadersFakeDelayer(b *testing.B) {
+	consensustest.BenchVerifyHeaders(b, consensus.NewFakeDelayer(time.Millisecond), params.TestChainConfig, 1000, false)
+}
+
+// Benchmarks the header verification throughput of a faking engine, one by one
+// and batched, for batches of up to 10000 headers with seal checks on and off.
+func BenchmarkVerifyHeaders(b *testing.B) {
+	for _, n := range []int{100, 1000, 10000} {
+		for _, seal := range []bool{false, true} {
+			consensustest.BenchVerifyHeaders(b, consensus.NewFaker(), params.TestChainConfig, n, seal)
+		}
+	}
+}
+
+// Benchmarks verifying a 2048-header batch a second time, which the verified
+// cache of the engine answers without checking the headers again.
+func BenchmarkVerifyHeadersCached(b *testing.B) {
+	config := &params.ChainConfig{ChainID: big.NewInt(1)}
+	consensustest.BenchVerifyHeadersCached(b, func() consensus.Engine { return consensus.NewPoW() }, config, 2048, false)
+}
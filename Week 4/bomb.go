@@ -0,0 +1,90 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package consensus
+
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// expDiffPeriod is the number of blocks after which the difficulty bomb doubles.
+var expDiffPeriod = big.NewInt(100000)
+
+// bombDelays lists the number of blocks each fork pushes the difficulty bomb
+// back by, latest fork first.
+var bombDelays = []struct {
+	fork  func(*params.ChainConfig) *big.Int
+	delay uint64
+}{
+	{func(c *params.ChainConfig) *big.Int { return c.GrayGlacierBlock }, 11_400_000},   // EIP-5133
+	{func(c *params.ChainConfig) *big.Int { return c.ArrowGlacierBlock }, 10_700_000},  // EIP-4345
+	{func(c *params.ChainConfig) *big.Int { return c.LondonBlock }, 9_700_000},         // EIP-3554
+	{func(c *params.ChainConfig) *big.Int { return c.MuirGlacierBlock }, 9_000_000},    // EIP-2384
+	{func(c *params.ChainConfig) *big.Int { return c.ConstantinopleBlock }, 5_000_000}, // EIP-1234
+	{func(c *params.ChainConfig) *big.Int { return c.ByzantiumBlock }, 3_000_000},      // EIP-649
+}
+
+// WithDifficultyBomb adds the exponential difficulty bomb to every difficulty
+// from the given block number on. The bomb is pushed back by the delay of the
+// latest fork active in the chain config, as Ethereum's ice age was.
+// metoda 'with difficulty bomb' akan menambahkan difficulty bomb eksponensial mulai dari nomor block tertentu.
+// Bomb ditunda sesuai fork terakhir yang aktif pada konfigurasi chain.
+func WithDifficultyBomb(activation uint64) PoWOption {
+	return func(pow *powEngine) {
+		pow.bomb = &activation
+	}
+}
+
+// BombDelay returns the number of blocks the difficulty bomb is delayed by at
+// the given block number, set by the latest fork active in the chain config.
+// metoda 'bomb delay' akan mengembalikan jumlah block penundaan difficulty bomb pada nomor block tertentu.
+func BombDelay(config *params.ChainConfig, number *big.Int) uint64 {
+	if config == nil {
+		return 0
+	}
+	for _, fork := range bombDelays {
+		if block := fork.fork(config); block != nil && block.Cmp(number) <= 0 {
+			return fork.delay
+		}
+	}
+	return 0
+}
+
+// DifficultyBomb returns the exponential difficulty bomb term for the block at
+// the given number, 2^((number - delay)/100000 - 2), or zero while fewer than
+// two bomb periods have elapsed.
+// metoda 'difficulty bomb' akan mengembalikan komponen difficulty bomb eksponensial untuk block dengan nomor
+// tertentu.
+func DifficultyBomb(number *big.Int, delay uint64) *big.Int {
+	// Calculate the fake block number the bomb is based on
+	fakeBlockNumber := new(big.Int).Sub(number, new(big.Int).SetUint64(delay))
+	if fakeBlockNumber.Sign() < 0 {
+		return new(big.Int)
+	}
+	// for the exponential factor
+	periodCount := fakeBlockNumber.Div(fakeBlockNumber, expDiffPeriod)
+
+	// the exponential factor, commonly referred to as "the bomb"
+	// diff = diff + 2^(periodCount - 2)
+	if periodCount.Cmp(common.Big1) > 0 {
+		y := new(big.Int).Sub(periodCount, common.Big2)
+		return y.Exp(common.Big2, y, nil)
+	}
+	return new(big.Int)
+}
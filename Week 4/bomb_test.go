@@ -0,0 +1,118 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package consensus
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+func TestDifficultyBomb(t *testing.T) {
+	tests := []struct {
+		number uint64
+		delay  uint64
+		want   int64
+	}{
+		{0, 0, 0},
+		{199_999, 0, 0},
+		{200_000, 0, 1},
+		{300_000, 0, 2},
+		{1_000_000, 0, 256},
+		{1_000_000, 3_000_000, 0},
+		{3_200_000, 3_000_000, 1},
+		{4_000_000, 3_000_000, 256},
+	}
+	for _, tt := range tests {
+		if have := DifficultyBomb(new(big.Int).SetUint64(tt.number), tt.delay); have.Cmp(big.NewInt(tt.want)) != 0 {
+			t.Errorf("block %d delay %d: bomb mismatch: have %v, want %v", tt.number, tt.delay, have, tt.want)
+		}
+	}
+}
+
+func TestBombDelay(t *testing.T) {
+	config := &params.ChainConfig{
+		ChainID:        big.NewInt(1),
+		ByzantiumBlock: big.NewInt(100),
+		LondonBlock:    big.NewInt(200),
+	}
+	tests := []struct {
+		number uint64
+		want   uint64
+	}{
+		{99, 0},
+		{100, 3_000_000},
+		{199, 3_000_000},
+		{200, 9_700_000},
+	}
+	for _, tt := range tests {
+		if have := BombDelay(config, new(big.Int).SetUint64(tt.number)); have != tt.want {
+			t.Errorf("block %d: delay mismatch: have %d, want %d", tt.number, have, tt.want)
+		}
+	}
+	if have := BombDelay(nil, big.NewInt(1000)); have != 0 {
+		t.Errorf("nil config: delay mismatch: have %d, want 0", have)
+	}
+}
+
+// Tests that the difficulty bomb doubles every period once active, and that a
+// fork delaying it brings the difficulty back down.
+func TestCalcDifficultyBomb(t *testing.T) {
+	config := &params.ChainConfig{ChainID: big.NewInt(1), ByzantiumBlock: big.NewInt(4_000_000)}
+	var (
+		plain = NewPoW()
+		bomb  = NewPoW(WithDifficultyBomb(0))
+		chain = newTestChain(config)
+	)
+	// parent creates the parent of the given block at a fixed difficulty
+	parent := func(number uint64) *types.Header {
+		return &types.Header{
+			Number:     new(big.Int).SetUint64(number - 1),
+			Difficulty: big.NewInt(1_000_000_000_000),
+			UncleHash:  types.EmptyUncleHash,
+			Time:       1_000_000,
+		}
+	}
+	// Before the fork the bomb term is all that separates the two engines
+	var last *big.Int
+	for number := uint64(200_000); number < 4_000_000; number += 100_000 {
+		header := parent(number)
+		term := new(big.Int).Sub(bomb.CalcDifficulty(chain, header.Time+10, header), plain.CalcDifficulty(chain, header.Time+10, header))
+		if want := DifficultyBomb(new(big.Int).SetUint64(number), 0); term.Cmp(want) != 0 {
+			t.Fatalf("block %d: bomb term mismatch: have %v, want %v", number, term, want)
+		}
+		if last != nil && term.Cmp(new(big.Int).Lsh(last, 1)) != 0 {
+			t.Fatalf("block %d: bomb term %v not double the previous %v", number, term, last)
+		}
+		last = term
+	}
+	// The fork delays the bomb, dropping the difficulty by far more than a
+	// regular adjustment could
+	before := parent(3_999_999)
+	after := parent(4_000_000)
+	prefork := bomb.CalcDifficulty(chain, before.Time+10, before)
+	postfork := bomb.CalcDifficulty(chain, after.Time+10, after)
+	if drop := new(big.Int).Sub(prefork, postfork); drop.Cmp(new(big.Int).Rsh(last, 1)) < 0 {
+		t.Errorf("delay didn't defuse the bomb: difficulty %v before the fork, %v after", prefork, postfork)
+	}
+	// The Byzantium rules carry the delayed bomb whether the option is set or not
+	if plainfork := plain.CalcDifficulty(chain, after.Time+10, after); postfork.Cmp(plainfork) != 0 {
+		t.Errorf("post-fork difficulty mismatch: have %v, want %v", postfork, plainfork)
+	}
+}
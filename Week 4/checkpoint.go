@@ -0,0 +1,82 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package consensus
+
+import (
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Checkpoint is a trusted block of the canonical chain. The seals of the block
+// and all of its ancestors are implied by its hash, so there's no need to verify
+// them again.
+// Checkpoint adalah block terpercaya dari chain kanonik. Segel block tersebut dan semua ancestor-nya sudah dijamin
+// oleh hash-nya, sehingga tidak perlu diverifikasi lagi.
+type Checkpoint struct {
+	Number uint64      // Number of the trusted block
+	Hash   common.Hash // Hash of the trusted block
+}
+
+// checkpointAncestry tracks the hashes of the checkpoint and its ancestors,
+// resolved downwards from the checkpoint as far as verification needed so far.
+type checkpointAncestry struct {
+	checkpoint Checkpoint
+	hashes     map[uint64]common.Hash // Hashes of the resolved ancestors, by number
+	lowest     uint64                 // Number of the lowest resolved ancestor
+	lock       sync.RWMutex           // Protects the resolved ancestry
+}
+
+// newCheckpointAncestry creates an ancestry tracker for the given checkpoint.
+func newCheckpointAncestry(checkpoint Checkpoint) *checkpointAncestry {
+	return &checkpointAncestry{
+		checkpoint: checkpoint,
+		hashes:     map[uint64]common.Hash{checkpoint.Number: checkpoint.Hash},
+		lowest:     checkpoint.Number,
+	}
+}
+
+// contains reports whether the header with the given number and hash is the
+// checkpoint or one of its ancestors. The ancestry is extended down to the
+// number through the chain, so the checkpoint header needs to be retrievable,
+// e.g. by having been downloaded first. Until then nothing below it is
+// considered trusted.
+func (a *checkpointAncestry) contains(chain ChainHeaderReader, number uint64, hash common.Hash) bool {
+	if number > a.checkpoint.Number {
+		return false
+	}
+	// Once resolved, the ancestry only needs to be looked up
+	a.lock.RLock()
+	if number >= a.lowest {
+		defer a.lock.RUnlock()
+		return a.hashes[number] == hash
+	}
+	a.lock.RUnlock()
+
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	for a.lowest > number {
+		ancestor := chain.GetHeader(a.hashes[a.lowest], a.lowest)
+		if ancestor == nil {
+			return false
+		}
+		a.lowest--
+		a.hashes[a.lowest] = ancestor.ParentHash
+	}
+	return a.hashes[number] == hash
+}
@@ -0,0 +1,124 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package consensus
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that the seals of the checkpoint and its ancestors are skipped, even
+// when requested, while headers above it or on another chain are verified in
+// full.
+func TestCheckpoint(t *testing.T) {
+	var (
+		genesis = testGenesis(params.MinimumDifficulty.Int64())
+		headers = makeHeaders(NewPoW(), newTestChain(frontierConfig, genesis), genesis, 10, 10)
+		sibling = makeHeaders(NewPoW(), newTestChain(frontierConfig, genesis), genesis, 10, 11)
+		engine  = NewPoW(WithCheckpoint(Checkpoint{Number: 6, Hash: headers[5].Hash()}))
+	)
+	// None of the headers is sealed, so their seals only pass when skipped.
+	// Known headers aren't verified again, so leave out the one verified.
+	for i, header := range headers {
+		known := append([]*types.Header{genesis}, headers[:i]...)
+		err := engine.VerifyHeader(newTestChain(frontierConfig, append(known, headers[i+1:]...)...), header, true)
+		if i < 6 && err != nil {
+			t.Errorf("header %d below the checkpoint rejected: %v", header.Number, err)
+		}
+		if i >= 6 && !errors.Is(err, errInvalidPoW) {
+			t.Errorf("header %d above the checkpoint error mismatch: have %v, want %v", header.Number, err, errInvalidPoW)
+		}
+	}
+	known := append(append([]*types.Header{genesis}, headers...), sibling[:2]...)
+	if err := engine.VerifyHeader(newTestChain(frontierConfig, known...), sibling[2], true); !errors.Is(err, errInvalidPoW) {
+		t.Errorf("sibling below the checkpoint error mismatch: have %v, want %v", err, errInvalidPoW)
+	}
+	// A batch resolves the ancestry through itself, the checkpoint winning over
+	// the requested seals
+	engine = NewPoW(WithCheckpoint(Checkpoint{Number: 6, Hash: headers[5].Hash()}))
+	seals := make([]bool, len(headers))
+	for i := range seals {
+		seals[i] = i != 9
+	}
+	abort, results := engine.VerifyHeaders(newTestChain(frontierConfig, genesis), headers, seals)
+	defer close(abort)
+
+	for i := range headers {
+		err := <-results
+		switch {
+		case i < 6 || i == 9:
+			if err != nil {
+				t.Errorf("batch header %d rejected: %v", i, err)
+			}
+		default:
+			if !errors.Is(err, errInvalidPoW) {
+				t.Errorf("batch header %d error mismatch: have %v, want %v", i, err, errInvalidPoW)
+			}
+		}
+	}
+}
+
+// Benchmarks the batch verification of a sealed chain with every seal checked
+// against the same chain trusted up to its head by a checkpoint. The speedup
+// is the ratio of the reported headers/s, which stays modest here as a seal
+// costs a single hash to verify.
+func BenchmarkCheckpoint(b *testing.B) {
+	const n = 256
+
+	var (
+		genesis = testGenesis(params.MinimumDifficulty.Int64())
+		chain   = newTestChain(frontierConfig, genesis)
+		headers = make([]*types.Header, 0, n)
+		parent  = genesis
+	)
+	// The tester caps the sealing difficulty, keeping the setup quick
+	sealer := NewTester()
+	for i := 0; i < n; i++ {
+		parent = sealHeader(b, sealer, chain, parent)
+		chain.insert(parent)
+		headers = append(headers, parent)
+	}
+	seals := make([]bool, n)
+	for i := range seals {
+		seals[i] = true
+	}
+	engines := []struct {
+		name   string
+		engine Engine
+	}{
+		{"full", NewTester()},
+		{"checkpoint", NewTester(WithCheckpoint(Checkpoint{Number: n, Hash: parent.Hash()}))},
+	}
+	for _, bb := range engines {
+		b.Run(fmt.Sprintf("%s/n=%d", bb.name, n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				abort, results := bb.engine.VerifyHeaders(newTestChain(frontierConfig, genesis), headers, seals)
+				for j := range headers {
+					if err := <-results; err != nil {
+						b.Fatalf("header %d: verification failed: %v", j, err)
+					}
+				}
+				close(abort)
+			}
+			b.ReportMetric(float64(n*b.N)/b.Elapsed().Seconds(), "headers/s")
+		})
+	}
+}
@@ -0,0 +1,44 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package consensus
+
+import (
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// AllowedFutureBlockTime is the default maximum time a header's timestamp may
+// be ahead of the local clock, before the block is considered a future block.
+// AllowedFutureBlockTime adalah batas default seberapa jauh timestamp header boleh mendahului jam lokal sebelum
+// block dianggap sebagai block masa depan.
+const AllowedFutureBlockTime = 15 * time.Second
+
+// Clock returns the current local time. Engines take it as a field so that
+// time dependent checks can be made deterministic in tests.
+// Clock adalah fungsi yang mengembalikan waktu lokal saat ini.
+type Clock func() time.Time
+
+// verifyFutureBlock returns ErrFutureBlock if the header's timestamp is more
+// than the allowed drift ahead of the clock. A header exactly at the edge of
+// the window is still accepted.
+func verifyFutureBlock(now Clock, drift time.Duration, header *types.Header) error {
+	if header.Time > uint64(now().Add(drift).Unix()) {
+		return ErrFutureBlock
+	}
+	return nil
+}
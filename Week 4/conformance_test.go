@@ -0,0 +1,96 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package consensus_test
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/consensus/consensustest"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// testConfig is a chain config without any fork activated.
+var testConfig = &params.ChainConfig{ChainID: big.NewInt(1)}
+
+// newConformanceChain creates a chain holding only a genesis header of the given
+// difficulty and extra-data.
+func newConformanceChain(config *params.ChainConfig, difficulty *big.Int, extra []byte) *consensustest.HeaderChain {
+	genesis := &types.Header{
+		Number:     new(big.Int),
+		Difficulty: difficulty,
+		GasLimit:   params.GenesisGasLimit,
+		UncleHash:  types.EmptyUncleHash,
+		Extra:      extra,
+	}
+	return consensustest.NewHeaderChain(config, genesis)
+}
+
+func TestConformancePoW(t *testing.T) {
+	consensustest.Run(t, consensus.NewPoW(), newConformanceChain(testConfig, params.MinimumDifficulty, nil))
+}
+
+func TestConformanceTester(t *testing.T) {
+	consensustest.Run(t, consensus.NewTester(), newConformanceChain(testConfig, params.GenesisDifficulty, nil))
+}
+
+func TestConformanceFaker(t *testing.T) {
+	consensustest.Run(t, consensus.NewFaker(), newConformanceChain(testConfig, params.GenesisDifficulty, nil))
+}
+
+func TestConformancePoA(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	signer := crypto.PubkeyToAddress(key.PublicKey)
+
+	// The genesis extra-data lists the only signer between vanity and seal
+	extra := make([]byte, 32+common.AddressLength+crypto.SignatureLength)
+	copy(extra[32:], signer[:])
+
+	config := &params.ChainConfig{ChainID: big.NewInt(1), Clique: &params.CliqueConfig{Period: 1, Epoch: 30000}}
+	engine := consensus.NewPoA(config.Clique, rawdb.NewMemoryDatabase())
+	engine.Authorize(signer, func(account common.Address, hash []byte) ([]byte, error) {
+		return crypto.Sign(hash, key)
+	})
+	consensustest.Run(t, engine, newConformanceChain(config, big.NewInt(1), extra))
+}
+
+func TestConformanceDev(t *testing.T) {
+	consensustest.Run(t, consensus.NewDev(0), newConformanceChain(testConfig, big.NewInt(1), nil))
+}
+
+func TestConformanceBeacon(t *testing.T) {
+	consensustest.Run(t, consensus.NewBeacon(consensus.NewFaker()), newConformanceChain(testConfig, params.GenesisDifficulty, nil))
+}
+
+func TestConformanceSwitch(t *testing.T) {
+	engine, err := consensus.NewSwitchEngine(
+		consensus.EngineSwitch{Block: 0, Engine: consensus.NewFaker()},
+		consensus.EngineSwitch{Block: 1, Engine: consensus.NewDev(0)},
+	)
+	if err != nil {
+		t.Fatalf("failed to create engine: %v", err)
+	}
+	consensustest.Run(t, engine, newConformanceChain(testConfig, big.NewInt(1), nil))
+}
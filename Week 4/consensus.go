@@ -87,9 +87,12 @@ type Engine interface {
 	// VerifyHeaders is similar to VerifyHeader, but verifies a batch of headers
 	// concurrently. The method returns a quit channel to abort the operations and
 	// a results channel to retrieve the async verifications (the order is that of
-	// the input slice).
+	// the input slice). Closing the quit channel never blocks on a pending result,
+	// and the results channel is closed once aborted or after the last result, so
+	// consumers ranging over it terminate.
 	// metoda 'verify headers' akan sama dengan metoda 'verify header', namun verifikasi header dalam batch secara bersamaan.
 	// Metoda ini akan mengembalikan channel output untuk membatalkan operasi dan channel input untuk mengambil verifikasi secara asinkron (urutan adalah urutan input).
+	// Channel hasil akan ditutup setelah operasi dibatalkan atau setelah hasil terakhir dikirim.
 	VerifyHeaders(chain ChainHeaderReader, headers []*types.Header, seals []bool) (chan<- struct{}, <-chan error)
 
 	// VerifyUncles verifies that the given block's uncles conform to the consensus
@@ -97,6 +100,13 @@ type Engine interface {
 	// metoda 'verify uncles' akan mengecek apakah block uncles sesuai dengan aturan consensus dari engine tertentu.
 	VerifyUncles(chain ChainReader, block *types.Block) error
 
+	// VerifySeal checks whether the crypto seal on a header is valid according to
+	// the consensus rules of the given engine. It allows callers to verify headers
+	// structurally first and defer the expensive seal check.
+	// metoda 'verify seal' akan mengecek apakah segel kriptografi pada header valid sesuai aturan consensus dari engine tertentu.
+	// Metoda ini memungkinkan verifikasi struktur header terlebih dahulu dan menunda pengecekan segel yang mahal.
+	VerifySeal(chain ChainHeaderReader, header *types.Header) error
+
 	// Prepare initializes the consensus fields of a block header according to the
 	// rules of a particular engine. The changes are executed inline.
 	// metoda 'prepare' akan menginisialisasi field consensus dari header block sesuai dengan aturan dari engine tertentu.
@@ -110,7 +120,7 @@ type Engine interface {
 	// consensus rules that happen at finalization (e.g. block rewards).
 	// catatan : header block dan state database mungkin akan diperbarui untuk mengikuti aturan consensus yang terjadi di akhir (misalnya block rewards).
 	Finalize(chain ChainHeaderReader, header *types.Header, state *state.StateDB, txs []*types.Transaction,
-		uncles []*types.Header)
+		uncles []*types.Header, withdrawals []*types.Withdrawal)
 
 	// FinalizeAndAssemble runs any post-transaction state modifications (e.g. block
 	// rewards) and assembles the final block.
@@ -120,7 +130,7 @@ type Engine interface {
 	// consensus rules that happen at finalization (e.g. block rewards).
 	// catatan : header block dan state database mungkin akan diperbarui untuk mengikuti aturan consensus yang terjadi di akhir (misalnya block rewards).
 	FinalizeAndAssemble(chain ChainHeaderReader, header *types.Header, state *state.StateDB, txs []*types.Transaction,
-		uncles []*types.Header, receipts []*types.Receipt) (*types.Block, error)
+		uncles []*types.Header, receipts []*types.Receipt, withdrawals []*types.Withdrawal) (*types.Block, error)
 
 	// Seal generates a new sealing request for the given input block and pushes
 	// the result into the given channel.
@@ -0,0 +1,221 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package consensustest
+
+import (
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// HeaderChain is an in-memory chain of headers, implementing the chain reader
+// the engines verify headers against.
+// HeaderChain adalah rangkaian header di memori yang mengimplementasikan chain reader untuk verifikasi header.
+type HeaderChain struct {
+	config   *params.ChainConfig
+	byHash   map[common.Hash]*types.Header
+	byNumber map[uint64]*types.Header
+	head     *types.Header
+}
+
+// NewHeaderChain creates an in-memory chain holding the given headers, the last
+// of which is the head.
+// metoda 'new header chain' akan membuat chain di memori berisi header yang diberikan.
+func NewHeaderChain(config *params.ChainConfig, headers ...*types.Header) *HeaderChain {
+	chain := &HeaderChain{
+		config:   config,
+		byHash:   make(map[common.Hash]*types.Header),
+		byNumber: make(map[uint64]*types.Header),
+	}
+	chain.Insert(headers...)
+	return chain
+}
+
+// Insert appends the given headers to the chain, making the last one the head.
+// metoda 'insert' akan menambahkan header ke chain, header terakhir menjadi head.
+func (c *HeaderChain) Insert(headers ...*types.Header) {
+	for _, header := range headers {
+		c.byHash[header.Hash()] = header
+		c.byNumber[header.Number.Uint64()] = header
+		c.head = header
+	}
+}
+
+// Config implements consensus.ChainHeaderReader.
+func (c *HeaderChain) Config() *params.ChainConfig { return c.config }
+
+// CurrentHeader implements consensus.ChainHeaderReader.
+func (c *HeaderChain) CurrentHeader() *types.Header { return c.head }
+
+// GetHeader implements consensus.ChainHeaderReader.
+func (c *HeaderChain) GetHeader(hash common.Hash, number uint64) *types.Header {
+	if header := c.byHash[hash]; header != nil && header.Number.Uint64() == number {
+		return header
+	}
+	return nil
+}
+
+// GetHeaderByNumber implements consensus.ChainHeaderReader.
+func (c *HeaderChain) GetHeaderByNumber(number uint64) *types.Header { return c.byNumber[number] }
+
+// GetHeaderByHash implements consensus.ChainHeaderReader.
+func (c *HeaderChain) GetHeaderByHash(hash common.Hash) *types.Header { return c.byHash[hash] }
+
+// GetTd implements consensus.ChainHeaderReader, summing up the difficulties
+// from genesis up to the requested header.
+func (c *HeaderChain) GetTd(hash common.Hash, number uint64) *big.Int {
+	td := new(big.Int)
+	for header := c.GetHeader(hash, number); header != nil; {
+		td.Add(td, header.Difficulty)
+		if header.Number.Sign() == 0 {
+			return td
+		}
+		header = c.GetHeader(header.ParentHash, header.Number.Uint64()-1)
+	}
+	return nil
+}
+
+// MakeHeaderChain generates a genesis header and n synthetic headers on top of
+// it, one second apart and with the difficulty the engine demands. The headers
+// carry no valid seals, so they only pass the verification of fake engines or
+// with seal checks disabled.
+// metoda 'make header chain' akan membuat header genesis dan n header sintetis di atasnya, berjarak satu detik.
+func MakeHeaderChain(engine consensus.Engine, config *params.ChainConfig, n int) (*types.Header, []*types.Header) {
+	genesis := &types.Header{
+		Number:     new(big.Int),
+		Difficulty: new(big.Int).Set(params.GenesisDifficulty),
+		GasLimit:   params.GenesisGasLimit,
+		UncleHash:  types.EmptyUncleHash,
+	}
+	var (
+		chain   = NewHeaderChain(config, genesis)
+		headers = make([]*types.Header, 0, n)
+		parent  = genesis
+	)
+	for i := 0; i < n; i++ {
+		header := &types.Header{
+			ParentHash: parent.Hash(),
+			UncleHash:  types.EmptyUncleHash,
+			Number:     new(big.Int).Add(parent.Number, common.Big1),
+			GasLimit:   parent.GasLimit,
+			Time:       parent.Time + 1,
+		}
+		header.Difficulty = engine.CalcDifficulty(chain, header.Time, parent)
+
+		chain.Insert(header)
+		headers = append(headers, header)
+		parent = header
+	}
+	return genesis, headers
+}
+
+// BenchVerifyHeaders measures the header verification throughput of the engine
+// over a synthetic chain of n headers, both one by one through VerifyHeader and
+// as a single batch through VerifyHeaders, reported in headers per second. The
+// engine has to accept the synthetic headers, e.g. by faking its verification.
+// Engines remembering verified headers only check the batch in full once, see
+// BenchVerifyHeadersCached for measuring them.
+// metoda 'bench verify headers' akan mengukur kecepatan verifikasi header dari engine pada chain sintetis dengan n
+// header, baik satu per satu maupun sebagai satu batch.
+func BenchVerifyHeaders(b *testing.B, engine consensus.Engine, config *params.ChainConfig, n int, seal bool) {
+	genesis, headers := MakeHeaderChain(engine, config, n)
+
+	seals := make([]bool, n)
+	for i := range seals {
+		seals[i] = seal
+	}
+	b.Run(fmt.Sprintf("Sequential/n=%d/seal=%t", n, seal), func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			// Every header needs its parent in the chain, but not itself
+			b.StopTimer()
+			chain := NewHeaderChain(config, genesis)
+			b.StartTimer()
+
+			for _, header := range headers {
+				if err := engine.VerifyHeader(chain, header, seal); err != nil {
+					b.Fatalf("header %d: verification failed: %v", header.Number, err)
+				}
+				chain.Insert(header)
+			}
+		}
+		b.ReportMetric(float64(n*b.N)/b.Elapsed().Seconds(), "headers/s")
+	})
+	b.Run(fmt.Sprintf("Concurrent/n=%d/seal=%t", n, seal), func(b *testing.B) {
+		chain := NewHeaderChain(config, genesis)
+		for i := 0; i < b.N; i++ {
+			abort, results := engine.VerifyHeaders(chain, headers, seals)
+			for j := range headers {
+				if err := <-results; err != nil {
+					b.Fatalf("header %d: verification failed: %v", j, err)
+				}
+			}
+			close(abort)
+		}
+		b.ReportMetric(float64(n*b.N)/b.Elapsed().Seconds(), "headers/s")
+	})
+}
+
+// BenchVerifyHeadersCached measures how much an engine remembering verified
+// headers saves on a batch of n headers seen again, e.g. after a reorg. The cold
+// run verifies the batch on a fresh engine every iteration, the warm run
+// verifies it over and over on an engine that already verified it once.
+// metoda 'bench verify headers cached' akan mengukur kecepatan verifikasi ulang batch n header yang sudah pernah
+// diverifikasi, dibandingkan dengan verifikasi pada engine baru.
+func BenchVerifyHeadersCached(b *testing.B, newEngine func() consensus.Engine, config *params.ChainConfig, n int, seal bool) {
+	genesis, headers := MakeHeaderChain(newEngine(), config, n)
+
+	seals := make([]bool, n)
+	for i := range seals {
+		seals[i] = seal
+	}
+	chain := NewHeaderChain(config, genesis)
+	verify := func(b *testing.B, engine consensus.Engine) {
+		abort, results := engine.VerifyHeaders(chain, headers, seals)
+		defer close(abort)
+
+		for j := range headers {
+			if err := <-results; err != nil {
+				b.Fatalf("header %d: verification failed: %v", j, err)
+			}
+		}
+	}
+	b.Run(fmt.Sprintf("Cold/n=%d/seal=%t", n, seal), func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			b.StopTimer()
+			engine := newEngine()
+			b.StartTimer()
+
+			verify(b, engine)
+		}
+		b.ReportMetric(float64(n*b.N)/b.Elapsed().Seconds(), "headers/s")
+	})
+	b.Run(fmt.Sprintf("Warm/n=%d/seal=%t", n, seal), func(b *testing.B) {
+		engine := newEngine()
+		verify(b, engine)
+
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			verify(b, engine)
+		}
+		b.ReportMetric(float64(n*b.N)/b.Elapsed().Seconds(), "headers/s")
+	})
+}
@@ -0,0 +1,220 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package consensustest implements a conformance suite that checks the
+// contract of the consensus.Engine interface against any implementation, along
+// with a trivial engine for tests that need a chain but not consensus.
+// Package consensustest berisi rangkaian pengujian kesesuaian untuk memeriksa kontrak interface consensus.Engine
+// pada implementasi apa pun, beserta engine sederhana untuk pengujian yang tidak membutuhkan consensus.
+package consensustest
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+const (
+	// sealTimeout is the time an engine may take to seal a block on top of the
+	// chain head.
+	sealTimeout = 30 * time.Second
+
+	// settleTimeout is the time an engine is given to notice a closed channel, or
+	// to deliver a result it should not deliver at all.
+	settleTimeout = 100 * time.Millisecond
+
+	// batchDepth is the number of chain headers verified along with the newly
+	// sealed one in the batch verification checks.
+	batchDepth = 8
+)
+
+// Run exercises the contract every consensus engine has to honour, building a
+// block on top of the current head of the given chain. The engine must be ready
+// to seal on that chain, e.g. authorized with a signer if it needs one. As the
+// suite ends with closing the engine, it must not be used afterwards.
+// metoda 'run' akan menguji kontrak yang harus dipenuhi setiap consensus engine dengan membangun block di atas
+// head dari chain. Engine harus siap untuk menyegel block pada chain tersebut.
+func Run(t *testing.T, engine consensus.Engine, chain consensus.ChainHeaderReader) {
+	var sealed *types.Header
+
+	t.Run("RoundTrip", func(t *testing.T) {
+		sealed = roundTrip(t, engine, chain)
+	})
+	t.Run("SealStop", func(t *testing.T) {
+		sealStop(t, engine, chain)
+	})
+	t.Run("VerifyHeaders", func(t *testing.T) {
+		verifyHeaders(t, engine, chain, sealed)
+	})
+	t.Run("VerifyHeadersEmpty", func(t *testing.T) {
+		verifyHeadersEmpty(t, engine, chain)
+	})
+	t.Run("Close", func(t *testing.T) {
+		closeTwice(t, engine)
+	})
+}
+
+// assemble prepares and assembles an empty block on top of the chain head.
+func assemble(t *testing.T, engine consensus.Engine, chain consensus.ChainHeaderReader) *types.Block {
+	t.Helper()
+
+	parent := chain.CurrentHeader()
+	header := &types.Header{
+		ParentHash: parent.Hash(),
+		Number:     new(big.Int).Add(parent.Number, common.Big1),
+		GasLimit:   parent.GasLimit,
+		Time:       parent.Time + 1,
+	}
+	if err := engine.Prepare(chain, header); err != nil {
+		t.Fatalf("failed to prepare header: %v", err)
+	}
+	statedb, err := state.New(types.EmptyRootHash, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	if err != nil {
+		t.Fatalf("failed to create state: %v", err)
+	}
+	block, err := engine.FinalizeAndAssemble(chain, header, statedb, nil, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to assemble block: %v", err)
+	}
+	return block
+}
+
+// roundTrip checks that a block prepared, assembled and sealed by the engine
+// passes its own header verification, returning the sealed header.
+func roundTrip(t *testing.T, engine consensus.Engine, chain consensus.ChainHeaderReader) *types.Header {
+	block := assemble(t, engine, chain)
+
+	results, stop := make(chan *types.Block, 1), make(chan struct{})
+	defer close(stop)
+
+	if err := engine.Seal(chain, block, results, stop); err != nil {
+		t.Fatalf("failed to seal block: %v", err)
+	}
+	var sealed *types.Block
+	select {
+	case sealed = <-results:
+	case <-time.After(sealTimeout):
+		t.Fatalf("sealing timed out after %v", sealTimeout)
+	}
+	if have, want := engine.SealHash(sealed.Header()), engine.SealHash(block.Header()); have != want {
+		t.Errorf("seal hash mismatch: have %x, want %x", have, want)
+	}
+	if err := engine.VerifyHeader(chain, sealed.Header(), true); err != nil {
+		t.Errorf("sealed header failed verification: %v", err)
+	}
+	if err := engine.VerifySeal(chain, sealed.Header()); err != nil {
+		t.Errorf("sealed header failed seal verification: %v", err)
+	}
+	return sealed.Header()
+}
+
+// sealStop checks that the engine abandons sealing once stop is closed, not
+// delivering a result afterwards.
+func sealStop(t *testing.T, engine consensus.Engine, chain consensus.ChainHeaderReader) {
+	block := assemble(t, engine, chain)
+
+	results, stop := make(chan *types.Block), make(chan struct{})
+	if err := engine.Seal(chain, block, results, stop); err != nil {
+		t.Fatalf("failed to seal block: %v", err)
+	}
+	close(stop)
+	time.Sleep(settleTimeout)
+
+	select {
+	case <-results:
+		t.Errorf("sealed block delivered after stop")
+	case <-time.After(settleTimeout):
+	}
+}
+
+// verifyHeaders checks that batch verification delivers exactly one result per
+// header, failures identifying the header at the matching index.
+func verifyHeaders(t *testing.T, engine consensus.Engine, chain consensus.ChainHeaderReader, sealed *types.Header) {
+	var headers []*types.Header
+	for head := chain.CurrentHeader(); head != nil && len(headers) < batchDepth; {
+		headers = append([]*types.Header{head}, headers...)
+		if head.Number.Sign() == 0 {
+			break
+		}
+		head = chain.GetHeader(head.ParentHash, head.Number.Uint64()-1)
+	}
+	if sealed != nil {
+		headers = append(headers, sealed)
+	}
+	seals := make([]bool, len(headers))
+	for i := range seals {
+		seals[i] = true
+	}
+	abort, results := engine.VerifyHeaders(chain, headers, seals)
+	defer close(abort)
+
+	for i := range headers {
+		select {
+		case err := <-results:
+			if err == nil {
+				continue
+			}
+			var verr *consensus.HeaderVerifyError
+			if errors.As(err, &verr) && verr.Index != i {
+				t.Errorf("result %d: reported index mismatch: have %d, want %d", i, verr.Index, i)
+			}
+			if headers[i] == sealed {
+				t.Errorf("result %d: sealed header failed verification: %v", i, err)
+			}
+		case <-time.After(sealTimeout):
+			t.Fatalf("result %d: verification timed out after %v", i, sealTimeout)
+		}
+	}
+	select {
+	case err, ok := <-results:
+		if ok {
+			t.Errorf("excess verification result: %v", err)
+		}
+	case <-time.After(sealTimeout):
+		t.Errorf("results channel not closed after the last result")
+	}
+}
+
+// verifyHeadersEmpty checks that batch verification of no headers delivers no
+// results and closes the results channel.
+func verifyHeadersEmpty(t *testing.T, engine consensus.Engine, chain consensus.ChainHeaderReader) {
+	abort, results := engine.VerifyHeaders(chain, nil, nil)
+	defer close(abort)
+
+	select {
+	case err, ok := <-results:
+		if ok {
+			t.Errorf("verification result for empty batch: %v", err)
+		}
+	case <-time.After(sealTimeout):
+		t.Errorf("results channel of empty batch not closed")
+	}
+}
+
+// closeTwice checks that closing the engine is idempotent.
+func closeTwice(t *testing.T, engine consensus.Engine) {
+	first := engine.Close()
+	if err := engine.Close(); err != nil && first == nil {
+		t.Errorf("second close failed: %v", err)
+	}
+}
@@ -0,0 +1,138 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package consensustest
+
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+// testDifficulty is the constant difficulty of every block built by TestEngine.
+var testDifficulty = big.NewInt(1)
+
+// TestEngine is a consensus engine accepting every header and sealing blocks
+// without doing any work, for tests that need a chain but not consensus.
+// TestEngine adalah consensus engine yang menerima semua header dan menyegel block tanpa pekerjaan apa pun,
+// untuk pengujian yang membutuhkan chain tapi tidak membutuhkan consensus.
+type TestEngine struct{}
+
+// NewTestEngine creates a consensus engine that accepts everything.
+// metoda 'new test engine' akan membuat consensus engine yang menerima semuanya.
+func NewTestEngine() *TestEngine {
+	return new(TestEngine)
+}
+
+// Author implements consensus.Engine, returning the header's coinbase.
+// metoda 'author' akan mengembalikan coinbase dari header.
+func (e *TestEngine) Author(header *types.Header) (common.Address, error) {
+	return header.Coinbase, nil
+}
+
+// VerifyHeader implements consensus.Engine, accepting every header.
+// metoda 'verify header' akan menerima semua header.
+func (e *TestEngine) VerifyHeader(chain consensus.ChainHeaderReader, header *types.Header, seal bool) error {
+	return nil
+}
+
+// VerifyHeaders implements consensus.Engine, accepting every header.
+// metoda 'verify headers' akan menerima semua header.
+func (e *TestEngine) VerifyHeaders(chain consensus.ChainHeaderReader, headers []*types.Header, seals []bool) (chan<- struct{}, <-chan error) {
+	abort, results := make(chan struct{}), make(chan error, len(headers))
+	for range headers {
+		results <- nil
+	}
+	close(results)
+	return abort, results
+}
+
+// VerifyUncles implements consensus.Engine, accepting every uncle.
+// metoda 'verify uncles' akan menerima semua uncle.
+func (e *TestEngine) VerifyUncles(chain consensus.ChainReader, block *types.Block) error {
+	return nil
+}
+
+// VerifySeal implements consensus.Engine, accepting every seal.
+// metoda 'verify seal' akan menerima semua segel.
+func (e *TestEngine) VerifySeal(chain consensus.ChainHeaderReader, header *types.Header) error {
+	return nil
+}
+
+// Prepare implements consensus.Engine, setting the constant difficulty.
+// metoda 'prepare' akan mengatur tingkat kesulitan yang konstan.
+func (e *TestEngine) Prepare(chain consensus.ChainHeaderReader, header *types.Header) error {
+	header.Difficulty = new(big.Int).Set(testDifficulty)
+	return nil
+}
+
+// Finalize implements consensus.Engine. No block rewards are paid, only the
+// withdrawals are credited.
+// metoda 'finalize' tidak memberikan block rewards, hanya withdrawal yang dikreditkan.
+func (e *TestEngine) Finalize(chain consensus.ChainHeaderReader, header *types.Header, state *state.StateDB, txs []*types.Transaction, uncles []*types.Header, withdrawals []*types.Withdrawal) {
+	consensus.ProcessWithdrawals(state, withdrawals)
+}
+
+// FinalizeAndAssemble implements consensus.Engine, setting the final state root
+// and assembling the block.
+// metoda 'finalize and assemble' akan mengatur state root akhir dan merakit block.
+func (e *TestEngine) FinalizeAndAssemble(chain consensus.ChainHeaderReader, header *types.Header, state *state.StateDB, txs []*types.Transaction, uncles []*types.Header, receipts []*types.Receipt, withdrawals []*types.Withdrawal) (*types.Block, error) {
+	e.Finalize(chain, header, state, txs, uncles, withdrawals)
+
+	header.Root = state.IntermediateRoot(true)
+	return types.NewBlockWithWithdrawals(header, txs, uncles, receipts, withdrawals, trie.NewStackTrie(nil)), nil
+}
+
+// Seal implements consensus.Engine, delivering the block unchanged as sealed.
+// metoda 'seal' akan mengirimkan block tanpa perubahan sebagai block yang tersegel.
+func (e *TestEngine) Seal(chain consensus.ChainHeaderReader, block *types.Block, results chan<- *types.Block, stop <-chan struct{}) error {
+	go func() {
+		select {
+		case results <- block:
+		case <-stop:
+		}
+	}()
+	return nil
+}
+
+// SealHash implements consensus.Engine, returning the RLP hash of the header.
+// metoda 'seal hash' akan mengembalikan hash RLP dari header.
+func (e *TestEngine) SealHash(header *types.Header) common.Hash {
+	return header.Hash()
+}
+
+// CalcDifficulty implements consensus.Engine, returning a constant difficulty.
+// metoda 'calc difficulty' akan mengembalikan tingkat kesulitan yang konstan.
+func (e *TestEngine) CalcDifficulty(chain consensus.ChainHeaderReader, time uint64, parent *types.Header) *big.Int {
+	return new(big.Int).Set(testDifficulty)
+}
+
+// APIs implements consensus.Engine, returning no RPC APIs.
+// metoda 'apis' tidak mengembalikan RPC API.
+func (e *TestEngine) APIs(chain consensus.ChainHeaderReader) []rpc.API {
+	return nil
+}
+
+// Close implements consensus.Engine. There are no background threads to stop.
+// metoda 'close' tidak melakukan apa-apa karena tidak ada thread background.
+func (e *TestEngine) Close() error {
+	return nil
+}
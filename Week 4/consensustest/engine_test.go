@@ -0,0 +1,75 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package consensustest
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+func TestConformance(t *testing.T) {
+	genesis := &types.Header{
+		Number:     new(big.Int),
+		Difficulty: new(big.Int).Set(testDifficulty),
+		GasLimit:   params.GenesisGasLimit,
+	}
+	Run(t, NewTestEngine(), NewHeaderChain(params.TestChainConfig, genesis))
+}
+
+// Tests that a block is sealed unchanged and verified in well under a
+// millisecond on average.
+func TestEngineSealVerify(t *testing.T) {
+	const rounds = 1000
+
+	var (
+		engine  = NewTestEngine()
+		genesis = &types.Header{Number: new(big.Int), GasLimit: params.GenesisGasLimit}
+		chain   = NewHeaderChain(params.TestChainConfig, genesis)
+		results = make(chan *types.Block, 1)
+		stop    = make(chan struct{})
+	)
+	defer close(stop)
+
+	start := time.Now()
+	for i := 0; i < rounds; i++ {
+		header := &types.Header{ParentHash: genesis.Hash(), Number: big.NewInt(1), GasLimit: genesis.GasLimit, Time: uint64(i)}
+		if err := engine.Prepare(chain, header); err != nil {
+			t.Fatalf("failed to prepare header: %v", err)
+		}
+		block := types.NewBlockWithHeader(header)
+		if err := engine.Seal(chain, block, results, stop); err != nil {
+			t.Fatalf("failed to seal block: %v", err)
+		}
+		sealed := <-results
+		if sealed.Hash() != block.Hash() {
+			t.Fatalf("sealed block changed: have %x, want %x", sealed.Hash(), block.Hash())
+		}
+		if err := engine.VerifyHeader(chain, sealed.Header(), true); err != nil {
+			t.Fatalf("sealed block failed verification: %v", err)
+		}
+		if have := engine.SealHash(header); have != header.Hash() {
+			t.Fatalf("seal hash mismatch: have %x, want %x", have, header.Hash())
+		}
+	}
+	if elapsed := time.Since(start) / rounds; elapsed >= time.Millisecond {
+		t.Errorf("sealing and verifying took %v per block, want under a millisecond", elapsed)
+	}
+}
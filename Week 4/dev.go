@@ -0,0 +1,271 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package consensus
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+var errInvalidTimestamp = errors.New("invalid timestamp")
+
+// devEngine is a developer consensus engine for single node testing. Blocks are
+// sealed without any proof, but headers must still link to their parent with
+// continuous numbers and timestamps at least one period apart.
+// devEngine adalah consensus engine untuk pengujian dengan satu node. Block tersegel tanpa bukti,
+// namun header tetap harus terhubung ke parent dengan nomor berurutan dan timestamp berjarak minimal satu periode.
+type devEngine struct {
+	period uint64 // Number of seconds between blocks to enforce
+}
+
+// NewDev creates a developer consensus engine. With a zero period blocks are
+// sealed instantly, otherwise each block is held back until period has passed
+// since its parent, producing (possibly empty) blocks on a timer. Timestamps
+// are in whole seconds, so a sub-second period is rounded up to one second.
+// metoda 'new dev' akan membuat consensus engine developer. Jika period nol block langsung disegel,
+// jika tidak setiap block ditahan sampai period berlalu sejak parent-nya. Period di bawah satu detik dibulatkan
+// ke atas menjadi satu detik.
+func NewDev(period time.Duration) *devEngine {
+	if period <= 0 {
+		return &devEngine{}
+	}
+	return &devEngine{period: uint64((period + time.Second - 1) / time.Second)}
+}
+
+// DevConstructor returns an EngineConstructor creating developer engines with
+// the given period. A zero period falls back to the Clique period of the chain
+// config, if any, so chains configured for proof-of-authority keep their pace.
+// metoda 'dev constructor' akan mengembalikan EngineConstructor yang membuat engine developer dengan period
+// tertentu. Jika period nol, period Clique dari konfigurasi chain yang digunakan.
+func DevConstructor(period time.Duration) EngineConstructor {
+	return func(config *params.ChainConfig) (Engine, error) {
+		if period == 0 {
+			return NewDev(devPeriod(config)), nil
+		}
+		return NewDev(period), nil
+	}
+}
+
+// devPeriod returns the block period a developer engine inherits from the chain
+// config, zero if the config has no Clique section.
+func devPeriod(config *params.ChainConfig) time.Duration {
+	if config == nil || config.Clique == nil {
+		return 0
+	}
+	return time.Duration(config.Clique.Period) * time.Second
+}
+
+// Author implements Engine, returning the header's coinbase as the author of
+// the block.
+// metoda 'author' akan mengembalikan coinbase dari header sebagai pembuat block.
+func (dev *devEngine) Author(header *types.Header) (common.Address, error) {
+	return header.Coinbase, nil
+}
+
+// VerifyHeader implements Engine, accepting any header that correctly links to
+// a known parent.
+// metoda 'verify header' akan menerima header apapun yang terhubung dengan benar ke parent yang dikenal.
+func (dev *devEngine) VerifyHeader(chain ChainHeaderReader, header *types.Header, seal bool) error {
+	if err := verifyNotBanned(header); err != nil {
+		return wrapHeaderError(header, err)
+	}
+	number := header.Number.Uint64()
+	if chain.GetHeader(header.Hash(), number) != nil {
+		return nil
+	}
+	// The genesis header has no parent to verify against
+	if number == 0 {
+		return wrapHeaderError(header, verifyGenesis(header))
+	}
+	return dev.VerifyHeaderWithParent(chain, header, chain.GetHeader(header.ParentHash, number-1), seal)
+}
+
+// VerifyHeaderWithParent implements ParentVerifier, accepting any header that
+// correctly links to the given parent.
+// metoda 'verify header with parent' akan menerima header apapun yang terhubung dengan benar ke parent yang diberikan.
+func (dev *devEngine) VerifyHeaderWithParent(chain ChainHeaderReader, header, parent *types.Header, seal bool) error {
+	if err := verifyNotBanned(header); err != nil {
+		return wrapHeaderError(header, err)
+	}
+	if parent == nil {
+		return wrapHeaderError(header, ErrUnknownAncestor)
+	}
+	return wrapHeaderError(header, dev.verifyHeader(chain, header, parent))
+}
+
+// VerifyHeaders implements Engine, verifying a batch of headers concurrently.
+// metoda 'verify headers' akan memverifikasi header dalam batch secara bersamaan.
+func (dev *devEngine) VerifyHeaders(chain ChainHeaderReader, headers []*types.Header, seals []bool) (chan<- struct{}, <-chan error) {
+	descending, err := validateBatch(headers, seals)
+	if err != nil {
+		return failedBatch(err)
+	}
+	hashes := newHeaderHashes(headers)
+	links := batchLinks(hashes, descending)
+	return verifyHeadersConcurrently(hashes, batchConfig{metrics: NoopMetrics{}}, func(index int) error {
+		if headers[index].Number.Sign() == 0 {
+			return verifyGenesis(headers[index])
+		}
+		parent := batchParent(chain, headers, links, index, descending)
+		if parent == nil {
+			return ErrUnknownAncestor
+		}
+		return dev.verifyHeader(chain, headers[index], parent)
+	})
+}
+
+// VerifyHeadersContext is similar to VerifyHeaders, but aborts verification
+// once the context is cancelled or its deadline elapses. Results are still
+// delivered in input order, headers left unverified report the context error.
+// metoda 'verify headers context' sama dengan 'verify headers', namun verifikasi dibatalkan ketika context dibatalkan atau melewati tenggat waktu.
+func (dev *devEngine) VerifyHeadersContext(ctx context.Context, chain ChainHeaderReader, headers []*types.Header, seals []bool) <-chan error {
+	return verifyHeadersContext(ctx, dev, chain, headers, seals)
+}
+
+// VerifyHeadersWithContext is VerifyHeadersContext, under the name callers built
+// around contexts look for.
+// metoda 'verify headers with context' sama dengan 'verify headers context'.
+func (dev *devEngine) VerifyHeadersWithContext(ctx context.Context, chain ChainHeaderReader, headers []*types.Header, seals []bool) <-chan error {
+	return verifyHeadersContext(ctx, dev, chain, headers, seals)
+}
+
+// verifyHeader checks the parent linkage of a header.
+func (dev *devEngine) verifyHeader(chain ChainHeaderReader, header, parent *types.Header) error {
+	if err := verifyHeaderBasics(chain, header, parent); err != nil {
+		return err
+	}
+	if header.Time < parent.Time+dev.period {
+		return errInvalidTimestamp
+	}
+	return nil
+}
+
+// VerifyUncles implements Engine, rejecting any uncles since blocks are never
+// contested on a single node.
+// metoda 'verify uncles' akan menolak semua uncle karena block tidak pernah diperebutkan pada satu node.
+func (dev *devEngine) VerifyUncles(chain ChainReader, block *types.Block) error {
+	if len(block.Uncles()) > 0 {
+		return errUnclesUnsupported
+	}
+	return nil
+}
+
+// VerifySeal implements Engine. Developer blocks carry no seal, so every header
+// passes.
+// metoda 'verify seal' akan selalu berhasil karena block developer tidak memiliki segel.
+func (dev *devEngine) VerifySeal(chain ChainHeaderReader, header *types.Header) error {
+	return nil
+}
+
+// Prepare implements Engine, setting the header's difficulty and pushing its
+// timestamp to at least one period after the parent.
+// metoda 'prepare' akan mengatur tingkat kesulitan header dan memajukan timestamp minimal satu periode setelah parent.
+func (dev *devEngine) Prepare(chain ChainHeaderReader, header *types.Header) error {
+	parent := chain.GetHeader(header.ParentHash, header.Number.Uint64()-1)
+	if parent == nil {
+		return ErrUnknownAncestor
+	}
+	if header.Time < parent.Time+dev.period {
+		header.Time = parent.Time + dev.period
+	}
+	header.Difficulty = dev.CalcDifficulty(chain, header.Time, parent)
+	return nil
+}
+
+// Finalize implements Engine. No block rewards are paid on a developer chain,
+// only the withdrawals are credited.
+// metoda 'finalize' tidak memberikan block rewards pada chain developer, hanya withdrawal yang dikreditkan.
+func (dev *devEngine) Finalize(chain ChainHeaderReader, header *types.Header, state *state.StateDB, txs []*types.Transaction, uncles []*types.Header, withdrawals []*types.Withdrawal) {
+	ProcessWithdrawals(state, withdrawals)
+}
+
+// FinalizeAndAssemble implements Engine, setting the final state root and
+// assembling the block.
+// metoda 'finalize and assemble' akan mengatur state root akhir dan membangun block.
+func (dev *devEngine) FinalizeAndAssemble(chain ChainHeaderReader, header *types.Header, state *state.StateDB, txs []*types.Transaction, uncles []*types.Header, receipts []*types.Receipt, withdrawals []*types.Withdrawal) (*types.Block, error) {
+	if err := assembleWithdrawals(chain.Config(), header, withdrawals); err != nil {
+		return nil, err
+	}
+	dev.Finalize(chain, header, state, txs, uncles, withdrawals)
+
+	header.Root = state.IntermediateRoot(true)
+	return types.NewBlockWithWithdrawals(header, txs, uncles, receipts, withdrawals, trie.NewStackTrie(nil)), nil
+}
+
+// Seal implements Engine, handing the block back unchanged. With a non-zero
+// period the block is held back until period has passed since its parent, or
+// until stop is closed.
+// metoda 'seal' akan mengembalikan block tanpa perubahan. Jika period tidak nol, block ditahan sampai period
+// berlalu sejak parent-nya, atau sampai channel stop ditutup.
+func (dev *devEngine) Seal(chain ChainHeaderReader, block *types.Block, results chan<- *types.Block, stop <-chan struct{}) error {
+	var delay time.Duration
+	if dev.period > 0 {
+		parent := chain.GetHeader(block.ParentHash(), block.NumberU64()-1)
+		if parent == nil {
+			return ErrUnknownAncestor
+		}
+		delay = time.Until(time.Unix(int64(parent.Time+dev.period), 0))
+	}
+	go func() {
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+
+		select {
+		case <-stop:
+			return
+		case <-timer.C:
+		}
+		select {
+		case results <- block:
+		case <-stop:
+		}
+	}()
+	return nil
+}
+
+// SealHash returns the hash of a block prior to it being sealed.
+// metoda 'seal hash' akan mengembalikan hash dari block sebelum dibungkus.
+func (dev *devEngine) SealHash(header *types.Header) common.Hash {
+	return sealHash(header)
+}
+
+// CalcDifficulty implements Engine. Every developer block has difficulty 1.
+// metoda 'calc difficulty' akan selalu mengembalikan tingkat kesulitan 1.
+func (dev *devEngine) CalcDifficulty(chain ChainHeaderReader, time uint64, parent *types.Header) *big.Int {
+	return big.NewInt(1)
+}
+
+// APIs implements Engine, returning no RPC APIs.
+// metoda 'apis' tidak mengembalikan RPC API apapun.
+func (dev *devEngine) APIs(chain ChainHeaderReader) []rpc.API {
+	return nil
+}
+
+// Close implements Engine. There are no background threads to stop.
+// metoda 'close' tidak melakukan apa-apa karena tidak ada thread background.
+func (dev *devEngine) Close() error {
+	return nil
+}
@@ -0,0 +1,156 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package consensus
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that the instant sealing developer engine builds and verifies a chain of
+// a hundred blocks in well under a second.
+func TestDevChain(t *testing.T) {
+	var (
+		engine = NewDev(0)
+		parent = testGenesis(1)
+		chain  = newTestChain(frontierConfig, parent)
+		start  = time.Now()
+	)
+	for i := 0; i < 100; i++ {
+		header := sealHeader(t, engine, chain, parent)
+		if err := engine.VerifyHeader(chain, header, true); err != nil {
+			t.Fatalf("block %d: verification failed: %v", header.Number, err)
+		}
+		if header.Difficulty.Cmp(big.NewInt(1)) != 0 {
+			t.Fatalf("block %d: difficulty mismatch: have %v, want 1", header.Number, header.Difficulty)
+		}
+		chain.insert(header)
+		parent = header
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("building the chain took %v, want under a second", elapsed)
+	}
+}
+
+// Tests that the developer engine still rejects headers breaking the number
+// continuity, timestamp monotonicity or parent linkage.
+func TestDevRejectsMalformed(t *testing.T) {
+	var (
+		engine  = NewDev(0)
+		genesis = testGenesis(1)
+		parent  = &types.Header{ParentHash: genesis.Hash(), Number: big.NewInt(1), Difficulty: big.NewInt(1), Time: 10}
+		chain   = newTestChain(frontierConfig, genesis, parent)
+	)
+	tests := []struct {
+		header *types.Header
+		want   error
+	}{
+		{&types.Header{ParentHash: parent.Hash(), Number: big.NewInt(2), Time: 10}, nil},
+		{&types.Header{ParentHash: parent.Hash(), Number: big.NewInt(2), Time: 9}, errInvalidTimestamp},
+		{&types.Header{ParentHash: parent.Hash(), Number: big.NewInt(3), Time: 11}, ErrUnknownAncestor},
+		{&types.Header{ParentHash: genesis.Hash(), Number: big.NewInt(2), Time: 11}, ErrUnknownAncestor},
+	}
+	for i, tt := range tests {
+		if err := engine.VerifyHeader(chain, tt.header, true); !errors.Is(err, tt.want) {
+			t.Errorf("test %d: error mismatch: have %v, want %v", i, err, tt.want)
+		}
+	}
+	// A parent handed out directly must still be numbered right before the header
+	header := &types.Header{ParentHash: genesis.Hash(), Number: big.NewInt(2), Time: 11}
+	if err := engine.VerifyHeaderWithParent(chain, header, genesis, true); !errors.Is(err, ErrInvalidNumber) {
+		t.Errorf("number gap error mismatch: have %v, want %v", err, ErrInvalidNumber)
+	}
+}
+
+// Tests that blocks sealed by a periodic developer engine are spaced at least a
+// period apart, with sub-second periods rounded up instead of disabling the
+// spacing altogether.
+func TestDevTimestampSpacing(t *testing.T) {
+	tests := []struct {
+		period time.Duration
+		want   uint64
+	}{
+		{0, 0},
+		{500 * time.Millisecond, 1},
+		{time.Second, 1},
+		{1500 * time.Millisecond, 2},
+		{5 * time.Second, 5},
+	}
+	for i, tt := range tests {
+		var (
+			engine = NewDev(tt.period)
+			parent = testGenesis(1)
+			chain  = newTestChain(frontierConfig, parent)
+		)
+		for j := 0; j < 10; j++ {
+			header := sealHeader(t, engine, chain, parent)
+			if header.Time < parent.Time+tt.want {
+				t.Fatalf("test %d, block %d: timestamp too close: have %d, want at least %d", i, header.Number, header.Time, parent.Time+tt.want)
+			}
+			if err := engine.VerifyHeader(chain, header, true); err != nil {
+				t.Fatalf("test %d, block %d: verification failed: %v", i, header.Number, err)
+			}
+			chain.insert(header)
+			parent = header
+		}
+		// A child stamped short of the period must be rejected
+		if tt.want > 1 {
+			header := &types.Header{ParentHash: parent.Hash(), Number: new(big.Int).Add(parent.Number, big.NewInt(1)), Time: parent.Time + tt.want - 1}
+			if err := engine.VerifyHeader(chain, header, true); !errors.Is(err, errInvalidTimestamp) {
+				t.Errorf("test %d: early timestamp error mismatch: have %v, want %v", i, err, errInvalidTimestamp)
+			}
+		}
+	}
+}
+
+// Tests that the developer engine constructed by name inherits its period from
+// the Clique section of the chain config, unless given one explicitly.
+func TestDevConstructorPeriod(t *testing.T) {
+	config := &params.ChainConfig{ChainID: big.NewInt(1), Clique: &params.CliqueConfig{Period: 3}}
+
+	tests := []struct {
+		period time.Duration
+		config *params.ChainConfig
+		want   uint64
+	}{
+		{0, nil, 0},
+		{0, frontierConfig, 0},
+		{0, config, 3},
+		{7 * time.Second, config, 7},
+	}
+	for i, tt := range tests {
+		engine, err := DevConstructor(tt.period)(tt.config)
+		if err != nil {
+			t.Fatalf("test %d: failed to create engine: %v", i, err)
+		}
+		if have := engine.(*devEngine).period; have != tt.want {
+			t.Errorf("test %d: period mismatch: have %d, want %d", i, have, tt.want)
+		}
+	}
+	engine, err := NewEngineByName("dev", config)
+	if err != nil {
+		t.Fatalf("failed to create engine by name: %v", err)
+	}
+	if have := engine.(*devEngine).period; have != 3 {
+		t.Errorf("registered engine period mismatch: have %d, want 3", have)
+	}
+}
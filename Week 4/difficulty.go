@@ -0,0 +1,189 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package consensus
+
+import (
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// VerifyDifficulty recomputes the difficulty of header from its parent with the
+// given difficulty algorithm, e.g. the CalcDifficulty method of an engine, and
+// returns an error detailing the expected and actual difficulty on mismatch.
+// metoda 'verify difficulty' akan menghitung ulang tingkat kesulitan header dari parent menggunakan algoritma yang
+// diberikan, dan mengembalikan error berisi tingkat kesulitan yang diharapkan dan yang sebenarnya jika berbeda.
+func VerifyDifficulty(chain ChainHeaderReader, header, parent *types.Header, calc func(ChainHeaderReader, uint64, *types.Header) *big.Int) error {
+	return checkDifficulty(header, calc(chain, header.Time, parent))
+}
+
+// checkDifficulty returns an error detailing the expected and actual difficulty
+// if the header doesn't carry the expected one.
+func checkDifficulty(header *types.Header, expected *big.Int) error {
+	if header.Difficulty == nil || expected.Cmp(header.Difficulty) != 0 {
+		return fmt.Errorf("%w: have %v, want %v", ErrInvalidDifficulty, header.Difficulty, expected)
+	}
+	return nil
+}
+
+// TargetFromDifficulty returns the proof-of-work target of a difficulty, i.e.
+// 2^256 / difficulty, which a seal hash interpreted as a number must not exceed.
+// A non-positive difficulty yields a zero target.
+// metoda 'target from difficulty' akan mengembalikan target proof-of-work dari tingkat kesulitan, yaitu 2^256 / difficulty.
+func TargetFromDifficulty(diff *big.Int) *big.Int {
+	if diff.Sign() <= 0 {
+		return new(big.Int)
+	}
+	return new(big.Int).Div(two256, diff)
+}
+
+// DifficultyFromTarget is the inverse of TargetFromDifficulty, returning the
+// difficulty 2^256 / target. A non-positive target yields a zero difficulty.
+// metoda 'difficulty from target' adalah kebalikan dari 'target from difficulty', yaitu 2^256 / target.
+func DifficultyFromTarget(target *big.Int) *big.Int {
+	if target.Sign() <= 0 {
+		return new(big.Int)
+	}
+	return new(big.Int).Div(two256, target)
+}
+
+// maxRetargetFactor is the largest factor the windowed difficulty may move up
+// or down by in a single retarget.
+var maxRetargetFactor = big.NewInt(4)
+
+// The proportional controller of TargetedDifficulty moves the difficulty by the
+// relative deviation of the recent block times from the target, divided by
+// targetGainDivisor, and never by more than 1/targetMaxStepDivisor per block.
+var (
+	targetGainDivisor    = big.NewInt(2)
+	targetMaxStepDivisor = big.NewInt(8)
+)
+
+// targetMilliseconds returns the target block time in milliseconds, the unit
+// block times are compared in, rounding sub-millisecond targets up to one so
+// that they never degenerate into a zero expected time.
+func targetMilliseconds(target time.Duration) int64 {
+	if ms := target.Milliseconds(); ms > 0 {
+		return ms
+	}
+	return 1
+}
+
+// TargetedDifficulty computes the difficulty of the block following parent with
+// a proportional controller keeping the block time near the target. The average
+// interval of the last intervals blocks, fetched via GetHeaderByNumber, is
+// compared against the target, and the parent difficulty is nudged by half the
+// relative deviation: up if the blocks came in faster, down if slower. A single
+// step is clamped to an eighth of the parent difficulty, so the difficulty
+// settles smoothly instead of overshooting. Targets below a millisecond count
+// as one. If the oldest header of the intervals is not available,
+// ErrUnknownAncestor is returned.
+// metoda 'targeted difficulty' akan menghitung tingkat kesulitan block berikutnya dengan kontroler proporsional
+// agar waktu block mendekati target. Perubahan per block dibatasi seperdelapan tingkat kesulitan parent.
+func TargetedDifficulty(chain ChainHeaderReader, parent *types.Header, intervals uint64, target time.Duration) (*big.Int, error) {
+	number := parent.Number.Uint64()
+	if intervals > number {
+		intervals = number
+	}
+	if intervals == 0 || target <= 0 {
+		return new(big.Int).Set(parent.Difficulty), nil
+	}
+	oldest := chain.GetHeaderByNumber(number - intervals)
+	if oldest == nil {
+		return nil, fmt.Errorf("%w: block time intervals start #%d", ErrUnknownAncestor, number-intervals)
+	}
+	// The deviation of the intervals from the target, relative to the target
+	var (
+		actual   = new(big.Int).SetUint64(parent.Time)
+		expected = new(big.Int).SetUint64(intervals)
+	)
+	actual.Sub(actual, new(big.Int).SetUint64(oldest.Time))
+	actual.Mul(actual, big.NewInt(1000))
+	expected.Mul(expected, big.NewInt(targetMilliseconds(target)))
+
+	step := new(big.Int).Sub(expected, actual)
+	step.Mul(step, parent.Difficulty)
+	step.Div(step, expected.Mul(expected, targetGainDivisor))
+
+	// Clamp the step, then apply it
+	limit := new(big.Int).Div(parent.Difficulty, targetMaxStepDivisor)
+	if step.Cmp(limit) > 0 {
+		step.Set(limit)
+	}
+	if limit.Neg(limit); step.Cmp(limit) < 0 {
+		step.Set(limit)
+	}
+	diff := step.Add(step, parent.Difficulty)
+	if diff.Cmp(params.MinimumDifficulty) < 0 {
+		diff.Set(params.MinimumDifficulty)
+	}
+	return diff, nil
+}
+
+// WindowedDifficulty computes the difficulty of the block following parent from
+// the average block time across the last window headers, fetched via
+// GetHeaderByNumber. Windows that were faster than the target block time raise
+// the difficulty proportionally, slower ones lower it, but never by more than a
+// factor of 4 per retarget. Block times are compared in milliseconds, so
+// sub-second targets are honoured, and targets below a millisecond count as
+// one. A zero target disables retargeting. If the oldest header of the window
+// is not available, ErrUnknownAncestor is returned.
+// metoda 'windowed difficulty' akan menghitung tingkat kesulitan block berikutnya dari rata-rata waktu block pada
+// window header terakhir. Perubahan tingkat kesulitan dibatasi maksimal 4 kali lipat naik maupun turun. Jika header
+// tertua dari window tidak tersedia, ErrUnknownAncestor dikembalikan.
+func WindowedDifficulty(chain ChainHeaderReader, parent *types.Header, window uint64, target time.Duration) (*big.Int, error) {
+	number := parent.Number.Uint64()
+	if window > number {
+		window = number
+	}
+	if window == 0 || target <= 0 {
+		return new(big.Int).Set(parent.Difficulty), nil
+	}
+	oldest := chain.GetHeaderByNumber(number - window)
+	if oldest == nil {
+		return nil, fmt.Errorf("%w: retarget window start #%d", ErrUnknownAncestor, number-window)
+	}
+	// Scale the parent difficulty by expected / actual time of the window
+	var (
+		actual   = new(big.Int).SetUint64(parent.Time)
+		expected = new(big.Int).SetUint64(window)
+	)
+	actual.Sub(actual, new(big.Int).SetUint64(oldest.Time))
+	actual.Mul(actual, big.NewInt(1000))
+	if actual.Sign() <= 0 {
+		actual.SetUint64(1)
+	}
+	expected.Mul(expected, big.NewInt(targetMilliseconds(target)))
+
+	diff := new(big.Int).Mul(parent.Difficulty, expected)
+	diff.Div(diff, actual)
+
+	// Clamp the adjustment to the maximum retarget factor
+	if upper := new(big.Int).Mul(parent.Difficulty, maxRetargetFactor); diff.Cmp(upper) > 0 {
+		diff = upper
+	}
+	if lower := new(big.Int).Div(parent.Difficulty, maxRetargetFactor); diff.Cmp(lower) < 0 {
+		diff = lower
+	}
+	if diff.Cmp(params.MinimumDifficulty) < 0 {
+		diff.Set(params.MinimumDifficulty)
+	}
+	return diff, nil
+}
@@ -0,0 +1,456 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package consensus
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// makeWindowChain creates a chain of n headers on top of a genesis, all of the
+// given difficulty and the given number of seconds apart.
+func makeWindowChain(n int, spacing uint64, difficulty int64) (*testChain, *types.Header) {
+	parent := testGenesis(difficulty)
+	chain := newTestChain(frontierConfig, parent)
+	for i := 0; i < n; i++ {
+		parent = &types.Header{
+			ParentHash: parent.Hash(),
+			Number:     new(big.Int).Add(parent.Number, common.Big1),
+			Difficulty: big.NewInt(difficulty),
+			GasLimit:   parent.GasLimit,
+			Time:       parent.Time + spacing,
+		}
+		chain.insert(parent)
+	}
+	return chain, parent
+}
+
+// Tests that the windowed difficulty follows the average block time of the
+// window, raising the difficulty after fast windows and lowering it after slow
+// ones, clamped to a factor of 4 either way.
+func TestWindowedDifficulty(t *testing.T) {
+	tests := []struct {
+		spacing uint64
+		window  uint64
+		target  time.Duration
+		want    int64
+	}{
+		{10, 10, 10 * time.Second, 10000000},       // On target
+		{5, 10, 10 * time.Second, 20000000},        // Fast window
+		{20, 10, 10 * time.Second, 5000000},        // Slow window
+		{1, 10, 10 * time.Second, 40000000},        // Too fast, clamped up
+		{100, 10, 10 * time.Second, 2500000},       // Too slow, clamped down
+		{1, 10, 500 * time.Millisecond, 5000000},   // Sub-second target
+		{1, 10, 1500 * time.Millisecond, 15000000}, // Fractional target
+		{5, 50, 10 * time.Second, 20000000},        // Window longer than the chain
+		{10, 0, 10 * time.Second, 10000000},        // No window at all
+		{0, 10, 10 * time.Second, 40000000},        // Identical timestamps
+		{0, 10, 500 * time.Microsecond, 40000000},  // Sub-millisecond target
+		{10, 10, 0, 10000000},                      // No target at all
+	}
+	for i, tt := range tests {
+		chain, parent := makeWindowChain(20, tt.spacing, 10000000)
+		diff, err := WindowedDifficulty(chain, parent, tt.window, tt.target)
+		if err != nil {
+			t.Fatalf("test %d: failed to compute difficulty: %v", i, err)
+		}
+		if diff.Cmp(big.NewInt(tt.want)) != 0 {
+			t.Errorf("test %d: difficulty mismatch: have %v, want %d", i, diff, tt.want)
+		}
+	}
+}
+
+// Tests that a window reaching beyond the known headers is reported as an
+// unknown ancestor instead of silently keeping the parent's difficulty.
+func TestWindowedDifficultyUnknownAncestor(t *testing.T) {
+	full, parent := makeWindowChain(20, 5, 10000000)
+	chain := newTestChain(frontierConfig, full.GetHeaderByNumber(19), parent)
+
+	if _, err := WindowedDifficulty(chain, parent, 1, 10*time.Second); err != nil {
+		t.Errorf("window within the chain failed: %v", err)
+	}
+	if _, err := WindowedDifficulty(chain, parent, 10, 10*time.Second); !errors.Is(err, ErrUnknownAncestor) {
+		t.Errorf("window beyond the chain error mismatch: have %v, want %v", err, ErrUnknownAncestor)
+	}
+	// The engine must reject the header rather than accept any difficulty
+	engine := NewPoW(WithWindowedDifficulty(10, 10*time.Second))
+	header := &types.Header{
+		ParentHash: parent.Hash(),
+		UncleHash:  types.EmptyUncleHash,
+		Number:     big.NewInt(21),
+		Difficulty: new(big.Int).Set(parent.Difficulty),
+		GasLimit:   parent.GasLimit,
+		Time:       parent.Time + 5,
+	}
+	if err := engine.VerifyHeader(chain, header, false); !errors.Is(err, ErrUnknownAncestor) {
+		t.Errorf("header verification error mismatch: have %v, want %v", err, ErrUnknownAncestor)
+	}
+	if err := engine.Prepare(chain, types.CopyHeader(header)); !errors.Is(err, ErrUnknownAncestor) {
+		t.Errorf("header preparation error mismatch: have %v, want %v", err, ErrUnknownAncestor)
+	}
+}
+
+// Tests that a batch whose retargeting windows start within the batch itself
+// verifies against a chain not holding those headers yet.
+func TestWindowedDifficultyBatch(t *testing.T) {
+	var (
+		engine  = NewPoW(WithWindowedDifficulty(4, 10*time.Second))
+		genesis = testGenesis(10000000)
+		full    = newTestChain(frontierConfig, genesis)
+		headers = makeHeaders(engine, full, genesis, 16, 5)
+		chain   = newTestChain(frontierConfig, genesis)
+	)
+	// The fast blocks must have pushed the difficulty up from the genesis
+	if headers[len(headers)-1].Difficulty.Cmp(genesis.Difficulty) <= 0 {
+		t.Fatalf("difficulty not raised by fast blocks: have %v, genesis %v", headers[len(headers)-1].Difficulty, genesis.Difficulty)
+	}
+	_, results := engine.VerifyHeaders(chain, headers, make([]bool, len(headers)))
+	for i := range headers {
+		if err := <-results; err != nil {
+			t.Errorf("header %d: verification failed: %v", i, err)
+		}
+	}
+}
+
+// Tests that the target block time controller nudges the difficulty by half the
+// relative deviation of the recent intervals, clamped to an eighth per block.
+func TestTargetedDifficulty(t *testing.T) {
+	tests := []struct {
+		spacing   uint64
+		intervals uint64
+		want      int64
+	}{
+		{10, 4, 10000000}, // On target
+		{9, 4, 10500000},  // Slightly fast
+		{11, 4, 9500000},  // Slightly slow
+		{5, 4, 11250000},  // Too fast, clamped up
+		{100, 4, 8750000}, // Too slow, clamped down
+		{0, 4, 11250000},  // Identical timestamps
+		{9, 50, 10500000}, // Intervals longer than the chain
+		{5, 0, 10000000},  // No intervals at all
+	}
+	for i, tt := range tests {
+		chain, parent := makeWindowChain(20, tt.spacing, 10000000)
+		diff, err := TargetedDifficulty(chain, parent, tt.intervals, 10*time.Second)
+		if err != nil {
+			t.Fatalf("test %d: failed to compute difficulty: %v", i, err)
+		}
+		if diff.Cmp(big.NewInt(tt.want)) != 0 {
+			t.Errorf("test %d: difficulty mismatch: have %v, want %d", i, diff, tt.want)
+		}
+	}
+	// Intervals reaching beyond the known headers are an unknown ancestor
+	full, parent := makeWindowChain(20, 5, 10000000)
+	chain := newTestChain(frontierConfig, full.GetHeaderByNumber(19), parent)
+	if _, err := TargetedDifficulty(chain, parent, 4, 10*time.Second); !errors.Is(err, ErrUnknownAncestor) {
+		t.Errorf("intervals beyond the chain error mismatch: have %v, want %v", err, ErrUnknownAncestor)
+	}
+}
+
+// Tests that the target block time controller converges: simulating a miner of
+// fixed hashrate, whose blocks take difficulty/hashrate seconds, the difficulty
+// must settle around hashrate*target from far below and far above, and the
+// Tests that targets below a millisecond count as one millisecond instead of
+// zeroing the expected block time, which would divide by zero in the targeted
+// controller and silently clamp every windowed retarget down.
+func TestSubMillisecondTarget(t *testing.T) {
+	const diff = 10000000
+	chain, parent := makeWindowChain(20, 0, diff)
+
+	for _, target := range []time.Duration{time.Nanosecond, 500 * time.Microsecond, time.Millisecond} {
+		if have, err := TargetedDifficulty(chain, parent, 4, target); err != nil {
+			t.Fatalf("target %v: failed to compute targeted difficulty: %v", target, err)
+		} else if want := int64(diff + diff/8); have.Cmp(big.NewInt(want)) != 0 {
+			t.Errorf("target %v: targeted difficulty mismatch: have %v, want %d", target, have, want)
+		}
+		if have, err := WindowedDifficulty(chain, parent, 4, target); err != nil {
+			t.Fatalf("target %v: failed to compute windowed difficulty: %v", target, err)
+		} else if want := int64(4 * diff); have.Cmp(big.NewInt(want)) != 0 {
+			t.Errorf("target %v: windowed difficulty mismatch: have %v, want %d", target, have, want)
+		}
+		engine := NewPoW(WithTargetBlockTime(target, 4))
+		if have := engine.CalcDifficulty(chain, parent.Time, parent); have.Cmp(big.NewInt(diff+diff/8)) != 0 {
+			t.Errorf("target %v: engine difficulty mismatch: have %v, want %d", target, have, diff+diff/8)
+		}
+	}
+}
+
+// block time around the target.
+func TestTargetBlockTimeConvergence(t *testing.T) {
+	const (
+		hashrate = 1000000 // Hashes per second of the simulated miner
+		target   = 10      // Block time in seconds the controller aims for
+		blocks   = 200     // Number of blocks to simulate
+	)
+	engine := NewPoW(WithTargetBlockTime(target*time.Second, 4))
+
+	for _, start := range []int64{1000000, 100000000} {
+		parent := testGenesis(start)
+		chain := newTestChain(frontierConfig, parent)
+
+		var times []uint64
+		for i := 0; i < blocks; i++ {
+			// Mine the next block in difficulty/hashrate seconds, at least one
+			spacing := parent.Difficulty.Uint64() / hashrate
+			if spacing == 0 {
+				spacing = 1
+			}
+			time := parent.Time + spacing
+			diff := engine.CalcDifficulty(chain, time, parent)
+			if diff == nil {
+				t.Fatalf("start %d, block %d: no difficulty computed", start, i+1)
+			}
+			parent = &types.Header{
+				ParentHash: parent.Hash(),
+				Number:     new(big.Int).Add(parent.Number, common.Big1),
+				Difficulty: diff,
+				GasLimit:   parent.GasLimit,
+				Time:       time,
+			}
+			chain.insert(parent)
+			times = append(times, spacing)
+		}
+		// The difficulty must have settled within 10% of the equilibrium
+		want := big.NewInt(hashrate * target)
+		dev := new(big.Int).Sub(parent.Difficulty, want)
+		if dev.Abs(dev).Cmp(new(big.Int).Div(want, big.NewInt(10))) > 0 {
+			t.Errorf("start %d: difficulty not converged: have %v, want %v±10%%", start, parent.Difficulty, want)
+		}
+		// And so must the block time over the last 50 blocks
+		var total uint64
+		for _, spacing := range times[len(times)-50:] {
+			total += spacing
+		}
+		if avg := float64(total) / 50; avg < target*0.9 || avg > target*1.1 {
+			t.Errorf("start %d: block time not converged: have %.2fs, want %ds±10%%", start, avg, target)
+		}
+	}
+}
+
+// Tests that CalcDifficulty never returns less than the configured floor, nor
+// the protocol minimum, for parents whose difficulty and block interval would
+// otherwise drive the result below either, or even negative.
+func TestCalcDifficultyFloor(t *testing.T) {
+	var (
+		homestead = &params.ChainConfig{ChainID: big.NewInt(1), HomesteadBlock: big.NewInt(0)}
+		byzantium = &params.ChainConfig{ChainID: big.NewInt(1), HomesteadBlock: big.NewInt(0), ByzantiumBlock: big.NewInt(0)}
+		floor     = big.NewInt(1000000)
+	)
+	tests := []struct {
+		name   string
+		config *params.ChainConfig
+		opts   []PoWOption
+		parent int64
+		delay  uint64
+		want   *big.Int
+	}{
+		// Slow blocks at the protocol minimum, pulling the difficulty down
+		{"frontier", frontierConfig, nil, 131072, 100, params.MinimumDifficulty},
+		{"homestead", homestead, nil, 131072, 10000, params.MinimumDifficulty},
+		{"byzantium", byzantium, nil, 131072, 10000, params.MinimumDifficulty},
+
+		// Degenerate parents far below the minimum
+		{"frontier zero", frontierConfig, nil, 0, 100, params.MinimumDifficulty},
+		{"homestead one", homestead, nil, 1, 10000, params.MinimumDifficulty},
+		{"byzantium one", byzantium, nil, 1, 10000, params.MinimumDifficulty},
+
+		// A configured floor above the protocol minimum
+		{"frontier floor", frontierConfig, []PoWOption{WithMinimumDifficulty(floor)}, 1000000, 100, floor},
+		{"homestead floor", homestead, []PoWOption{WithMinimumDifficulty(floor)}, 1000000, 10000, floor},
+		{"byzantium floor", byzantium, []PoWOption{WithMinimumDifficulty(floor)}, 1000000, 10000, floor},
+		{"windowed floor", frontierConfig, []PoWOption{WithMinimumDifficulty(floor), WithWindowedDifficulty(1, time.Second)}, 1000000, 100, floor},
+		{"targeted floor", frontierConfig, []PoWOption{WithMinimumDifficulty(floor), WithTargetBlockTime(time.Second, 1)}, 1000000, 100, floor},
+
+		// Floors that can't be honoured keep the default
+		{"nil floor", frontierConfig, []PoWOption{WithMinimumDifficulty(nil)}, 0, 100, params.MinimumDifficulty},
+		{"zero floor", frontierConfig, []PoWOption{WithMinimumDifficulty(common.Big0)}, 0, 100, params.MinimumDifficulty},
+		{"negative floor", frontierConfig, []PoWOption{WithMinimumDifficulty(big.NewInt(-1))}, 0, 100, params.MinimumDifficulty},
+		{"low floor", frontierConfig, []PoWOption{WithMinimumDifficulty(common.Big1)}, 0, 100, params.MinimumDifficulty},
+	}
+	for _, tt := range tests {
+		var (
+			genesis = testGenesis(tt.parent)
+			chain   = newTestChain(tt.config, genesis)
+			parent  = &types.Header{
+				ParentHash: genesis.Hash(),
+				Number:     big.NewInt(1),
+				Difficulty: big.NewInt(tt.parent),
+				GasLimit:   genesis.GasLimit,
+				Time:       genesis.Time + 1,
+			}
+		)
+		chain.insert(parent)
+
+		diff := NewPoW(tt.opts...).CalcDifficulty(chain, parent.Time+tt.delay, parent)
+		if diff.Cmp(tt.want) != 0 {
+			t.Errorf("%s: difficulty mismatch: have %v, want %v", tt.name, diff, tt.want)
+		}
+	}
+}
+
+// Tests difficulty verification against mainnet blocks 1 and 2, and against
+// block 2 with a corrupted difficulty.
+func TestVerifyDifficulty(t *testing.T) {
+	var (
+		chain  = newTestChain(frontierConfig)
+		engine = NewPoW()
+		parent = &types.Header{Number: big.NewInt(1), Time: 1438269988, Difficulty: big.NewInt(17171480576)}
+		header = &types.Header{Number: big.NewInt(2), Time: 1438270017, Difficulty: big.NewInt(17163096064)}
+	)
+	if err := VerifyDifficulty(chain, header, parent, engine.CalcDifficulty); err != nil {
+		t.Fatalf("mainnet block 2 failed verification: %v", err)
+	}
+	corrupted := types.CopyHeader(header)
+	corrupted.Difficulty = new(big.Int).Add(header.Difficulty, common.Big1)
+
+	err := VerifyDifficulty(chain, corrupted, parent, engine.CalcDifficulty)
+	if !errors.Is(err, ErrInvalidDifficulty) {
+		t.Fatalf("error mismatch: have %v, want %v", err, ErrInvalidDifficulty)
+	}
+	if want := fmt.Sprintf("have %v, want %v", corrupted.Difficulty, header.Difficulty); !strings.Contains(err.Error(), want) {
+		t.Errorf("error %q lacks the difficulties %q", err, want)
+	}
+}
+
+// Tests the Homestead difficulty rules of EIP-2: a step of 1/2048 of the parent
+// difficulty per 10 seconds below 10 to 20 seconds, capped at 99 steps down,
+// floored at the minimum difficulty, plus the difficulty bomb.
+func TestCalcDifficultyHomestead(t *testing.T) {
+	const (
+		diff = 1_000_000_000
+		step = diff / 2048
+	)
+	tests := []struct {
+		number     int64 // Number of the parent
+		parentTime uint64
+		parentDiff int64
+		time       uint64
+		want       int64
+	}{
+		// Mainnet blocks 1 and 2, on which Frontier and Homestead agree
+		{1, 1438269988, 17171480576, 1438270017, 17163096064},
+
+		{1000, 100, diff, 101, diff + step},       // Fastest block
+		{1000, 100, diff, 109, diff + step},       // Still fast
+		{1000, 100, diff, 110, diff},              // On target, a Frontier decrease
+		{1000, 100, diff, 119, diff},              // Still on target
+		{1000, 100, diff, 120, diff - step},       // Slow
+		{1000, 100, diff, 1090, diff - 98*step},   // Just above the cap
+		{1000, 100, diff, 1100, diff - 99*step},   // On the cap
+		{1000, 100, diff, 100000, diff - 99*step}, // Far beyond the cap
+		{1000, 100, 131072, 100000, 131072},       // Floored at the minimum
+
+		{199998, 100, diff, 115, diff},     // Bomb period 1, no bomb yet
+		{199999, 100, diff, 115, diff + 1}, // Bomb period 2, 2^0
+		{299999, 100, diff, 115, diff + 2}, // Bomb period 3, 2^1
+	}
+	for i, tt := range tests {
+		parent := &types.Header{Number: big.NewInt(tt.number), Time: tt.parentTime, Difficulty: big.NewInt(tt.parentDiff)}
+		if have := calcDifficultyHomestead(tt.time, parent); have.Cmp(big.NewInt(tt.want)) != 0 {
+			t.Errorf("test %d: difficulty mismatch: have %v, want %v", i, have, tt.want)
+		}
+	}
+}
+
+// Tests the Byzantium difficulty rules of EIP-100, which adjust by one step of
+// 1/2048 of the parent difficulty more for parents with uncles, and delay the
+// difficulty bomb.
+func TestCalcDifficultyByzantium(t *testing.T) {
+	const (
+		diff  = 1_000_000_000
+		step  = diff / 2048
+		delay = 3_000_000
+	)
+	uncled := types.CalcUncleHash([]*types.Header{{Number: big.NewInt(1)}})
+
+	tests := []struct {
+		number int64 // Number of the parent
+		uncles bool  // Whether the parent has uncles
+		time   uint64
+		want   int64
+	}{
+		{1000, false, 101, diff + step},     // Fast, no uncles
+		{1000, true, 101, diff + 2*step},    // Fast, uncles
+		{1000, false, 109, diff},            // On target, no uncles
+		{1000, true, 109, diff + step},      // On target, uncles
+		{1000, false, 118, diff - step},     // Slow, no uncles
+		{1000, true, 118, diff},             // Slow, uncles
+		{1000, false, 1000, diff - 99*step}, // Capped, no uncles
+		{1000, true, 1000, diff - 98*step},  // Nearly capped, uncles
+		{1000, true, 1009, diff - 99*step},  // Capped, uncles
+
+		{3_199_998, false, 109, diff},           // Delayed bomb period 1, no bomb yet
+		{3_199_999, false, 109, diff + 1},       // Delayed bomb period 2, 2^0
+		{3_299_999, true, 109, diff + step + 2}, // Delayed bomb period 3, 2^1
+	}
+	for i, tt := range tests {
+		parent := &types.Header{Number: big.NewInt(tt.number), Time: 100, Difficulty: big.NewInt(diff), UncleHash: types.EmptyUncleHash}
+		if tt.uncles {
+			parent.UncleHash = uncled
+		}
+		if have := calcDifficultyByzantium(tt.time, parent, delay); have.Cmp(big.NewInt(tt.want)) != 0 {
+			t.Errorf("test %d: difficulty mismatch: have %v, want %v", i, have, tt.want)
+		}
+	}
+}
+
+// Tests that difficulties convert to targets and back, and the conversions on
+// the boundaries: difficulty 1 admits every hash, 2^256 only the zero hash.
+func TestTargetFromDifficulty(t *testing.T) {
+	for _, diff := range []*big.Int{
+		big.NewInt(1),
+		big.NewInt(2),
+		big.NewInt(131072),
+		big.NewInt(17171480576),
+		new(big.Int).Lsh(common.Big1, 64),
+		new(big.Int).Sub(new(big.Int).Lsh(common.Big1, 128), common.Big1),
+	} {
+		if have := DifficultyFromTarget(TargetFromDifficulty(diff)); have.Cmp(diff) != 0 {
+			t.Errorf("difficulty %v: round trip mismatch: have %v", diff, have)
+		}
+	}
+	max := new(big.Int).Lsh(common.Big1, 256)
+	if have := TargetFromDifficulty(common.Big1); have.Cmp(max) != 0 {
+		t.Errorf("difficulty 1: target mismatch: have %v, want 2^256", have)
+	}
+	header := &types.Header{Number: big.NewInt(1), Difficulty: common.Big1, Nonce: types.EncodeNonce(12345)}
+	if err := NewPoW().VerifySeal(nil, header); err != nil {
+		t.Errorf("difficulty 1: arbitrary nonce rejected: %v", err)
+	}
+	if have := TargetFromDifficulty(max); have.Cmp(common.Big1) != 0 {
+		t.Errorf("difficulty 2^256: target mismatch: have %v, want 1", have)
+	}
+	if have := TargetFromDifficulty(new(big.Int).Add(max, common.Big1)); have.Sign() != 0 {
+		t.Errorf("difficulty past 2^256: target mismatch: have %v, want 0", have)
+	}
+	for _, bad := range []*big.Int{new(big.Int), big.NewInt(-1)} {
+		if have := TargetFromDifficulty(bad); have.Sign() != 0 {
+			t.Errorf("difficulty %v: target mismatch: have %v, want 0", bad, have)
+		}
+		if have := DifficultyFromTarget(bad); have.Sign() != 0 {
+			t.Errorf("target %v: difficulty mismatch: have %v, want 0", bad, have)
+		}
+	}
+}
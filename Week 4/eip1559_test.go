@@ -0,0 +1,185 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package consensus
+
+import (
+	"crypto/ecdsa"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// londonConfig is a chain config with the London fork active from genesis.
+var londonConfig = &params.ChainConfig{
+	ChainID:             big.NewInt(1),
+	HomesteadBlock:      big.NewInt(0),
+	EIP150Block:         big.NewInt(0),
+	EIP155Block:         big.NewInt(0),
+	EIP158Block:         big.NewInt(0),
+	ByzantiumBlock:      big.NewInt(0),
+	ConstantinopleBlock: big.NewInt(0),
+	PetersburgBlock:     big.NewInt(0),
+	IstanbulBlock:       big.NewInt(0),
+	BerlinBlock:         big.NewInt(0),
+	LondonBlock:         big.NewInt(0),
+}
+
+// Tests that the base fee rises when the parent overshoots its gas target,
+// falls when it undershoots it, and stays put when it hits the target.
+func TestCalcBaseFee(t *testing.T) {
+	tests := []struct {
+		parentBaseFee   int64
+		parentGasLimit  uint64
+		parentGasUsed   uint64
+		expectedBaseFee int64
+	}{
+		{params.InitialBaseFee, 20000000, 10000000, params.InitialBaseFee}, // usage == target
+		{params.InitialBaseFee, 20000000, 15000000, 1062500000},            // usage above target
+		{params.InitialBaseFee, 20000000, 20000000, 1125000000},            // usage at the limit
+		{params.InitialBaseFee, 20000000, 5000000, 937500000},              // usage below target
+		{params.InitialBaseFee, 20000000, 0, 875000000},                    // empty block
+		{1, 20000000, 10000001, 2},                                         // increase of at least one
+	}
+	for i, test := range tests {
+		parent := &types.Header{
+			Number:   common.Big32,
+			GasLimit: test.parentGasLimit,
+			GasUsed:  test.parentGasUsed,
+			BaseFee:  big.NewInt(test.parentBaseFee),
+		}
+		if have, want := CalcBaseFee(londonConfig, parent), big.NewInt(test.expectedBaseFee); have.Cmp(want) != 0 {
+			t.Errorf("test %d: base fee mismatch: have %v, want %v", i, have, want)
+		}
+	}
+}
+
+// Tests that Prepare sets the base fee following the parent's gas usage, that
+// header verification rejects any other base fee, and that no base fee is
+// accepted before the fork.
+func TestPrepareBaseFee(t *testing.T) {
+	engine := NewPoW()
+	for _, used := range []uint64{0, params.GenesisGasLimit / 4, params.GenesisGasLimit} {
+		genesis := testGenesis(params.MinimumDifficulty.Int64())
+		genesis.BaseFee = big.NewInt(params.InitialBaseFee)
+		genesis.GasUsed = used
+		chain := newTestChain(londonConfig, genesis)
+
+		header := &types.Header{
+			ParentHash: genesis.Hash(),
+			UncleHash:  types.EmptyUncleHash,
+			Number:     big.NewInt(1),
+			GasLimit:   genesis.GasLimit,
+			Time:       genesis.Time + 1,
+		}
+		if err := engine.Prepare(chain, header); err != nil {
+			t.Fatalf("gas used %d: failed to prepare header: %v", used, err)
+		}
+		if want := CalcBaseFee(londonConfig, genesis); header.BaseFee == nil || header.BaseFee.Cmp(want) != 0 {
+			t.Errorf("gas used %d: prepared base fee mismatch: have %v, want %v", used, header.BaseFee, want)
+		}
+		if err := engine.VerifyHeader(chain, header, false); err != nil {
+			t.Errorf("gas used %d: prepared header failed verification: %v", used, err)
+		}
+		header.BaseFee = new(big.Int).Add(header.BaseFee, common.Big1)
+		if err := engine.VerifyHeader(chain, header, false); err == nil {
+			t.Errorf("gas used %d: mismatching base fee accepted", used)
+		}
+	}
+	// Before the fork there's no base fee to prepare, nor any accepted
+	genesis := testGenesis(params.MinimumDifficulty.Int64())
+	chain := newTestChain(frontierConfig, genesis)
+	header := &types.Header{
+		ParentHash: genesis.Hash(),
+		UncleHash:  types.EmptyUncleHash,
+		Number:     big.NewInt(1),
+		GasLimit:   genesis.GasLimit,
+		Time:       genesis.Time + 1,
+	}
+	if err := engine.Prepare(chain, header); err != nil {
+		t.Fatalf("failed to prepare pre-fork header: %v", err)
+	}
+	if header.BaseFee != nil {
+		t.Errorf("pre-fork header prepared with base fee %v", header.BaseFee)
+	}
+	header.BaseFee = big.NewInt(params.InitialBaseFee)
+	if err := engine.VerifyHeader(chain, header, false); err == nil {
+		t.Errorf("pre-fork base fee accepted")
+	}
+}
+
+// Tests the base fee rules around the fork block, where the initial base fee
+// applies and the gas target doubles, and for a parent hitting its target.
+func TestVerifyEIP1559Header(t *testing.T) {
+	config := *londonConfig
+	config.LondonBlock = big.NewInt(5)
+
+	prefork := &types.Header{Number: big.NewInt(4), GasLimit: 10_000_000, GasUsed: 7_000_000}
+	postfork := &types.Header{Number: big.NewInt(5), GasLimit: 20_000_000, GasUsed: 10_000_000, BaseFee: big.NewInt(params.InitialBaseFee)}
+
+	tests := []struct {
+		name    string
+		parent  *types.Header
+		header  *types.Header
+		invalid bool
+	}{
+		{"fork block", prefork, &types.Header{Number: big.NewInt(5), GasLimit: 20_000_000, BaseFee: big.NewInt(params.InitialBaseFee)}, false},
+		{"fork block off fee", prefork, &types.Header{Number: big.NewInt(5), GasLimit: 20_000_000, BaseFee: big.NewInt(params.InitialBaseFee + 1)}, true},
+		{"fork block missing fee", prefork, &types.Header{Number: big.NewInt(5), GasLimit: 20_000_000}, true},
+		{"fork block gas limit", prefork, &types.Header{Number: big.NewInt(5), GasLimit: 30_000_000, BaseFee: big.NewInt(params.InitialBaseFee)}, true},
+		{"parent at target", postfork, &types.Header{Number: big.NewInt(6), GasLimit: 20_000_000, BaseFee: big.NewInt(params.InitialBaseFee)}, false},
+		{"parent at target off fee", postfork, &types.Header{Number: big.NewInt(6), GasLimit: 20_000_000, BaseFee: big.NewInt(params.InitialBaseFee - 1)}, true},
+	}
+	for _, tt := range tests {
+		if err := VerifyEIP1559Header(&config, tt.parent, tt.header); (err != nil) != tt.invalid {
+			t.Errorf("%s: have error %v, want invalid %v", tt.name, err, tt.invalid)
+		}
+	}
+}
+
+// Tests that the proof-of-authority engine enforces the base fee as well.
+func TestVerifyHeaderBaseFeePoA(t *testing.T) {
+	key, _ := newTestKey(t)
+	base, _ := makeVotingChain(t, []*ecdsa.PrivateKey{key}, 1, nil)
+
+	genesis := types.CopyHeader(base.GetHeaderByNumber(0))
+	genesis.BaseFee = big.NewInt(params.InitialBaseFee)
+	chain := newTestChain(londonConfig, genesis)
+
+	engine := NewPoA(testCliqueConfig, nil)
+	for _, baseFee := range []*big.Int{nil, new(big.Int).Add(CalcBaseFee(londonConfig, genesis), common.Big1)} {
+		header := &types.Header{
+			ParentHash: genesis.Hash(),
+			UncleHash:  types.EmptyUncleHash,
+			Number:     big.NewInt(1),
+			GasLimit:   genesis.GasLimit,
+			Time:       genesis.Time + testCliqueConfig.Period,
+			Difficulty: diffInTurn,
+			Extra:      make([]byte, extraVanity+extraSeal),
+			BaseFee:    baseFee,
+		}
+		if err := engine.VerifyHeader(chain, header, false); err == nil {
+			t.Errorf("base fee %v accepted", baseFee)
+		}
+		header.BaseFee = CalcBaseFee(londonConfig, genesis)
+		if err := engine.VerifyHeader(chain, header, false); err != nil {
+			t.Errorf("correct base fee rejected: %v", err)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package consensus
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// VerifyEIP4844Header verifies the presence of the excessBlobGas field and that
+// if the current block contains no transactions, the excessBlobGas is updated
+// accordingly.
+// metoda 'verify eip4844 header' akan memverifikasi keberadaan field blob gas pada header serta nilai excessBlobGas
+// terhadap parent.
+func VerifyEIP4844Header(parent, header *types.Header) error {
+	// Verify the header is not malformed
+	if header.ExcessBlobGas == nil {
+		return errors.New("header is missing excessBlobGas")
+	}
+	if header.BlobGasUsed == nil {
+		return errors.New("header is missing blobGasUsed")
+	}
+	// Verify that the blob gas used remains within reasonable limits.
+	if *header.BlobGasUsed > params.MaxBlobGasPerBlock {
+		return fmt.Errorf("blob gas used %d exceeds maximum allowance %d", *header.BlobGasUsed, params.MaxBlobGasPerBlock)
+	}
+	if *header.BlobGasUsed%params.BlobTxBlobGasPerBlob != 0 {
+		return fmt.Errorf("blob gas used %d not a multiple of blob gas per blob %d", *header.BlobGasUsed, params.BlobTxBlobGasPerBlob)
+	}
+	// Verify the excessBlobGas is correct based on the parent header
+	var (
+		parentExcessBlobGas uint64
+		parentBlobGasUsed   uint64
+	)
+	if parent.ExcessBlobGas != nil {
+		parentExcessBlobGas = *parent.ExcessBlobGas
+		parentBlobGasUsed = *parent.BlobGasUsed
+	}
+	expectedExcessBlobGas := CalcExcessBlobGas(parentExcessBlobGas, parentBlobGasUsed)
+	if *header.ExcessBlobGas != expectedExcessBlobGas {
+		return fmt.Errorf("invalid excessBlobGas: have %d, want %d, parent excessBlobGas %d, parent blobDataUsed %d",
+			*header.ExcessBlobGas, expectedExcessBlobGas, parentExcessBlobGas, parentBlobGasUsed)
+	}
+	return nil
+}
+
+// CalcExcessBlobGas calculates the excess blob gas after applying the set of
+// blobs on top of the excess blob gas.
+// metoda 'calc excess blob gas' akan menghitung kelebihan blob gas setelah blob parent diterapkan.
+func CalcExcessBlobGas(parentExcessBlobGas uint64, parentBlobGasUsed uint64) uint64 {
+	excessBlobGas := parentExcessBlobGas + parentBlobGasUsed
+	if excessBlobGas < params.BlobTxTargetBlobGasPerBlock {
+		return 0
+	}
+	return excessBlobGas - params.BlobTxTargetBlobGasPerBlock
+}
+
+// verifyParentBeaconRoot checks that a header carries the parent beacon block
+// root exactly from the Cancun fork on. Chains without a config skip the check.
+func verifyParentBeaconRoot(config *params.ChainConfig, header *types.Header) error {
+	if config == nil {
+		return nil
+	}
+	cancun := config.IsCancun(header.Number, header.Time)
+	if !cancun && header.ParentBeaconRoot != nil {
+		return fmt.Errorf("invalid parentBeaconRoot: have %x, expected nil", *header.ParentBeaconRoot)
+	}
+	if cancun && header.ParentBeaconRoot == nil {
+		return errors.New("header is missing beaconRoot")
+	}
+	return nil
+}
+
+// VerifyParentBeaconRoot checks that a header carries the parent beacon block
+// root exactly from the Cancun fork on, so engines outside this package apply
+// the same rule. Chains without a config skip the check.
+// metoda 'verify parent beacon root' akan mengecek bahwa header membawa root block beacon induk tepat sejak fork Cancun.
+func VerifyParentBeaconRoot(config *params.ChainConfig, header *types.Header) error {
+	return verifyParentBeaconRoot(config, header)
+}
+
+// verifyBlobGas checks the blob gas fields of a header against the chain config:
+// absent before the Cancun fork and valid as per VerifyEIP4844Header from then
+// on. Chains without a config skip the check.
+func verifyBlobGas(config *params.ChainConfig, parent, header *types.Header) error {
+	if config == nil {
+		return nil
+	}
+	if !config.IsCancun(header.Number, header.Time) {
+		switch {
+		case header.ExcessBlobGas != nil:
+			return fmt.Errorf("invalid excessBlobGas: have %d, expected nil", *header.ExcessBlobGas)
+		case header.BlobGasUsed != nil:
+			return fmt.Errorf("invalid blobGasUsed: have %d, expected nil", *header.BlobGasUsed)
+		}
+		return nil
+	}
+	return VerifyEIP4844Header(parent, header)
+}
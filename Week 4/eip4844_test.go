@@ -0,0 +1,169 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package consensus
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+func TestCalcExcessBlobGas(t *testing.T) {
+	tests := []struct {
+		excess uint64
+		blobs  uint64
+		want   uint64
+	}{
+		// The excess blob gas should not increase from zero if the used blob
+		// slots are below - or equal - to the target.
+		{0, 0, 0},
+		{0, 1, 0},
+		{0, params.BlobTxTargetBlobGasPerBlock / params.BlobTxBlobGasPerBlob, 0},
+
+		// If the target blob gas is exceeded, the excessBlobGas should increase
+		// by however much it was overshot
+		{0, (params.BlobTxTargetBlobGasPerBlock / params.BlobTxBlobGasPerBlob) + 1, params.BlobTxBlobGasPerBlob},
+		{1, (params.BlobTxTargetBlobGasPerBlock / params.BlobTxBlobGasPerBlob) + 1, params.BlobTxBlobGasPerBlob + 1},
+		{1, (params.BlobTxTargetBlobGasPerBlock / params.BlobTxBlobGasPerBlob) + 2, 2*params.BlobTxBlobGasPerBlob + 1},
+
+		// The excess blob gas should decrease by however much the target was
+		// under-shot, capped at zero.
+		{params.BlobTxTargetBlobGasPerBlock, params.BlobTxTargetBlobGasPerBlock / params.BlobTxBlobGasPerBlob, params.BlobTxTargetBlobGasPerBlock},
+		{params.BlobTxTargetBlobGasPerBlock, (params.BlobTxTargetBlobGasPerBlock / params.BlobTxBlobGasPerBlob) - 1, params.BlobTxTargetBlobGasPerBlock - params.BlobTxBlobGasPerBlob},
+		{params.BlobTxBlobGasPerBlob - 1, (params.BlobTxTargetBlobGasPerBlock / params.BlobTxBlobGasPerBlob) - 1, 0},
+	}
+	for i, tt := range tests {
+		if have := CalcExcessBlobGas(tt.excess, tt.blobs*params.BlobTxBlobGasPerBlob); have != tt.want {
+			t.Errorf("test %d: excess blob gas mismatch: have %v, want %v", i, have, tt.want)
+		}
+	}
+}
+
+// Tests the blob gas fields on both sides of the Cancun fork, including the
+// fork block itself, whose parent carries none.
+func TestVerifyBlobGas(t *testing.T) {
+	config := *londonConfig
+	config.ShanghaiTime, config.CancunTime = new(uint64), new(uint64)
+	*config.CancunTime = 10
+
+	u64 := func(v uint64) *uint64 { return &v }
+	var (
+		prefork  = &types.Header{Number: big.NewInt(1), Time: 9}
+		postfork = &types.Header{Number: big.NewInt(2), Time: 10, ExcessBlobGas: u64(0), BlobGasUsed: u64(params.MaxBlobGasPerBlock)}
+		excess   = CalcExcessBlobGas(0, params.MaxBlobGasPerBlock)
+	)
+	tests := []struct {
+		name    string
+		parent  *types.Header
+		header  *types.Header
+		invalid bool
+	}{
+		{"before fork", prefork, &types.Header{Number: big.NewInt(2), Time: 9}, false},
+		{"before fork with excess", prefork, &types.Header{Number: big.NewInt(2), Time: 9, ExcessBlobGas: u64(0)}, true},
+		{"before fork with used", prefork, &types.Header{Number: big.NewInt(2), Time: 9, BlobGasUsed: u64(0)}, true},
+		{"fork block", prefork, &types.Header{Number: big.NewInt(2), Time: 10, ExcessBlobGas: u64(0), BlobGasUsed: u64(0)}, false},
+		{"fork block missing excess", prefork, &types.Header{Number: big.NewInt(2), Time: 10, BlobGasUsed: u64(0)}, true},
+		{"fork block missing used", prefork, &types.Header{Number: big.NewInt(2), Time: 10, ExcessBlobGas: u64(0)}, true},
+		{"fork block with excess", prefork, &types.Header{Number: big.NewInt(2), Time: 10, ExcessBlobGas: u64(1), BlobGasUsed: u64(0)}, true},
+		{"after fork", postfork, &types.Header{Number: big.NewInt(3), Time: 11, ExcessBlobGas: u64(excess), BlobGasUsed: u64(params.BlobTxBlobGasPerBlob)}, false},
+		{"after fork wrong excess", postfork, &types.Header{Number: big.NewInt(3), Time: 11, ExcessBlobGas: u64(excess + 1), BlobGasUsed: u64(0)}, true},
+		{"after fork partial blob", postfork, &types.Header{Number: big.NewInt(3), Time: 11, ExcessBlobGas: u64(excess), BlobGasUsed: u64(params.BlobTxBlobGasPerBlob + 1)}, true},
+		{"after fork over max", postfork, &types.Header{Number: big.NewInt(3), Time: 11, ExcessBlobGas: u64(excess), BlobGasUsed: u64(params.MaxBlobGasPerBlock + params.BlobTxBlobGasPerBlob)}, true},
+	}
+	for _, tt := range tests {
+		if err := verifyBlobGas(&config, tt.parent, tt.header); (err != nil) != tt.invalid {
+			t.Errorf("%s: have error %v, want invalid %v", tt.name, err, tt.invalid)
+		}
+	}
+}
+
+// Tests that the parent beacon root is required exactly from the Cancun fork on,
+// flipping the field on both sides of the fork block.
+func TestVerifyParentBeaconRoot(t *testing.T) {
+	config := *londonConfig
+	config.ShanghaiTime, config.CancunTime = new(uint64), new(uint64)
+	*config.CancunTime = 10
+
+	root := &common.Hash{0x01}
+	tests := []struct {
+		name    string
+		header  *types.Header
+		invalid bool
+	}{
+		{"before fork", &types.Header{Number: big.NewInt(1), Time: 9}, false},
+		{"before fork with root", &types.Header{Number: big.NewInt(1), Time: 9, ParentBeaconRoot: root}, true},
+		{"fork block", &types.Header{Number: big.NewInt(2), Time: 10, ParentBeaconRoot: root}, false},
+		{"fork block missing root", &types.Header{Number: big.NewInt(2), Time: 10}, true},
+		{"after fork", &types.Header{Number: big.NewInt(3), Time: 11, ParentBeaconRoot: root}, false},
+		{"after fork missing root", &types.Header{Number: big.NewInt(3), Time: 11}, true},
+	}
+	for _, tt := range tests {
+		if err := verifyParentBeaconRoot(&config, tt.header); (err != nil) != tt.invalid {
+			t.Errorf("%s: have error %v, want invalid %v", tt.name, err, tt.invalid)
+		}
+	}
+	// Chains without a config skip the check
+	if err := verifyParentBeaconRoot(nil, &types.Header{Number: big.NewInt(1), ParentBeaconRoot: root}); err != nil {
+		t.Errorf("check without config failed: %v", err)
+	}
+}
+
+// Tests that the seal hashes of both engines commit to the parent beacon root,
+// and that assembling a block leaves the root set by the caller untouched.
+func TestSealHashParentBeaconRoot(t *testing.T) {
+	header := &types.Header{Number: big.NewInt(1), Difficulty: new(big.Int), Time: 1, GasLimit: params.GenesisGasLimit, Extra: make([]byte, extraVanity+extraSeal)}
+	withRoot := func(root common.Hash) *types.Header {
+		header := types.CopyHeader(header)
+		header.ParentBeaconRoot = &root
+		return header
+	}
+	hashers := map[string]func(*types.Header) common.Hash{
+		"pow": sealHash,
+		"poa": poaSealHash,
+	}
+	for name, hash := range hashers {
+		var (
+			none = hash(header)
+			one  = hash(withRoot(common.Hash{0x01}))
+			two  = hash(withRoot(common.Hash{0x02}))
+		)
+		if none == one || one == two {
+			t.Errorf("%s: seal hash ignores the parent beacon root: %x, %x, %x", name, none, one, two)
+		}
+		if again := hash(withRoot(common.Hash{0x01})); again != one {
+			t.Errorf("%s: seal hash unstable: have %x, want %x", name, again, one)
+		}
+	}
+	cancun := *londonConfig
+	cancun.ShanghaiTime, cancun.CancunTime = new(uint64), new(uint64)
+
+	var (
+		chain  = newTestChain(&cancun, testGenesis(0))
+		engine = NewBeacon(NewPoW())
+		root   = common.Hash{0xbe, 0xac, 0x04}
+	)
+	block, err := engine.FinalizeAndAssemble(chain, withRoot(root), newTestState(t), nil, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to assemble block: %v", err)
+	}
+	if have := block.Header().ParentBeaconRoot; have == nil || *have != root {
+		t.Errorf("parent beacon root mismatch: have %v, want %x", have, root)
+	}
+}
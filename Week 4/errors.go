@@ -0,0 +1,135 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package consensus
+
+import "errors"
+
+var (
+	// ErrUnknownAncestor is returned when validating a block requires an ancestor
+	// that is unknown.
+	// ErrUnknownAncestor dikembalikan jika validasi block membutuhkan ancestor yang tidak dikenal.
+	ErrUnknownAncestor = errors.New("unknown ancestor")
+
+	// ErrPrunedAncestor is returned when validating a block requires an ancestor
+	// that is known, but the state of which is not available.
+	// ErrPrunedAncestor dikembalikan jika validasi block membutuhkan ancestor yang dikenal, namun state-nya tidak tersedia.
+	ErrPrunedAncestor = errors.New("pruned ancestor")
+
+	// ErrFutureBlock is returned when a block's timestamp is in the future according
+	// to the current node.
+	// ErrFutureBlock dikembalikan jika timestamp block berada di masa depan menurut node saat ini.
+	ErrFutureBlock = errors.New("block in the future")
+
+	// ErrInvalidNumber is returned if a block's number doesn't equal its parent's
+	// plus one.
+	// ErrInvalidNumber dikembalikan jika nomor block tidak sama dengan nomor parent ditambah satu.
+	ErrInvalidNumber = errors.New("invalid block number")
+
+	// ErrInvalidParentHash is returned if the parent a block is verified against
+	// is not the block its parent hash refers to.
+	// ErrInvalidParentHash dikembalikan jika parent yang dipakai untuk verifikasi block bukan block yang dirujuk
+	// oleh parent hash-nya.
+	ErrInvalidParentHash = errors.New("invalid parent hash")
+
+	// ErrInvalidGasLimit is returned if a block's gas limit is out of the bounds
+	// allowed relative to its parent's, or below the minimum gas limit.
+	// ErrInvalidGasLimit dikembalikan jika gas limit block berada di luar batas yang diizinkan terhadap gas limit
+	// parent, atau di bawah gas limit minimum.
+	ErrInvalidGasLimit = errors.New("invalid gas limit")
+
+	// ErrInvalidDifficulty is returned if a block's difficulty doesn't match the
+	// one the difficulty algorithm computes for it.
+	// ErrInvalidDifficulty dikembalikan jika tingkat kesulitan block tidak sama dengan hasil algoritma tingkat kesulitan.
+	ErrInvalidDifficulty = errors.New("invalid difficulty")
+
+	// ErrBannedHash is returned if a block to import is on the denylist of bad
+	// blocks.
+	// ErrBannedHash dikembalikan jika block yang akan diimpor termasuk dalam daftar block buruk.
+	ErrBannedHash = errors.New("banned hash")
+
+	// ErrBadForkHash is returned if a block at a pinned fork height is not the
+	// pinned block, i.e. its chain diverges from the canonical one at the fork.
+	// ErrBadForkHash dikembalikan jika block pada ketinggian fork yang disematkan bukan block yang disematkan.
+	ErrBadForkHash = errors.New("bad fork hash")
+
+	// ErrRemoteStale is returned by ValidateForkID if a remote fork checksum is a
+	// subset of our already applied forks, but the announced next fork block is
+	// not on our already passed chain.
+	// ErrRemoteStale dikembalikan oleh 'validate fork id' jika peer belum mengetahui fork yang sudah dilewati chain lokal.
+	ErrRemoteStale = errors.New("remote needs update")
+
+	// ErrLocalIncompatibleOrStale is returned by ValidateForkID if a remote fork
+	// checksum does not match any local checksum variation, signalling that the
+	// two chains have diverged in the past at some point (possibly at genesis).
+	// ErrLocalIncompatibleOrStale dikembalikan oleh 'validate fork id' jika chain lokal dan peer telah berpisah.
+	ErrLocalIncompatibleOrStale = errors.New("local incompatible or needs update")
+
+	// ErrInvalidTerminalBlock is returned if a block is invalid by the terminal
+	// block determination.
+	// ErrInvalidTerminalBlock dikembalikan jika block tidak valid menurut penentuan terminal block.
+	ErrInvalidTerminalBlock = errors.New("invalid terminal block")
+
+	// ErrTimestampTooOld is returned if a block's timestamp is not strictly greater
+	// than the timestamp of its parent.
+	// ErrTimestampTooOld dikembalikan jika timestamp block tidak lebih besar dari timestamp parent.
+	ErrTimestampTooOld = errors.New("timestamp older than parent")
+
+	// ErrExtraDataTooLong is returned if a header's extra-data section is longer
+	// than the engine allows.
+	// ErrExtraDataTooLong dikembalikan jika bagian extra-data header lebih panjang dari yang diizinkan engine.
+	ErrExtraDataTooLong = errors.New("extra-data too long")
+
+	// ErrAborted is reported for the headers of a batch left unverified because
+	// the verification gave up on the batch at an earlier failure.
+	// ErrAborted dilaporkan untuk header dalam batch yang tidak diverifikasi karena verifikasi berhenti pada kegagalan sebelumnya.
+	ErrAborted = errors.New("verification aborted")
+
+	// ErrInvalidUncleHash is returned if a block's uncle hash claims an empty
+	// uncle set while the block carries uncles, or the other way around.
+	// ErrInvalidUncleHash dikembalikan jika uncle hash block tidak sesuai dengan ada tidaknya uncle pada block.
+	ErrInvalidUncleHash = errors.New("invalid uncle hash")
+
+	// ErrTooManyUncles is returned if a block includes more uncles than allowed.
+	// ErrTooManyUncles dikembalikan jika block membawa uncle lebih banyak dari yang diizinkan.
+	ErrTooManyUncles = errors.New("too many uncles")
+
+	// ErrDuplicateUncle is returned if an uncle is included more than once,
+	// either by the block itself or by one of its recent ancestors.
+	// ErrDuplicateUncle dikembalikan jika sebuah uncle dimasukkan lebih dari sekali, baik oleh block itu sendiri
+	// maupun oleh ancestor terdekatnya.
+	ErrDuplicateUncle = errors.New("duplicate uncle")
+
+	// ErrUncleIsAncestor is returned if an uncle is an ancestor of the block
+	// including it.
+	// ErrUncleIsAncestor dikembalikan jika sebuah uncle merupakan ancestor dari block yang memasukkannya.
+	ErrUncleIsAncestor = errors.New("uncle is ancestor")
+
+	// ErrUncleIsCanonical is returned if an uncle is the canonical block at its
+	// height, which is rewarded as a block and never as an uncle.
+	// ErrUncleIsCanonical dikembalikan jika sebuah uncle merupakan block kanonik pada ketinggiannya.
+	ErrUncleIsCanonical = errors.New("uncle is canonical")
+
+	// ErrDanglingUncle is returned if an uncle's parent is not one of the recent
+	// ancestors of the block including it.
+	// ErrDanglingUncle dikembalikan jika parent dari uncle bukan ancestor terdekat dari block yang memasukkannya.
+	ErrDanglingUncle = errors.New("uncle's parent is not ancestor")
+
+	// ErrUncleTooDeep is returned if an uncle lags too many generations behind
+	// the block including it.
+	// ErrUncleTooDeep dikembalikan jika sebuah uncle tertinggal terlalu banyak generasi dari block yang memasukkannya.
+	ErrUncleTooDeep = errors.New("uncle too deep")
+)
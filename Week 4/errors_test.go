@@ -0,0 +1,118 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package consensus
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that every header verification failure is reported with its sentinel
+// error, retrievable via errors.Is through any wrapping.
+func TestVerifyHeaderSentinels(t *testing.T) {
+	var (
+		now     = time.Unix(1_000_000, 0)
+		genesis = testGenesis(params.MinimumDifficulty.Int64())
+	)
+	genesis.Time = uint64(now.Unix()) - 60
+	chain := newTestChain(frontierConfig, genesis)
+
+	// child creates a valid child of the genesis, modified by fn
+	engine := NewPoW(WithClock(func() time.Time { return now }))
+	child := func(fn func(*types.Header)) *types.Header {
+		header := &types.Header{
+			ParentHash: genesis.Hash(),
+			UncleHash:  types.EmptyUncleHash,
+			Number:     big.NewInt(1),
+			GasLimit:   genesis.GasLimit,
+			Time:       genesis.Time + 1,
+		}
+		header.Difficulty = engine.CalcDifficulty(chain, header.Time, genesis)
+		if fn != nil {
+			fn(header)
+		}
+		return header
+	}
+	banned := child(func(h *types.Header) { h.Extra = []byte("banned") })
+	BanHash(banned.Hash())
+	defer func() {
+		badHashesLock.Lock()
+		delete(BadHashes, banned.Hash())
+		badHashesLock.Unlock()
+	}()
+
+	tests := []struct {
+		name   string
+		engine Engine
+		header *types.Header
+		want   error
+	}{
+		{"valid", engine, child(nil), nil},
+		{"unknown ancestor", engine, child(func(h *types.Header) { h.ParentHash = common.Hash{0x01} }), ErrUnknownAncestor},
+		{"future block", engine, child(func(h *types.Header) { h.Time = uint64(now.Unix()) + 60 }), ErrFutureBlock},
+		{"timestamp too old", engine, child(func(h *types.Header) { h.Time = genesis.Time }), ErrTimestampTooOld},
+		{"gas limit", engine, child(func(h *types.Header) { h.GasLimit *= 2 }), ErrInvalidGasLimit},
+		{"difficulty", engine, child(func(h *types.Header) { h.Difficulty = new(big.Int).Add(h.Difficulty, common.Big1) }), ErrInvalidDifficulty},
+		{"extra-data", engine, child(func(h *types.Header) { h.Extra = make([]byte, params.MaximumExtraDataSize+1) }), ErrExtraDataTooLong},
+		{"banned hash", engine, banned, ErrBannedHash},
+		{"fork hash", NewPoW(WithClock(func() time.Time { return now }), WithForkHashes(ForkHashes{1: {0x01}})), child(nil), ErrBadForkHash},
+	}
+	for _, tt := range tests {
+		if err := tt.engine.VerifyHeader(chain, tt.header, false); !errors.Is(err, tt.want) {
+			t.Errorf("%s: error mismatch: have %v, want %v", tt.name, err, tt.want)
+		}
+	}
+	// The chain linkage is checked independently of the engines
+	first := child(nil)
+	gap := child(func(h *types.Header) { h.ParentHash, h.Number = first.Hash(), big.NewInt(3) })
+	if err := VerifyChain(chain, []*types.Header{first, gap}); !errors.Is(err, ErrInvalidNumber) {
+		t.Errorf("number gap error mismatch: have %v, want %v", err, ErrInvalidNumber)
+	}
+	if err := VerifyChain(chain, []*types.Header{child(nil), child(nil)}); !errors.Is(err, ErrInvalidParentHash) {
+		t.Errorf("parent hash error mismatch: have %v, want %v", err, ErrInvalidParentHash)
+	}
+}
+
+// Tests that a proof-of-stake header on top of a parent short of the terminal
+// total difficulty is rejected as an invalid terminal block.
+func TestVerifyHeaderInvalidTerminalBlock(t *testing.T) {
+	config := *frontierConfig
+	config.TerminalTotalDifficulty = big.NewInt(1_000_000)
+
+	var (
+		genesis = testGenesis(params.MinimumDifficulty.Int64())
+		chain   = newTestChain(&config, genesis)
+		engine  = NewBeacon(NewFaker())
+	)
+	header := &types.Header{
+		ParentHash: genesis.Hash(),
+		UncleHash:  types.EmptyUncleHash,
+		Number:     big.NewInt(1),
+		GasLimit:   genesis.GasLimit,
+		Time:       genesis.Time + 1,
+		Difficulty: new(big.Int),
+	}
+	if err := engine.VerifyHeader(chain, header, false); !errors.Is(err, ErrInvalidTerminalBlock) {
+		t.Errorf("error mismatch: have %v, want %v", err, ErrInvalidTerminalBlock)
+	}
+}
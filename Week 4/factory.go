@@ -0,0 +1,71 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package consensus
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+var errContradictoryConfig = errors.New("chain config contains both ethash and clique sections")
+
+// EngineOverride forces CreateEngineWithOverride to construct a testing engine
+// regardless of the consensus sections of the chain config.
+// EngineOverride memaksa 'create engine with override' untuk membuat engine pengujian tanpa melihat konfigurasi consensus.
+type EngineOverride uint
+
+const (
+	OverrideNone EngineOverride = iota // Pick the engine from the chain config
+	OverrideDev                        // Instant sealing developer engine
+	OverrideFake                       // Fake proof-of-work engine accepting every header
+)
+
+// CreateEngine constructs the consensus engine described by the chain config: a
+// proof-of-authority engine if it has a Clique section, proof-of-work otherwise.
+// metoda 'create engine' akan membuat consensus engine sesuai konfigurasi chain: proof-of-authority jika ada bagian
+// Clique, proof-of-work jika tidak.
+func CreateEngine(config *params.ChainConfig, db ethdb.Database) (Engine, error) {
+	return CreateEngineWithOverride(config, db, OverrideNone)
+}
+
+// CreateEngineWithOverride is similar to CreateEngine, but constructs the given
+// testing engine instead if an override is requested.
+// metoda 'create engine with override' sama dengan 'create engine', namun membuat engine pengujian jika diminta.
+func CreateEngineWithOverride(config *params.ChainConfig, db ethdb.Database, override EngineOverride) (Engine, error) {
+	if config == nil {
+		return nil, errMissingChainConfig
+	}
+	if config.Ethash != nil && config.Clique != nil {
+		return nil, errContradictoryConfig
+	}
+	switch override {
+	case OverrideNone:
+	case OverrideDev:
+		return NewDev(devPeriod(config)), nil
+	case OverrideFake:
+		return NewFaker(), nil
+	default:
+		return nil, fmt.Errorf("unknown engine override %d", override)
+	}
+	if config.Clique != nil {
+		return NewPoA(config.Clique, db), nil
+	}
+	return NewPoW(), nil
+}
@@ -0,0 +1,66 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package consensus
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that the factory picks the engine described by the chain config or the
+// override, and rejects configs it cannot make sense of.
+func TestCreateEngine(t *testing.T) {
+	var (
+		db       = rawdb.NewMemoryDatabase()
+		clique   = &params.ChainConfig{ChainID: big.NewInt(1), Clique: &params.CliqueConfig{Period: 5, Epoch: 30000}}
+		ethash   = &params.ChainConfig{ChainID: big.NewInt(1), Ethash: new(params.EthashConfig)}
+		contrary = &params.ChainConfig{ChainID: big.NewInt(1), Ethash: new(params.EthashConfig), Clique: &params.CliqueConfig{Period: 5}}
+	)
+	tests := []struct {
+		config   *params.ChainConfig
+		override EngineOverride
+		check    func(Engine) bool
+		err      error
+	}{
+		{clique, OverrideNone, func(e Engine) bool { _, ok := e.(*poaEngine); return ok }, nil},
+		{ethash, OverrideNone, func(e Engine) bool { pow, ok := e.(*powEngine); return ok && pow.mode == ModeNormal }, nil},
+		{frontierConfig, OverrideNone, func(e Engine) bool { pow, ok := e.(*powEngine); return ok && pow.mode == ModeNormal }, nil},
+		{clique, OverrideDev, func(e Engine) bool { dev, ok := e.(*devEngine); return ok && dev.period == 5 }, nil},
+		{ethash, OverrideDev, func(e Engine) bool { dev, ok := e.(*devEngine); return ok && dev.period == 0 }, nil},
+		{ethash, OverrideFake, func(e Engine) bool { pow, ok := e.(*powEngine); return ok && pow.mode == ModeFake }, nil},
+		{nil, OverrideNone, nil, errMissingChainConfig},
+		{contrary, OverrideNone, nil, errContradictoryConfig},
+		{contrary, OverrideFake, nil, errContradictoryConfig},
+	}
+	for i, tt := range tests {
+		engine, err := CreateEngineWithOverride(tt.config, db, tt.override)
+		if !errors.Is(err, tt.err) {
+			t.Errorf("test %d: error mismatch: have %v, want %v", i, err, tt.err)
+			continue
+		}
+		if tt.check != nil && !tt.check(engine) {
+			t.Errorf("test %d: unexpected engine %T", i, engine)
+		}
+	}
+	if _, err := CreateEngineWithOverride(ethash, db, EngineOverride(100)); err == nil {
+		t.Errorf("unknown override accepted")
+	}
+}
@@ -0,0 +1,46 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package consensus
+
+import (
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// IsCanonicalReplacement applies the heaviest chain rule, reporting whether the
+// branch ending in newHead should replace the canonical chain, which it should
+// only if its total difficulty is strictly greater than the current head's. On
+// a tie the current head is kept. Both total difficulties are retrieved via
+// GetTd, failing with ErrUnknownAncestor if either isn't known.
+// metoda 'is canonical replacement' akan menerapkan aturan chain terberat: branch dengan head baru menggantikan
+// chain kanonik hanya jika total difficulty-nya lebih besar dari head saat ini.
+func IsCanonicalReplacement(chain ChainHeaderReader, newHead *types.Header) (bool, error) {
+	head := chain.CurrentHeader()
+	if head == nil {
+		return false, errNoCurrentHeader
+	}
+	headTd := chain.GetTd(head.Hash(), head.Number.Uint64())
+	if headTd == nil {
+		return false, fmt.Errorf("%w: total difficulty of current head %d unknown", ErrUnknownAncestor, head.Number)
+	}
+	newTd := chain.GetTd(newHead.Hash(), newHead.Number.Uint64())
+	if newTd == nil {
+		return false, fmt.Errorf("%w: total difficulty of new head %d unknown", ErrUnknownAncestor, newHead.Number)
+	}
+	return newTd.Cmp(headTd) > 0, nil
+}
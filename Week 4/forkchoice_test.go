@@ -0,0 +1,89 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package consensus
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// makeBranch creates n headers of the given difficulty on top of parent, with
+// the coinbase telling the branches apart.
+func makeBranch(parent *types.Header, n int, difficulty int64, coinbase byte) []*types.Header {
+	headers := make([]*types.Header, n)
+	for i := range headers {
+		headers[i] = &types.Header{
+			ParentHash: parent.Hash(),
+			Coinbase:   common.Address{coinbase},
+			Number:     new(big.Int).Add(parent.Number, common.Big1),
+			Difficulty: big.NewInt(difficulty),
+			Time:       parent.Time + 1,
+		}
+		parent = headers[i]
+	}
+	return headers
+}
+
+// Tests the heaviest chain rule between competing branches: a branch replaces
+// the canonical one only with a strictly greater total difficulty, however
+// long either branch is.
+func TestIsCanonicalReplacement(t *testing.T) {
+	var (
+		genesis   = testGenesis(1000)
+		chain     = newTestChain(frontierConfig, genesis)
+		canonical = makeBranch(genesis, 3, 100, 0xaa) // Total difficulty 1300
+		heavier   = makeBranch(genesis, 2, 200, 0xbb) // Total difficulty 1400
+		tied      = makeBranch(genesis, 2, 150, 0xcc) // Total difficulty 1300
+		lighter   = makeBranch(genesis, 4, 50, 0xdd)  // Total difficulty 1200
+	)
+	for _, branch := range [][]*types.Header{heavier, tied, lighter, canonical} {
+		chain.insert(branch...)
+	}
+	tests := []struct {
+		name string
+		head *types.Header
+		want bool
+	}{
+		{"heavier, shorter", heavier[1], true},
+		{"tied", tied[1], false},
+		{"lighter, longer", lighter[3], false},
+		{"current head", canonical[2], false},
+		{"canonical ancestor", canonical[1], false},
+	}
+	for _, tt := range tests {
+		have, err := IsCanonicalReplacement(chain, tt.head)
+		if err != nil {
+			t.Fatalf("%s: failed to compare branches: %v", tt.name, err)
+		}
+		if have != tt.want {
+			t.Errorf("%s: replacement mismatch: have %t, want %t", tt.name, have, tt.want)
+		}
+	}
+	// Heads without a known total difficulty can't be compared
+	dangling := makeBranch(&types.Header{Number: big.NewInt(10), Difficulty: common.Big1}, 1, 1000, 0xee)[0]
+	if _, err := IsCanonicalReplacement(chain, dangling); !errors.Is(err, ErrUnknownAncestor) {
+		t.Errorf("unknown head error mismatch: have %v, want %v", err, ErrUnknownAncestor)
+	}
+	chain.head = nil
+	if _, err := IsCanonicalReplacement(chain, heavier[1]); !errors.Is(err, errNoCurrentHeader) {
+		t.Errorf("missing current head error mismatch: have %v, want %v", err, errNoCurrentHeader)
+	}
+}
@@ -0,0 +1,45 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package consensus
+
+import (
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// ForkHashes pins the hashes of the canonical blocks at specific heights, e.g.
+// the blocks of hard forks where the network split. Chains diverging at any of
+// the pinned heights are rejected.
+// ForkHashes menyimpan hash block kanonik pada ketinggian tertentu, misalnya block hard fork tempat jaringan terpecah.
+type ForkHashes map[uint64]common.Hash
+
+// VerifyForkHashes verifies that a header at a pinned height is the pinned
+// block. Headers at any other height pass with a single map lookup.
+// metoda 'verify fork hashes' akan memverifikasi bahwa header pada ketinggian yang disematkan adalah block yang
+// disematkan.
+func VerifyForkHashes(pins ForkHashes, header *types.Header) error {
+	want, ok := pins[header.Number.Uint64()]
+	if !ok {
+		return nil
+	}
+	if hash := header.Hash(); hash != want {
+		return fmt.Errorf("%w: have %x, want %x", ErrBadForkHash, hash, want)
+	}
+	return nil
+}
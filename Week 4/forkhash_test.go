@@ -0,0 +1,60 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package consensus
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that a header at a pinned height passes only if it's the pinned block,
+// while its sibling at the same height is rejected.
+func TestVerifyForkHashes(t *testing.T) {
+	var (
+		engine    = NewPoW()
+		genesis   = testGenesis(params.MinimumDifficulty.Int64())
+		canonical = makeHeaders(engine, newTestChain(frontierConfig, genesis), genesis, 3, 10)
+		sibling   = makeHeaders(engine, newTestChain(frontierConfig, genesis), genesis, 3, 11)
+		pins      = ForkHashes{2: canonical[1].Hash()}
+	)
+	for _, header := range canonical {
+		if err := VerifyForkHashes(pins, header); err != nil {
+			t.Errorf("canonical header %d rejected: %v", header.Number, err)
+		}
+	}
+	if err := VerifyForkHashes(pins, sibling[0]); err != nil {
+		t.Errorf("sibling at unpinned height rejected: %v", err)
+	}
+	if err := VerifyForkHashes(pins, sibling[1]); !errors.Is(err, ErrBadForkHash) {
+		t.Errorf("sibling at pinned height error mismatch: have %v, want %v", err, ErrBadForkHash)
+	}
+	// The pins are consulted by the engine's own header verification
+	engine = NewPoW(WithForkHashes(pins))
+	for name, headers := range map[string][]*types.Header{"canonical": canonical, "sibling": sibling} {
+		chain := newTestChain(frontierConfig, genesis, headers[0])
+		err := engine.VerifyHeader(chain, headers[1], false)
+		if name == "canonical" && err != nil {
+			t.Errorf("canonical header rejected by engine: %v", err)
+		}
+		if name == "sibling" && !errors.Is(err, ErrBadForkHash) {
+			t.Errorf("sibling error mismatch: have %v, want %v", err, ErrBadForkHash)
+		}
+	}
+}
@@ -0,0 +1,161 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package consensus
+
+import (
+	"encoding/binary"
+	"hash/crc32"
+	"math"
+	"math/big"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// ForkID calculates the EIP-2124 fork identifier of a chain at the given head:
+// the CRC32 checksum of the genesis hash and every fork block passed so far,
+// along with the number of the next upcoming fork, or 0 if none is known.
+// metoda 'fork id' akan menghitung identitas fork EIP-2124 dari chain pada head tertentu, yaitu checksum CRC32 dari
+// hash genesis dan setiap block fork yang sudah dilewati, beserta nomor fork berikutnya.
+func ForkID(genesis common.Hash, config *params.ChainConfig, head uint64) (hash [4]byte, next uint64) {
+	// Calculate the starting checksum from the genesis hash
+	sum := crc32.ChecksumIEEE(genesis[:])
+
+	// Calculate the current fork checksum and the next fork block
+	for _, fork := range gatherForks(config) {
+		if fork <= head {
+			// Fork already passed, checksum the previous hash and the fork number
+			sum = checksumUpdate(sum, fork)
+			continue
+		}
+		next = fork
+		break
+	}
+	binary.BigEndian.PutUint32(hash[:], sum)
+	return hash, next
+}
+
+// ValidateForkID classifies the fork identifier announced by a remote peer
+// against the local chain at the given head, following the rules of EIP-2124.
+// Nil is returned if the remote is compatible, possibly just syncing or running
+// ahead. ErrRemoteStale is returned if the remote runs software unaware of a
+// fork the local chain knows about, and ErrLocalIncompatibleOrStale if the
+// chains diverged or the local node misses a fork the remote already passed.
+// metoda 'validate fork id' akan mengklasifikasikan identitas fork dari peer terhadap chain lokal sesuai aturan
+// EIP-2124: kompatibel (nil), basi (ErrRemoteStale), atau tidak kompatibel (ErrLocalIncompatibleOrStale).
+func ValidateForkID(genesis common.Hash, config *params.ChainConfig, head uint64, remoteHash [4]byte, remoteNext uint64) error {
+	// Calculate the all the valid fork hash and fork next combos
+	var (
+		forks = gatherForks(config)
+		sums  = make([][4]byte, len(forks)+1) // 0th is the genesis
+	)
+	hash := crc32.ChecksumIEEE(genesis[:])
+	binary.BigEndian.PutUint32(sums[0][:], hash)
+	for i, fork := range forks {
+		hash = checksumUpdate(hash, fork)
+		binary.BigEndian.PutUint32(sums[i+1][:], hash)
+	}
+	// Add an infinitely far future fork to make the unpassed fork always exist
+	forks = append(forks, math.MaxUint64)
+
+	for i, fork := range forks {
+		// If our head is beyond this fork, continue to the next (we have a dummy
+		// fork of maxuint64 as the last item to always fail this check eventually).
+		if head >= fork {
+			continue
+		}
+		// Found the first unpassed fork block, check if our current state matches
+		// the remote checksum (rule #1).
+		if sums[i] == remoteHash {
+			// Fork checksum matched, check if a remote future fork block already passed
+			// locally without the local node being aware of it (rule #1a).
+			if remoteNext > 0 && head >= remoteNext {
+				return ErrLocalIncompatibleOrStale
+			}
+			// Haven't passed locally a remote-only fork, accept the connection (rule #1b).
+			return nil
+		}
+		// The local and remote nodes are in different forks currently, check if the
+		// remote checksum is a subset of our local forks (rule #2).
+		for j := 0; j < i; j++ {
+			if sums[j] == remoteHash {
+				// Remote checksum is a subset, validate based on the announced next fork
+				if forks[j] != remoteNext {
+					return ErrRemoteStale
+				}
+				return nil
+			}
+		}
+		// Remote chain is not a subset of our local one, check if it's a superset by
+		// any chance, signalling that we're simply out of sync (rule #3).
+		for j := i + 1; j < len(sums); j++ {
+			if sums[j] == remoteHash {
+				// Yay, remote checksum is a superset, ignore upcoming forks
+				return nil
+			}
+		}
+		// No exact, subset or superset match. We are on differing chains, reject.
+		return ErrLocalIncompatibleOrStale
+	}
+	return nil // Unreachable thanks to the dummy fork
+}
+
+// checksumUpdate calculates the next IEEE CRC32 checksum based on the previous
+// one and a fork block number (equivalent to CRC32(original-blob || fork)).
+func checksumUpdate(hash uint32, fork uint64) uint32 {
+	var blob [8]byte
+	binary.BigEndian.PutUint64(blob[:], fork)
+	return crc32.Update(hash, crc32.IEEETable, blob[:])
+}
+
+// gatherForks gathers all the known block forks of the chain config in
+// ascending order, without duplicates and without the ones at genesis.
+func gatherForks(config *params.ChainConfig) []uint64 {
+	var forks []uint64
+	for _, block := range []*big.Int{
+		config.HomesteadBlock,
+		config.DAOForkBlock,
+		config.EIP150Block,
+		config.EIP155Block,
+		config.EIP158Block,
+		config.ByzantiumBlock,
+		config.ConstantinopleBlock,
+		config.PetersburgBlock,
+		config.IstanbulBlock,
+		config.MuirGlacierBlock,
+		config.BerlinBlock,
+		config.LondonBlock,
+		config.ArrowGlacierBlock,
+		config.GrayGlacierBlock,
+		config.MergeNetsplitBlock,
+	} {
+		if block != nil && block.Sign() > 0 {
+			forks = append(forks, block.Uint64())
+		}
+	}
+	sort.Slice(forks, func(i, j int) bool { return forks[i] < forks[j] })
+
+	// Deduplicate fork identifiers applying multiple forks
+	for i := 1; i < len(forks); i++ {
+		if forks[i] == forks[i-1] {
+			forks = append(forks[:i], forks[i+1:]...)
+			i--
+		}
+	}
+	return forks
+}
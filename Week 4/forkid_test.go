@@ -0,0 +1,210 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package consensus
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+var (
+	// mainnetGenesisHash and ropstenGenesisHash are the genesis hashes of the
+	// chains the EIP-2124 test vectors are published for.
+	mainnetGenesisHash = common.HexToHash("0xd4e56740f876aef8c010b86a40d5f56745a118d0906a34e69aec8c0db1cb8fa3")
+	ropstenGenesisHash = common.HexToHash("0x41941023680923e0fe4d74a34bdac8141f2540e3ae90623718e47d66d1ca4a2d")
+
+	// mainnetForkConfig holds the block forks of the Ethereum main network up
+	// to Gray Glacier.
+	mainnetForkConfig = &params.ChainConfig{
+		ChainID:             big.NewInt(1),
+		HomesteadBlock:      big.NewInt(1_150_000),
+		DAOForkBlock:        big.NewInt(1_920_000),
+		EIP150Block:         big.NewInt(2_463_000),
+		EIP155Block:         big.NewInt(2_675_000),
+		EIP158Block:         big.NewInt(2_675_000),
+		ByzantiumBlock:      big.NewInt(4_370_000),
+		ConstantinopleBlock: big.NewInt(7_280_000),
+		PetersburgBlock:     big.NewInt(7_280_000),
+		IstanbulBlock:       big.NewInt(9_069_000),
+		MuirGlacierBlock:    big.NewInt(9_200_000),
+		BerlinBlock:         big.NewInt(12_244_000),
+		LondonBlock:         big.NewInt(12_965_000),
+		ArrowGlacierBlock:   big.NewInt(13_773_000),
+		GrayGlacierBlock:    big.NewInt(15_050_000),
+	}
+	// ropstenForkConfig holds the block forks of the Ropsten test network up
+	// to Muir Glacier.
+	ropstenForkConfig = &params.ChainConfig{
+		ChainID:             big.NewInt(3),
+		HomesteadBlock:      big.NewInt(0),
+		EIP150Block:         big.NewInt(0),
+		EIP155Block:         big.NewInt(10),
+		EIP158Block:         big.NewInt(10),
+		ByzantiumBlock:      big.NewInt(1_700_000),
+		ConstantinopleBlock: big.NewInt(4_230_000),
+		PetersburgBlock:     big.NewInt(4_939_394),
+		IstanbulBlock:       big.NewInt(6_485_846),
+		MuirGlacierBlock:    big.NewInt(7_117_117),
+	}
+)
+
+// forkIDTest is a fork identifier expected for a chain at a given head.
+type forkIDTest struct {
+	config  *params.ChainConfig
+	genesis common.Hash
+	head    uint64
+	hash    uint32
+	next    uint64
+}
+
+// Tests the fork identifiers against the published EIP-2124 vectors.
+func TestForkID(t *testing.T) {
+	mainnet := func(head uint64, hash uint32, next uint64) forkIDTest {
+		return forkIDTest{mainnetForkConfig, mainnetGenesisHash, head, hash, next}
+	}
+	ropsten := func(head uint64, hash uint32, next uint64) forkIDTest {
+		return forkIDTest{ropstenForkConfig, ropstenGenesisHash, head, hash, next}
+	}
+	tests := []forkIDTest{
+		mainnet(0, 0xfc64ec04, 1_150_000),           // Unsynced
+		mainnet(1_149_999, 0xfc64ec04, 1_150_000),   // Last Frontier block
+		mainnet(1_150_000, 0x97c2c34c, 1_920_000),   // First Homestead block
+		mainnet(1_919_999, 0x97c2c34c, 1_920_000),   // Last Homestead block
+		mainnet(1_920_000, 0x91d1f948, 2_463_000),   // First DAO block
+		mainnet(2_463_000, 0x7a64da13, 2_675_000),   // First Tangerine block
+		mainnet(2_675_000, 0x3edd5b10, 4_370_000),   // First Spurious block
+		mainnet(4_370_000, 0xa00bc324, 7_280_000),   // First Byzantium block
+		mainnet(7_280_000, 0x668db0af, 9_069_000),   // First and last Constantinople, first Petersburg block
+		mainnet(9_069_000, 0x879d6e30, 9_200_000),   // First Istanbul block
+		mainnet(9_200_000, 0xe029e991, 12_244_000),  // First Muir Glacier block
+		mainnet(12_244_000, 0x0eb440f6, 12_965_000), // First Berlin block
+		mainnet(12_965_000, 0xb715077d, 13_773_000), // First London block
+		mainnet(13_773_000, 0x20c327fc, 15_050_000), // First Arrow Glacier block
+		mainnet(15_050_000, 0xf0afd0e3, 0),          // First Gray Glacier block
+		mainnet(20_000_000, 0xf0afd0e3, 0),          // Future block
+
+		ropsten(0, 0x30c7ddbc, 10),                // Unsynced, last Frontier, Homestead and first Tangerine block
+		ropsten(9, 0x30c7ddbc, 10),                // Last Tangerine block
+		ropsten(10, 0x63760190, 1_700_000),        // First Spurious block
+		ropsten(1_699_999, 0x63760190, 1_700_000), // Last Spurious block
+		ropsten(1_700_000, 0x3ea159c7, 4_230_000), // First Byzantium block
+		ropsten(4_229_999, 0x3ea159c7, 4_230_000), // Last Byzantium block
+		ropsten(4_230_000, 0x97b544f3, 4_939_394), // First Constantinople block
+		ropsten(4_939_393, 0x97b544f3, 4_939_394), // Last Constantinople block
+		ropsten(4_939_394, 0xd6e2149b, 6_485_846), // First Petersburg block
+		ropsten(6_485_845, 0xd6e2149b, 6_485_846), // Last Petersburg block
+		ropsten(6_485_846, 0x4bc66396, 7_117_117), // First Istanbul block
+		ropsten(7_117_116, 0x4bc66396, 7_117_117), // Last Istanbul block
+		ropsten(7_117_117, 0x6727ef90, 0),         // First Muir Glacier block
+	}
+	for _, tt := range tests {
+		hash, next := ForkID(tt.genesis, tt.config, tt.head)
+		if have := binary.BigEndian.Uint32(hash[:]); have != tt.hash || next != tt.next {
+			t.Errorf("chain %v, head %d: fork id mismatch: have %#x/%d, want %#x/%d", tt.config.ChainID, tt.head, have, next, tt.hash, tt.next)
+		}
+	}
+}
+
+// Tests the classification of remote fork identifiers against the published
+// EIP-2124 vectors, with the local node on mainnet as of Petersburg.
+func TestValidateForkID(t *testing.T) {
+	config := *mainnetForkConfig
+	config.IstanbulBlock, config.MuirGlacierBlock, config.BerlinBlock, config.LondonBlock = nil, nil, nil, nil
+	config.ArrowGlacierBlock, config.GrayGlacierBlock = nil, nil
+
+	tests := []struct {
+		head uint64
+		hash uint32
+		next uint64
+		want error
+	}{
+		// Local is mainnet Petersburg, remote announces the same. No future fork is announced.
+		{7_987_396, 0x668db0af, 0, nil},
+
+		// Local is mainnet Petersburg, remote announces the same. Remote also announces a next fork
+		// at block 0xffffffff, but that is uncertain.
+		{7_987_396, 0x668db0af, math.MaxUint64, nil},
+
+		// Local is mainnet currently in Byzantium only (so it's aware of Petersburg), remote announces
+		// also Byzantium, but it's not yet aware of Petersburg (e.g. non updated node before the fork).
+		// In this case we don't know if Petersburg passed yet or not.
+		{7_279_999, 0xa00bc324, 0, nil},
+
+		// Local is mainnet currently in Byzantium only (so it's aware of Petersburg), remote announces
+		// also Byzantium, and it's also aware of Petersburg (e.g. updated node before the fork). We
+		// don't know if Petersburg passed yet (will pass) or not.
+		{7_279_999, 0xa00bc324, 7_280_000, nil},
+
+		// Local is mainnet currently in Byzantium only (so it's aware of Petersburg), remote announces
+		// also Byzantium, and it's also aware of some random fork (e.g. misconfigured Petersburg). As
+		// neither forks passed at neither nodes, they may mismatch, but we still connect for now.
+		{7_279_999, 0xa00bc324, math.MaxUint64, nil},
+
+		// Local is mainnet Petersburg, remote announces Byzantium + knowledge about Petersburg. Remote
+		// is simply out of sync, accept.
+		{7_987_396, 0xa00bc324, 7_280_000, nil},
+
+		// Local is mainnet Petersburg, remote announces Spurious + knowledge about Byzantium. Remote
+		// is definitely out of sync. It may or may not need the Petersburg update, we don't know yet.
+		{7_987_396, 0x3edd5b10, 4_370_000, nil},
+
+		// Local is mainnet Byzantium, remote announces Petersburg. Local is out of sync, accept.
+		{7_279_999, 0x668db0af, 0, nil},
+
+		// Local is mainnet Spurious, remote announces Byzantium, but is not aware of Petersburg. Local
+		// out of sync. Local also knows about a future fork, but that is uncertain yet.
+		{4_369_999, 0xa00bc324, 0, nil},
+
+		// Local is mainnet Petersburg. remote announces Byzantium but is not aware of further forks.
+		// Remote needs software update.
+		{7_987_396, 0xa00bc324, 0, ErrRemoteStale},
+
+		// Local is mainnet Petersburg, and isn't aware of more forks. Remote announces Petersburg +
+		// 0xffffffff. Local needs software update, reject.
+		{7_987_396, 0x5cddc0e1, 0, ErrLocalIncompatibleOrStale},
+
+		// Local is mainnet Byzantium, and is aware of Petersburg. Remote announces Petersburg +
+		// 0xffffffff. Local needs software update, reject.
+		{7_279_999, 0x5cddc0e1, 0, ErrLocalIncompatibleOrStale},
+
+		// Local is mainnet Petersburg, remote is Rinkeby Petersburg.
+		{7_987_396, 0xafec6b27, 0, ErrLocalIncompatibleOrStale},
+
+		// Local is mainnet Petersburg, far in the future. Remote announces Gopherium (non existing fork)
+		// at some future block 88888888, for itself, but past block for local. Local is incompatible.
+		//
+		// This case detects non-upgraded nodes with majority hash power (typical Ropsten mess).
+		{88_888_888, 0x668db0af, 88_888_888, ErrLocalIncompatibleOrStale},
+
+		// Local is mainnet Byzantium. Remote is also in Byzantium, but announces Gopherium (non existing
+		// fork) at block 7279999, before Petersburg. Local is incompatible.
+		{7_279_999, 0xa00bc324, 7_279_999, ErrLocalIncompatibleOrStale},
+	}
+	for i, tt := range tests {
+		var hash [4]byte
+		binary.BigEndian.PutUint32(hash[:], tt.hash)
+		if err := ValidateForkID(mainnetGenesisHash, &config, tt.head, hash, tt.next); !errors.Is(err, tt.want) {
+			t.Errorf("test %d: validation error mismatch: have %v, want %v", i, err, tt.want)
+		}
+	}
+}
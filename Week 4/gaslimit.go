@@ -0,0 +1,100 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package consensus
+
+import (
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// GasLimitBounds configures the gas limit elasticity rule enforced before the
+// London fork. Zero fields fall back to the protocol defaults. From London on
+// the bound follows the gas target of EIP-1559 instead.
+// GasLimitBounds mengatur aturan elastisitas gas limit sebelum fork London. Field bernilai nol memakai nilai
+// default protokol.
+type GasLimitBounds struct {
+	Divisor uint64 // A gas limit moves by less than 1/Divisor of its parent's (0 = params.GasLimitBoundDivisor)
+	Floor   uint64 // Lowest gas limit a header may have (0 = params.MinGasLimit)
+}
+
+// divisor returns the configured bound divisor or the protocol default.
+func (b GasLimitBounds) divisor() uint64 {
+	if b.Divisor == 0 {
+		return params.GasLimitBoundDivisor
+	}
+	return b.Divisor
+}
+
+// floor returns the configured minimum gas limit or the protocol default.
+func (b GasLimitBounds) floor() uint64 {
+	if b.Floor == 0 {
+		return params.MinGasLimit
+	}
+	return b.Floor
+}
+
+// Verify checks that the header gas limit moves by less than 1/Divisor of the
+// parent gas limit and doesn't fall below the floor. A parent gas limit below
+// the divisor leaves no room to move at all.
+// metoda 'verify' akan mengecek bahwa gas limit header berubah kurang dari 1/Divisor gas limit parent dan tidak
+// di bawah batas minimum.
+func (b GasLimitBounds) Verify(parentGasLimit, headerGasLimit uint64) error {
+	diff := headerGasLimit - parentGasLimit
+	if headerGasLimit < parentGasLimit {
+		diff = parentGasLimit - headerGasLimit
+	}
+	// The limit is exclusive, so the largest move allowed is one below it
+	delta := parentGasLimit / b.divisor()
+	if delta > 0 {
+		delta--
+	}
+	if diff > delta {
+		return fmt.Errorf("%w: have %d, want %d +-= %d", ErrInvalidGasLimit, headerGasLimit, parentGasLimit, delta)
+	}
+	if floor := b.floor(); headerGasLimit < floor {
+		return fmt.Errorf("%w: have %d, below minimum %d", ErrInvalidGasLimit, headerGasLimit, floor)
+	}
+	return nil
+}
+
+// VerifyGaslimit verifies the header gas limit according increase/decrease
+// in relation to the parent gas limit, with the protocol default bounds.
+// metoda 'verify gaslimit' akan memverifikasi kenaikan/penurunan gas limit header terhadap gas limit parent.
+func VerifyGaslimit(parentGasLimit, headerGasLimit uint64) error {
+	return GasLimitBounds{}.Verify(parentGasLimit, headerGasLimit)
+}
+
+// verifyGasLimit checks the gas fields of a header against its parent: the
+// gas limit is capped, not exceeded by the gas used, and stays within the
+// bounds relative to the parent's. From the London fork on the bound is checked
+// against the parent's gas target by VerifyEIP1559Header instead.
+func verifyGasLimit(config *params.ChainConfig, bounds GasLimitBounds, parent, header *types.Header) error {
+	// Verify that the gas limit is <= 2^63-1
+	if header.GasLimit > params.MaxGasLimit {
+		return fmt.Errorf("%w: have %v, max %v", ErrInvalidGasLimit, header.GasLimit, params.MaxGasLimit)
+	}
+	// Verify that the gasUsed is <= gasLimit
+	if header.GasUsed > header.GasLimit {
+		return fmt.Errorf("invalid gasUsed: have %d, gasLimit %d", header.GasUsed, header.GasLimit)
+	}
+	if config != nil && config.IsLondon(header.Number) {
+		return nil
+	}
+	return bounds.Verify(parent.GasLimit, header.GasLimit)
+}
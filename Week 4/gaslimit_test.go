@@ -0,0 +1,159 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package consensus
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+func TestVerifyGaslimit(t *testing.T) {
+	const parent = 8_000_000 // moves by at most 8_000_000/1024 - 1 = 7811
+
+	tests := []struct {
+		parent, header uint64
+		ok             bool
+	}{
+		{parent, parent, true},
+		{parent, parent + 7811, true},
+		{parent, parent + 7812, false},
+		{parent, parent - 7811, true},
+		{parent, parent - 7812, false},
+		{params.MinGasLimit, params.MinGasLimit, true},
+		{params.MinGasLimit, params.MinGasLimit - 1, false},
+	}
+	for _, tt := range tests {
+		err := VerifyGaslimit(tt.parent, tt.header)
+		if tt.ok && err != nil {
+			t.Errorf("parent %d, header %d: unexpected error: %v", tt.parent, tt.header, err)
+		}
+		if !tt.ok && !errors.Is(err, ErrInvalidGasLimit) {
+			t.Errorf("parent %d, header %d: error mismatch: have %v, want %v", tt.parent, tt.header, err, ErrInvalidGasLimit)
+		}
+	}
+}
+
+// Tests that custom bounds reject gas limits stepping too far up, too far down
+// or below the floor, and that zero fields keep the protocol defaults.
+func TestGasLimitBounds(t *testing.T) {
+	bounds := GasLimitBounds{Divisor: 100, Floor: 1_000_000}
+	const parent = 2_000_000 // moves by at most 2_000_000/100 - 1 = 19_999
+
+	tests := []struct {
+		name           string
+		bounds         GasLimitBounds
+		parent, header uint64
+		ok             bool
+	}{
+		{"up", bounds, parent, parent + 19_999, true},
+		{"too far up", bounds, parent, parent + 20_000, false},
+		{"down", bounds, parent, parent - 19_999, true},
+		{"too far down", bounds, parent, parent - 20_000, false},
+		{"at floor", bounds, 1_000_000, 1_000_000, true},
+		{"below floor", bounds, 1_000_000, 999_999, false},
+		{"default divisor", GasLimitBounds{Floor: 1}, parent, parent + parent/params.GasLimitBoundDivisor, false},
+		{"default floor", GasLimitBounds{Divisor: 2}, params.MinGasLimit, params.MinGasLimit - 1, false},
+	}
+	for _, tt := range tests {
+		err := tt.bounds.Verify(tt.parent, tt.header)
+		if tt.ok && err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.name, err)
+		}
+		if !tt.ok && !errors.Is(err, ErrInvalidGasLimit) {
+			t.Errorf("%s: error mismatch: have %v, want %v", tt.name, err, ErrInvalidGasLimit)
+		}
+	}
+}
+
+// Tests that a parent gas limit below the divisor allows no move at all, and
+// that the reported bound doesn't wrap around.
+func TestGasLimitBoundsSmallParent(t *testing.T) {
+	bounds := GasLimitBounds{Divisor: 1024, Floor: 1}
+
+	if err := bounds.Verify(1000, 1000); err != nil {
+		t.Fatalf("unchanged gas limit rejected: %v", err)
+	}
+	err := bounds.Verify(1000, 1001)
+	if !errors.Is(err, ErrInvalidGasLimit) {
+		t.Fatalf("error mismatch: have %v, want %v", err, ErrInvalidGasLimit)
+	}
+	if !strings.HasSuffix(err.Error(), "+-= 0") {
+		t.Errorf("bound mismatch in message: %v", err)
+	}
+}
+
+// Tests that both the proof-of-work and proof-of-authority engines enforce the
+// gas limit bound on the boundary, with the default and with custom bounds.
+func TestVerifyHeaderGasLimit(t *testing.T) {
+	key, _ := newTestKey(t)
+	chain, _ := makeVotingChain(t, []*ecdsa.PrivateKey{key}, 1, nil)
+	genesis := chain.GetHeaderByNumber(0)
+
+	pow := func(gasLimit uint64) *types.Header {
+		header := &types.Header{
+			ParentHash: genesis.Hash(),
+			UncleHash:  types.EmptyUncleHash,
+			Number:     big.NewInt(1),
+			GasLimit:   gasLimit,
+			Time:       genesis.Time + 1,
+		}
+		header.Difficulty = NewPoW().CalcDifficulty(chain, header.Time, genesis)
+		return header
+	}
+	poa := func(gasLimit uint64) *types.Header {
+		return &types.Header{
+			ParentHash: genesis.Hash(),
+			UncleHash:  types.EmptyUncleHash,
+			Number:     big.NewInt(1),
+			GasLimit:   gasLimit,
+			Time:       genesis.Time + testCliqueConfig.Period,
+			Difficulty: diffInTurn,
+			Extra:      make([]byte, extraVanity+extraSeal),
+		}
+	}
+	bounds := GasLimitBounds{Divisor: 64}
+
+	engines := map[string]struct {
+		engine  Engine
+		divisor uint64
+		child   func(gasLimit uint64) *types.Header
+	}{
+		"pow":        {NewPoW(), params.GasLimitBoundDivisor, pow},
+		"poa":        {NewPoA(testCliqueConfig, nil), params.GasLimitBoundDivisor, poa},
+		"pow-bounds": {NewPoW(WithGasLimitBounds(bounds)), bounds.Divisor, pow},
+		"poa-bounds": {NewPoA(testCliqueConfig, nil, WithPoAGasLimitBounds(bounds)), bounds.Divisor, poa},
+	}
+	for name, e := range engines {
+		bound := genesis.GasLimit/e.divisor - 1
+		for _, gasLimit := range []uint64{genesis.GasLimit + bound, genesis.GasLimit - bound} {
+			if err := e.engine.VerifyHeader(chain, e.child(gasLimit), false); err != nil {
+				t.Errorf("%s: gas limit %d rejected: %v", name, gasLimit, err)
+			}
+		}
+		for _, gasLimit := range []uint64{genesis.GasLimit + bound + 1, genesis.GasLimit - bound - 1} {
+			if err := e.engine.VerifyHeader(chain, e.child(gasLimit), false); !errors.Is(err, ErrInvalidGasLimit) {
+				t.Errorf("%s: gas limit %d error mismatch: have %v, want %v", name, gasLimit, err, ErrInvalidGasLimit)
+			}
+		}
+	}
+}
@@ -0,0 +1,74 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package consensus
+
+import (
+	"errors"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+var (
+	errMissingChainConfig = errors.New("missing chain config")
+	errInvalidGenesis     = errors.New("invalid genesis header")
+)
+
+// BuildGenesis creates the genesis block of a chain, crediting every account of
+// the allocation with its balance. The header carries timestamp 0, the genesis
+// difficulty and the state root of the allocation, and is accepted by the
+// engines' VerifyHeader without a parent.
+// metoda 'build genesis' akan membuat block genesis dari sebuah chain dan mengisi saldo setiap akun pada alokasi.
+// Header memiliki timestamp 0, tingkat kesulitan genesis dan state root dari alokasi, dan diterima oleh
+// 'verify header' tanpa parent.
+func BuildGenesis(config *params.ChainConfig, alloc map[common.Address]*big.Int) (*types.Block, error) {
+	if config == nil {
+		return nil, errMissingChainConfig
+	}
+	statedb, err := state.New(types.EmptyRootHash, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	if err != nil {
+		return nil, err
+	}
+	for addr, balance := range alloc {
+		statedb.AddBalance(addr, balance)
+	}
+	head := &types.Header{
+		Number:     new(big.Int),
+		Time:       0,
+		GasLimit:   params.GenesisGasLimit,
+		Difficulty: new(big.Int).Set(params.GenesisDifficulty),
+		Root:       statedb.IntermediateRoot(false),
+	}
+	if config.IsLondon(common.Big0) {
+		head.BaseFee = new(big.Int).SetUint64(params.InitialBaseFee)
+	}
+	return types.NewBlock(head, nil, nil, nil, trie.NewStackTrie(nil)), nil
+}
+
+// verifyGenesis checks a header at number 0, which has no parent to be
+// verified against.
+func verifyGenesis(header *types.Header) error {
+	if header.ParentHash != (common.Hash{}) {
+		return errInvalidGenesis
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package consensus
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that a built genesis block round-trips through the chain by number and
+// is accepted by the engines without a parent.
+func TestBuildGenesis(t *testing.T) {
+	alloc := map[common.Address]*big.Int{
+		{1}: big.NewInt(1000),
+		{2}: big.NewInt(2000),
+	}
+	block, err := BuildGenesis(frontierConfig, alloc)
+	if err != nil {
+		t.Fatalf("failed to build genesis: %v", err)
+	}
+	header := block.Header()
+	if header.Number.Sign() != 0 || header.Time != 0 || header.Difficulty.Cmp(params.GenesisDifficulty) != 0 {
+		t.Errorf("genesis header mismatch: number %v, time %d, difficulty %v", header.Number, header.Time, header.Difficulty)
+	}
+	// The state root must commit to the allocation
+	again, err := BuildGenesis(frontierConfig, alloc)
+	if err != nil {
+		t.Fatalf("failed to rebuild genesis: %v", err)
+	}
+	if again.Root() != block.Root() {
+		t.Errorf("state root not deterministic: have %x, want %x", again.Root(), block.Root())
+	}
+	chain := newTestChain(frontierConfig, header)
+	if have := chain.GetHeaderByNumber(0); have == nil || have.Hash() != block.Hash() {
+		t.Fatalf("genesis not retrievable by number: have %v, want %x", have, block.Hash())
+	}
+	// The engines must accept the genesis without any chain to look its parent up in
+	for name, engine := range map[string]Engine{"pow": NewPoW(), "dev": NewDev(0)} {
+		if err := engine.VerifyHeader(newTestChain(frontierConfig), header, false); err != nil {
+			t.Errorf("%s: genesis failed verification: %v", name, err)
+		}
+	}
+	header.ParentHash = common.Hash{1}
+	if err := NewPoW().VerifyHeader(newTestChain(frontierConfig), header, false); !errors.Is(err, errInvalidGenesis) {
+		t.Errorf("genesis with parent error mismatch: have %v, want %v", err, errInvalidGenesis)
+	}
+	if _, err := BuildGenesis(nil, alloc); !errors.Is(err, errMissingChainConfig) {
+		t.Errorf("missing config error mismatch: have %v, want %v", err, errMissingChainConfig)
+	}
+}
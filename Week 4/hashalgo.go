@@ -0,0 +1,75 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package consensus
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"golang.org/x/crypto/blake2b"
+)
+
+// HashAlgo is a hash function usable for proof-of-work sealing.
+// HashAlgo adalah fungsi hash yang dapat digunakan untuk penyegelan proof-of-work.
+type HashAlgo interface {
+	// Sum returns the digest of data.
+	// metoda 'sum' akan mengembalikan digest dari data.
+	Sum(data []byte) []byte
+}
+
+var (
+	// Keccak256 hashes with the Keccak-256 function used throughout Ethereum.
+	// Keccak256 melakukan hash dengan fungsi Keccak-256 yang digunakan di seluruh Ethereum.
+	Keccak256 HashAlgo = keccak256Algo{}
+
+	// SHA256 hashes with SHA-256 as used by Bitcoin.
+	// SHA256 melakukan hash dengan SHA-256 seperti yang digunakan Bitcoin.
+	SHA256 HashAlgo = sha256Algo{}
+
+	// Blake2b hashes with the 256 bit variant of BLAKE2b.
+	// Blake2b melakukan hash dengan varian 256 bit dari BLAKE2b.
+	Blake2b HashAlgo = blake2bAlgo{}
+)
+
+type keccak256Algo struct{}
+
+func (keccak256Algo) Sum(data []byte) []byte { return crypto.Keccak256(data) }
+
+type sha256Algo struct{}
+
+func (sha256Algo) Sum(data []byte) []byte {
+	sum := sha256.Sum256(data)
+	return sum[:]
+}
+
+type blake2bAlgo struct{}
+
+func (blake2bAlgo) Sum(data []byte) []byte {
+	sum := blake2b.Sum256(data)
+	return sum[:]
+}
+
+// powHash computes algo(sealhash ++ nonce), with the nonce encoded as 8 big
+// endian bytes.
+func powHash(algo HashAlgo, hash common.Hash, nonce uint64) []byte {
+	var enc [common.HashLength + 8]byte
+	copy(enc[:], hash[:])
+	binary.BigEndian.PutUint64(enc[common.HashLength:], nonce)
+	return algo.Sum(enc[:])
+}
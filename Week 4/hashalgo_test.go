@@ -0,0 +1,119 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package consensus
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+var hashAlgos = []struct {
+	name string
+	algo HashAlgo
+}{
+	{"Keccak256", Keccak256},
+	{"SHA256", SHA256},
+	{"Blake2b", Blake2b},
+}
+
+// Tests that every hash algorithm seals blocks its own engine accepts, and that
+// the seal hash doesn't depend on the algorithm.
+func TestHashAlgoSealVerify(t *testing.T) {
+	genesis := testGenesis(params.MinimumDifficulty.Int64())
+	chain := newTestChain(frontierConfig, genesis)
+
+	header := sealHeader(t, NewPoW(), chain, genesis)
+	for _, tt := range hashAlgos {
+		engine := NewPoW(WithHashAlgo(tt.algo))
+		if have, want := engine.SealHash(header), NewPoW().SealHash(header); have != want {
+			t.Errorf("%s: seal hash mismatch: have %x, want %x", tt.name, have, want)
+		}
+		sealed := sealHeader(t, engine, chain, genesis)
+		if err := engine.VerifyHeader(chain, sealed, true); err != nil {
+			t.Errorf("%s: sealed header failed verification: %v", tt.name, err)
+		}
+	}
+}
+
+// Tests that a nonce valid under one hash algorithm is checked against the one
+// the verifying engine is configured with.
+func TestHashAlgoMismatch(t *testing.T) {
+	header := &types.Header{Number: big.NewInt(1), Difficulty: big.NewInt(1024)}
+	target := TargetFromDifficulty(header.Difficulty)
+	hash := NewPoW().SealHash(header)
+
+	// Find a nonce meeting the target under SHA-256 but not under Keccak-256
+	for nonce := uint64(0); ; nonce++ {
+		if new(big.Int).SetBytes(powHash(SHA256, hash, nonce)).Cmp(target) <= 0 &&
+			new(big.Int).SetBytes(powHash(Keccak256, hash, nonce)).Cmp(target) > 0 {
+			header.Nonce = types.EncodeNonce(nonce)
+			break
+		}
+	}
+	if err := NewPoW(WithHashAlgo(SHA256)).VerifySeal(nil, header); err != nil {
+		t.Errorf("seal rejected by its own algorithm: %v", err)
+	}
+	if err := NewPoW(WithHashAlgo(Keccak256)).VerifySeal(nil, header); !errors.Is(err, errInvalidPoW) {
+		t.Errorf("foreign seal error mismatch: have %v, want %v", err, errInvalidPoW)
+	}
+}
+
+// Benchmarks the raw proof-of-work hashing throughput of the algorithms.
+func BenchmarkHashAlgo(b *testing.B) {
+	hash := NewPoW().SealHash(testGenesis(1))
+	for _, bb := range hashAlgos {
+		b.Run(bb.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				powHash(bb.algo, hash, uint64(i))
+			}
+		})
+	}
+}
+
+// Benchmarks the sealing throughput of the algorithms at the minimum difficulty.
+func BenchmarkHashAlgoSeal(b *testing.B) {
+	genesis := testGenesis(params.MinimumDifficulty.Int64())
+	chain := newTestChain(frontierConfig, genesis)
+
+	for _, bb := range hashAlgos {
+		b.Run(bb.name, func(b *testing.B) {
+			engine := NewPoW(WithHashAlgo(bb.algo))
+			for i := 0; i < b.N; i++ {
+				header := &types.Header{
+					ParentHash: genesis.Hash(),
+					UncleHash:  types.EmptyUncleHash,
+					Number:     big.NewInt(1),
+					GasLimit:   genesis.GasLimit,
+					Time:       uint64(i + 1),
+				}
+				if err := engine.Prepare(chain, header); err != nil {
+					b.Fatalf("failed to prepare header: %v", err)
+				}
+				results, stop := make(chan *types.Block, 1), make(chan struct{})
+				if err := engine.Seal(chain, types.NewBlockWithHeader(header), results, stop); err != nil {
+					b.Fatalf("failed to seal block: %v", err)
+				}
+				<-results
+				close(stop)
+			}
+		})
+	}
+}
@@ -0,0 +1,62 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package consensus
+
+import (
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// headerHashes memoizes the hashes of the headers of a batch for the duration
+// of its verification. Unlike blocks, headers don't cache their hash, yet the
+// linkage, denylist, cache and error checks of every stage all need it. Each
+// hash is computed lazily, at most once, by whichever goroutine needs it first.
+type headerHashes struct {
+	headers []*types.Header
+	hashes  []common.Hash
+	once    []sync.Once
+}
+
+// newHeaderHashes creates the hash memo of a batch, computing no hash yet.
+func newHeaderHashes(headers []*types.Header) *headerHashes {
+	return &headerHashes{
+		headers: headers,
+		hashes:  make([]common.Hash, len(headers)),
+		once:    make([]sync.Once, len(headers)),
+	}
+}
+
+// hash returns the hash of the header at the given index of the batch, safe to
+// call concurrently.
+func (h *headerHashes) hash(index int) common.Hash {
+	h.once[index].Do(func() {
+		h.hashes[index] = h.headers[index].Hash()
+	})
+	return h.hashes[index]
+}
+
+// slice returns the memo of the headers from start up to end, sharing the
+// hashes already computed and those computed through either memo from now on.
+func (h *headerHashes) slice(start, end int) *headerHashes {
+	return &headerHashes{
+		headers: h.headers[start:end],
+		hashes:  h.hashes[start:end],
+		once:    h.once[start:end],
+	}
+}
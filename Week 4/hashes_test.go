@@ -0,0 +1,115 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package consensus
+
+import (
+	"errors"
+	"math/big"
+	"sync"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// Tests that the hash memo returns the hash of every header, consistently when
+// raced for by many goroutines, and that a slice of it shares the hashes with
+// the memo it was taken from.
+func TestHeaderHashes(t *testing.T) {
+	headers := make([]*types.Header, 64)
+	for i := range headers {
+		headers[i] = &types.Header{Number: big.NewInt(int64(i)), Difficulty: big.NewInt(1)}
+	}
+	hashes := newHeaderHashes(headers)
+
+	var pend sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		pend.Add(1)
+		go func() {
+			defer pend.Done()
+			for index := range headers {
+				if have, want := hashes.hash(index), headers[index].Hash(); have != want {
+					t.Errorf("header %d: hash mismatch: have %x, want %x", index, have, want)
+				}
+			}
+		}()
+	}
+	pend.Wait()
+
+	// A memo computes every hash once, later changes to the header go unseen
+	want := headers[40].Hash()
+	headers[40].Extra = []byte("changed")
+	if have := hashes.slice(32, 64).hash(8); have != want {
+		t.Errorf("sliced hash mismatch: have %x, want %x", have, want)
+	}
+}
+
+// Tests that memoizing the hashes leaves the results of a batch unchanged: the
+// valid headers pass, and the failure is reported with the hash of the header
+// at fault.
+func TestVerifyHeadersHashed(t *testing.T) {
+	genesis, headers, seals := makeTrapdoorHeaders(t, 50, 7)
+
+	_, results := NewPoW(WithHashAlgo(trapdoorAlgo{})).VerifyHeaders(newTestChain(frontierConfig, genesis), headers, seals)
+	for i := range headers {
+		err := <-results
+		if i != 7 {
+			if err != nil {
+				t.Errorf("header %d: verification failed: %v", i, err)
+			}
+			continue
+		}
+		var verr *HeaderVerifyError
+		if !errors.As(err, &verr) || !errors.Is(err, errInvalidPoW) {
+			t.Fatalf("header %d: error mismatch: have %v, want %v", i, err, errInvalidPoW)
+		}
+		if verr.Index != i || verr.Hash != headers[i].Hash() {
+			t.Errorf("header %d: failure identity mismatch: have #%d %x, want #%d %x", i, verr.Index, verr.Hash, i, headers[i].Hash())
+		}
+	}
+}
+
+// BenchmarkHeaderHashes compares looking up the hash of every header of a batch
+// as often as the verification stages do, once for the linkage, the denylist,
+// the verified cache and the error reporting each, recomputed every time or
+// memoized.
+func BenchmarkHeaderHashes(b *testing.B) {
+	const lookups = 4
+
+	headers := make([]*types.Header, 1024)
+	for i := range headers {
+		headers[i] = &types.Header{Number: big.NewInt(int64(i)), Difficulty: big.NewInt(1), Extra: make([]byte, 32)}
+	}
+	b.Run("direct", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			for _, header := range headers {
+				for j := 0; j < lookups; j++ {
+					header.Hash()
+				}
+			}
+		}
+	})
+	b.Run("memo", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			hashes := newHeaderHashes(headers)
+			for index := range headers {
+				for j := 0; j < lookups; j++ {
+					hashes.hash(index)
+				}
+			}
+		}
+	})
+}
@@ -0,0 +1,59 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package consensus
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// hashrateWindow is the number of seconds the hashrate is averaged over.
+const hashrateWindow = 10
+
+// hashMeter measures the hash attempts of a single mining worker over a
+// sliding window of one second slots. Marking is only ever done by the worker
+// owning the meter, while the rate may be read concurrently by anyone.
+type hashMeter struct {
+	counts [hashrateWindow]atomic.Uint64 // Attempts made within each slot
+	stamps [hashrateWindow]atomic.Int64  // Unix second each slot currently counts for
+}
+
+// mark records the given number of hash attempts at the current time.
+func (m *hashMeter) mark(attempts uint64) {
+	now := time.Now().Unix()
+
+	slot := now % hashrateWindow
+	if m.stamps[slot].Load() != now {
+		// Slot holds a stale second, recycle it for the current one
+		m.counts[slot].Store(0)
+		m.stamps[slot].Store(now)
+	}
+	m.counts[slot].Add(attempts)
+}
+
+// rate returns the hash attempts per second averaged over the window.
+func (m *hashMeter) rate() float64 {
+	now := time.Now().Unix()
+
+	var attempts uint64
+	for i := 0; i < hashrateWindow; i++ {
+		if now-m.stamps[i].Load() < hashrateWindow {
+			attempts += m.counts[i].Load()
+		}
+	}
+	return float64(attempts) / hashrateWindow
+}
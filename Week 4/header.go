@@ -0,0 +1,139 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package consensus
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// ParentVerifier is implemented by engines able to verify a header against a
+// parent the caller already holds, such as during block import, sparing the
+// lookup of the parent through the chain. The parent must be the header the
+// header's parent hash refers to, otherwise verification fails.
+// ParentVerifier adalah interface untuk engine yang dapat memverifikasi header terhadap parent yang sudah dimiliki
+// pemanggil, tanpa mencarinya di chain.
+type ParentVerifier interface {
+	// VerifyHeaderWithParent checks whether a header conforms to the consensus
+	// rules of the engine, given its parent.
+	// metoda 'verify header with parent' akan mengecek apakah header sesuai aturan consensus terhadap parent-nya.
+	VerifyHeaderWithParent(chain ChainHeaderReader, header, parent *types.Header, seal bool) error
+}
+
+// verifyHeaderBasics checks the structural linkage of a header to the parent
+// resolved for it from the chain, before any engine specific rules run: the
+// parent must be the block the header's parent hash refers to, and the header
+// must be numbered directly after it. This guards against chain readers handing
+// out a wrong parent as much as against malformed headers.
+func verifyHeaderBasics(chain ChainHeaderReader, header, parent *types.Header) error {
+	if hash := parent.Hash(); hash != header.ParentHash {
+		return fmt.Errorf("%w: have %x, want %x", ErrInvalidParentHash, header.ParentHash, hash)
+	}
+	if diff := new(big.Int).Sub(header.Number, parent.Number); diff.Cmp(common.Big1) != 0 {
+		return fmt.Errorf("%w: have %v, want %v", ErrInvalidNumber, header.Number, new(big.Int).Add(parent.Number, common.Big1))
+	}
+	return nil
+}
+
+// VerifyChain verifies the linkage of a contiguous sequence of headers: the
+// first must extend a header of the chain, every other one the header before
+// it, each numbered directly after and referring to its predecessor by hash.
+// The first failure is returned, wrapped with the offending header's number.
+// Only the structure is checked, the consensus rules are left to the engines.
+// metoda 'verify chain' akan memverifikasi keterkaitan rangkaian header yang berurutan: nomor block yang
+// berkesinambungan dan parent hash yang merujuk ke header sebelumnya. Error pertama akan dikembalikan.
+func VerifyChain(chain ChainHeaderReader, headers []*types.Header) error {
+	for i, header := range headers {
+		var parent *types.Header
+		if i == 0 {
+			if header.Number.Sign() == 0 {
+				return wrapHeaderError(header, ErrUnknownAncestor)
+			}
+			parent = chain.GetHeader(header.ParentHash, header.Number.Uint64()-1)
+			if parent == nil {
+				return wrapHeaderError(header, ErrUnknownAncestor)
+			}
+		} else {
+			parent = headers[i-1]
+		}
+		if err := verifyHeaderBasics(chain, header, parent); err != nil {
+			return wrapHeaderError(header, err)
+		}
+	}
+	return nil
+}
+
+// parentChain is a ChainHeaderReader knowing nothing but the chain config and a
+// single parent header, letting the parent-relative rules of the engines run
+// without access to the chain. Lookups of anything but the parent fail.
+type parentChain struct {
+	config *params.ChainConfig
+	parent *types.Header
+}
+
+// Config implements ChainHeaderReader, returning the chain config.
+func (c *parentChain) Config() *params.ChainConfig {
+	return c.config
+}
+
+// CurrentHeader implements ChainHeaderReader, returning the parent.
+func (c *parentChain) CurrentHeader() *types.Header {
+	return c.parent
+}
+
+// GetHeader implements ChainHeaderReader, returning the parent if requested.
+func (c *parentChain) GetHeader(hash common.Hash, number uint64) *types.Header {
+	if c.parent.Number.Uint64() == number && c.parent.Hash() == hash {
+		return c.parent
+	}
+	return nil
+}
+
+// GetHeaderByNumber implements ChainHeaderReader, returning the parent if
+// requested.
+func (c *parentChain) GetHeaderByNumber(number uint64) *types.Header {
+	if c.parent.Number.Uint64() == number {
+		return c.parent
+	}
+	return nil
+}
+
+// GetHeaderByHash implements ChainHeaderReader, returning the parent if
+// requested.
+func (c *parentChain) GetHeaderByHash(hash common.Hash) *types.Header {
+	if c.parent.Hash() == hash {
+		return c.parent
+	}
+	return nil
+}
+
+// GetTd implements ChainHeaderReader. The total difficulty of the parent is not
+// known, but a parent without difficulty can only exist past the merge, so the
+// terminal total difficulty is reported for it to be recognized as such.
+func (c *parentChain) GetTd(hash common.Hash, number uint64) *big.Int {
+	if c.GetHeader(hash, number) == nil || c.config == nil || c.config.TerminalTotalDifficulty == nil {
+		return nil
+	}
+	if c.parent.Difficulty == nil || c.parent.Difficulty.Sign() == 0 {
+		return new(big.Int).Set(c.config.TerminalTotalDifficulty)
+	}
+	return nil
+}
@@ -0,0 +1,189 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package consensus
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// lyingChain is a chain answering every header lookup at the given height with
+// the same header, whatever was asked for.
+type lyingChain struct {
+	*testChain
+	number uint64
+	header *types.Header
+}
+
+func (c *lyingChain) GetHeader(hash common.Hash, number uint64) *types.Header {
+	if number == c.number {
+		return c.header
+	}
+	return c.testChain.GetHeader(hash, number)
+}
+
+// Tests that every engine rejects a header whose parent, as returned by the
+// chain, isn't numbered right before it or hashes differently than claimed.
+func TestVerifyHeaderBasics(t *testing.T) {
+	key, _ := newTestKey(t)
+	base, _ := makeVotingChain(t, []*ecdsa.PrivateKey{key}, 1, nil)
+
+	parent := types.CopyHeader(base.GetHeaderByNumber(0))
+	parent.Number = big.NewInt(3)
+
+	tests := []struct {
+		name   string
+		header *types.Header
+		want   error
+	}{
+		{"number gap", &types.Header{ParentHash: parent.Hash(), Number: big.NewInt(500)}, ErrInvalidNumber},
+		{"parent hash", &types.Header{ParentHash: common.Hash{0x01}, Number: big.NewInt(4)}, ErrInvalidParentHash},
+	}
+	engines := map[string]Engine{
+		"pow":    NewPoW(),
+		"dev":    NewDev(0),
+		"beacon": NewBeacon(NewPoW()),
+		"poa":    NewPoA(testCliqueConfig, nil),
+	}
+	for name, engine := range engines {
+		for _, tt := range tests {
+			header := types.CopyHeader(tt.header)
+			header.UncleHash = types.EmptyUncleHash
+			header.Difficulty = big.NewInt(1)
+			header.GasLimit = params.GenesisGasLimit
+			header.Time = parent.Time + 1
+			header.Extra = make([]byte, extraVanity+extraSeal)
+
+			chain := &lyingChain{testChain: base, number: header.Number.Uint64() - 1, header: parent}
+			if err := engine.VerifyHeader(chain, header, false); !errors.Is(err, tt.want) {
+				t.Errorf("%s: %s: error mismatch: have %v, want %v", name, tt.name, err, tt.want)
+			}
+		}
+	}
+}
+
+// Tests that VerifyChain accepts a contiguous sequence and stops at the first
+// gap in numbering or broken parent hash link, naming the offending block.
+func TestVerifyChain(t *testing.T) {
+	var (
+		genesis = testGenesis(params.MinimumDifficulty.Int64())
+		chain   = newTestChain(frontierConfig, genesis)
+		headers = makeHeaders(NewPoW(), chain, genesis, 5, 10)
+	)
+	if err := VerifyChain(chain, headers); err != nil {
+		t.Fatalf("valid chain rejected: %v", err)
+	}
+	// Skip a number, keeping the link to the previous header intact
+	gap := types.CopyHeader(headers[3])
+	gap.ParentHash = headers[1].Hash()
+	gap.Number = big.NewInt(4)
+
+	// Refer to a parent that isn't the previous header, keeping the numbering
+	unlinked := types.CopyHeader(headers[2])
+	unlinked.ParentHash = headers[0].Hash()
+
+	tests := []struct {
+		name    string
+		headers []*types.Header
+		number  uint64
+		want    error
+	}{
+		{"number gap", []*types.Header{headers[0], headers[1], gap, headers[4]}, 4, ErrInvalidNumber},
+		{"parent hash", []*types.Header{headers[0], headers[1], unlinked, headers[3]}, 3, ErrInvalidParentHash},
+	}
+	for _, tt := range tests {
+		err := VerifyChain(chain, tt.headers)
+		if !errors.Is(err, tt.want) {
+			t.Errorf("%s: error mismatch: have %v, want %v", tt.name, err, tt.want)
+			continue
+		}
+		if !strings.HasPrefix(err.Error(), fmt.Sprintf("header %d ", tt.number)) {
+			t.Errorf("%s: error doesn't name block %d: %v", tt.name, tt.number, err)
+		}
+	}
+	// The first header must extend one already in the chain
+	orphan := types.CopyHeader(headers[0])
+	orphan.ParentHash = common.Hash{0x01}
+	if err := VerifyChain(chain, []*types.Header{orphan}); !errors.Is(err, ErrUnknownAncestor) {
+		t.Errorf("orphan error mismatch: have %v, want %v", err, ErrUnknownAncestor)
+	}
+}
+
+// Tests that engines verifying against a parent handed in by the caller reject
+// a parent other than the one the header refers to, even after the header
+// passed against the right one.
+func TestVerifyHeaderWithParentMismatch(t *testing.T) {
+	key, signer := newTestKey(t)
+	poaChain, _ := makeVotingChain(t, []*ecdsa.PrivateKey{key}, 1, nil)
+	poaGenesis := poaChain.GetHeaderByNumber(0)
+	poaHeader := &types.Header{
+		ParentHash: poaGenesis.Hash(),
+		UncleHash:  types.EmptyUncleHash,
+		Number:     big.NewInt(1),
+		GasLimit:   poaGenesis.GasLimit,
+		Time:       poaGenesis.Time + testCliqueConfig.Period,
+		Difficulty: diffInTurn,
+		Coinbase:   signer,
+	}
+	signHeader(t, poaHeader, key)
+
+	var (
+		powEngine  = NewTester()
+		powGenesis = testGenesis(params.MinimumDifficulty.Int64())
+		powHeader  = sealHeader(t, powEngine, newTestChain(frontierConfig, powGenesis), powGenesis)
+		devGenesis = testGenesis(1)
+		devHeader  = &types.Header{ParentHash: devGenesis.Hash(), Number: big.NewInt(1), Time: devGenesis.Time + 1}
+	)
+	tests := []struct {
+		name   string
+		engine Engine
+		chain  *testChain
+		parent *types.Header
+		header *types.Header
+	}{
+		{"pow", powEngine, newTestChain(frontierConfig, powGenesis), powGenesis, powHeader},
+		{"poa", NewPoA(testCliqueConfig, nil), poaChain, poaGenesis, poaHeader},
+		{"dev", NewDev(0), newTestChain(frontierConfig, devGenesis), devGenesis, devHeader},
+	}
+	for _, tt := range tests {
+		verifier, ok := tt.engine.(ParentVerifier)
+		if !ok {
+			t.Errorf("%s: engine doesn't implement ParentVerifier", tt.name)
+			continue
+		}
+		if err := verifier.VerifyHeaderWithParent(tt.chain, tt.header, tt.parent, true); err != nil {
+			t.Errorf("%s: valid header rejected: %v", tt.name, err)
+		}
+		// A sibling of the parent has the right number, but not the right hash
+		sibling := types.CopyHeader(tt.parent)
+		sibling.Time++
+		if err := verifier.VerifyHeaderWithParent(tt.chain, tt.header, sibling, true); !errors.Is(err, ErrInvalidParentHash) {
+			t.Errorf("%s: mismatched parent error: have %v, want %v", tt.name, err, ErrInvalidParentHash)
+		}
+		if err := verifier.VerifyHeaderWithParent(tt.chain, tt.header, nil, true); !errors.Is(err, ErrUnknownAncestor) {
+			t.Errorf("%s: missing parent error: have %v, want %v", tt.name, err, ErrUnknownAncestor)
+		}
+	}
+}
@@ -0,0 +1,219 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package consensus
+
+import (
+	"context"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// logEngine is a consensus engine delegating every call unchanged to an inner
+// engine, logging the method, block, duration and error of each call.
+// logEngine adalah consensus engine yang meneruskan setiap pemanggilan ke engine di dalamnya tanpa perubahan,
+// sambil mencatat nama metoda, block, durasi dan error dari setiap pemanggilan.
+type logEngine struct {
+	inner  Engine     // Consensus engine doing the actual work
+	logger log.Logger // Logger to record the calls into
+}
+
+// WrapWithLogging wraps a consensus engine so that every call made to it is
+// logged at debug level, which helps tracking down which rule rejected a block.
+// metoda 'wrap with logging' akan membungkus consensus engine sehingga setiap pemanggilan dicatat pada level debug.
+func WrapWithLogging(inner Engine, logger log.Logger) Engine {
+	return &logEngine{inner: inner, logger: logger}
+}
+
+// log records a finished call to the inner engine.
+func (l *logEngine) log(method string, start time.Time, err error, ctx ...interface{}) {
+	ctx = append([]interface{}{"method", method}, ctx...)
+	ctx = append(ctx, "elapsed", common.PrettyDuration(time.Since(start)))
+	if err != nil {
+		l.logger.Debug("Consensus call failed", append(ctx, "err", err)...)
+		return
+	}
+	l.logger.Debug("Consensus call", ctx...)
+}
+
+// Author implements Engine, delegating to the inner engine.
+// metoda 'author' akan meneruskan ke engine di dalamnya.
+func (l *logEngine) Author(header *types.Header) (common.Address, error) {
+	start := time.Now()
+	author, err := l.inner.Author(header)
+	l.log("Author", start, err, "number", header.Number, "hash", header.Hash(), "author", author)
+	return author, err
+}
+
+// VerifyHeader implements Engine, delegating to the inner engine.
+// metoda 'verify header' akan meneruskan ke engine di dalamnya.
+func (l *logEngine) VerifyHeader(chain ChainHeaderReader, header *types.Header, seal bool) error {
+	start := time.Now()
+	err := l.inner.VerifyHeader(chain, header, seal)
+	l.log("VerifyHeader", start, err, "number", header.Number, "hash", header.Hash(), "seal", seal)
+	return err
+}
+
+// VerifyHeaders implements Engine, delegating to the inner engine and logging
+// every result as it comes off the inner results channel, in input order.
+// metoda 'verify headers' akan meneruskan ke engine di dalamnya dan mencatat setiap hasil sesuai urutan input.
+func (l *logEngine) VerifyHeaders(chain ChainHeaderReader, headers []*types.Header, seals []bool) (chan<- struct{}, <-chan error) {
+	if _, err := validateBatch(headers, seals); err != nil {
+		l.log("VerifyHeaders", time.Now(), err, "headers", len(headers), "seals", len(seals))
+		return failedBatch(err)
+	}
+	var (
+		start          = time.Now()
+		abort          = make(chan struct{})
+		results        = make(chan error, len(headers))
+		inner, verdict = l.inner.VerifyHeaders(chain, headers, seals)
+	)
+	go func() {
+		defer close(results)
+
+		for i, header := range headers {
+			select {
+			case err, ok := <-verdict:
+				if !ok {
+					// The batch was rejected as a whole, its error already delivered
+					return
+				}
+				l.log("VerifyHeaders", start, err, "index", i, "number", header.Number, "hash", header.Hash(), "seal", seals[i])
+				results <- err
+			case <-abort:
+				close(inner)
+				l.logger.Debug("Consensus call aborted", "method", "VerifyHeaders", "verified", i, "headers", len(headers))
+				return
+			}
+		}
+	}()
+	return abort, results
+}
+
+// VerifyHeadersContext is similar to VerifyHeaders, but aborts verification
+// once the context is cancelled or its deadline elapses. Results are still
+// delivered in input order, headers left unverified report the context error.
+// metoda 'verify headers context' sama dengan 'verify headers', namun verifikasi dibatalkan ketika context dibatalkan atau melewati tenggat waktu.
+func (l *logEngine) VerifyHeadersContext(ctx context.Context, chain ChainHeaderReader, headers []*types.Header, seals []bool) <-chan error {
+	return verifyHeadersContext(ctx, l, chain, headers, seals)
+}
+
+// VerifyHeadersWithContext is VerifyHeadersContext, under the name callers built
+// around contexts look for.
+// metoda 'verify headers with context' sama dengan 'verify headers context'.
+func (l *logEngine) VerifyHeadersWithContext(ctx context.Context, chain ChainHeaderReader, headers []*types.Header, seals []bool) <-chan error {
+	return verifyHeadersContext(ctx, l, chain, headers, seals)
+}
+
+// VerifyUncles implements Engine, delegating to the inner engine.
+// metoda 'verify uncles' akan meneruskan ke engine di dalamnya.
+func (l *logEngine) VerifyUncles(chain ChainReader, block *types.Block) error {
+	start := time.Now()
+	err := l.inner.VerifyUncles(chain, block)
+	l.log("VerifyUncles", start, err, "number", block.Number(), "hash", block.Hash(), "uncles", len(block.Uncles()))
+	return err
+}
+
+// VerifySeal implements Engine, delegating to the inner engine.
+// metoda 'verify seal' akan meneruskan ke engine di dalamnya.
+func (l *logEngine) VerifySeal(chain ChainHeaderReader, header *types.Header) error {
+	start := time.Now()
+	err := l.inner.VerifySeal(chain, header)
+	l.log("VerifySeal", start, err, "number", header.Number, "hash", header.Hash())
+	return err
+}
+
+// Prepare implements Engine, delegating to the inner engine.
+// metoda 'prepare' akan meneruskan ke engine di dalamnya.
+func (l *logEngine) Prepare(chain ChainHeaderReader, header *types.Header) error {
+	start := time.Now()
+	err := l.inner.Prepare(chain, header)
+	l.log("Prepare", start, err, "number", header.Number, "parent", header.ParentHash)
+	return err
+}
+
+// Finalize implements Engine, delegating to the inner engine.
+// metoda 'finalize' akan meneruskan ke engine di dalamnya.
+func (l *logEngine) Finalize(chain ChainHeaderReader, header *types.Header, state *state.StateDB, txs []*types.Transaction, uncles []*types.Header, withdrawals []*types.Withdrawal) {
+	start := time.Now()
+	l.inner.Finalize(chain, header, state, txs, uncles, withdrawals)
+	l.log("Finalize", start, nil, "number", header.Number, "txs", len(txs), "uncles", len(uncles), "withdrawals", len(withdrawals))
+}
+
+// FinalizeAndAssemble implements Engine, delegating to the inner engine.
+// metoda 'finalize and assemble' akan meneruskan ke engine di dalamnya.
+func (l *logEngine) FinalizeAndAssemble(chain ChainHeaderReader, header *types.Header, state *state.StateDB, txs []*types.Transaction, uncles []*types.Header, receipts []*types.Receipt, withdrawals []*types.Withdrawal) (*types.Block, error) {
+	start := time.Now()
+	block, err := l.inner.FinalizeAndAssemble(chain, header, state, txs, uncles, receipts, withdrawals)
+	if block != nil {
+		l.log("FinalizeAndAssemble", start, err, "number", header.Number, "hash", block.Hash(), "txs", len(txs), "uncles", len(uncles), "withdrawals", len(withdrawals))
+	} else {
+		l.log("FinalizeAndAssemble", start, err, "number", header.Number, "txs", len(txs), "uncles", len(uncles), "withdrawals", len(withdrawals))
+	}
+	return block, err
+}
+
+// Seal implements Engine, delegating to the inner engine. Only starting the
+// sealing is logged, the asynchronous result is delivered untouched.
+// metoda 'seal' akan meneruskan ke engine di dalamnya. Hanya dimulainya proses seal yang dicatat.
+func (l *logEngine) Seal(chain ChainHeaderReader, block *types.Block, results chan<- *types.Block, stop <-chan struct{}) error {
+	start := time.Now()
+	err := l.inner.Seal(chain, block, results, stop)
+	l.log("Seal", start, err, "number", block.Number(), "sealhash", l.inner.SealHash(block.Header()))
+	return err
+}
+
+// SealHash implements Engine, delegating to the inner engine.
+// metoda 'seal hash' akan meneruskan ke engine di dalamnya.
+func (l *logEngine) SealHash(header *types.Header) common.Hash {
+	start := time.Now()
+	hash := l.inner.SealHash(header)
+	l.log("SealHash", start, nil, "number", header.Number, "sealhash", hash)
+	return hash
+}
+
+// CalcDifficulty implements Engine, delegating to the inner engine.
+// metoda 'calc difficulty' akan meneruskan ke engine di dalamnya.
+func (l *logEngine) CalcDifficulty(chain ChainHeaderReader, timestamp uint64, parent *types.Header) *big.Int {
+	start := time.Now()
+	diff := l.inner.CalcDifficulty(chain, timestamp, parent)
+	l.log("CalcDifficulty", start, nil, "parent", parent.Number, "hash", parent.Hash(), "time", timestamp, "difficulty", diff)
+	return diff
+}
+
+// APIs implements Engine, delegating to the inner engine.
+// metoda 'apis' akan meneruskan ke engine di dalamnya.
+func (l *logEngine) APIs(chain ChainHeaderReader) []rpc.API {
+	start := time.Now()
+	apis := l.inner.APIs(chain)
+	l.log("APIs", start, nil, "apis", len(apis))
+	return apis
+}
+
+// Close implements Engine, delegating to the inner engine.
+// metoda 'close' akan meneruskan ke engine di dalamnya.
+func (l *logEngine) Close() error {
+	start := time.Now()
+	err := l.inner.Close()
+	l.log("Close", start, err)
+	return err
+}
@@ -0,0 +1,110 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package consensus
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// recordingLogger is a logger recording the method of every debug record.
+type recordingLogger struct {
+	log.Logger
+
+	lock    sync.Mutex
+	methods []string
+}
+
+func (l *recordingLogger) Debug(msg string, ctx ...interface{}) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	for i := 0; i+1 < len(ctx); i += 2 {
+		if ctx[i] == "method" {
+			l.methods = append(l.methods, ctx[i+1].(string))
+		}
+	}
+}
+
+// count returns the number of records logged for the given method.
+func (l *recordingLogger) count(method string) int {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	var n int
+	for _, have := range l.methods {
+		if have == method {
+			n++
+		}
+	}
+	return n
+}
+
+// sameError reports whether two errors are both nil or carry the same message.
+func sameError(a, b error) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Error() == b.Error()
+}
+
+// Tests that a wrapped engine behaves exactly like the unwrapped one, while a
+// record is logged for every call and every header of a batch.
+func TestWrapWithLogging(t *testing.T) {
+	var (
+		logger  = &recordingLogger{Logger: log.Root()}
+		inner   = NewFakeFailer(5)
+		wrapped = WrapWithLogging(inner, logger)
+		genesis = testGenesis(100)
+		chain   = newTestChain(frontierConfig, genesis)
+		headers = makeHeaders(inner, chain, genesis, 8, 1)
+		seals   = make([]bool, len(headers))
+	)
+	for i, header := range headers {
+		if have, want := wrapped.VerifyHeader(chain, header, false), inner.VerifyHeader(chain, header, false); !sameError(have, want) {
+			t.Errorf("header %d: verification mismatch: have %v, want %v", i, have, want)
+		}
+		if have, want := wrapped.SealHash(header), inner.SealHash(header); have != want {
+			t.Errorf("header %d: seal hash mismatch: have %x, want %x", i, have, want)
+		}
+		if have, want := wrapped.CalcDifficulty(chain, header.Time+1, header), inner.CalcDifficulty(chain, header.Time+1, header); have.Cmp(want) != 0 {
+			t.Errorf("header %d: difficulty mismatch: have %v, want %v", i, have, want)
+		}
+	}
+	_, have := wrapped.VerifyHeaders(chain, headers, seals)
+	_, want := inner.VerifyHeaders(chain, headers, seals)
+	for i := range headers {
+		if h, w := <-have, <-want; !sameError(h, w) {
+			t.Errorf("batch header %d: result mismatch: have %v, want %v", i, h, w)
+		}
+	}
+	if _, ok := <-have; ok {
+		t.Errorf("results channel not closed after the batch")
+	}
+	for method, want := range map[string]int{
+		"VerifyHeader":   len(headers),
+		"SealHash":       len(headers),
+		"CalcDifficulty": len(headers),
+		"VerifyHeaders":  len(headers),
+	} {
+		if have := logger.count(method); have != want {
+			t.Errorf("%s: record count mismatch: have %d, want %d", method, have, want)
+		}
+	}
+}
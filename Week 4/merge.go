@@ -0,0 +1,89 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package consensus
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// Various error messages to mark post-merge blocks invalid.
+var (
+	errInvalidPoSDifficulty = errors.New("invalid difficulty after the merge")
+	errInvalidPoSNonce      = errors.New("invalid nonce after the merge")
+	errInvalidPoSUncleHash  = errors.New("invalid uncle hash after the merge")
+)
+
+// isPostMerge reports whether the block following parent is past the
+// transition to proof-of-stake, which is the case once the total difficulty of
+// the parent reached the terminal total difficulty of the chain config. Chains
+// without a terminal total difficulty never merge.
+func isPostMerge(chain ChainHeaderReader, parent *types.Header) bool {
+	config := chain.Config()
+	if config == nil || config.TerminalTotalDifficulty == nil {
+		return false
+	}
+	td := chain.GetTd(parent.Hash(), parent.Number.Uint64())
+	return td != nil && td.Cmp(config.TerminalTotalDifficulty) >= 0
+}
+
+// ReachedTTD reports whether the total difficulty of the chain reaches the
+// terminal total difficulty of the chain config with the given header, i.e.
+// the total difficulty of its parent plus its own difficulty, in which case the
+// header is the terminal proof-of-work block or already past the transition.
+// Chains without a terminal total difficulty never reach it. The total
+// difficulty of the parent is retrieved via GetTd, failing with
+// ErrUnknownAncestor if it isn't known.
+// metoda 'reached ttd' akan melaporkan apakah total difficulty chain mencapai terminal total difficulty dengan
+// header yang diberikan, yaitu total difficulty parent ditambah difficulty header.
+func ReachedTTD(chain ChainHeaderReader, header *types.Header) (bool, error) {
+	config := chain.Config()
+	if config == nil || config.TerminalTotalDifficulty == nil {
+		return false, nil
+	}
+	td := new(big.Int)
+	if header.Difficulty != nil {
+		td.Set(header.Difficulty)
+	}
+	if number := header.Number.Uint64(); number > 0 {
+		parentTd := chain.GetTd(header.ParentHash, number-1)
+		if parentTd == nil {
+			return false, fmt.Errorf("%w: total difficulty of parent %d unknown", ErrUnknownAncestor, number-1)
+		}
+		td.Add(td, parentTd)
+	}
+	return td.Cmp(config.TerminalTotalDifficulty) >= 0, nil
+}
+
+// verifyPostMerge checks the fields a proof-of-stake header has to zero out,
+// as the work they used to carry is no longer done: the difficulty and nonce
+// must be zero and the header must not contain any uncles.
+func verifyPostMerge(header *types.Header) error {
+	if header.Difficulty == nil || header.Difficulty.Sign() != 0 {
+		return fmt.Errorf("%w: have %v, want 0", errInvalidPoSDifficulty, header.Difficulty)
+	}
+	if header.Nonce != (types.BlockNonce{}) {
+		return fmt.Errorf("%w: have %x, want %x", errInvalidPoSNonce, header.Nonce, types.BlockNonce{})
+	}
+	if header.UncleHash != types.EmptyUncleHash {
+		return fmt.Errorf("%w: have %x, want %x", errInvalidPoSUncleHash, header.UncleHash, types.EmptyUncleHash)
+	}
+	return nil
+}
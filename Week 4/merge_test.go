@@ -0,0 +1,166 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package consensus
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that the first block past the transition must zero out its difficulty
+// and nonce and carry no uncles, while the same fields are invalid before the
+// transition.
+func TestVerifyPostMerge(t *testing.T) {
+	genesis := testGenesis(params.MinimumDifficulty.Int64())
+
+	// child creates a proof-of-stake child of the genesis, modified by fn
+	child := func(fn func(*types.Header)) *types.Header {
+		header := &types.Header{
+			ParentHash: genesis.Hash(),
+			UncleHash:  types.EmptyUncleHash,
+			Number:     big.NewInt(1),
+			GasLimit:   genesis.GasLimit,
+			Time:       genesis.Time + 12,
+			Difficulty: new(big.Int),
+		}
+		if fn != nil {
+			fn(header)
+		}
+		return header
+	}
+	merged := *frontierConfig
+	merged.TerminalTotalDifficulty = new(big.Int).Set(genesis.Difficulty)
+	chain := newTestChain(&merged, genesis)
+
+	tests := []struct {
+		name   string
+		header *types.Header
+		want   error
+	}{
+		{"valid", child(nil), nil},
+		{"difficulty", child(func(h *types.Header) { h.Difficulty = big.NewInt(1) }), errInvalidPoSDifficulty},
+		{"nonce", child(func(h *types.Header) { h.Nonce = types.EncodeNonce(1) }), errInvalidPoSNonce},
+		{"uncle hash", child(func(h *types.Header) { h.UncleHash = types.CalcUncleHash([]*types.Header{genesis}) }), errInvalidPoSUncleHash},
+	}
+	for _, engine := range []Engine{NewPoW(), NewBeacon(NewPoW())} {
+		for _, tt := range tests {
+			if err := engine.VerifyHeader(chain, tt.header, false); !errors.Is(err, tt.want) {
+				t.Errorf("%T: %s: error mismatch: have %v, want %v", engine, tt.name, err, tt.want)
+			}
+		}
+		// Uncles are rejected outright past the transition
+		uncle := child(func(h *types.Header) { h.Extra = []byte("uncle") })
+		header := child(func(h *types.Header) { h.UncleHash = types.CalcUncleHash([]*types.Header{uncle}) })
+		block := types.NewBlockWithHeader(header).WithBody(nil, []*types.Header{uncle})
+		if err := engine.VerifyUncles(chain, block); !errors.Is(err, ErrTooManyUncles) {
+			t.Errorf("%T: uncle error mismatch: have %v, want %v", engine, err, ErrTooManyUncles)
+		}
+	}
+	// Before the transition a zero difficulty is simply wrong
+	pending := *frontierConfig
+	pending.TerminalTotalDifficulty = new(big.Int).Lsh(genesis.Difficulty, 1)
+	chain = newTestChain(&pending, genesis)
+
+	if err := NewPoW().VerifyHeader(chain, child(nil), false); !errors.Is(err, ErrInvalidDifficulty) {
+		t.Errorf("pre-transition error mismatch: have %v, want %v", err, ErrInvalidDifficulty)
+	}
+	if err := NewBeacon(NewPoW()).VerifyHeader(chain, child(nil), false); !errors.Is(err, ErrInvalidTerminalBlock) {
+		t.Errorf("pre-transition beacon error mismatch: have %v, want %v", err, ErrInvalidTerminalBlock)
+	}
+}
+
+// Tests terminal total difficulty detection on the blocks straddling it: the
+// block reaching it exactly is the terminal proof-of-work block, verified by
+// the proof-of-work rules, and its children must zero out the difficulty and
+// nonce.
+func TestReachedTTD(t *testing.T) {
+	var (
+		engine  = NewPoW()
+		genesis = testGenesis(params.MinimumDifficulty.Int64())
+		headers = makeHeaders(engine, newTestChain(frontierConfig, genesis), genesis, 3, 1)
+		config  = *frontierConfig
+		chain   = newTestChain(&config, append([]*types.Header{genesis}, headers...)...)
+	)
+	// Without a terminal total difficulty, the transition never happens
+	if reached, err := ReachedTTD(chain, headers[2]); reached || err != nil {
+		t.Errorf("no terminal total difficulty: have %t, %v, want false", reached, err)
+	}
+	terminal := chain.GetTd(headers[1].Hash(), 2)
+	tests := []struct {
+		ttd  *big.Int
+		want []bool
+	}{
+		{terminal, []bool{false, true, true}},                                 // Reached exactly by the second header
+		{new(big.Int).Add(terminal, common.Big1), []bool{false, false, true}}, // Missed by a single unit
+		{new(big.Int).Sub(terminal, common.Big1), []bool{false, true, true}},  // Overshot by a single unit
+	}
+	for i, tt := range tests {
+		config.TerminalTotalDifficulty = tt.ttd
+		for j, header := range headers {
+			reached, err := ReachedTTD(chain, header)
+			if err != nil {
+				t.Fatalf("test %d, header %d: failed to check the transition: %v", i, j+1, err)
+			}
+			if reached != tt.want[j] {
+				t.Errorf("test %d, header %d: transition mismatch: have %t, want %t", i, j+1, reached, tt.want[j])
+			}
+		}
+	}
+	// The terminal block is still proof-of-work, the block after it isn't
+	config.TerminalTotalDifficulty = terminal
+	chain = newTestChain(&config, genesis, headers[0])
+	if err := engine.VerifyHeader(chain, headers[1], false); err != nil {
+		t.Errorf("terminal block rejected: %v", err)
+	}
+	chain.insert(headers[1])
+
+	child := func(difficulty int64, nonce uint64) *types.Header {
+		return &types.Header{
+			ParentHash: headers[1].Hash(),
+			UncleHash:  types.EmptyUncleHash,
+			Number:     big.NewInt(3),
+			GasLimit:   headers[1].GasLimit,
+			Time:       headers[1].Time + 12,
+			Difficulty: big.NewInt(difficulty),
+			Nonce:      types.EncodeNonce(nonce),
+		}
+	}
+	for _, tt := range []struct {
+		name   string
+		header *types.Header
+		want   error
+	}{
+		{"zeroed", child(0, 0), nil},
+		{"proof-of-work", headers[2], errInvalidPoSDifficulty},
+		{"nonce", child(0, 1), errInvalidPoSNonce},
+	} {
+		if err := engine.VerifyHeader(chain, tt.header, false); !errors.Is(err, tt.want) {
+			t.Errorf("%s: error mismatch: have %v, want %v", tt.name, err, tt.want)
+		}
+	}
+	// The total difficulty of the parent has to be known
+	orphan := child(0, 0)
+	orphan.ParentHash = common.Hash{0x01}
+	if _, err := ReachedTTD(chain, orphan); !errors.Is(err, ErrUnknownAncestor) {
+		t.Errorf("unknown parent error mismatch: have %v, want %v", err, ErrUnknownAncestor)
+	}
+}
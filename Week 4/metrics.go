@@ -0,0 +1,57 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package consensus
+
+import "time"
+
+// Metrics receives observations from the consensus engines, so they can be fed
+// into any metrics system (e.g. Prometheus or expvar) without this package
+// depending on one. The callbacks may be invoked concurrently.
+// Metrics menerima hasil pengamatan dari consensus engine, sehingga dapat diteruskan ke sistem metrik apa pun
+// tanpa package ini bergantung padanya.
+type Metrics interface {
+	// OnSealFound is called when the proof-of-work search sealed a block, with
+	// the time the search took and the number of nonces tried locally.
+	// metoda 'on seal found' dipanggil ketika pencarian proof-of-work berhasil menyegel block.
+	OnSealFound(duration time.Duration, attempts uint64)
+
+	// OnVerifyFail is called for every header failing verification.
+	// metoda 'on verify fail' dipanggil untuk setiap header yang gagal diverifikasi.
+	OnVerifyFail(err error)
+}
+
+// NoopMetrics is a Metrics implementation discarding every observation. It's
+// the default of the engines, and can be embedded to implement only some of the
+// callbacks.
+// NoopMetrics adalah implementasi Metrics yang mengabaikan semua pengamatan, dan merupakan bawaan dari engine.
+type NoopMetrics struct{}
+
+// OnSealFound implements Metrics, doing nothing.
+// metoda 'on seal found' tidak melakukan apa-apa.
+func (NoopMetrics) OnSealFound(duration time.Duration, attempts uint64) {}
+
+// OnVerifyFail implements Metrics, doing nothing.
+// metoda 'on verify fail' tidak melakukan apa-apa.
+func (NoopMetrics) OnVerifyFail(err error) {}
+
+// observeFailure reports the error to the metrics, if verification failed.
+func observeFailure(metrics Metrics, err error) error {
+	if err != nil {
+		metrics.OnVerifyFail(err)
+	}
+	return err
+}
@@ -0,0 +1,99 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package consensus
+
+import (
+	"errors"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// countingMetrics is a Metrics collector counting the calls it receives.
+type countingMetrics struct {
+	seals    int
+	attempts uint64
+	failures []error
+	lock     sync.Mutex
+}
+
+func (m *countingMetrics) OnSealFound(duration time.Duration, attempts uint64) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.seals++
+	m.attempts += attempts
+}
+
+func (m *countingMetrics) OnVerifyFail(err error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.failures = append(m.failures, err)
+}
+
+// Tests that a seal-then-verify cycle reports the found seal once, and every
+// failed verification, single or batched, exactly once.
+func TestMetrics(t *testing.T) {
+	var (
+		metrics = new(countingMetrics)
+		engine  = NewPoW(WithMetrics(metrics))
+		genesis = testGenesis(params.MinimumDifficulty.Int64())
+		chain   = newTestChain(frontierConfig, genesis)
+	)
+	header := sealHeader(t, engine, chain, genesis)
+	if metrics.seals != 1 || metrics.attempts == 0 {
+		t.Errorf("seal observations mismatch: have %d seals after %d attempts, want 1 after some", metrics.seals, metrics.attempts)
+	}
+	if err := engine.VerifyHeader(chain, header, true); err != nil {
+		t.Fatalf("sealed header failed verification: %v", err)
+	}
+	if len(metrics.failures) != 0 {
+		t.Fatalf("successful verification reported as failed: %v", metrics.failures)
+	}
+	// Break the seal and verify it both alone and within a batch
+	forged := types.CopyHeader(header)
+	target := TargetFromDifficulty(header.Difficulty)
+	for nonce := header.Nonce.Uint64() + 1; ; nonce++ {
+		if new(big.Int).SetBytes(powHash(Keccak256, engine.SealHash(header), nonce)).Cmp(target) > 0 {
+			forged.Nonce = types.EncodeNonce(nonce)
+			break
+		}
+	}
+	if err := engine.VerifyHeader(chain, forged, true); !errors.Is(err, errInvalidPoW) {
+		t.Fatalf("forged header error mismatch: have %v, want %v", err, errInvalidPoW)
+	}
+	abort, results := engine.VerifyHeaders(chain, []*types.Header{forged}, []bool{true})
+	defer close(abort)
+	<-results
+
+	if len(metrics.failures) != 2 {
+		t.Fatalf("failure observations mismatch: have %d, want 2", len(metrics.failures))
+	}
+	for i, err := range metrics.failures {
+		if !errors.Is(err, errInvalidPoW) {
+			t.Errorf("failure %d mismatch: have %v, want %v", i, err, errInvalidPoW)
+		}
+	}
+	if metrics.seals != 1 {
+		t.Errorf("verification reported as seals: have %d, want 1", metrics.seals)
+	}
+}
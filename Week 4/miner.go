@@ -0,0 +1,246 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package consensus
+
+import (
+	crand "crypto/rand"
+	"math"
+	"math/big"
+	"math/rand"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// sealProgressInterval is the default time between two progress reports of a
+// sealing run.
+const sealProgressInterval = time.Second
+
+// sealProgress holds the progress reporting settings of sealing runs.
+type sealProgress struct {
+	every  time.Duration                                // Time between two reports
+	report func(attempts uint64, elapsed time.Duration) // Callback receiving the reports (nil = no reporting)
+}
+
+// Miner searches for proof-of-work seals on a pool of worker goroutines, each
+// scanning a disjoint range of the nonce space. Every call to Seal starts a
+// fresh pool, so mining is restarted on a new block by closing the stop channel
+// of the previous call and sealing the new one.
+// Miner mencari segel proof-of-work menggunakan sekumpulan goroutine worker, masing-masing memindai rentang nonce
+// yang berbeda. Setiap pemanggilan 'seal' memulai pool baru.
+type Miner struct {
+	pow     *powEngine // Engine defining the seal hash, hash function and difficulty
+	threads int        // Number of worker goroutines to mine with
+}
+
+// NewMiner creates a miner sealing blocks for the given proof-of-work engine on
+// the given number of threads. A non-positive thread count uses every CPU core.
+// metoda 'new miner' akan membuat miner untuk engine proof-of-work dengan jumlah thread tertentu. Jumlah thread
+// yang tidak positif berarti menggunakan semua core CPU.
+func NewMiner(pow *powEngine, threads int) *Miner {
+	if threads <= 0 {
+		threads = runtime.NumCPU()
+	}
+	return &Miner{pow: pow, threads: threads}
+}
+
+// Seal starts searching for a nonce satisfying the block's difficulty on all
+// worker threads. The first sealed block found is pushed into results, after
+// which every worker is torn down. With seal streaming enabled, the workers go
+// on for the grace window and every further sealed block is pushed as well.
+// Closing stop aborts the search. In either case no worker outlives the sealing
+// run.
+// metoda 'seal' akan mencari nonce yang memenuhi tingkat kesulitan block pada semua thread worker. Block tersegel
+// pertama dikirim ke channel results, lalu semua worker dihentikan.
+func (m *Miner) Seal(block *types.Block, results chan<- *types.Block, stop <-chan struct{}) error {
+	_, err := m.seal(block, results, stop, nil)
+	return err
+}
+
+// seal is Seal, additionally accepting solutions found outside of the miner on
+// the external channel, which terminate the local search just the same. The
+// returned channel is closed once the run stops reading the external channel,
+// after which any solution offered on it would go unseen.
+func (m *Miner) seal(block *types.Block, results chan<- *types.Block, stop <-chan struct{}, external <-chan *types.Block) (<-chan struct{}, error) {
+	header := block.Header()
+	if header.Difficulty == nil || header.Difficulty.Sign() <= 0 {
+		return nil, errInvalidDifficulty
+	}
+	// Start the nonce search from a random position
+	nonce, err := m.startNonce()
+	if err != nil {
+		return nil, err
+	}
+	var (
+		hash   = m.pow.SealHash(header)
+		target = TargetFromDifficulty(m.pow.sealDifficulty(header.Difficulty))
+		span   = math.MaxUint64 / uint64(m.threads)
+
+		start    = time.Now()
+		attempts atomic.Uint64
+		abort    = make(chan struct{})
+		done     = make(chan struct{})
+		locals   = make(chan *types.Block, m.threads)
+		pend     sync.WaitGroup
+	)
+	for i := 0; i < m.threads; i++ {
+		pend.Add(1)
+		go func(meter *hashMeter, nonce uint64) {
+			defer pend.Done()
+			m.mine(block, hash, target, nonce, abort, locals, meter, &attempts)
+		}(m.pow.meter(i), nonce+uint64(i)*span)
+	}
+	// Report the progress from a goroutine of its own, never holding up the workers
+	if progress := m.pow.sealProg; progress.report != nil {
+		go m.report(progress, start, &attempts, abort)
+	}
+	// Wait until sealing is terminated or a nonce is found
+	go func() {
+		var result *types.Block
+		select {
+		case <-stop:
+		case result = <-locals:
+		case result = <-external:
+		}
+		if result != nil && m.pow.grace > 0 {
+			// Hand out the first result, then keep streaming further solutions
+			// before tearing down every worker
+			m.pow.metrics.OnSealFound(time.Since(start), attempts.Load())
+			m.stream(result, results, stop, locals, external)
+			close(done)
+			close(abort)
+			pend.Wait()
+			return
+		}
+		// Tear down every worker before handing out the result
+		close(done)
+		close(abort)
+		pend.Wait()
+
+		if result != nil {
+			m.pow.metrics.OnSealFound(time.Since(start), attempts.Load())
+			select {
+			case results <- result:
+			case <-stop:
+			}
+		}
+	}()
+	return done, nil
+}
+
+// stream delivers the first result and every further solution found by the
+// workers or externally to results, until the grace window elapses or stop is
+// closed.
+func (m *Miner) stream(result *types.Block, results chan<- *types.Block, stop <-chan struct{}, locals, external <-chan *types.Block) {
+	timeout := time.NewTimer(m.pow.grace)
+	defer timeout.Stop()
+
+	for {
+		select {
+		case results <- result:
+		case <-stop:
+			return
+		}
+		select {
+		case result = <-locals:
+		case result = <-external:
+		case <-timeout.C:
+			return
+		case <-stop:
+			return
+		}
+	}
+}
+
+// report invokes the progress callback with the attempts made so far at every
+// tick of the configured interval, until the sealing run is torn down.
+func (m *Miner) report(progress sealProgress, start time.Time, attempts *atomic.Uint64, abort <-chan struct{}) {
+	every := progress.every
+	if every <= 0 {
+		every = sealProgressInterval
+	}
+	ticker := time.NewTicker(every)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			progress.report(attempts.Load(), time.Since(start))
+		case <-abort:
+			return
+		}
+	}
+}
+
+// startNonce returns the nonce the search starts from, drawn from the nonce
+// source of the engine if it has one, or randomly otherwise.
+func (m *Miner) startNonce() (uint64, error) {
+	if m.pow.nonces != nil {
+		return m.pow.nonces(), nil
+	}
+	seed, err := crand.Int(crand.Reader, big.NewInt(math.MaxInt64))
+	if err != nil {
+		return 0, err
+	}
+	return rand.New(rand.NewSource(seed.Int64())).Uint64(), nil
+}
+
+// mine is the actual proof-of-work worker, scanning the nonce space upwards
+// from the given seed until a valid nonce is found or abort is closed. Every
+// attempt is accounted on the worker's hash meter and the total of the run.
+func (m *Miner) mine(block *types.Block, hash common.Hash, target *big.Int, nonce uint64, abort <-chan struct{}, found chan<- *types.Block, meter *hashMeter, total *atomic.Uint64) {
+	header := block.Header()
+	for attempts := uint64(0); ; attempts++ {
+		// Check for abort requests and update the hash meter once in a while
+		if attempts == 1<<15 {
+			meter.mark(attempts)
+			total.Add(attempts)
+			attempts = 0
+		}
+		if attempts == 0 {
+			select {
+			case <-abort:
+				return
+			default:
+			}
+		}
+		if new(big.Int).SetBytes(powHash(m.pow.hashAlgo, hash, nonce)).Cmp(target) <= 0 {
+			// Correct nonce found, create a new header with it
+			header.Nonce = types.EncodeNonce(nonce)
+			meter.mark(attempts + 1)
+			total.Add(attempts + 1)
+
+			// The channel has room for every worker, sending only blocks
+			// while solutions are streamed
+			select {
+			case found <- block.WithSeal(header):
+			case <-abort:
+				return
+			}
+			if m.pow.grace <= 0 {
+				return
+			}
+			// Keep searching for further solutions, accounted afresh
+			attempts = 0
+		}
+		nonce++
+	}
+}
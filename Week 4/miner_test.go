@@ -0,0 +1,209 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package consensus
+
+import (
+	"math/big"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// waitGoroutines waits for the number of goroutines to drop back to at most
+// want, failing the test if it doesn't within a second.
+func waitGoroutines(t *testing.T, want int) {
+	t.Helper()
+
+	deadline := time.Now().Add(time.Second)
+	for runtime.NumGoroutine() > want {
+		if time.Now().After(deadline) {
+			t.Fatalf("goroutines leaked: have %d, want %d", runtime.NumGoroutine(), want)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// Tests that the miner delivers exactly one sealed block found by its pool of
+// workers, none of which outlives the sealing run.
+func TestMinerSeal(t *testing.T) {
+	var (
+		engine = NewPoW()
+		miner  = NewMiner(engine, 4)
+		header = &types.Header{Number: big.NewInt(1), Difficulty: big.NewInt(4096)}
+		before = runtime.NumGoroutine()
+	)
+	results, stop := make(chan *types.Block, 2), make(chan struct{})
+	defer close(stop)
+
+	if err := miner.Seal(types.NewBlockWithHeader(header), results, stop); err != nil {
+		t.Fatalf("failed to start sealing: %v", err)
+	}
+	select {
+	case block := <-results:
+		if err := engine.VerifySeal(nil, block.Header()); err != nil {
+			t.Errorf("sealed block failed verification: %v", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatalf("sealing timed out")
+	}
+	waitGoroutines(t, before)
+	if len(results) != 0 {
+		t.Errorf("more than one sealed block delivered")
+	}
+}
+
+// Tests that closing the stop channel tears down every worker of a search that
+// would never succeed, without delivering a block.
+func TestMinerStop(t *testing.T) {
+	var (
+		miner  = NewMiner(NewPoW(), 4)
+		header = &types.Header{Number: big.NewInt(1), Difficulty: new(big.Int).Lsh(common.Big1, 200)}
+		before = runtime.NumGoroutine()
+	)
+	results, stop := make(chan *types.Block, 1), make(chan struct{})
+	if err := miner.Seal(types.NewBlockWithHeader(header), results, stop); err != nil {
+		t.Fatalf("failed to start sealing: %v", err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(stop)
+
+	waitGoroutines(t, before)
+	if len(results) != 0 {
+		t.Errorf("block delivered despite the search being stopped")
+	}
+}
+
+// Tests that the hashrate of the engine is the sum of its per-worker rates,
+// measured while every worker mines.
+func TestHashratePerWorker(t *testing.T) {
+	var (
+		engine = NewPoW(WithThreads(4))
+		header = &types.Header{Number: big.NewInt(1), Difficulty: new(big.Int).Lsh(common.Big1, 200)}
+	)
+	results, stop := make(chan *types.Block, 1), make(chan struct{})
+	if err := NewMiner(engine, 4).Seal(types.NewBlockWithHeader(header), results, stop); err != nil {
+		t.Fatalf("failed to start sealing: %v", err)
+	}
+	time.Sleep(1500 * time.Millisecond)
+	close(stop)
+
+	var (
+		rates = engine.HashratePerWorker()
+		total = engine.Hashrate()
+		sum   float64
+	)
+	if len(rates) != 4 {
+		t.Fatalf("worker count mismatch: have %d, want 4", len(rates))
+	}
+	for i, rate := range rates {
+		if rate <= 0 {
+			t.Errorf("worker %d: no hashrate measured", i)
+		}
+		sum += rate
+	}
+	if total < sum*0.9 || total > sum*1.1 {
+		t.Errorf("aggregate hashrate mismatch: have %v, want about %v", total, sum)
+	}
+}
+
+// Tests that a miner streaming seals keeps delivering distinct solutions of an
+// easy block until stopped, with no worker outliving the run.
+func TestMinerSealStreaming(t *testing.T) {
+	var (
+		engine = NewPoW(WithSealStreaming(10 * time.Second))
+		miner  = NewMiner(engine, 2)
+		header = &types.Header{Number: big.NewInt(1), Difficulty: big.NewInt(16)}
+		before = runtime.NumGoroutine()
+	)
+	results, stop := make(chan *types.Block), make(chan struct{})
+	if err := miner.Seal(types.NewBlockWithHeader(header), results, stop); err != nil {
+		t.Fatalf("failed to start sealing: %v", err)
+	}
+	seen := make(map[types.BlockNonce]bool)
+	for len(seen) < 2 {
+		select {
+		case block := <-results:
+			if err := engine.VerifySeal(nil, block.Header()); err != nil {
+				t.Fatalf("streamed block failed verification: %v", err)
+			}
+			seen[block.Header().Nonce] = true
+		case <-time.After(10 * time.Second):
+			t.Fatalf("streamed %d solutions, want at least 2", len(seen))
+		}
+	}
+	close(stop)
+	waitGoroutines(t, before)
+}
+
+// Tests that a sealing run that would never succeed keeps reporting its
+// progress, with the attempts and elapsed time growing, and that the reporter
+// goroutine doesn't outlive the run.
+func TestMinerSealProgress(t *testing.T) {
+	type report struct {
+		attempts uint64
+		elapsed  time.Duration
+	}
+	var (
+		reports = make(chan report, 1024)
+		engine  = NewPoW(WithSealProgress(10*time.Millisecond, func(attempts uint64, elapsed time.Duration) {
+			select {
+			case reports <- report{attempts, elapsed}:
+			default:
+			}
+		}))
+		header = &types.Header{Number: big.NewInt(1), Difficulty: new(big.Int).Lsh(common.Big1, 200)}
+		before = runtime.NumGoroutine()
+	)
+	results, stop := make(chan *types.Block, 1), make(chan struct{})
+	if err := NewMiner(engine, 2).Seal(types.NewBlockWithHeader(header), results, stop); err != nil {
+		t.Fatalf("failed to start sealing: %v", err)
+	}
+	var (
+		last    report
+		counted bool
+		timeout = time.After(10 * time.Second)
+	)
+	for !counted {
+		select {
+		case r := <-reports:
+			if r.attempts < last.attempts || r.elapsed < last.elapsed {
+				t.Fatalf("progress went backwards: have %+v, after %+v", r, last)
+			}
+			last, counted = r, r.attempts > 0
+		case <-timeout:
+			t.Fatalf("no progress reported with attempts: last %+v", last)
+		}
+	}
+	close(stop)
+	waitGoroutines(t, before)
+
+	// Drain the reports racing with the teardown, no further ones may arrive
+	time.Sleep(50 * time.Millisecond)
+	for len(reports) > 0 {
+		<-reports
+	}
+	time.Sleep(50 * time.Millisecond)
+	if len(reports) != 0 {
+		t.Errorf("progress reported after the sealing run was stopped")
+	}
+	if len(results) != 0 {
+		t.Errorf("block delivered despite the search being stopped")
+	}
+}
@@ -0,0 +1,897 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package consensus
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/lru"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+// Proof-of-authority protocol constants.
+var (
+	checkpointInterval = uint64(1024)  // Number of blocks after which to save the vote snapshot to the database
+	inmemorySnapshots  = 128           // Number of recent vote snapshots to keep in memory
+	inmemorySignatures = 4096          // Default number of recent block signatures to keep in memory
+	epochLength        = uint64(30000) // Default number of blocks after which to checkpoint
+
+	extraVanity = 32                     // Fixed number of extra-data prefix bytes reserved for signer vanity
+	extraSeal   = crypto.SignatureLength // Fixed number of extra-data suffix bytes reserved for signer seal
+
+	nonceAuthVote = types.BlockNonce{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff} // Magic nonce number to vote on adding a new signer
+	nonceDropVote = types.BlockNonce{}                                               // Magic nonce number to vote on removing a signer.
+
+	diffInTurn = big.NewInt(2) // Block difficulty for in-turn signatures
+	diffNoTurn = big.NewInt(1) // Block difficulty for out-of-turn signatures
+)
+
+// Various error messages to mark blocks invalid. These should be private to
+// prevent engine specific errors from being referenced in the remainder of the
+// codebase, inherently breaking if the engine is swapped out. Please put common
+// error types into the consensus package.
+var (
+	// errUnknownBlock is returned when the list of signers is requested for a block
+	// that is not part of the local blockchain.
+	errUnknownBlock = errors.New("unknown block")
+
+	// errInvalidVote is returned if a nonce value is something else that the two
+	// allowed constants of 0x00..0 or 0xff..f.
+	errInvalidVote = errors.New("vote nonce not 0x00..0 or 0xff..f")
+
+	// errInvalidCheckpointBeneficiary is returned if a checkpoint/epoch transition
+	// block has a beneficiary set to non-zeroes.
+	errInvalidCheckpointBeneficiary = errors.New("beneficiary in checkpoint block non-zero")
+
+	// errInvalidCheckpointVote is returned if a checkpoint/epoch transition block
+	// has a vote nonce set to non-zeroes.
+	errInvalidCheckpointVote = errors.New("vote nonce in checkpoint block non-zero")
+
+	// errMissingVanity is returned if a block's extra-data section is shorter than
+	// 32 bytes, which is required to store the signer vanity.
+	errMissingVanity = errors.New("extra-data 32 byte vanity prefix missing")
+
+	// errMissingSignature is returned if a block's extra-data section doesn't seem
+	// to contain a 65 byte secp256k1 signature.
+	errMissingSignature = errors.New("extra-data 65 byte signature suffix missing")
+
+	// errInvalidSignerList is returned if a checkpoint block contains an invalid
+	// list of signers (i.e. non divisible by 20 bytes).
+	errInvalidSignerList = errors.New("invalid signer list on checkpoint block")
+
+	// errMismatchingCheckpointSigners is returned if a checkpoint block contains a
+	// list of signers different than the one the local node calculated.
+	errMismatchingCheckpointSigners = errors.New("mismatching signer list on checkpoint block")
+
+	// errWrongDifficulty is returned if the difficulty of a block doesn't match the
+	// turn of the signer.
+	errWrongDifficulty = errors.New("wrong difficulty")
+
+	// errUnauthorizedSigner is returned if a header is signed by a non-authorized entity.
+	errUnauthorizedSigner = errors.New("unauthorized signer")
+
+	// errMissingSignerFn is returned if sealing is attempted without an authorized
+	// signing function.
+	errMissingSignerFn = errors.New("missing signer function")
+
+	// errEngineClosed is returned if sealing is attempted after the engine was
+	// closed, and for the headers of a batch left unverified by the closing.
+	errEngineClosed = errors.New("consensus engine closed")
+)
+
+// SignerFn hashes and signs the data to be signed by a backing account.
+// SignerFn adalah fungsi untuk menandatangani data oleh akun yang digunakan.
+type SignerFn func(signer common.Address, hash []byte) ([]byte, error)
+
+// sigLRU maps block hashes to the signers recovered from them.
+type sigLRU = lru.Cache[common.Hash, common.Address]
+
+// ecrecover extracts the Ethereum account address from a signed header.
+func ecrecover(header *types.Header, sigcache *sigLRU) (common.Address, error) {
+	return ecrecoverHashed(header, header.Hash(), sigcache)
+}
+
+// ecrecoverHashed is ecrecover for a header whose hash is already known.
+func ecrecoverHashed(header *types.Header, hash common.Hash, sigcache *sigLRU) (common.Address, error) {
+	// If the signature's already cached, return that
+	if address, known := sigcache.Get(hash); known {
+		return address, nil
+	}
+	// Retrieve the signature from the header extra-data
+	if len(header.Extra) < extraSeal {
+		return common.Address{}, errMissingSignature
+	}
+	signature := header.Extra[len(header.Extra)-extraSeal:]
+
+	// Recover the public key and the Ethereum address
+	pubkey, err := crypto.Ecrecover(poaSealHash(header).Bytes(), signature)
+	if err != nil {
+		return common.Address{}, err
+	}
+	var signer common.Address
+	copy(signer[:], crypto.Keccak256(pubkey[1:])[12:])
+
+	sigcache.Add(hash, signer)
+	return signer, nil
+}
+
+// poaEngine is a proof-of-authority consensus engine in the style of Clique.
+// Blocks are sealed by a set of authorized signers listed in the genesis
+// extra-data and amended by voting, with in-turn signers producing blocks of
+// higher difficulty. Every epoch the full signer list is checkpointed into the
+// header, discarding all pending votes.
+// poaEngine adalah consensus engine proof-of-authority seperti Clique. Block disegel oleh sekumpulan signer
+// yang terdaftar pada extra-data genesis dan diubah melalui voting, dan signer yang mendapat giliran menghasilkan block
+// dengan kesulitan lebih tinggi. Setiap epoch daftar signer disimpan pada header dan voting yang tertunda dihapus.
+type poaEngine struct {
+	config *params.CliqueConfig // Consensus engine configuration parameters
+	db     ethdb.Database       // Database to store and retrieve snapshot checkpoints
+
+	recents       *lru.Cache[common.Hash, *Snapshot] // Snapshots for recent block to speed up reorgs
+	signatures    *sigLRU                            // Signatures of recent blocks to speed up mining
+	signaturesCap int                                // Number of recent block signatures to keep in memory
+	verified      *verifiedCache                     // Hashes of the headers that recently passed verification
+
+	clock       Clock          // Local clock the future block check compares against
+	futureDrift time.Duration  // Max time a header may be ahead of the clock
+	pins        ForkHashes     // Hashes of the canonical blocks at pinned fork heights
+	gasBounds   GasLimitBounds // Gas limit elasticity rule before London (zero = protocol defaults)
+	metrics     Metrics        // Receiver of the verification observations
+	workers     int            // Number of goroutines verifying header batches (0 = GOMAXPROCS)
+	failFast    bool           // Whether VerifyHeaders gives up on a batch at its first failure
+	inflight    int            // Number of headers of a batch in flight at once (0 = four per worker)
+	progress    batchProgress  // Reporting of the VerifyHeaders batch progress (nil callback = off)
+
+	proposals map[common.Address]bool // Current list of proposals we are pushing
+
+	signer common.Address // Ethereum address of the signing key
+	signFn SignerFn       // Signer function to authorize hashes with
+	lock   sync.RWMutex   // Protects the signer and proposals fields
+
+	quit      chan struct{}  // Channel signalling the engine was closed
+	closeOnce sync.Once      // Ensures the engine is torn down only once
+	sealing   sync.WaitGroup // Tracks the goroutines delivering sealed blocks
+}
+
+// PoAOption configures optional behaviour of the proof-of-authority engine.
+// PoAOption mengatur perilaku opsional dari engine proof-of-authority.
+type PoAOption func(*poaEngine)
+
+// WithSignatureCache sets the number of recovered block signers kept in the
+// LRU cache backing Author, which defaults to 4096.
+// metoda 'with signature cache' akan mengatur jumlah signer block yang disimpan di cache LRU untuk 'author'.
+func WithSignatureCache(size int) PoAOption {
+	return func(p *poaEngine) {
+		p.signaturesCap = size
+	}
+}
+
+// WithPoAVerifiedCache sets the number of headers that passed verification the
+// engine remembers by hash, which defaults to 4096. VerifyHeader and
+// VerifyHeaders accept remembered headers without checking them again, unless
+// the seal is requested but wasn't checked the first time.
+// metoda 'with poa verified cache' akan mengatur jumlah header terverifikasi yang diingat engine berdasarkan hash.
+func WithPoAVerifiedCache(size int) PoAOption {
+	return func(p *poaEngine) {
+		p.verified = newVerifiedCache(size)
+	}
+}
+
+// WithPoAClock replaces the local clock headers are checked against for being
+// too far in the future, and Prepare stamps new headers with, which makes both
+// deterministic in tests.
+// metoda 'with poa clock' akan mengganti jam lokal yang dipakai untuk mengecek apakah header terlalu jauh di masa depan.
+func WithPoAClock(now Clock) PoAOption {
+	return func(p *poaEngine) {
+		p.clock = now
+	}
+}
+
+// WithPoAAllowedFutureBlockTime sets how far a header's timestamp may be ahead
+// of the local clock before it's rejected as a future block, 15 seconds by
+// default.
+// metoda 'with poa allowed future block time' akan mengatur seberapa jauh timestamp header boleh mendahului jam lokal.
+func WithPoAAllowedFutureBlockTime(drift time.Duration) PoAOption {
+	return func(p *poaEngine) {
+		p.futureDrift = drift
+	}
+}
+
+// WithPoAForkHashes pins the hashes of the canonical blocks at the given
+// heights, rejecting headers of diverging chains at those heights.
+// metoda 'with poa fork hashes' akan menyematkan hash block kanonik pada ketinggian tertentu.
+func WithPoAForkHashes(pins ForkHashes) PoAOption {
+	return func(p *poaEngine) {
+		p.pins = pins
+	}
+}
+
+// WithPoAMetrics reports the failed verifications of the engine to the given
+// metrics, instead of discarding them.
+// metoda 'with poa metrics' akan melaporkan verifikasi yang gagal ke metrics yang diberikan.
+func WithPoAMetrics(metrics Metrics) PoAOption {
+	return func(p *poaEngine) {
+		p.metrics = metrics
+	}
+}
+
+// WithPoAGasLimitBounds replaces the divisor bounding how far a header's gas
+// limit may move from its parent's, and the lowest gas limit allowed, before the
+// London fork. Zero fields keep the protocol defaults.
+// metoda 'with poa gas limit bounds' akan mengganti pembagi batas perubahan gas limit dan gas limit minimum sebelum
+// fork London.
+func WithPoAGasLimitBounds(bounds GasLimitBounds) PoAOption {
+	return func(p *poaEngine) {
+		p.gasBounds = bounds
+	}
+}
+
+// WithPoAVerifyWorkers caps the number of goroutines VerifyHeaders verifies a
+// batch on, which defaults to one per allowed thread.
+// metoda 'with poa verify workers' akan membatasi jumlah goroutine yang dipakai 'verify headers' untuk satu batch.
+func WithPoAVerifyWorkers(workers int) PoAOption {
+	return func(p *poaEngine) {
+		p.workers = workers
+	}
+}
+
+// WithPoAVerifyWindow sets how many headers of a batch VerifyHeaders keeps in
+// flight at once, four per worker by default. Results are reassembled into
+// input order within the window, so memory stays proportional to it rather
+// than to the batch.
+// metoda 'with poa verify window' akan mengatur jumlah header dalam batch yang diproses bersamaan oleh 'verify headers'.
+func WithPoAVerifyWindow(window int) PoAOption {
+	return func(p *poaEngine) {
+		p.inflight = window
+	}
+}
+
+// WithPoAAbortOnError makes VerifyHeaders give up on a batch at its first
+// failure, as a single invalid header makes the rest of the batch useless
+// during sync. Every header after it is reported with ErrAborted instead of
+// being verified, and the results channel is closed.
+// metoda 'with poa abort on error' akan membuat 'verify headers' berhenti pada kegagalan pertama dalam batch.
+func WithPoAAbortOnError(abort bool) PoAOption {
+	return func(p *poaEngine) {
+		p.failFast = abort
+	}
+}
+
+// WithPoAProgress makes VerifyHeaders report the progress of every batch to
+// the given callback, at most every N verified headers or every second,
+// whichever is less frequent, and once more when the batch is done. Updates
+// arriving while the callback is busy are dropped.
+// metoda 'with poa progress' akan membuat 'verify headers' melaporkan kemajuan setiap batch ke callback yang diberikan.
+func WithPoAProgress(every int, report ProgressFunc) PoAOption {
+	return func(p *poaEngine) {
+		p.progress = batchProgress{every: every, report: report}
+	}
+}
+
+// WithEpoch sets the number of blocks after which the signer list is
+// checkpointed into the header and pending votes are reset, overriding the
+// epoch of the chain config. A zero epoch keeps the configured one.
+// metoda 'with epoch' akan mengatur jumlah block setelah daftar signer disimpan pada header dan voting direset.
+func WithEpoch(epoch uint64) PoAOption {
+	return func(p *poaEngine) {
+		if epoch > 0 {
+			p.config.Epoch = epoch
+		}
+	}
+}
+
+// NewPoA creates a proof-of-authority consensus engine with the initial
+// signers set to the ones provided by the genesis block.
+// metoda 'new poa' akan membuat consensus engine proof-of-authority dengan signer awal dari block genesis.
+func NewPoA(config *params.CliqueConfig, db ethdb.Database, opts ...PoAOption) *poaEngine {
+	// Set any missing consensus parameters to their defaults
+	conf := *config
+	if conf.Epoch == 0 {
+		conf.Epoch = epochLength
+	}
+	p := &poaEngine{
+		config:        &conf,
+		db:            db,
+		recents:       lru.NewCache[common.Hash, *Snapshot](inmemorySnapshots),
+		proposals:     make(map[common.Address]bool),
+		signaturesCap: inmemorySignatures,
+		clock:         time.Now,
+		futureDrift:   AllowedFutureBlockTime,
+		metrics:       NoopMetrics{},
+		quit:          make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	if p.signaturesCap <= 0 {
+		p.signaturesCap = inmemorySignatures
+	}
+	p.signatures = lru.NewCache[common.Hash, common.Address](p.signaturesCap)
+	if p.verified == nil {
+		p.verified = newVerifiedCache(inmemoryVerified)
+	}
+	return p
+}
+
+// Authorize injects a private key into the consensus engine to mint new blocks
+// with.
+// metoda 'authorize' akan memasukkan kunci signer ke consensus engine untuk membuat block baru.
+func (p *poaEngine) Authorize(signer common.Address, signFn SignerFn) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.signer = signer
+	p.signFn = signFn
+}
+
+// Author implements Engine, returning the Ethereum address recovered from the
+// signature in the header's extra-data section. Recovered signers are cached by
+// block hash, so repeated lookups skip the elliptic curve work.
+// metoda 'author' akan mengembalikan alamat Ethereum yang dipulihkan dari tanda tangan pada extra-data header.
+// Hasilnya disimpan di cache berdasarkan hash block.
+func (p *poaEngine) Author(header *types.Header) (common.Address, error) {
+	return ecrecover(header, p.signatures)
+}
+
+// VerifyHeader checks whether a header conforms to the consensus rules.
+// metoda 'verify header' akan mengecek apakah header sesuai dengan aturan consensus proof-of-authority.
+func (p *poaEngine) VerifyHeader(chain ChainHeaderReader, header *types.Header, seal bool) error {
+	return observeFailure(p.metrics, p.checkHeader(chain, header, seal))
+}
+
+// checkHeader is VerifyHeader without reporting failures to the metrics.
+func (p *poaEngine) checkHeader(chain ChainHeaderReader, header *types.Header, seal bool) error {
+	if err := verifyNotBanned(header); err != nil {
+		return wrapHeaderError(header, err)
+	}
+	number := header.Number.Uint64()
+	if chain.GetHeader(header.Hash(), number) != nil {
+		return nil
+	}
+	// The genesis header has no parent to verify against
+	if number == 0 {
+		return wrapHeaderError(header, verifyGenesis(header))
+	}
+	return p.checkHeaderWithParent(chain, header, chain.GetHeader(header.ParentHash, number-1), seal)
+}
+
+// VerifyHeaderWithParent implements ParentVerifier, checking whether a header
+// conforms to the consensus rules of the proof-of-authority engine against a
+// parent the caller already holds, instead of looking it up in the chain.
+// metoda 'verify header with parent' akan mengecek header terhadap parent yang sudah dimiliki pemanggil, tanpa
+// mencarinya di chain.
+func (p *poaEngine) VerifyHeaderWithParent(chain ChainHeaderReader, header, parent *types.Header, seal bool) error {
+	return observeFailure(p.metrics, p.checkHeaderWithParent(chain, header, parent, seal))
+}
+
+// checkHeaderWithParent is VerifyHeaderWithParent without reporting failures to
+// the metrics.
+func (p *poaEngine) checkHeaderWithParent(chain ChainHeaderReader, header, parent *types.Header, seal bool) error {
+	if err := verifyNotBanned(header); err != nil {
+		return wrapHeaderError(header, err)
+	}
+	if parent == nil {
+		return wrapHeaderError(header, ErrUnknownAncestor)
+	}
+	// A cached verdict vouches for the header, not for the parent handed in
+	if err := verifyHeaderBasics(chain, header, parent); err != nil {
+		return wrapHeaderError(header, err)
+	}
+	return wrapHeaderError(header, p.verified.verifyCached(header.Hash(), seal, func() error {
+		return p.verifyHeader(chain, header, parent, nil, seal)
+	}))
+}
+
+// VerifyHeaders is similar to VerifyHeader, but verifies a batch of headers
+// concurrently. The method returns a quit channel to abort the operations and
+// a results channel to retrieve the async verifications.
+// metoda 'verify headers' sama dengan 'verify header', namun memverifikasi header dalam batch secara bersamaan.
+func (p *poaEngine) VerifyHeaders(chain ChainHeaderReader, headers []*types.Header, seals []bool) (chan<- struct{}, <-chan error) {
+	descending, err := validateBatch(headers, seals)
+	if err != nil {
+		return failedBatch(err)
+	}
+	hashes := newHeaderHashes(headers)
+	links := batchLinks(hashes, descending)
+	// Check the header fields in order, leaving only the signatures to the workers
+	cheap := func(index int) (bool, error) {
+		if headers[index].Number.Sign() == 0 {
+			return false, verifyGenesis(headers[index])
+		}
+		parent := batchParent(chain, headers, links, index, descending)
+		if parent == nil {
+			return false, ErrUnknownAncestor
+		}
+		hash := hashes.hash(index)
+		if p.verified.known(hash, seals[index]) {
+			return false, nil
+		}
+		if err := p.verifyHeaderFields(chain, headers[index], parent, batchAncestors(headers, links, index, descending)); err != nil {
+			return false, err
+		}
+		if seals[index] {
+			return true, nil
+		}
+		p.verified.add(hash, false)
+		return false, nil
+	}
+	config := batchConfig{workers: p.workers, abortOnError: p.failFast, metrics: p.metrics, progress: p.progress, closed: p.quit, window: p.inflight}
+	return verifyHeadersPipelined(hashes, config, cheap, func(index int) error {
+		if err := p.verifySeal(chain, headers[index], hashes.hash(index), batchAncestors(headers, links, index, descending)); err != nil {
+			return err
+		}
+		p.verified.add(hashes.hash(index), true)
+		return nil
+	})
+}
+
+// VerifyHeadersContext is similar to VerifyHeaders, but aborts verification
+// once the context is cancelled or its deadline elapses. Results are still
+// delivered in input order, headers left unverified report the context error.
+// metoda 'verify headers context' sama dengan 'verify headers', namun verifikasi dibatalkan ketika context dibatalkan atau melewati tenggat waktu.
+func (p *poaEngine) VerifyHeadersContext(ctx context.Context, chain ChainHeaderReader, headers []*types.Header, seals []bool) <-chan error {
+	return verifyHeadersContext(ctx, p, chain, headers, seals)
+}
+
+// VerifyHeadersWithContext is VerifyHeadersContext, under the name callers built
+// around contexts look for.
+// metoda 'verify headers with context' sama dengan 'verify headers context'.
+func (p *poaEngine) VerifyHeadersWithContext(ctx context.Context, chain ChainHeaderReader, headers []*types.Header, seals []bool) <-chan error {
+	return verifyHeadersContext(ctx, p, chain, headers, seals)
+}
+
+// verifyHeader checks whether a header conforms to the consensus rules, given
+// its already resolved parent. The parents are the batch headers preceding it,
+// which are not yet part of the chain.
+func (p *poaEngine) verifyHeader(chain ChainHeaderReader, header, parent *types.Header, parents []*types.Header, seal bool) error {
+	if err := p.verifyHeaderFields(chain, header, parent, parents); err != nil {
+		return err
+	}
+	if seal {
+		return p.verifySeal(chain, header, header.Hash(), parents)
+	}
+	return nil
+}
+
+// verifyHeaderFields checks whether a header conforms to every consensus rule
+// but its signature, which is left to verifySeal.
+func (p *poaEngine) verifyHeaderFields(chain ChainHeaderReader, header, parent *types.Header, parents []*types.Header) error {
+	if err := verifyHeaderBasics(chain, header, parent); err != nil {
+		return err
+	}
+	if err := VerifyForkHashes(p.pins, header); err != nil {
+		return err
+	}
+	// Don't waste time checking blocks from the future
+	if err := verifyFutureBlock(p.clock, p.futureDrift, header); err != nil {
+		return err
+	}
+	// Check that the extra-data contains both the vanity and signature
+	if len(header.Extra) < extraVanity {
+		return errMissingVanity
+	}
+	if len(header.Extra) < extraVanity+extraSeal {
+		return errMissingSignature
+	}
+	// Ensure that the extra-data contains a signer list on checkpoint, but none
+	// otherwise. Anything beyond vanity and seal on other blocks is just bloat.
+	number := header.Number.Uint64()
+	checkpoint := number%p.config.Epoch == 0
+
+	signersBytes := len(header.Extra) - extraVanity - extraSeal
+	if !checkpoint && signersBytes != 0 {
+		return fmt.Errorf("%w: %d > %d", ErrExtraDataTooLong, len(header.Extra), extraVanity+extraSeal)
+	}
+	if checkpoint && signersBytes%common.AddressLength != 0 {
+		return errInvalidSignerList
+	}
+	// Checkpoint blocks need to enforce zero beneficiary
+	if checkpoint && header.Coinbase != (common.Address{}) {
+		return errInvalidCheckpointBeneficiary
+	}
+	// Nonces must be 0x00..0 or 0xff..f, zeroes enforced on checkpoints
+	if header.Nonce != nonceAuthVote && header.Nonce != nonceDropVote {
+		return errInvalidVote
+	}
+	if checkpoint && header.Nonce != nonceDropVote {
+		return errInvalidCheckpointVote
+	}
+	// Ensure that the block doesn't contain any uncles which are meaningless in PoA
+	if header.UncleHash != types.EmptyUncleHash {
+		return errUnclesUnsupported
+	}
+	if header.Time < parent.Time+p.config.Period {
+		return errInvalidTimestamp
+	}
+	// Verify the gas limit and base fee, if the fee market is active
+	if err := verifyGasLimit(chain.Config(), p.gasBounds, parent, header); err != nil {
+		return err
+	}
+	if err := verifyBaseFee(chain.Config(), parent, header); err != nil {
+		return err
+	}
+	if err := verifyWithdrawals(chain.Config(), header); err != nil {
+		return err
+	}
+	if err := verifyBlobGas(chain.Config(), parent, header); err != nil {
+		return err
+	}
+	if err := verifyParentBeaconRoot(chain.Config(), header); err != nil {
+		return err
+	}
+	// If the block is a checkpoint block, verify the signer list
+	if checkpoint {
+		snap, err := p.snapshot(chain, number-1, header.ParentHash, parents)
+		if err != nil {
+			return err
+		}
+		signers := make([]byte, len(snap.Signers)*common.AddressLength)
+		for i, signer := range snap.signers() {
+			copy(signers[i*common.AddressLength:], signer[:])
+		}
+		extraSuffix := len(header.Extra) - extraSeal
+		if !bytes.Equal(header.Extra[extraVanity:extraSuffix], signers) {
+			return errMismatchingCheckpointSigners
+		}
+	}
+	return nil
+}
+
+// VerifySeal implements Engine, checking whether the signature contained in
+// the header satisfies the consensus protocol requirements.
+// metoda 'verify seal' akan mengecek apakah tanda tangan pada header memenuhi aturan protokol consensus.
+func (p *poaEngine) VerifySeal(chain ChainHeaderReader, header *types.Header) error {
+	return p.verifySeal(chain, header, header.Hash(), nil)
+}
+
+// verifySeal checks whether the signature contained in the header satisfies the
+// consensus protocol requirements, against the authorization snapshot of its
+// parent. The hash of the header keys the cache of recovered signers.
+func (p *poaEngine) verifySeal(chain ChainHeaderReader, header *types.Header, hash common.Hash, parents []*types.Header) error {
+	// Verifying the genesis block is not supported
+	number := header.Number.Uint64()
+	if number == 0 {
+		return errUnknownBlock
+	}
+	// Retrieve the snapshot needed to verify this header and cache it
+	snap, err := p.snapshot(chain, number-1, header.ParentHash, parents)
+	if err != nil {
+		return err
+	}
+	// Resolve the authorization key and check against signers
+	signer, err := ecrecoverHashed(header, hash, p.signatures)
+	if err != nil {
+		return err
+	}
+	if _, ok := snap.Signers[signer]; !ok {
+		return errUnauthorizedSigner
+	}
+	if snap.recentlySigned(number, signer) {
+		return errRecentlySigned
+	}
+	// Ensure that the difficulty corresponds to the turn-ness of the signer
+	if snap.inturn(number, signer) {
+		if header.Difficulty.Cmp(diffInTurn) != 0 {
+			return errWrongDifficulty
+		}
+	} else if header.Difficulty.Cmp(diffNoTurn) != 0 {
+		return errWrongDifficulty
+	}
+	return nil
+}
+
+// VerifyUncles implements Engine, always returning an error for any uncles as
+// this consensus mechanism doesn't permit uncles.
+// metoda 'verify uncles' akan selalu menolak uncle karena mekanisme consensus ini tidak mengizinkan uncle.
+func (p *poaEngine) VerifyUncles(chain ChainReader, block *types.Block) error {
+	if len(block.Uncles()) > 0 {
+		return errUnclesUnsupported
+	}
+	return nil
+}
+
+// Prepare implements Engine, preparing all the consensus fields of the header
+// for running the transactions on top. As signers have no natural coinbase, it
+// is zeroed, unless the block casts a vote on the address it names.
+// metoda 'prepare' akan menyiapkan semua field consensus dari header sebelum transaksi dijalankan.
+func (p *poaEngine) Prepare(chain ChainHeaderReader, header *types.Header) error {
+	header.Coinbase = common.Address{}
+	header.Nonce = types.BlockNonce{}
+	header.MixDigest = common.Hash{}
+
+	number := header.Number.Uint64()
+	parent := chain.GetHeader(header.ParentHash, number-1)
+	if parent == nil {
+		return ErrUnknownAncestor
+	}
+	// Assemble the voting snapshot to check which votes make sense
+	snap, err := p.snapshot(chain, number-1, header.ParentHash, nil)
+	if err != nil {
+		return err
+	}
+	// If the block isn't a checkpoint, cast a random vote (good enough for now)
+	if number%p.config.Epoch != 0 {
+		p.lock.RLock()
+
+		// Gather all the proposals that make sense voting on
+		addresses := make([]common.Address, 0, len(p.proposals))
+		for address, authorize := range p.proposals {
+			if snap.validVote(address, authorize) {
+				addresses = append(addresses, address)
+			}
+		}
+		// If there's pending proposals, cast a vote on them
+		if len(addresses) > 0 {
+			header.Coinbase = addresses[rand.Intn(len(addresses))]
+			if p.proposals[header.Coinbase] {
+				copy(header.Nonce[:], nonceAuthVote[:])
+			} else {
+				copy(header.Nonce[:], nonceDropVote[:])
+			}
+		}
+		p.lock.RUnlock()
+	}
+
+	// Set the correct difficulty and base fee
+	header.Difficulty = p.CalcDifficulty(chain, header.Time, parent)
+	prepareBaseFee(chain.Config(), parent, header)
+
+	// Ensure the extra data has all its components
+	if len(header.Extra) < extraVanity {
+		header.Extra = append(header.Extra, bytes.Repeat([]byte{0x00}, extraVanity-len(header.Extra))...)
+	}
+	header.Extra = header.Extra[:extraVanity]
+
+	if number%p.config.Epoch == 0 {
+		for _, signer := range snap.signers() {
+			header.Extra = append(header.Extra, signer[:]...)
+		}
+	}
+	header.Extra = append(header.Extra, make([]byte, extraSeal)...)
+
+	// Ensure the timestamp has the correct delay
+	header.Time = parent.Time + p.config.Period
+	if now := uint64(p.clock().Unix()); header.Time < now {
+		header.Time = now
+	}
+	return nil
+}
+
+// Finalize implements Engine. There are no block rewards in proof-of-authority,
+// only the withdrawals are credited.
+// metoda 'finalize' tidak memberikan block rewards karena proof-of-authority tidak memilikinya, hanya withdrawal yang dikreditkan.
+func (p *poaEngine) Finalize(chain ChainHeaderReader, header *types.Header, state *state.StateDB, txs []*types.Transaction, uncles []*types.Header, withdrawals []*types.Withdrawal) {
+	ProcessWithdrawals(state, withdrawals)
+}
+
+// FinalizeAndAssemble implements Engine, setting the final state root and
+// assembling the block.
+// metoda 'finalize and assemble' akan mengatur state root akhir dan membangun block.
+func (p *poaEngine) FinalizeAndAssemble(chain ChainHeaderReader, header *types.Header, state *state.StateDB, txs []*types.Transaction, uncles []*types.Header, receipts []*types.Receipt, withdrawals []*types.Withdrawal) (*types.Block, error) {
+	if err := assembleWithdrawals(chain.Config(), header, withdrawals); err != nil {
+		return nil, err
+	}
+	// Finalize block
+	p.Finalize(chain, header, state, txs, uncles, withdrawals)
+
+	// Assign the final state root to header.
+	header.Root = state.IntermediateRoot(true)
+
+	// Assemble and return the final block for sealing.
+	return types.NewBlockWithWithdrawals(header, txs, nil, receipts, withdrawals, trie.NewStackTrie(nil)), nil
+}
+
+// Seal implements Engine, attempting to create a sealed block using the local
+// signing credentials. The block is delivered once its timestamp is reached.
+// metoda 'seal' akan membuat block tersegel menggunakan kredensial signer lokal, dan mengirimnya saat timestamp tercapai.
+func (p *poaEngine) Seal(chain ChainHeaderReader, block *types.Block, results chan<- *types.Block, stop <-chan struct{}) error {
+	header := block.Header()
+
+	// Sealing the genesis block is not supported
+	if header.Number.Sign() == 0 {
+		return errUnknownBlock
+	}
+	// Don't hold the signer fields for the entire sealing procedure
+	p.lock.RLock()
+	signer, signFn := p.signer, p.signFn
+	p.lock.RUnlock()
+
+	if signFn == nil {
+		return errMissingSignerFn
+	}
+	// Bail out if we're unauthorized to sign a block
+	number := header.Number.Uint64()
+	snap, err := p.snapshot(chain, number-1, header.ParentHash, nil)
+	if err != nil {
+		return err
+	}
+	if _, authorized := snap.Signers[signer]; !authorized {
+		return errUnauthorizedSigner
+	}
+	// Refuse to sign if we're amongst the recent signers, others must seal first
+	if snap.recentlySigned(number, signer) {
+		return errRecentlySigned
+	}
+	// Sign all the things!
+	sighash, err := signFn(signer, p.SealHash(header).Bytes())
+	if err != nil {
+		return err
+	}
+	copy(header.Extra[len(header.Extra)-extraSeal:], sighash)
+
+	// Refuse to start sealing on a closed engine, Close must be able to wait for
+	// every delivery goroutine
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	select {
+	case <-p.quit:
+		return errEngineClosed
+	default:
+	}
+	p.sealing.Add(1)
+
+	// Wait until sealing is terminated or the block's time is reached
+	delay := time.Until(time.Unix(int64(header.Time), 0))
+	go func() {
+		defer p.sealing.Done()
+
+		select {
+		case <-stop:
+			return
+		case <-p.quit:
+			return
+		case <-time.After(delay):
+		}
+		select {
+		case results <- block.WithSeal(header):
+		case <-stop:
+		case <-p.quit:
+		}
+	}()
+	return nil
+}
+
+// SealHash returns the hash of a block prior to it being sealed.
+// metoda 'seal hash' akan mengembalikan hash dari block sebelum dibungkus.
+func (p *poaEngine) SealHash(header *types.Header) common.Hash {
+	return poaSealHash(header)
+}
+
+// poaSealHash returns the hash of a block prior to it being sealed, which is
+// every header field with the signature stripped from the extra-data.
+func poaSealHash(header *types.Header) (hash common.Hash) {
+	hasher := crypto.NewKeccakState()
+
+	enc := []interface{}{
+		header.ParentHash,
+		header.UncleHash,
+		header.Coinbase,
+		header.Root,
+		header.TxHash,
+		header.ReceiptHash,
+		header.Bloom,
+		header.Difficulty,
+		header.Number,
+		header.GasLimit,
+		header.GasUsed,
+		header.Time,
+		header.Extra[:len(header.Extra)-extraSeal], // Yes, this will panic if extra is too short
+		header.MixDigest,
+		header.Nonce,
+	}
+	if header.BaseFee != nil {
+		enc = append(enc, header.BaseFee)
+	}
+	if header.WithdrawalsHash != nil {
+		enc = append(enc, header.WithdrawalsHash)
+	}
+	if header.ExcessBlobGas != nil {
+		enc = append(enc, header.BlobGasUsed, header.ExcessBlobGas)
+	}
+	if header.ParentBeaconRoot != nil {
+		enc = append(enc, header.ParentBeaconRoot)
+	}
+	rlp.Encode(hasher, enc)
+	hasher.Sum(hash[:0])
+	return hash
+}
+
+// CalcDifficulty is the difficulty adjustment algorithm. It returns the
+// difficulty that a new block should have based on whether the local signer is
+// in-turn or not.
+// metoda 'calc difficulty' akan mengembalikan tingkat kesulitan block baru berdasarkan giliran signer lokal.
+func (p *poaEngine) CalcDifficulty(chain ChainHeaderReader, time uint64, parent *types.Header) *big.Int {
+	snap, err := p.snapshot(chain, parent.Number.Uint64(), parent.Hash(), nil)
+	if err != nil {
+		return nil
+	}
+	p.lock.RLock()
+	signer := p.signer
+	p.lock.RUnlock()
+
+	if snap.inturn(parent.Number.Uint64()+1, signer) {
+		return new(big.Int).Set(diffInTurn)
+	}
+	return new(big.Int).Set(diffNoTurn)
+}
+
+// APIs implements Engine, returning the user facing RPC APIs to manage the
+// signer proposals.
+// metoda 'apis' akan mengembalikan RPC API untuk mengelola usulan signer.
+func (p *poaEngine) APIs(chain ChainHeaderReader) []rpc.API {
+	return []rpc.API{{
+		Namespace: "poa",
+		Service:   &PoAAPI{poa: p},
+	}}
+}
+
+// Close implements Engine, stopping every pending seal delivery and waiting
+// for it to exit. The most recent snapshot is flushed to the database, after
+// which the in-memory snapshot and signature caches are cleared, so nothing
+// stale outlives the engine. The flush error, if any, is returned.
+// metoda 'close' akan menghentikan semua pengiriman seal yang tertunda, menyimpan snapshot terbaru ke database,
+// lalu mengosongkan cache snapshot dan tanda tangan di memori.
+func (p *poaEngine) Close() error {
+	var err error
+	p.closeOnce.Do(func() {
+		p.lock.Lock()
+		close(p.quit)
+		p.lock.Unlock()
+
+		p.sealing.Wait()
+
+		err = p.flushSnapshots()
+		p.recents.Purge()
+		p.signatures.Purge()
+		p.verified.purge()
+	})
+	return err
+}
+
+// flushSnapshots stores the most recent in-memory snapshot to the database, so
+// a restarted engine doesn't need to rebuild it from the last checkpoint.
+func (p *poaEngine) flushSnapshots() error {
+	if p.db == nil {
+		return nil
+	}
+	var head *Snapshot
+	for _, hash := range p.recents.Keys() {
+		if snap, ok := p.recents.Get(hash); ok && (head == nil || snap.Number > head.Number) {
+			head = snap
+		}
+	}
+	if head == nil {
+		return nil
+	}
+	return head.store(p.db)
+}
@@ -0,0 +1,61 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package consensus
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// PoAAPI is a user facing RPC API to allow controlling the signer and voting
+// mechanisms of the proof-of-authority scheme.
+// PoAAPI menyediakan RPC API untuk mengendalikan mekanisme signer dan voting proof-of-authority.
+type PoAAPI struct {
+	poa *poaEngine
+}
+
+// Proposals returns the current proposals the node tries to uphold and vote on.
+// metoda 'proposals' akan mengembalikan usulan yang sedang diperjuangkan node ini.
+func (api *PoAAPI) Proposals() map[common.Address]bool {
+	api.poa.lock.RLock()
+	defer api.poa.lock.RUnlock()
+
+	proposals := make(map[common.Address]bool)
+	for address, auth := range api.poa.proposals {
+		proposals[address] = auth
+	}
+	return proposals
+}
+
+// Propose injects a new authorization proposal that the signer will attempt to
+// push through.
+// metoda 'propose' akan menambahkan usulan otorisasi baru yang akan diperjuangkan signer.
+func (api *PoAAPI) Propose(address common.Address, auth bool) {
+	api.poa.lock.Lock()
+	defer api.poa.lock.Unlock()
+
+	api.poa.proposals[address] = auth
+}
+
+// Discard drops a currently running proposal, stopping the signer from casting
+// further votes (either for or against).
+// metoda 'discard' akan menghapus usulan yang sedang berjalan.
+func (api *PoAAPI) Discard(address common.Address) {
+	api.poa.lock.Lock()
+	defer api.poa.lock.Unlock()
+
+	delete(api.poa.proposals, address)
+}
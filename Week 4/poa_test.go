@@ -0,0 +1,525 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package consensus
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"math/big"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// testCliqueConfig is the proof-of-authority config of the tests.
+var testCliqueConfig = &params.CliqueConfig{Period: 1, Epoch: 30000}
+
+// newTestKey generates a signing key and returns it along with its address.
+func newTestKey(t testing.TB) (*ecdsa.PrivateKey, common.Address) {
+	t.Helper()
+
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	return key, crypto.PubkeyToAddress(key.PublicKey)
+}
+
+// signHeader reserves the vanity and seal of the header's extra-data, if not
+// done yet, and fills the seal with the key's signature.
+func signHeader(t testing.TB, header *types.Header, key *ecdsa.PrivateKey) {
+	t.Helper()
+
+	if len(header.Extra) < extraVanity+extraSeal {
+		header.Extra = append(header.Extra, make([]byte, extraVanity+extraSeal-len(header.Extra))...)
+	}
+	sig, err := crypto.Sign(poaSealHash(header).Bytes(), key)
+	if err != nil {
+		t.Fatalf("failed to sign header: %v", err)
+	}
+	copy(header.Extra[len(header.Extra)-extraSeal:], sig)
+}
+
+// makeSignedHeaders creates n headers, each signed by the key, numbered from 1.
+func makeSignedHeaders(t testing.TB, key *ecdsa.PrivateKey, n int) []*types.Header {
+	t.Helper()
+
+	headers := make([]*types.Header, n)
+	for i := range headers {
+		headers[i] = &types.Header{Number: big.NewInt(int64(i + 1)), Difficulty: diffInTurn, Time: uint64(i + 1)}
+		signHeader(t, headers[i], key)
+	}
+	return headers
+}
+
+// Tests that concurrent Author calls on a cache smaller than the working set
+// all recover the right signer.
+func TestAuthorCacheConcurrent(t *testing.T) {
+	var (
+		engine    = NewPoA(testCliqueConfig, nil, WithSignatureCache(8))
+		key, addr = newTestKey(t)
+		headers   = makeSignedHeaders(t, key, 32)
+		pend      sync.WaitGroup
+	)
+	for i := 0; i < 8; i++ {
+		pend.Add(1)
+		go func() {
+			defer pend.Done()
+			for j := 0; j < 4; j++ {
+				for _, header := range headers {
+					if signer, err := engine.Author(header); err != nil || signer != addr {
+						t.Errorf("header %d: author mismatch: have %x, %v, want %x", header.Number, signer, err, addr)
+						return
+					}
+				}
+			}
+		}()
+	}
+	pend.Wait()
+
+	if have := engine.signatures.Len(); have != 8 {
+		t.Errorf("cache size mismatch: have %d, want 8", have)
+	}
+}
+
+// Tests that the signer cache evicts the least recently used header.
+func TestAuthorCacheEviction(t *testing.T) {
+	var (
+		engine  = NewPoA(testCliqueConfig, nil, WithSignatureCache(2))
+		key, _  = newTestKey(t)
+		headers = makeSignedHeaders(t, key, 3)
+	)
+	engine.Author(headers[0])
+	engine.Author(headers[1])
+	engine.Author(headers[0]) // Refresh, making the second header the oldest
+	engine.Author(headers[2])
+
+	for i, want := range []bool{true, false, true} {
+		if have := engine.signatures.Contains(headers[i].Hash()); have != want {
+			t.Errorf("header %d: cached mismatch: have %t, want %t", i, have, want)
+		}
+	}
+}
+
+// Tests that repeated Author calls are served from the signature cache, which
+// holds 4096 signers by default, returning the recovered address.
+func TestAuthorCacheHit(t *testing.T) {
+	var (
+		engine      = NewPoA(testCliqueConfig, nil)
+		key, signer = newTestKey(t)
+		header      = makeSignedHeaders(t, key, 1)[0]
+	)
+	if engine.signaturesCap != 4096 {
+		t.Errorf("default cache size mismatch: have %d, want 4096", engine.signaturesCap)
+	}
+	first, err := engine.Author(header)
+	if err != nil || first != signer {
+		t.Fatalf("author mismatch: have %x (%v), want %x", first, err, signer)
+	}
+	if cached, ok := engine.signatures.Peek(header.Hash()); !ok || cached != signer {
+		t.Fatalf("signer not cached: have %x (%t), want %x", cached, ok, signer)
+	}
+	second, err := engine.Author(header)
+	if err != nil || second != first {
+		t.Errorf("repeated author mismatch: have %x (%v), want %x", second, err, first)
+	}
+	// A planted entry proves lookups don't go past the cache
+	planted := common.Address{0xaa}
+	engine.signatures.Add(header.Hash(), planted)
+	if have, _ := engine.Author(header); have != planted {
+		t.Errorf("author not served from the cache: have %x, want %x", have, planted)
+	}
+}
+
+// Tests that two of three signers proposing a new signer vote it in through
+// their prepared blocks, after which it may seal blocks itself.
+func TestVoteInSigner(t *testing.T) {
+	keys := make([]*ecdsa.PrivateKey, 4)
+	addrs := make([]common.Address, 4)
+	engines := make([]*poaEngine, 4)
+	for i := range keys {
+		keys[i], addrs[i] = newTestKey(t)
+		engines[i] = NewPoA(testCliqueConfig, nil)
+		engines[i].Authorize(addrs[i], nil)
+	}
+	chain, _ := makeVotingChain(t, keys, 3, nil)
+	verifier := NewPoA(testCliqueConfig, nil)
+
+	// seal prepares the next block with the signer's engine, signs it and
+	// returns it verified, or the verification error
+	seal := func(signer int) (*types.Header, error) {
+		parent := chain.CurrentHeader()
+		header := &types.Header{
+			ParentHash: parent.Hash(),
+			UncleHash:  types.EmptyUncleHash,
+			Number:     new(big.Int).Add(parent.Number, common.Big1),
+			GasLimit:   parent.GasLimit,
+		}
+		if err := engines[signer].Prepare(chain, header); err != nil {
+			t.Fatalf("signer %d: failed to prepare header: %v", signer, err)
+		}
+		signHeader(t, header, keys[signer])
+		return header, verifier.VerifyHeader(chain, header, true)
+	}
+	// A proposal discarded again casts no vote
+	api := engines[2].APIs(chain)[0].Service.(*PoAAPI)
+	api.Propose(addrs[3], true)
+	api.Discard(addrs[3])
+	if proposals := api.Proposals(); len(proposals) != 0 {
+		t.Fatalf("proposals left after discarding: %v", proposals)
+	}
+	for _, signer := range []int{0, 1} {
+		engines[signer].APIs(chain)[0].Service.(*PoAAPI).Propose(addrs[3], true)
+	}
+	// The candidate can't sign before the vote passed
+	if _, err := seal(3); !errors.Is(err, errUnauthorizedSigner) {
+		t.Fatalf("candidate seal error mismatch: have %v, want %v", err, errUnauthorizedSigner)
+	}
+	for _, signer := range []int{0, 1} {
+		header, err := seal(signer)
+		if err != nil {
+			t.Fatalf("signer %d: block failed verification: %v", signer, err)
+		}
+		if header.Coinbase != addrs[3] || header.Nonce != nonceAuthVote {
+			t.Fatalf("signer %d: vote mismatch: have %x/%x, want %x/%x", signer, header.Coinbase, header.Nonce, addrs[3], nonceAuthVote)
+		}
+		chain.insert(header)
+	}
+	head := chain.CurrentHeader()
+	snap, err := verifier.snapshot(chain, head.Number.Uint64(), head.Hash(), nil)
+	if err != nil {
+		t.Fatalf("failed to retrieve snapshot: %v", err)
+	}
+	if _, ok := snap.Signers[addrs[3]]; !ok || len(snap.Signers) != 4 {
+		t.Fatalf("candidate not voted in: signers %x", snap.signers())
+	}
+	// The new signer seals, and its proposers stop voting on it
+	header, err := seal(3)
+	if err != nil {
+		t.Fatalf("new signer's block failed verification: %v", err)
+	}
+	chain.insert(header)
+
+	if header, _ = seal(0); header.Coinbase != (common.Address{}) {
+		t.Errorf("vote cast on a passed proposal: %x", header.Coinbase)
+	}
+}
+
+// Tests that checkpoint headers are prepared with the full signer list and no
+// vote, and that only headers listing exactly the authorized signers verify.
+func TestCheckpointSigners(t *testing.T) {
+	key, addr := newTestKey(t)
+	_, other := newTestKey(t)
+
+	config := &params.CliqueConfig{Period: 1, Epoch: 3}
+	engine := NewPoA(config, nil)
+	engine.Authorize(addr, nil)
+	engine.APIs(nil)[0].Service.(*PoAAPI).Propose(other, true)
+
+	chain, headers := makeVotingChain(t, []*ecdsa.PrivateKey{key}, 1, make([]testVote, 2))
+	parent := headers[len(headers)-1]
+
+	header := &types.Header{
+		ParentHash: parent.Hash(),
+		UncleHash:  types.EmptyUncleHash,
+		Number:     big.NewInt(3),
+		GasLimit:   parent.GasLimit,
+	}
+	if err := engine.Prepare(chain, header); err != nil {
+		t.Fatalf("failed to prepare checkpoint: %v", err)
+	}
+	if header.Coinbase != (common.Address{}) || header.Nonce != nonceDropVote {
+		t.Errorf("vote cast on checkpoint: %x/%x", header.Coinbase, header.Nonce)
+	}
+	signers, err := checkpointSigners(header)
+	if err != nil {
+		t.Fatalf("failed to parse checkpoint signers: %v", err)
+	}
+	if len(signers) != 1 || signers[0] != addr {
+		t.Fatalf("checkpoint signers mismatch: have %x, want [%x]", signers, addr)
+	}
+	signHeader(t, header, key)
+	if err := engine.VerifyHeader(chain, header, true); err != nil {
+		t.Fatalf("checkpoint failed verification: %v", err)
+	}
+	// Tamper with the signer list in every way
+	list := func(addrs ...common.Address) []byte {
+		extra := make([]byte, extraVanity, extraVanity+len(addrs)*common.AddressLength+extraSeal)
+		for _, addr := range addrs {
+			extra = append(extra, addr[:]...)
+		}
+		return append(extra, make([]byte, extraSeal)...)
+	}
+	tests := []struct {
+		name  string
+		extra []byte
+		want  error
+	}{
+		{"replaced signer", list(other), errMismatchingCheckpointSigners},
+		{"extra signer", list(addr, other), errMismatchingCheckpointSigners},
+		{"missing signer", list(), errMismatchingCheckpointSigners},
+		{"truncated signer", append(list(addr)[:extraVanity+common.AddressLength-1], make([]byte, extraSeal)...), errInvalidSignerList},
+	}
+	for _, tt := range tests {
+		tampered := types.CopyHeader(header)
+		tampered.Extra = tt.extra
+		signHeader(t, tampered, key)
+		if err := engine.VerifyHeader(chain, tampered, true); !errors.Is(err, tt.want) {
+			t.Errorf("%s: error mismatch: have %v, want %v", tt.name, err, tt.want)
+		}
+	}
+	// Signer lists are rejected outside of checkpoints
+	tampered := types.CopyHeader(headers[0])
+	tampered.Extra = list(addr)
+	signHeader(t, tampered, key)
+	if err := engine.VerifyHeader(chain, tampered, true); !errors.Is(err, ErrExtraDataTooLong) {
+		t.Errorf("non-checkpoint signer list error mismatch: have %v, want %v", err, ErrExtraDataTooLong)
+	}
+}
+
+// Tests that the extra-data of non-checkpoint headers must hold exactly the
+// vanity and the seal, each shortfall or excess reported with its own error.
+func TestVerifyHeaderExtraDataPoA(t *testing.T) {
+	key, _ := newTestKey(t)
+	chain, _ := makeVotingChain(t, []*ecdsa.PrivateKey{key}, 1, nil)
+	genesis := chain.GetHeaderByNumber(0)
+	engine := NewPoA(testCliqueConfig, nil)
+
+	tests := []struct {
+		size int
+		want error
+	}{
+		{extraVanity - 1, errMissingVanity},
+		{extraVanity + extraSeal - 1, errMissingSignature},
+		{extraVanity + extraSeal, nil},
+		{extraVanity + extraSeal + 1, ErrExtraDataTooLong},
+		{extraVanity + common.AddressLength + extraSeal, ErrExtraDataTooLong},
+	}
+	for _, tt := range tests {
+		header := &types.Header{
+			ParentHash: genesis.Hash(),
+			UncleHash:  types.EmptyUncleHash,
+			Number:     big.NewInt(1),
+			GasLimit:   genesis.GasLimit,
+			Time:       genesis.Time + testCliqueConfig.Period,
+			Difficulty: diffInTurn,
+			Extra:      make([]byte, tt.size),
+		}
+		if err := engine.VerifyHeader(chain, header, false); !errors.Is(err, tt.want) {
+			t.Errorf("extra-data of %d bytes: error mismatch: have %v, want %v", tt.size, err, tt.want)
+		}
+	}
+}
+
+// Tests that closing the engine stops the pending seal deliveries, flushes the
+// newest snapshot to the database and clears every in-memory cache.
+func TestPoACloseFlushesCaches(t *testing.T) {
+	key, signer := newTestKey(t)
+	chain, headers := makeVotingChain(t, []*ecdsa.PrivateKey{key}, 1, make([]testVote, 4))
+	parent := headers[len(headers)-1]
+	// Known headers skip verification, so keep the head out of the chain until
+	// it passed
+	head := &types.Header{
+		ParentHash: parent.Hash(),
+		UncleHash:  types.EmptyUncleHash,
+		Number:     new(big.Int).Add(parent.Number, common.Big1),
+		GasLimit:   parent.GasLimit,
+		Time:       parent.Time + testCliqueConfig.Period,
+		Difficulty: diffInTurn,
+	}
+	signHeader(t, head, key)
+
+	baseline := runtime.NumGoroutine()
+
+	engine := NewPoA(testCliqueConfig, rawdb.NewMemoryDatabase())
+	engine.Authorize(signer, func(account common.Address, hash []byte) ([]byte, error) {
+		return crypto.Sign(hash, key)
+	})
+	// Populate the signature, snapshot and verified header caches
+	if err := engine.VerifyHeader(chain, head, true); err != nil {
+		t.Fatalf("failed to verify head: %v", err)
+	}
+	if _, err := engine.Author(head); err != nil {
+		t.Fatalf("failed to recover author: %v", err)
+	}
+	if engine.recents.Len() == 0 || engine.signatures.Len() == 0 || !engine.verified.known(head.Hash(), true) {
+		t.Fatalf("caches not populated")
+	}
+	chain.insert(head)
+
+	// Start a seal whose delivery is far in the future, parking its goroutine
+	header := &types.Header{
+		ParentHash: head.Hash(),
+		UncleHash:  types.EmptyUncleHash,
+		Number:     new(big.Int).Add(head.Number, common.Big1),
+		GasLimit:   head.GasLimit,
+		Time:       uint64(time.Now().Add(time.Hour).Unix()),
+		Difficulty: diffInTurn,
+		Extra:      make([]byte, extraVanity+extraSeal),
+	}
+	results := make(chan *types.Block)
+	if err := engine.Seal(chain, types.NewBlockWithHeader(header), results, nil); err != nil {
+		t.Fatalf("failed to start sealing: %v", err)
+	}
+	if err := engine.Close(); err != nil {
+		t.Fatalf("failed to close engine: %v", err)
+	}
+	// Close waits for the sealing goroutines, so none may linger
+	waitGoroutines(t, baseline)
+	if n := engine.recents.Len(); n != 0 {
+		t.Errorf("snapshot cache not cleared: %d entries", n)
+	}
+	if n := engine.signatures.Len(); n != 0 {
+		t.Errorf("signature cache not cleared: %d entries", n)
+	}
+	if engine.verified.known(head.Hash(), false) {
+		t.Errorf("verified header cache not cleared")
+	}
+	// The newest snapshot must have survived in the database
+	snap, err := loadSnapshot(testCliqueConfig, nil, engine.db, head.Hash())
+	if err != nil {
+		t.Fatalf("snapshot not flushed: %v", err)
+	}
+	if snap.Number != head.Number.Uint64() {
+		t.Errorf("flushed snapshot number mismatch: have %d, want %d", snap.Number, head.Number)
+	}
+	select {
+	case block := <-results:
+		t.Errorf("block %d delivered after close", block.NumberU64())
+	default:
+	}
+	// A closed engine refuses to seal and closing again is a no-op
+	if err := engine.Seal(chain, types.NewBlockWithHeader(header), results, nil); !errors.Is(err, errEngineClosed) {
+		t.Errorf("seal after close: error mismatch: have %v, want %v", err, errEngineClosed)
+	}
+	if err := engine.Close(); err != nil {
+		t.Errorf("second close failed: %v", err)
+	}
+}
+
+// Benchmarks recovering the author of the same header over and over, with the
+// signature cache purged before every call and with the cache left warm.
+func BenchmarkAuthor(b *testing.B) {
+	var (
+		engine = NewPoA(testCliqueConfig, nil)
+		key, _ = newTestKey(b)
+		header = makeSignedHeaders(b, key, 1)[0]
+	)
+	b.Run("uncached", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			engine.signatures.Purge()
+			if _, err := engine.Author(header); err != nil {
+				b.Fatalf("failed to recover author: %v", err)
+			}
+		}
+	})
+	b.Run("cached", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if _, err := engine.Author(header); err != nil {
+				b.Fatalf("failed to recover author: %v", err)
+			}
+		}
+	})
+}
+
+// Tests that Prepare zeroes the coinbase of proof-of-authority headers, unless
+// the block casts a vote on the address it names, and never votes on
+// checkpoint blocks.
+func TestPrepareCoinbasePoA(t *testing.T) {
+	var (
+		key, signer  = newTestKey(t)
+		_, candidate = newTestKey(t)
+		chain, _     = makeVotingChain(t, []*ecdsa.PrivateKey{key}, 1, nil)
+		genesis      = chain.GetHeaderByNumber(0)
+	)
+	prepare := func(engine *poaEngine) *types.Header {
+		header := &types.Header{
+			ParentHash: genesis.Hash(),
+			Coinbase:   common.Address{0x11},
+			Number:     big.NewInt(1),
+			Nonce:      types.BlockNonce{0x22},
+		}
+		if err := engine.Prepare(chain, header); err != nil {
+			t.Fatalf("failed to prepare header: %v", err)
+		}
+		return header
+	}
+	engine := NewPoA(testCliqueConfig, nil)
+	if header := prepare(engine); header.Coinbase != (common.Address{}) || header.Nonce != (types.BlockNonce{}) {
+		t.Errorf("no vote: have coinbase %x nonce %x, want zero", header.Coinbase, header.Nonce)
+	}
+	tests := []struct {
+		address   common.Address
+		authorize bool
+		nonce     types.BlockNonce
+	}{
+		{candidate, true, nonceAuthVote},
+		{signer, false, nonceDropVote},
+	}
+	for _, tt := range tests {
+		engine := NewPoA(testCliqueConfig, nil)
+		engine.proposals[tt.address] = tt.authorize
+
+		header := prepare(engine)
+		if header.Coinbase != tt.address || header.Nonce != tt.nonce {
+			t.Errorf("vote on %x: have coinbase %x nonce %x, want %x %x", tt.address, header.Coinbase, header.Nonce, tt.address, tt.nonce)
+		}
+	}
+	// Checkpoints carry the signer list instead of a vote
+	engine = NewPoA(&params.CliqueConfig{Period: 1, Epoch: 1}, nil)
+	engine.proposals[candidate] = true
+	if header := prepare(engine); header.Coinbase != (common.Address{}) || header.Nonce != (types.BlockNonce{}) {
+		t.Errorf("checkpoint vote: have coinbase %x nonce %x, want zero", header.Coinbase, header.Nonce)
+	}
+}
+
+// Tests that Prepare stamps headers with the time of the engine's clock, or the
+// parent time plus the period if the clock is behind that.
+func TestPrepareClockPoA(t *testing.T) {
+	var (
+		key, _   = newTestKey(t)
+		chain, _ = makeVotingChain(t, []*ecdsa.PrivateKey{key}, 1, nil)
+		genesis  = chain.GetHeaderByNumber(0)
+		earliest = genesis.Time + testCliqueConfig.Period
+	)
+	tests := []struct {
+		now  uint64
+		want uint64
+	}{
+		{earliest + 100, earliest + 100},
+		{earliest, earliest},
+		{earliest - 1, earliest},
+	}
+	for _, tt := range tests {
+		engine := NewPoA(testCliqueConfig, nil, WithPoAClock(func() time.Time { return time.Unix(int64(tt.now), 0) }))
+
+		header := &types.Header{ParentHash: genesis.Hash(), Number: big.NewInt(1)}
+		if err := engine.Prepare(chain, header); err != nil {
+			t.Fatalf("clock %d: failed to prepare header: %v", tt.now, err)
+		}
+		if header.Time != tt.want {
+			t.Errorf("clock %d: timestamp mismatch: have %d, want %d", tt.now, header.Time, tt.want)
+		}
+	}
+}
@@ -0,0 +1,1331 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package consensus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+// Proof-of-work protocol constants.
+var (
+	maxUncles     = 2 // Maximum number of uncles allowed in a single block
+	maxUncleDepth = 7 // Maximum number of generations an uncle may lag behind the block
+
+	// two256 is a big integer representing 2^256.
+	two256 = new(big.Int).Exp(big.NewInt(2), big.NewInt(256), big.NewInt(0))
+
+	// Big integer constants of the difficulty adjustment algorithms.
+	big9       = big.NewInt(9)
+	big10      = big.NewInt(10)
+	bigMinus99 = big.NewInt(-99)
+)
+
+// Various error messages to mark blocks invalid. These should be private to
+// prevent engine specific errors from being referenced in the remainder of the
+// codebase, inherently breaking if the engine is swapped out. Please put common
+// error types into the consensus package.
+var (
+	errUnclesUnsupported = errors.New("uncles not supported")
+	errInvalidDifficulty = errors.New("non-positive difficulty")
+	errInvalidPoW        = errors.New("invalid proof-of-work")
+)
+
+// powEngine is a CPU proof-of-work consensus engine. A block is sealed once
+// keccak256(sealhash ++ nonce) interpreted as a number is at most 2^256 / difficulty.
+// powEngine adalah consensus engine proof-of-work berbasis CPU. Block dianggap tersegel jika keccak256(sealhash ++ nonce) tidak lebih dari 2^256 / difficulty.
+type powEngine struct {
+	mode      Mode          // Amount of verification the engine makes
+	fakeFail  *uint64       // Block number which fails verification in fake mode (nil = none)
+	fakeDelay time.Duration // Time delay to sleep for before returning from verify in fake mode
+
+	window    uint64        // Number of headers to average difficulty over (0 = parent only)
+	intervals uint64        // Number of block intervals the target controller smooths over (0 = no controller)
+	target    time.Duration // Block time the windowed difficulty or the target controller aims for
+	bomb      *uint64       // Block number the difficulty bomb activates at (nil = no bomb)
+	floor     *big.Int      // Lowest difficulty CalcDifficulty returns (nil = params.MinimumDifficulty)
+
+	hashAlgo  HashAlgo            // Hash function securing the proof-of-work
+	rewards   RewardSchedule      // Block rewards paid out by Finalize
+	payee     RewardRecipient     // Account the rewards are paid to (nil = coinbase)
+	threads   int                 // Number of mining threads used by Seal (0 = all CPU cores)
+	pins      ForkHashes          // Hashes of the canonical blocks at pinned fork heights
+	sealEvery int                 // Verify every Nth seal of a batch and its last, regardless of seals (0 = off)
+	trusted   *checkpointAncestry // Trusted checkpoint below which seals aren't verified (nil = none)
+	metrics   Metrics             // Receiver of the seal and verification observations
+	nonces    func() uint64       // Source of the nonces Seal starts searching from (nil = crypto seeded)
+	grace     time.Duration       // Time Seal keeps streaming solutions after the first (0 = first only)
+	maxExtra  uint64              // Max length of the header extra-data (0 = params.MaximumExtraDataSize)
+	gasBounds GasLimitBounds      // Gas limit elasticity rule before London (zero = protocol defaults)
+	workers   int                 // Number of goroutines verifying header batches (0 = GOMAXPROCS)
+	failFast  bool                // Whether VerifyHeaders gives up on a batch at its first failure
+	inflight  int                 // Number of headers of a batch in flight at once (0 = four per worker)
+	progress  batchProgress       // Reporting of the VerifyHeaders batch progress (nil callback = off)
+	verified  *verifiedCache      // Hashes of the headers that recently passed verification
+	sealProg  sealProgress        // Progress reporting of sealing runs (nil callback = off)
+
+	clock       Clock         // Local clock the future block check compares against
+	futureDrift time.Duration // Max time a header may be ahead of the clock
+
+	work remoteWork // Block being sealed, exposed to external miners over RPC
+
+	etherbase     common.Address // Coinbase Prepare sets on new headers (zero = keep the caller's)
+	etherbaseLock sync.RWMutex   // Protects the etherbase from concurrent updates
+
+	meters     []*hashMeter // Hash attempt meters of the mining workers, by worker index
+	metersLock sync.Mutex   // Protects the meters slice from concurrent growth
+}
+
+// Mode defines the type and amount of verification the proof-of-work engine
+// makes.
+// Mode menentukan jenis dan jumlah verifikasi yang dilakukan engine proof-of-work.
+type Mode uint
+
+const (
+	ModeNormal Mode = iota // Full header and seal verification
+	ModeTest               // Full verification, but sealing difficulty capped at testDifficulty
+	ModeFake               // Every header is accepted and blocks are sealed instantly
+)
+
+// testDifficulty is the highest difficulty a tester engine seals and verifies
+// against, so that tests find a nonce within milliseconds.
+var testDifficulty = big.NewInt(128)
+
+// PoWOption configures optional behaviour of the proof-of-work engine.
+// PoWOption mengatur perilaku opsional dari engine proof-of-work.
+type PoWOption func(*powEngine)
+
+// WithWindowedDifficulty makes CalcDifficulty retarget from the average block
+// time of the last window headers instead of the parent alone.
+// metoda 'with windowed difficulty' akan membuat 'calc difficulty' menghitung dari rata-rata waktu window header terakhir.
+func WithWindowedDifficulty(window uint64, target time.Duration) PoWOption {
+	return func(pow *powEngine) {
+		pow.window, pow.intervals, pow.target = window, 0, target
+	}
+}
+
+// WithTargetBlockTime makes CalcDifficulty steer the block time towards the
+// target with a proportional controller, smoothing over the last intervals
+// block times and clamping every step, instead of retargeting from the parent
+// alone. It replaces any windowed difficulty, and a zero target or number of
+// intervals disables the controller. Targets below a millisecond count as one.
+// metoda 'with target block time' akan membuat 'calc difficulty' mengarahkan waktu block ke target dengan kontroler
+// proporsional atas beberapa interval block terakhir.
+func WithTargetBlockTime(target time.Duration, intervals uint64) PoWOption {
+	return func(pow *powEngine) {
+		if target <= 0 {
+			intervals = 0
+		}
+		pow.window, pow.intervals, pow.target = 0, intervals, target
+	}
+}
+
+// WithHashAlgo selects the hash function used by Seal and VerifySeal. The seal
+// hash of a header is unaffected, as it only covers the header fields.
+// metoda 'with hash algo' akan memilih fungsi hash yang dipakai 'seal' dan 'verify seal'.
+func WithHashAlgo(algo HashAlgo) PoWOption {
+	return func(pow *powEngine) {
+		pow.hashAlgo = algo
+	}
+}
+
+// WithRewardSchedule replaces the block rewards paid out by Finalize, which
+// default to DefaultRewardSchedule.
+// metoda 'with reward schedule' akan mengganti jadwal block reward yang dibayarkan oleh 'finalize'.
+func WithRewardSchedule(schedule RewardSchedule) PoWOption {
+	return func(pow *powEngine) {
+		pow.rewards = schedule
+	}
+}
+
+// WithRewardRecipient redirects the block and uncle rewards paid out by
+// Finalize to the account returned by recipient, instead of the coinbase.
+// metoda 'with reward recipient' akan mengalihkan block reward dan uncle reward ke akun yang dikembalikan recipient.
+func WithRewardRecipient(recipient RewardRecipient) PoWOption {
+	return func(pow *powEngine) {
+		pow.payee = recipient
+	}
+}
+
+// WithThreads sets the number of threads Seal mines on. A non-positive count,
+// the default, uses every CPU core.
+// metoda 'with threads' akan mengatur jumlah thread yang dipakai 'seal' untuk menambang.
+func WithThreads(threads int) PoWOption {
+	return func(pow *powEngine) {
+		pow.threads = threads
+	}
+}
+
+// WithNonceSource replaces the source of the nonce every Seal starts searching
+// from, which is seeded from crypto/rand by default. A deterministic source on a
+// single thread makes the sealed block fully reproducible, e.g. for golden file
+// tests. With more threads the workers race and any of them may win.
+// metoda 'with nonce source' akan mengganti sumber nonce awal pencarian 'seal'. Sumber yang deterministik dengan satu
+// thread membuat block tersegel dapat direproduksi.
+func WithNonceSource(source func() uint64) PoWOption {
+	return func(pow *powEngine) {
+		pow.nonces = source
+	}
+}
+
+// WithForkHashes pins the hashes of the canonical blocks at the given heights,
+// rejecting headers of diverging chains at those heights.
+// metoda 'with fork hashes' akan menyematkan hash block kanonik pada ketinggian tertentu.
+func WithForkHashes(pins ForkHashes) PoWOption {
+	return func(pow *powEngine) {
+		pow.pins = pins
+	}
+}
+
+// WithCheckpoint trusts the given checkpoint, skipping the seal verification of
+// the checkpoint block and its ancestors, whatever seals are requested. All the
+// other checks still run, and headers above the checkpoint or on other chains
+// are verified as usual.
+// metoda 'with checkpoint' akan mempercayai checkpoint tertentu, sehingga verifikasi segel block checkpoint dan
+// ancestor-nya dilewati.
+func WithCheckpoint(checkpoint Checkpoint) PoWOption {
+	return func(pow *powEngine) {
+		pow.trusted = newCheckpointAncestry(checkpoint)
+	}
+}
+
+// WithSealVerifyFraction forces VerifyHeaders to verify the seal of every Nth
+// header of a batch and of its last header, even if the seals argument doesn't
+// request it. This allows fast imports that only verify a subset of the seals.
+// A zero fraction disables the option.
+// metoda 'with seal verify fraction' akan memaksa 'verify headers' memverifikasi segel setiap header ke-N dan header
+// terakhir dari batch.
+func WithSealVerifyFraction(n int) PoWOption {
+	return func(pow *powEngine) {
+		pow.sealEvery = n
+	}
+}
+
+// WithSealStreaming makes Seal keep mining after the first solution is found,
+// streaming every further sealed block found within the given grace window to
+// the results channel until the window elapses or sealing is stopped. Callers
+// can then pick the best of the blocks. A zero window delivers only the first
+// solution, which is the default.
+// metoda 'with seal streaming' akan membuat 'seal' tetap menambang setelah solusi pertama ditemukan, dan mengirim
+// setiap block tersegel berikutnya dalam jendela waktu tertentu ke channel results.
+func WithSealStreaming(grace time.Duration) PoWOption {
+	return func(pow *powEngine) {
+		pow.grace = grace
+	}
+}
+
+// WithSealProgress makes Seal report the number of hash attempts made so far
+// and the time elapsed to the given callback at the given interval, one second
+// if not positive, so callers can render the progress of long sealing runs or
+// give up on them. The callback is invoked from a goroutine of its own and
+// never holds up the mining workers. The attempts are accounted in chunks, so
+// the first reports of a run may still count none.
+// metoda 'with seal progress' akan membuat 'seal' melaporkan jumlah percobaan hash dan waktu yang telah berlalu ke
+// callback secara berkala.
+func WithSealProgress(interval time.Duration, report func(attempts uint64, elapsed time.Duration)) PoWOption {
+	return func(pow *powEngine) {
+		pow.sealProg = sealProgress{every: interval, report: report}
+	}
+}
+
+// WithVerifyWorkers caps the number of goroutines VerifyHeaders verifies a
+// batch on, which defaults to one per allowed thread.
+// metoda 'with verify workers' akan membatasi jumlah goroutine yang dipakai 'verify headers' untuk satu batch.
+func WithVerifyWorkers(workers int) PoWOption {
+	return func(pow *powEngine) {
+		pow.workers = workers
+	}
+}
+
+// WithVerifyWindow sets how many headers of a batch VerifyHeaders keeps in
+// flight at once, four per worker by default. Results are reassembled into
+// input order within the window, so memory stays proportional to it rather
+// than to the batch, while a larger window lets the workers run further ahead
+// of a slow consumer.
+// metoda 'with verify window' akan mengatur jumlah header dalam batch yang diproses bersamaan oleh 'verify headers'.
+func WithVerifyWindow(window int) PoWOption {
+	return func(pow *powEngine) {
+		pow.inflight = window
+	}
+}
+
+// WithAbortOnError makes VerifyHeaders give up on a batch at its first
+// failure, as a single invalid header makes the rest of the batch useless
+// during sync. Every header after it is reported with ErrAborted instead of
+// being verified, and the results channel is closed.
+// metoda 'with abort on error' akan membuat 'verify headers' berhenti pada kegagalan pertama dalam batch.
+func WithAbortOnError(abort bool) PoWOption {
+	return func(pow *powEngine) {
+		pow.failFast = abort
+	}
+}
+
+// WithProgress makes VerifyHeaders report the progress of every batch to the
+// given callback, at most every N verified headers or every second, whichever
+// is less frequent, and once more when the batch is done. The callback is
+// invoked from a single goroutine per batch; updates arriving while it is busy
+// are dropped rather than holding up the verification.
+// metoda 'with progress' akan membuat 'verify headers' melaporkan kemajuan setiap batch ke callback yang diberikan.
+func WithProgress(every int, report ProgressFunc) PoWOption {
+	return func(pow *powEngine) {
+		pow.progress = batchProgress{every: every, report: report}
+	}
+}
+
+// WithVerifiedCache sets the number of headers that passed verification the
+// engine remembers by hash, which defaults to 4096. VerifyHeader and
+// VerifyHeaders accept remembered headers without checking them again, unless
+// the seal is requested but wasn't checked the first time.
+// metoda 'with verified cache' akan mengatur jumlah header terverifikasi yang diingat engine berdasarkan hash.
+func WithVerifiedCache(size int) PoWOption {
+	return func(pow *powEngine) {
+		pow.verified = newVerifiedCache(size)
+	}
+}
+
+// batchConfig returns the settings batch verifications of the engine run with.
+func (pow *powEngine) batchConfig() batchConfig {
+	return batchConfig{workers: pow.workers, abortOnError: pow.failFast, metrics: pow.metrics, progress: pow.progress, window: pow.inflight}
+}
+
+// WithMinimumDifficulty sets the lowest difficulty CalcDifficulty returns,
+// params.MinimumDifficulty by default. A nil or non-positive floor keeps the
+// default. The difficulty rules clamp against the protocol minimum on their
+// own, so a floor below it has no effect.
+// metoda 'with minimum difficulty' akan mengatur tingkat kesulitan terendah yang dikembalikan 'calc difficulty'.
+func WithMinimumDifficulty(floor *big.Int) PoWOption {
+	return func(pow *powEngine) {
+		if floor == nil || floor.Sign() <= 0 {
+			pow.floor = nil
+			return
+		}
+		pow.floor = new(big.Int).Set(floor)
+	}
+}
+
+// WithMaxExtraData sets the maximum length of the extra-data section headers
+// may carry, 32 bytes by default. Longer extra-data fails verification with
+// ErrExtraDataTooLong.
+// metoda 'with max extra data' akan mengatur panjang maksimal bagian extra-data pada header, 32 byte secara default.
+func WithMaxExtraData(size uint64) PoWOption {
+	return func(pow *powEngine) {
+		pow.maxExtra = size
+	}
+}
+
+// WithGasLimitBounds replaces the divisor bounding how far a header's gas limit
+// may move from its parent's, and the lowest gas limit allowed, before the
+// London fork. Zero fields keep the protocol defaults.
+// metoda 'with gas limit bounds' akan mengganti pembagi batas perubahan gas limit dan gas limit minimum sebelum fork
+// London.
+func WithGasLimitBounds(bounds GasLimitBounds) PoWOption {
+	return func(pow *powEngine) {
+		pow.gasBounds = bounds
+	}
+}
+
+// maxExtraData returns the maximum length of the header extra-data.
+func (pow *powEngine) maxExtraData() uint64 {
+	if pow.maxExtra == 0 {
+		return params.MaximumExtraDataSize
+	}
+	return pow.maxExtra
+}
+
+// checkpointed reports whether the header with the given hash is covered by the
+// trusted checkpoint.
+func (pow *powEngine) checkpointed(chain ChainHeaderReader, header *types.Header, hash common.Hash) bool {
+	return pow.trusted != nil && pow.trusted.contains(chain, header.Number.Uint64(), hash)
+}
+
+// WithMetrics reports the found seals and failed verifications of the engine
+// to the given metrics, instead of discarding them.
+// metoda 'with metrics' akan melaporkan segel yang ditemukan dan verifikasi yang gagal ke metrics yang diberikan.
+func WithMetrics(metrics Metrics) PoWOption {
+	return func(pow *powEngine) {
+		pow.metrics = metrics
+	}
+}
+
+// WithClock replaces the local clock headers are checked against for being
+// too far in the future, which makes the check deterministic in tests.
+// metoda 'with clock' akan mengganti jam lokal yang dipakai untuk mengecek apakah header terlalu jauh di masa depan.
+func WithClock(now Clock) PoWOption {
+	return func(pow *powEngine) {
+		pow.clock = now
+	}
+}
+
+// WithAllowedFutureBlockTime sets how far a header's timestamp may be ahead of
+// the local clock before it's rejected as a future block, 15 seconds by
+// default.
+// metoda 'with allowed future block time' akan mengatur seberapa jauh timestamp header boleh mendahului jam lokal.
+func WithAllowedFutureBlockTime(drift time.Duration) PoWOption {
+	return func(pow *powEngine) {
+		pow.futureDrift = drift
+	}
+}
+
+// NewPoW creates a CPU proof-of-work consensus engine, hashing with Keccak-256
+// unless configured otherwise.
+// metoda 'new pow' akan membuat consensus engine proof-of-work berbasis CPU, menggunakan Keccak-256 secara default.
+func NewPoW(opts ...PoWOption) *powEngine {
+	pow := &powEngine{
+		hashAlgo:    Keccak256,
+		rewards:     DefaultRewardSchedule,
+		clock:       time.Now,
+		futureDrift: AllowedFutureBlockTime,
+		metrics:     NoopMetrics{},
+	}
+	for _, opt := range opts {
+		opt(pow)
+	}
+	if pow.verified == nil {
+		pow.verified = newVerifiedCache(inmemoryVerified)
+	}
+	return pow
+}
+
+// NewTester creates a proof-of-work engine for tests that does real nonce
+// grinding, but never against a difficulty higher than 128, regardless of what
+// CalcDifficulty demands. All other header rules are enforced as usual, so the
+// tester accepts the blocks it sealed itself but still rejects garbage nonces.
+// metoda 'new tester' akan membuat engine proof-of-work untuk pengujian yang tetap mencari nonce, namun tingkat
+// kesulitan segel dibatasi maksimal 128. Aturan header lainnya tetap diperiksa seperti biasa.
+func NewTester(opts ...PoWOption) *powEngine {
+	pow := NewPoW(opts...)
+	pow.mode = ModeTest
+	return pow
+}
+
+// NewFaker creates a proof-of-work engine that accepts every header as valid
+// and seals blocks without doing any work, which is useful for unit tests.
+// metoda 'new faker' akan membuat engine proof-of-work yang menerima semua header dan menyegel block tanpa kerja,
+// berguna untuk unit test.
+func NewFaker() *powEngine {
+	return &powEngine{mode: ModeFake, rewards: DefaultRewardSchedule, metrics: NoopMetrics{}}
+}
+
+// NewFakeFailer creates a fake proof-of-work engine that accepts every header
+// except the one numbered failAt, so error paths can be exercised, e.g. that a
+// chain importer rejects a bad block at a known height.
+// metoda 'new fake failer' akan membuat engine palsu yang menerima semua header kecuali header pada nomor block failAt.
+func NewFakeFailer(failAt uint64) *powEngine {
+	return &powEngine{mode: ModeFake, fakeFail: &failAt, rewards: DefaultRewardSchedule, metrics: NoopMetrics{}}
+}
+
+// NewFakeDelayer creates a fake proof-of-work engine that accepts every header,
+// but sleeps for the given delay before returning from each header
+// verification. It makes the cost of verification controllable, which is handy
+// for benchmarking the batch verification pipeline and for exercising the
+// cancellation of VerifyHeadersContext mid-batch.
+// metoda 'new fake delayer' akan membuat engine palsu yang menerima semua header, namun menunggu selama delay
+// sebelum selesai memverifikasi setiap header. Berguna juga untuk menguji pembatalan 'verify headers context'.
+func NewFakeDelayer(delay time.Duration) *powEngine {
+	return &powEngine{mode: ModeFake, fakeDelay: delay, rewards: DefaultRewardSchedule, metrics: NoopMetrics{}}
+}
+
+// Author implements Engine, returning the header's coinbase as the
+// proof-of-work verified author of the block.
+// metoda 'author' akan mengembalikan coinbase dari header sebagai pembuat block.
+func (pow *powEngine) Author(header *types.Header) (common.Address, error) {
+	return header.Coinbase, nil
+}
+
+// VerifyHeader checks whether a header conforms to the consensus rules of the
+// proof-of-work engine.
+// metoda 'verify header' akan mengecek apakah header sesuai dengan aturan consensus proof-of-work.
+func (pow *powEngine) VerifyHeader(chain ChainHeaderReader, header *types.Header, seal bool) error {
+	return observeFailure(pow.metrics, pow.checkHeader(chain, header, seal))
+}
+
+// checkHeader is VerifyHeader without reporting failures to the metrics.
+func (pow *powEngine) checkHeader(chain ChainHeaderReader, header *types.Header, seal bool) error {
+	if err := verifyNotBanned(header); err != nil {
+		return wrapHeaderError(header, err)
+	}
+	// If we're running a fake engine, accept any input as valid
+	if pow.mode == ModeFake {
+		return wrapHeaderError(header, pow.verifyFake(header))
+	}
+	// Short circuit if the header is known, or its parent not
+	number := header.Number.Uint64()
+	if chain.GetHeader(header.Hash(), number) != nil {
+		return nil
+	}
+	// The genesis header has no parent to verify against
+	if number == 0 {
+		return wrapHeaderError(header, verifyGenesis(header))
+	}
+	return pow.checkHeaderWithParent(chain, header, chain.GetHeader(header.ParentHash, number-1), seal)
+}
+
+// VerifyHeaderWithParent implements ParentVerifier, checking whether a header
+// conforms to the consensus rules of the proof-of-work engine against a parent
+// the caller already holds, instead of looking it up in the chain.
+// metoda 'verify header with parent' akan mengecek header terhadap parent yang sudah dimiliki pemanggil, tanpa
+// mencarinya di chain.
+func (pow *powEngine) VerifyHeaderWithParent(chain ChainHeaderReader, header, parent *types.Header, seal bool) error {
+	return observeFailure(pow.metrics, pow.checkHeaderWithParent(chain, header, parent, seal))
+}
+
+// checkHeaderWithParent is VerifyHeaderWithParent without reporting failures to
+// the metrics.
+func (pow *powEngine) checkHeaderWithParent(chain ChainHeaderReader, header, parent *types.Header, seal bool) error {
+	if err := verifyNotBanned(header); err != nil {
+		return wrapHeaderError(header, err)
+	}
+	// If we're running a fake engine, accept any input as valid
+	if pow.mode == ModeFake {
+		return wrapHeaderError(header, pow.verifyFake(header))
+	}
+	if parent == nil {
+		return wrapHeaderError(header, ErrUnknownAncestor)
+	}
+	// A cached verdict vouches for the header, not for the parent handed in
+	if err := verifyHeaderBasics(chain, header, parent); err != nil {
+		return wrapHeaderError(header, err)
+	}
+	hash := header.Hash()
+	seal = seal && !pow.checkpointed(chain, header, hash)
+	return wrapHeaderError(header, pow.verified.verifyCached(hash, seal, func() error {
+		return pow.verifyHeader(chain, header, parent, false, seal)
+	}))
+}
+
+// VerifyHeaderAgainst checks whether a header conforms to the consensus rules
+// of the proof-of-work engine, verifying it against the given parent without
+// any access to the chain. Every parent-relative rule is checked, but as the
+// chain is not available, a retargeting window cannot be resolved, failing
+// with ErrUnknownAncestor unless the parent is the genesis block, and the merge
+// transition is only recognized past a parent without difficulty.
+// metoda 'verify header against' akan mengecek apakah header sesuai dengan aturan consensus proof-of-work terhadap
+// parent yang diberikan, tanpa akses ke chain.
+func (pow *powEngine) VerifyHeaderAgainst(parent, header *types.Header, seal bool, config *params.ChainConfig) error {
+	return observeFailure(pow.metrics, pow.checkHeaderAgainst(parent, header, seal, config))
+}
+
+// checkHeaderAgainst is VerifyHeaderAgainst without reporting failures to the
+// metrics.
+func (pow *powEngine) checkHeaderAgainst(parent, header *types.Header, seal bool, config *params.ChainConfig) error {
+	if err := verifyNotBanned(header); err != nil {
+		return wrapHeaderError(header, err)
+	}
+	// If we're running a fake engine, accept any input as valid
+	if pow.mode == ModeFake {
+		return wrapHeaderError(header, pow.verifyFake(header))
+	}
+	return wrapHeaderError(header, pow.verifyHeader(&parentChain{config: config, parent: parent}, header, parent, false, seal))
+}
+
+// VerifyHeaders is similar to VerifyHeader, but verifies a batch of headers
+// concurrently. The method returns a quit channel to abort the operations and
+// a results channel to retrieve the async verifications.
+//
+// Headers whose seal isn't requested still undergo every other check. With a
+// seal verify fraction configured, the seal of every Nth header and of the last
+// header of the batch is verified regardless. This trades security for import
+// speed: a forged seal on an unchecked header goes unnoticed, but as every
+// header commits to its parent, forging a whole batch still takes the work of
+// sealing the headers that are checked, the tip of the batch included. Headers
+// covered by a trusted checkpoint are never seal checked.
+//
+// The batch may be ascending, or descending as handed out by a backwards sync,
+// in which case every header is verified against the one following it in the
+// batch. Malformed batches, with mismatching seals, nil or non-consecutive
+// headers, fail as a whole with a single error.
+//
+// The header fields are checked in input order first, and only the headers
+// passing them have their seal verified concurrently.
+// metoda 'verify headers' sama dengan 'verify header', namun memverifikasi header dalam batch secara bersamaan.
+// Dengan seal verify fraction, segel setiap header ke-N dan header terakhir batch selalu diverifikasi.
+func (pow *powEngine) VerifyHeaders(chain ChainHeaderReader, headers []*types.Header, seals []bool) (chan<- struct{}, <-chan error) {
+	return pow.verifyHeadersHashed(chain, newHeaderHashes(headers), seals)
+}
+
+// verifyHeadersHashed implements hashedVerifier, running VerifyHeaders on a
+// batch whose header hashes are memoized by the caller.
+func (pow *powEngine) verifyHeadersHashed(chain ChainHeaderReader, hashes *headerHashes, seals []bool) (chan<- struct{}, <-chan error) {
+	headers := hashes.headers
+	descending, err := validateBatch(headers, seals)
+	if err != nil {
+		return failedBatch(err)
+	}
+	if pow.mode == ModeFake {
+		return verifyHeadersConcurrently(hashes, pow.batchConfig(), func(index int) error {
+			return pow.verifyFake(headers[index])
+		})
+	}
+	// The tip of the batch is its newest header, first if the batch is descending
+	tip := len(headers) - 1
+	if descending {
+		tip = 0
+	}
+	links := batchLinks(hashes, descending)
+
+	// Make the batch visible when resolving the ancestry of the checkpoint and
+	// the start of the retargeting windows, which may lie within the batch
+	var ancestry ChainHeaderReader = chain
+	if pow.trusted != nil || pow.window > 0 || pow.intervals > 0 {
+		overlay := newBatchChain(chain)
+		overlay.addHashed(hashes)
+		ancestry = overlay
+	}
+	// Check the header fields in order, leaving only the costly seals to the workers
+	cheap := func(index int) (bool, error) {
+		if headers[index].Number.Sign() == 0 {
+			return false, verifyGenesis(headers[index])
+		}
+		parent := batchParent(chain, headers, links, index, descending)
+		if parent == nil {
+			return false, ErrUnknownAncestor
+		}
+		seal := seals[index]
+		if pow.sealEvery > 0 && ((index+1)%pow.sealEvery == 0 || index == tip) {
+			seal = true
+		}
+		hash := hashes.hash(index)
+		seal = seal && !pow.checkpointed(ancestry, headers[index], hash)
+
+		if pow.verified.known(hash, seal) {
+			return false, nil
+		}
+		sealed, err := pow.verifyHeaderFields(ancestry, headers[index], parent, false)
+		if err != nil {
+			return false, err
+		}
+		if seal && sealed {
+			return true, nil
+		}
+		pow.verified.add(hash, seal)
+		return false, nil
+	}
+	return verifyHeadersPipelined(hashes, pow.batchConfig(), cheap, func(index int) error {
+		if err := pow.VerifySeal(chain, headers[index]); err != nil {
+			return err
+		}
+		pow.verified.add(hashes.hash(index), true)
+		return nil
+	})
+}
+
+// VerifyHeaderStream is similar to VerifyHeaders, but pulls the headers lazily
+// from the next iterator until it reports no more, so huge header exports can
+// be verified without holding them all in memory. Every header has to extend
+// the one before it, the first one a header of the chain. Only a bounded
+// window of headers is in flight, and the iterator isn't called while the
+// window waits for the results to be read. The results are delivered in input
+// order and the channel is closed after the last one. Closing the returned quit
+// channel stops the stream early, the iterator isn't called again.
+// metoda 'verify header stream' sama dengan 'verify headers', namun mengambil header satu per satu dari iterator
+// sehingga hanya sebagian kecil header yang berada di memori.
+func (pow *powEngine) VerifyHeaderStream(chain ChainHeaderReader, next func() (*types.Header, bool), seal bool) (chan<- struct{}, <-chan error) {
+	return verifyHeaderStream(next, pow.workers, pow.metrics, func(header, prev *types.Header) error {
+		if pow.mode == ModeFake {
+			return pow.verifyFake(header)
+		}
+		number := header.Number.Uint64()
+		if number == 0 {
+			return verifyGenesis(header)
+		}
+		parent := prev
+		if parent == nil {
+			parent = chain.GetHeader(header.ParentHash, number-1)
+		}
+		if parent == nil {
+			return ErrUnknownAncestor
+		}
+		return pow.verifyHeader(chain, header, parent, false, seal && !pow.checkpointed(chain, header, header.Hash()))
+	})
+}
+
+// VerifyHeadersContext is similar to VerifyHeaders, but aborts verification
+// once the context is cancelled or its deadline elapses. Results are still
+// delivered in input order, headers left unverified report the context error.
+// metoda 'verify headers context' sama dengan 'verify headers', namun verifikasi dibatalkan ketika context dibatalkan atau melewati tenggat waktu.
+func (pow *powEngine) VerifyHeadersContext(ctx context.Context, chain ChainHeaderReader, headers []*types.Header, seals []bool) <-chan error {
+	return verifyHeadersContext(ctx, pow, chain, headers, seals)
+}
+
+// VerifyHeadersWithContext is VerifyHeadersContext, under the name callers built
+// around contexts look for.
+// metoda 'verify headers with context' sama dengan 'verify headers context'.
+func (pow *powEngine) VerifyHeadersWithContext(ctx context.Context, chain ChainHeaderReader, headers []*types.Header, seals []bool) <-chan error {
+	return verifyHeadersContext(ctx, pow, chain, headers, seals)
+}
+
+// verifyHeader checks whether a header conforms to the consensus rules of the
+// proof-of-work engine, given its already resolved parent.
+func (pow *powEngine) verifyHeader(chain ChainHeaderReader, header, parent *types.Header, uncle, seal bool) error {
+	sealed, err := pow.verifyHeaderFields(chain, header, parent, uncle)
+	if err != nil {
+		return err
+	}
+	// Verify the engine specific seal securing the block
+	if seal && sealed {
+		return pow.VerifySeal(chain, header)
+	}
+	return nil
+}
+
+// verifyHeaderFields checks whether a header conforms to every consensus rule
+// of the proof-of-work engine but its seal, which is by far the costliest. It
+// reports whether the header is secured by a seal at all, which it isn't past
+// the merge.
+func (pow *powEngine) verifyHeaderFields(chain ChainHeaderReader, header, parent *types.Header, uncle bool) (bool, error) {
+	if err := verifyHeaderBasics(chain, header, parent); err != nil {
+		return false, err
+	}
+	if err := VerifyForkHashes(pow.pins, header); err != nil {
+		return false, err
+	}
+	// Ensure that the header's extra-data section is of a reasonable size
+	if limit := pow.maxExtraData(); uint64(len(header.Extra)) > limit {
+		return false, fmt.Errorf("%w: %d > %d", ErrExtraDataTooLong, len(header.Extra), limit)
+	}
+	// Verify the header's timestamp
+	if !uncle {
+		if err := verifyFutureBlock(pow.clock, pow.futureDrift, header); err != nil {
+			return false, err
+		}
+	}
+	if header.Time <= parent.Time {
+		return false, ErrTimestampTooOld
+	}
+	// Verify the gas limit and base fee, if the fee market is active
+	if err := verifyGasLimit(chain.Config(), pow.gasBounds, parent, header); err != nil {
+		return false, err
+	}
+	if err := verifyBaseFee(chain.Config(), parent, header); err != nil {
+		return false, err
+	}
+	if err := verifyWithdrawals(chain.Config(), header); err != nil {
+		return false, err
+	}
+	if err := verifyBlobGas(chain.Config(), parent, header); err != nil {
+		return false, err
+	}
+	if err := verifyParentBeaconRoot(chain.Config(), header); err != nil {
+		return false, err
+	}
+	// Past the merge there is no work left to verify, only zeroed out fields
+	if isPostMerge(chain, parent) {
+		return false, verifyPostMerge(header)
+	}
+	// Verify the block's difficulty based on its timestamp and parent's difficulty
+	expected, err := pow.difficulty(chain, header.Time, parent)
+	if err != nil {
+		return false, err
+	}
+	if err := checkDifficulty(header, expected); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// VerifySeal implements Engine, checking whether the header's nonce satisfies
+// the proof-of-work difficulty requirements.
+// metoda 'verify seal' akan mengecek apakah nonce pada header memenuhi tingkat kesulitan proof-of-work.
+func (pow *powEngine) VerifySeal(chain ChainHeaderReader, header *types.Header) error {
+	// If we're running a fake engine, accept any seal as valid
+	if pow.mode == ModeFake {
+		return pow.verifyFake(header)
+	}
+	// Ensure that we have a valid difficulty for the block
+	if header.Difficulty.Sign() <= 0 {
+		return errInvalidDifficulty
+	}
+	target := TargetFromDifficulty(pow.sealDifficulty(header.Difficulty))
+	if new(big.Int).SetBytes(powHash(pow.hashAlgo, pow.SealHash(header), header.Nonce.Uint64())).Cmp(target) > 0 {
+		return errInvalidPoW
+	}
+	return nil
+}
+
+// verifyFake returns the verification result of a header in fake mode, which
+// only ever fails for the configured fake failure block, after sleeping for the
+// configured fake delay.
+func (pow *powEngine) verifyFake(header *types.Header) error {
+	time.Sleep(pow.fakeDelay)
+	if pow.fakeFail != nil && *pow.fakeFail == header.Number.Uint64() {
+		return errInvalidPoW
+	}
+	return nil
+}
+
+// VerifyUncles implements Engine, verifying that the given block's uncles
+// conform to the consensus rules: at most two uncles, each a sibling of one of
+// the last seven ancestors, neither an ancestor itself nor included before.
+// Blocks without uncles are accepted right away, provided their uncle hash is
+// the empty one.
+// metoda 'verify uncles' akan memverifikasi uncle dari block: maksimal dua uncle, masing-masing merupakan saudara
+// dari salah satu dari tujuh ancestor terakhir, bukan ancestor, dan belum pernah dimasukkan sebelumnya.
+func (pow *powEngine) VerifyUncles(chain ChainReader, block *types.Block) error {
+	_, err := pow.verifyUncles(chain, block, nil)
+	return err
+}
+
+// verifyUncles checks the uncles of a block against the window of its recent
+// ancestors. If no window is given, it is gathered from the chain when needed.
+// The window checked against is returned, nil if none was needed.
+func (pow *powEngine) verifyUncles(chain ChainReader, block *types.Block, window *uncleWindow) (*uncleWindow, error) {
+	// If we're running a fake engine, accept any input as valid
+	if pow.mode == ModeFake {
+		return window, nil
+	}
+	// Blocks without uncles are the common case, accept them without walking the
+	// ancestry, but only if the header agrees that the uncle set is empty
+	if empty := block.UncleHash() == types.EmptyUncleHash; empty != (len(block.Uncles()) == 0) {
+		return window, fmt.Errorf("%w: have %x with %d uncles", ErrInvalidUncleHash, block.UncleHash(), len(block.Uncles()))
+	} else if empty {
+		return window, nil
+	}
+	// Verify that there are at most 2 uncles included in this block, none after the merge
+	if len(block.Uncles()) > 0 {
+		if parent := chain.GetHeader(block.ParentHash(), block.NumberU64()-1); parent != nil && isPostMerge(chain, parent) {
+			return window, fmt.Errorf("%w: have %d after the merge, want 0", ErrTooManyUncles, len(block.Uncles()))
+		}
+	}
+	if len(block.Uncles()) > maxUncles {
+		return window, fmt.Errorf("%w: have %d, want at most %d", ErrTooManyUncles, len(block.Uncles()), maxUncles)
+	}
+	// Gather the set of past uncles and ancestors
+	if window == nil {
+		var err error
+		if window, err = newUncleWindow(chain, block); err != nil {
+			return nil, err
+		}
+	}
+	uncles, ancestors := window.sets(block)
+
+	// Check each of the uncles that it's recent, but not an ancestor. These checks
+	// depend on the uncles before, so run them in order up to the first failure.
+	var (
+		included = block.Uncles()
+		errs     = make([]error, len(included))
+		valid    = len(included)
+	)
+	for i, uncle := range included {
+		if errs[i] = checkUncle(chain, block, uncle, uncles, ancestors); errs[i] != nil {
+			valid = i
+			break
+		}
+	}
+	// Verify the headers of the uncles preceding any failure concurrently, the
+	// lowest failing index wins to keep the reported error deterministic
+	pow.verifyUncleHeaders(chain, included[:valid], ancestors, errs)
+	for i, err := range errs {
+		if err != nil {
+			return window, wrapHeaderError(included[i], err)
+		}
+	}
+	return window, nil
+}
+
+// checkUncle verifies that an uncle is not yet rewarded, recent enough, not an
+// ancestor nor canonical, and has one of the ancestors as its parent. The uncle
+// is marked as seen in the uncles set.
+func checkUncle(chain ChainReader, block *types.Block, uncle *types.Header, uncles map[common.Hash]struct{}, ancestors map[common.Hash]*types.Header) error {
+	// Make sure every uncle is rewarded only once
+	hash := uncle.Hash()
+	if _, ok := uncles[hash]; ok {
+		return ErrDuplicateUncle
+	}
+	uncles[hash] = struct{}{}
+
+	// Make sure the uncle is recent enough to have its parent among the ancestors
+	if uncle.Number.Uint64()+uint64(maxUncleDepth) <= block.NumberU64() {
+		return fmt.Errorf("%w: uncle #%d in block #%d, max depth %d", ErrUncleTooDeep, uncle.Number, block.NumberU64(), maxUncleDepth-1)
+	}
+	// Make sure the uncle has a valid ancestry
+	if ancestors[hash] != nil {
+		return ErrUncleIsAncestor
+	}
+	// The ancestor set only covers the recent past of this block, make sure
+	// the uncle isn't the canonical header at its height either
+	if canonical := chain.GetHeaderByNumber(uncle.Number.Uint64()); canonical != nil && canonical.Hash() == hash {
+		return ErrUncleIsCanonical
+	}
+	if ancestors[uncle.ParentHash] == nil || uncle.ParentHash == block.ParentHash() {
+		return ErrDanglingUncle
+	}
+	return nil
+}
+
+// verifyUncleHeaders verifies the headers of the given uncles, including their
+// seals, against their parents among the ancestors. The uncles are verified in
+// parallel on at most one goroutine per allowed thread, every failure stored at
+// the uncle's index of errs. It returns only once every goroutine finished.
+func (pow *powEngine) verifyUncleHeaders(chain ChainReader, uncles []*types.Header, ancestors map[common.Hash]*types.Header, errs []error) {
+	var (
+		slots = make(chan struct{}, runtime.GOMAXPROCS(0))
+		pend  sync.WaitGroup
+	)
+	for i, uncle := range uncles {
+		slots <- struct{}{}
+		pend.Add(1)
+		go func(i int, uncle *types.Header) {
+			defer func() {
+				<-slots
+				pend.Done()
+			}()
+			errs[i] = pow.verifyHeader(chain, uncle, ancestors[uncle.ParentHash], true, true)
+		}(i, uncle)
+	}
+	pend.Wait()
+}
+
+// SetEtherbase sets the address Prepare credits new blocks to by setting it as
+// their coinbase. The zero address keeps the coinbase the caller set.
+// metoda 'set etherbase' akan mengatur alamat yang dipasang 'prepare' sebagai coinbase block baru.
+func (pow *powEngine) SetEtherbase(etherbase common.Address) {
+	pow.etherbaseLock.Lock()
+	defer pow.etherbaseLock.Unlock()
+
+	pow.etherbase = etherbase
+}
+
+// Prepare implements Engine, initializing the difficulty field of a header to
+// conform to the proof-of-work protocol, and the base fee once the London fork
+// is active. The coinbase is set to the etherbase, if configured. The changes
+// are done inline.
+// metoda 'prepare' akan menginisialisasi field difficulty dari header sesuai protokol proof-of-work, serta base fee
+// setelah fork London aktif.
+func (pow *powEngine) Prepare(chain ChainHeaderReader, header *types.Header) error {
+	parent := chain.GetHeader(header.ParentHash, header.Number.Uint64()-1)
+	if parent == nil {
+		return ErrUnknownAncestor
+	}
+	pow.etherbaseLock.RLock()
+	if pow.etherbase != (common.Address{}) {
+		header.Coinbase = pow.etherbase
+	}
+	pow.etherbaseLock.RUnlock()
+
+	difficulty, err := pow.difficulty(chain, header.Time, parent)
+	if err != nil {
+		return err
+	}
+	header.Difficulty = difficulty
+	prepareBaseFee(chain.Config(), parent, header)
+	return nil
+}
+
+// Finalize implements Engine, accumulating the block and uncle rewards of the
+// configured reward schedule.
+// metoda 'finalize' akan menambahkan block reward dan uncle reward sesuai jadwal reward yang diatur.
+func (pow *powEngine) Finalize(chain ChainHeaderReader, header *types.Header, state *state.StateDB, txs []*types.Transaction, uncles []*types.Header, withdrawals []*types.Withdrawal) {
+	// Accumulate any block and uncle rewards
+	accumulateRewards(pow.rewards, pow.payee, state, header, uncles)
+
+	// Credit the withdrawals, if any
+	ProcessWithdrawals(state, withdrawals)
+}
+
+// FinalizeAndAssemble implements Engine, setting the final state root and
+// assembling the block.
+// metoda 'finalize and assemble' akan mengatur state root akhir dan membangun block.
+func (pow *powEngine) FinalizeAndAssemble(chain ChainHeaderReader, header *types.Header, state *state.StateDB, txs []*types.Transaction, uncles []*types.Header, receipts []*types.Receipt, withdrawals []*types.Withdrawal) (*types.Block, error) {
+	if err := assembleWithdrawals(chain.Config(), header, withdrawals); err != nil {
+		return nil, err
+	}
+	// Finalize block
+	pow.Finalize(chain, header, state, txs, uncles, withdrawals)
+
+	// Assign the final state root to header.
+	header.Root = state.IntermediateRoot(true)
+
+	// Header seems complete, assemble into a block and return
+	return types.NewBlockWithWithdrawals(header, txs, uncles, receipts, withdrawals, trie.NewStackTrie(nil)), nil
+}
+
+// Seal implements Engine, attempting to find a nonce that satisfies the
+// block's difficulty requirements. The search runs in the background on a
+// Miner worker pool and the sealed block is pushed into results, unless stop
+// is closed first. The block is also offered to external miners through the
+// pow RPC namespace.
+// metoda 'seal' akan mencari nonce yang memenuhi tingkat kesulitan block di background, lalu mengirim block tersegel ke channel results.
+func (pow *powEngine) Seal(chain ChainHeaderReader, block *types.Block, results chan<- *types.Block, stop <-chan struct{}) error {
+	// If we're running a fake engine, hand the block back unchanged
+	if pow.mode == ModeFake {
+		go func() {
+			select {
+			case results <- block:
+			case <-stop:
+			}
+		}()
+		return nil
+	}
+	// Expose the block to external miners while mining it locally. A solution is
+	// only accepted when handed over to the miner, never while it's winding down
+	solved := make(chan *types.Block)
+	done, err := NewMiner(pow, pow.threads).seal(block, results, stop, solved)
+	if err != nil {
+		return err
+	}
+	pow.work.set(block, solved, stop, done)
+	return nil
+}
+
+// EstimateSeal is a dry run of Seal, reporting the difficulty the header needs
+// on top of its parent, the target its seal hash must not exceed, and the time
+// the local miner is expected to take finding a nonce at the current hashrate,
+// without searching for one. The expected time is zero if no hashrate was
+// measured yet. The header is left untouched.
+// metoda 'estimate seal' akan melaporkan tingkat kesulitan, target, dan perkiraan waktu untuk menyegel header
+// berdasarkan hashrate saat ini, tanpa mencari nonce.
+func (pow *powEngine) EstimateSeal(chain ChainHeaderReader, header *types.Header) (difficulty *big.Int, target *big.Int, expected time.Duration, err error) {
+	parent := chain.GetHeader(header.ParentHash, header.Number.Uint64()-1)
+	if parent == nil {
+		return nil, nil, 0, ErrUnknownAncestor
+	}
+	if difficulty, err = pow.difficulty(chain, header.Time, parent); err != nil {
+		return nil, nil, 0, err
+	}
+	sealing := pow.sealDifficulty(difficulty)
+	target = TargetFromDifficulty(sealing)
+
+	// A seal takes as many hashes as the difficulty on average
+	hashrate := pow.Hashrate()
+	if hashrate == 0 {
+		return difficulty, target, 0, nil
+	}
+	hashes, _ := new(big.Float).SetInt(sealing).Float64()
+	if seconds := hashes / hashrate; seconds < math.MaxInt64/float64(time.Second) {
+		expected = time.Duration(seconds * float64(time.Second))
+	} else {
+		expected = time.Duration(math.MaxInt64)
+	}
+	return difficulty, target, expected, nil
+}
+
+// sealDifficulty returns the difficulty a seal is searched and verified
+// against, which is capped in test mode.
+func (pow *powEngine) sealDifficulty(difficulty *big.Int) *big.Int {
+	if pow.mode == ModeTest && difficulty.Cmp(testDifficulty) > 0 {
+		return testDifficulty
+	}
+	return difficulty
+}
+
+// meter returns the hash attempt meter of the mining worker with the given
+// index, creating it if this is the first time the worker mines.
+func (pow *powEngine) meter(worker int) *hashMeter {
+	pow.metersLock.Lock()
+	defer pow.metersLock.Unlock()
+
+	for len(pow.meters) <= worker {
+		pow.meters = append(pow.meters, new(hashMeter))
+	}
+	return pow.meters[worker]
+}
+
+// SealHash returns the hash of a block prior to it being sealed. The nonce and
+// mix digest are the seal itself and don't participate, so the hash stays the
+// same across the nonce iterations of a mining run.
+// metoda 'seal hash' akan mengembalikan hash dari block sebelum dibungkus. Nonce dan mix digest tidak ikut di-hash,
+// sehingga hash tetap sama selama iterasi nonce saat mining.
+func (pow *powEngine) SealHash(header *types.Header) common.Hash {
+	return sealHash(header)
+}
+
+// sealHash returns the Keccak-256 hash of the RLP encoding of a header without
+// its seal fields. The participating fields, in order, are the parent hash,
+// uncle hash, coinbase, state root, transaction root, receipt root, bloom,
+// difficulty, number, gas limit, gas used, time, extra-data and, whenever set by
+// the later forks, the base fee, withdrawals hash, blob gas used, excess blob
+// gas and parent beacon root. MixDigest and Nonce are excluded.
+func sealHash(header *types.Header) (hash common.Hash) {
+	hasher := crypto.NewKeccakState()
+
+	enc := []interface{}{
+		header.ParentHash,
+		header.UncleHash,
+		header.Coinbase,
+		header.Root,
+		header.TxHash,
+		header.ReceiptHash,
+		header.Bloom,
+		header.Difficulty,
+		header.Number,
+		header.GasLimit,
+		header.GasUsed,
+		header.Time,
+		header.Extra,
+	}
+	if header.BaseFee != nil {
+		enc = append(enc, header.BaseFee)
+	}
+	if header.WithdrawalsHash != nil {
+		enc = append(enc, header.WithdrawalsHash)
+	}
+	if header.ExcessBlobGas != nil {
+		enc = append(enc, header.BlobGasUsed, header.ExcessBlobGas)
+	}
+	if header.ParentBeaconRoot != nil {
+		enc = append(enc, header.ParentBeaconRoot)
+	}
+	rlp.Encode(hasher, enc)
+	hasher.Sum(hash[:0])
+	return hash
+}
+
+// CalcDifficulty is the difficulty adjustment algorithm. It returns the
+// difficulty that a new block should have when created at time given the
+// parent block's time and difficulty, plus the difficulty bomb if enabled.
+// Once the Homestead or Byzantium forks are active their rules are used instead
+// of the Frontier ones, unless a retargeting window is configured. The forks
+// are checked at the number of the new block, so a parent right before a fork
+// block already has its child follow the new rules.
+// The result never drops below the minimum difficulty of the engine. As the
+// method cannot fail, the parent's difficulty is returned if the retargeting
+// window reaches beyond the known headers; header verification and Prepare
+// reject such headers with ErrUnknownAncestor instead.
+// metoda 'calc difficulty' akan mengembalikan tingkat kesulitan block baru berdasarkan waktu dan tingkat kesulitan block parent.
+func (pow *powEngine) CalcDifficulty(chain ChainHeaderReader, time uint64, parent *types.Header) *big.Int {
+	diff, err := pow.difficulty(chain, time, parent)
+	if err != nil {
+		return new(big.Int).Set(parent.Difficulty)
+	}
+	return diff
+}
+
+// difficulty is CalcDifficulty, failing if the retargeting window cannot be
+// resolved from the chain.
+func (pow *powEngine) difficulty(chain ChainHeaderReader, time uint64, parent *types.Header) (*big.Int, error) {
+	if pow.mode == ModeFake {
+		return new(big.Int).Set(parent.Difficulty), nil
+	}
+	diff, err := pow.calcDifficulty(chain, time, parent)
+	if err != nil {
+		return nil, err
+	}
+	// Never go below the floor, however fast the blocks came in
+	if floor := pow.minDifficulty(); diff.Cmp(floor) < 0 {
+		diff.Set(floor)
+	}
+	return diff, nil
+}
+
+// minDifficulty returns the lowest difficulty CalcDifficulty may return.
+func (pow *powEngine) minDifficulty() *big.Int {
+	if pow.floor == nil {
+		return params.MinimumDifficulty
+	}
+	return pow.floor
+}
+
+// calcDifficulty is CalcDifficulty without the final floor applied.
+func (pow *powEngine) calcDifficulty(chain ChainHeaderReader, time uint64, parent *types.Header) (*big.Int, error) {
+	number := new(big.Int).Add(parent.Number, common.Big1)
+
+	var diff *big.Int
+	switch config := chain.Config(); {
+	case pow.window > 0:
+		var err error
+		if diff, err = WindowedDifficulty(chain, parent, pow.window, pow.target); err != nil {
+			return nil, err
+		}
+	case pow.intervals > 0:
+		var err error
+		if diff, err = TargetedDifficulty(chain, parent, pow.intervals, pow.target); err != nil {
+			return nil, err
+		}
+	case config != nil && config.IsByzantium(number):
+		// The Byzantium rules carry their own, delayed difficulty bomb
+		return calcDifficultyByzantium(time, parent, BombDelay(config, number)), nil
+	case config != nil && config.IsHomestead(number):
+		// The Homestead rules carry their own difficulty bomb
+		return calcDifficultyHomestead(time, parent), nil
+	default:
+		diff = calcDifficultyFrontier(time, parent)
+	}
+	// Add the difficulty bomb once it's activated
+	if pow.bomb != nil && number.Cmp(new(big.Int).SetUint64(*pow.bomb)) >= 0 {
+		diff.Add(diff, DifficultyBomb(number, BombDelay(chain.Config(), number)))
+	}
+	return diff, nil
+}
+
+// calcDifficultyFrontier is the difficulty adjustment algorithm. It returns the
+// difficulty that a new block should have when created at time given the parent
+// block's time and difficulty. The calculation uses the Frontier rules.
+func calcDifficultyFrontier(time uint64, parent *types.Header) *big.Int {
+	diff := new(big.Int)
+	adjust := new(big.Int).Div(parent.Difficulty, params.DifficultyBoundDivisor)
+	bigTime := new(big.Int)
+	bigParentTime := new(big.Int)
+
+	bigTime.SetUint64(time)
+	bigParentTime.SetUint64(parent.Time)
+
+	if bigTime.Sub(bigTime, bigParentTime).Cmp(params.DurationLimit) < 0 {
+		diff.Add(parent.Difficulty, adjust)
+	} else {
+		diff.Sub(parent.Difficulty, adjust)
+	}
+	if diff.Cmp(params.MinimumDifficulty) < 0 {
+		diff.Set(params.MinimumDifficulty)
+	}
+	return diff
+}
+
+// calcDifficultyHomestead is the difficulty adjustment algorithm. It returns
+// the difficulty that a new block should have when created at time given the
+// parent block's time and difficulty. The calculation uses the Homestead rules
+// of EIP-2, including the undelayed difficulty bomb.
+func calcDifficultyHomestead(time uint64, parent *types.Header) *big.Int {
+	// https://github.com/ethereum/EIPs/blob/master/EIPS/eip-2.md
+	// algorithm:
+	// diff = (parent_diff +
+	//         (parent_diff / 2048 * max(1 - (block_timestamp - parent_timestamp) // 10, -99))
+	//        ) + 2^(periodCount - 2)
+	bigTime := new(big.Int).SetUint64(time)
+	bigParentTime := new(big.Int).SetUint64(parent.Time)
+
+	// holds intermediate values to make the algo easier to read & audit
+	x := new(big.Int)
+	y := new(big.Int)
+
+	// 1 - (block_timestamp - parent_timestamp) // 10
+	x.Sub(bigTime, bigParentTime)
+	x.Div(x, big10)
+	x.Sub(common.Big1, x)
+
+	// max(1 - (block_timestamp - parent_timestamp) // 10, -99)
+	if x.Cmp(bigMinus99) < 0 {
+		x.Set(bigMinus99)
+	}
+	// (parent_diff + parent_diff // 2048 * max(1 - (block_timestamp - parent_timestamp) // 10, -99))
+	y.Div(parent.Difficulty, params.DifficultyBoundDivisor)
+	x.Mul(y, x)
+	x.Add(parent.Difficulty, x)
+
+	// minimum difficulty can ever be (before exponential factor)
+	if x.Cmp(params.MinimumDifficulty) < 0 {
+		x.Set(params.MinimumDifficulty)
+	}
+	// Add the exponential factor, not yet delayed by any fork
+	number := new(big.Int).Add(parent.Number, common.Big1)
+	return x.Add(x, DifficultyBomb(number, 0))
+}
+
+// calcDifficultyByzantium is the difficulty adjustment algorithm. It returns
+// the difficulty that a new block should have when created at time given the
+// parent block's time, difficulty and uncles. The calculation uses the Byzantium
+// rules of EIP-100, with the difficulty bomb pushed back by the given delay.
+func calcDifficultyByzantium(time uint64, parent *types.Header, bombDelay uint64) *big.Int {
+	// https://github.com/ethereum/EIPs/issues/100.
+	// algorithm:
+	// diff = (parent_diff +
+	//         (parent_diff / 2048 * max((2 if len(parent.uncles) else 1) - ((timestamp - parent.timestamp) // 9), -99))
+	//        ) + 2^(periodCount - 2)
+	bigTime := new(big.Int).SetUint64(time)
+	bigParentTime := new(big.Int).SetUint64(parent.Time)
+
+	// holds intermediate values to make the algo easier to read & audit
+	x := new(big.Int)
+	y := new(big.Int)
+
+	// (2 if len(parent_uncles) else 1) - (block_timestamp - parent_timestamp) // 9
+	x.Sub(bigTime, bigParentTime)
+	x.Div(x, big9)
+	if parent.UncleHash == types.EmptyUncleHash {
+		x.Sub(common.Big1, x)
+	} else {
+		x.Sub(common.Big2, x)
+	}
+	// max((2 if len(parent_uncles) else 1) - (block_timestamp - parent_timestamp) // 9, -99)
+	if x.Cmp(bigMinus99) < 0 {
+		x.Set(bigMinus99)
+	}
+	// parent_diff + (parent_diff / 2048 * max((2 if len(parent.uncles) else 1) - ((timestamp - parent.timestamp) // 9), -99))
+	y.Div(parent.Difficulty, params.DifficultyBoundDivisor)
+	x.Mul(y, x)
+	x.Add(parent.Difficulty, x)
+
+	// minimum difficulty can ever be (before exponential factor)
+	if x.Cmp(params.MinimumDifficulty) < 0 {
+		x.Set(params.MinimumDifficulty)
+	}
+	// Add the exponential factor, based on the fake block number of the delay
+	number := new(big.Int).Add(parent.Number, common.Big1)
+	return x.Add(x, DifficultyBomb(number, bombDelay))
+}
+
+// APIs implements Engine, returning the user facing RPC APIs.
+// metoda 'apis' akan mengembalikan RPC API untuk pengguna.
+func (pow *powEngine) APIs(chain ChainHeaderReader) []rpc.API {
+	return []rpc.API{
+		{
+			Namespace: "pow",
+			Service:   &API{pow: pow, chain: chain},
+		},
+	}
+}
+
+// Close implements Engine. Sealing goroutines are bound to their stop
+// channels, so there are no goroutines to tear down, but the cache of verified
+// headers is cleared, as PoA does, so nothing stale outlives the engine.
+// metoda 'close' akan mengosongkan cache header yang telah diverifikasi; goroutine seal dihentikan melalui channel stop.
+func (pow *powEngine) Close() error {
+	pow.verified.purge()
+	return nil
+}
+
+// Hashrate implements PoW, returning the combined hash attempts per second of
+// all mining workers, averaged over the last ten seconds.
+// metoda 'hashrate' akan mengembalikan gabungan jumlah percobaan hash per detik dari semua worker, dirata-rata
+// selama sepuluh detik terakhir.
+func (pow *powEngine) Hashrate() float64 {
+	var hashrate float64
+	for _, rate := range pow.HashratePerWorker() {
+		hashrate += rate
+	}
+	return hashrate
+}
+
+// HashratePerWorker returns the hash attempts per second of every mining
+// worker, averaged over the last ten seconds and indexed by worker, showing how
+// the load is balanced across the CPU cores.
+// metoda 'hashrate per worker' akan mengembalikan jumlah percobaan hash per detik dari setiap worker, dirata-rata
+// selama sepuluh detik terakhir.
+func (pow *powEngine) HashratePerWorker() []float64 {
+	pow.metersLock.Lock()
+	defer pow.metersLock.Unlock()
+
+	rates := make([]float64, len(pow.meters))
+	for i, meter := range pow.meters {
+		rates[i] = meter.rate()
+	}
+	return rates
+}
@@ -0,0 +1,704 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package consensus
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// frontierConfig is a chain config without any fork activated.
+var frontierConfig = &params.ChainConfig{ChainID: big.NewInt(1)}
+
+// testChain is an in-memory chain of headers and blocks, implementing the
+// chain readers the engines verify headers and uncles against.
+type testChain struct {
+	config  *params.ChainConfig
+	headers map[common.Hash]*types.Header
+	numbers map[uint64]*types.Header
+	blocks  map[common.Hash]*types.Block
+	head    *types.Header
+}
+
+// newTestChain creates an in-memory chain holding the given headers, the last
+// of which is the head.
+func newTestChain(config *params.ChainConfig, headers ...*types.Header) *testChain {
+	chain := &testChain{
+		config:  config,
+		headers: make(map[common.Hash]*types.Header),
+		numbers: make(map[uint64]*types.Header),
+		blocks:  make(map[common.Hash]*types.Block),
+	}
+	chain.insert(headers...)
+	return chain
+}
+
+// insert appends the given headers to the chain, making the last one the head.
+func (c *testChain) insert(headers ...*types.Header) {
+	for _, header := range headers {
+		c.headers[header.Hash()] = header
+		c.numbers[header.Number.Uint64()] = header
+		c.head = header
+	}
+}
+
+// insertBlock appends the given block to the chain, body included, making it
+// the head.
+func (c *testChain) insertBlock(block *types.Block) {
+	c.insert(block.Header())
+	c.blocks[block.Hash()] = block
+}
+
+func (c *testChain) Config() *params.ChainConfig  { return c.config }
+func (c *testChain) CurrentHeader() *types.Header { return c.head }
+
+func (c *testChain) GetHeader(hash common.Hash, number uint64) *types.Header {
+	if header := c.headers[hash]; header != nil && header.Number.Uint64() == number {
+		return header
+	}
+	return nil
+}
+
+func (c *testChain) GetHeaderByNumber(number uint64) *types.Header  { return c.numbers[number] }
+func (c *testChain) GetHeaderByHash(hash common.Hash) *types.Header { return c.headers[hash] }
+
+func (c *testChain) GetBlock(hash common.Hash, number uint64) *types.Block {
+	if block := c.blocks[hash]; block != nil && block.NumberU64() == number {
+		return block
+	}
+	return nil
+}
+
+func (c *testChain) GetTd(hash common.Hash, number uint64) *big.Int {
+	td := new(big.Int)
+	for header := c.GetHeader(hash, number); header != nil; {
+		td.Add(td, header.Difficulty)
+		if header.Number.Sign() == 0 {
+			return td
+		}
+		header = c.GetHeader(header.ParentHash, header.Number.Uint64()-1)
+	}
+	return nil
+}
+
+// testGenesis creates a genesis header of the given difficulty.
+func testGenesis(difficulty int64) *types.Header {
+	return &types.Header{
+		Number:     new(big.Int),
+		Difficulty: big.NewInt(difficulty),
+		GasLimit:   params.GenesisGasLimit,
+		UncleHash:  types.EmptyUncleHash,
+	}
+}
+
+// makeHeaders generates n headers on top of parent, the given number of seconds
+// apart and with the difficulty the engine demands, inserting them into the
+// chain as they are generated.
+func makeHeaders(engine Engine, chain *testChain, parent *types.Header, n int, spacing uint64) []*types.Header {
+	headers := make([]*types.Header, 0, n)
+	for i := 0; i < n; i++ {
+		header := &types.Header{
+			ParentHash: parent.Hash(),
+			UncleHash:  types.EmptyUncleHash,
+			Number:     new(big.Int).Add(parent.Number, common.Big1),
+			GasLimit:   parent.GasLimit,
+			Time:       parent.Time + spacing,
+		}
+		header.Difficulty = engine.CalcDifficulty(chain, header.Time, parent)
+
+		chain.insert(header)
+		headers = append(headers, header)
+		parent = header
+	}
+	return headers
+}
+
+// sealHeader prepares a child of the given parent one second after it and seals
+// it with the engine.
+func sealHeader(t testing.TB, engine Engine, chain ChainHeaderReader, parent *types.Header) *types.Header {
+	t.Helper()
+
+	header := &types.Header{
+		ParentHash: parent.Hash(),
+		UncleHash:  types.EmptyUncleHash,
+		Number:     new(big.Int).Add(parent.Number, common.Big1),
+		GasLimit:   parent.GasLimit,
+		Time:       parent.Time + 1,
+	}
+	if err := engine.Prepare(chain, header); err != nil {
+		t.Fatalf("failed to prepare header: %v", err)
+	}
+	results, stop := make(chan *types.Block, 1), make(chan struct{})
+	defer close(stop)
+
+	if err := engine.Seal(chain, types.NewBlockWithHeader(header), results, stop); err != nil {
+		t.Fatalf("failed to seal block: %v", err)
+	}
+	select {
+	case block := <-results:
+		return block.Header()
+	case <-time.After(30 * time.Second):
+		t.Fatalf("sealing timed out")
+	}
+	return nil
+}
+
+// Tests that a block sealed at the lowest difficulty passes the engine's own
+// header verification, and that the hashrate of the run is measured.
+func TestSealVerify(t *testing.T) {
+	var (
+		engine  = NewPoW()
+		genesis = testGenesis(params.MinimumDifficulty.Int64())
+		chain   = newTestChain(frontierConfig, genesis)
+	)
+	header := sealHeader(t, engine, chain, genesis)
+	if err := engine.VerifyHeader(chain, header, true); err != nil {
+		t.Fatalf("sealed header failed verification: %v", err)
+	}
+	if rate := engine.Hashrate(); rate <= 0 {
+		t.Errorf("hashrate not measured after sealing: %v", rate)
+	}
+	// Against a target this low the nonce only holds with negligible probability
+	forged := types.CopyHeader(header)
+	forged.Difficulty = new(big.Int).Lsh(common.Big1, 200)
+	if err := engine.VerifySeal(chain, forged); !errors.Is(err, errInvalidPoW) {
+		t.Errorf("forged seal error mismatch: have %v, want %v", err, errInvalidPoW)
+	}
+}
+
+// Tests that sealing the same header twice from the same deterministic nonce
+// source produces byte-identical blocks.
+func TestSealNonceSource(t *testing.T) {
+	var (
+		genesis = testGenesis(params.MinimumDifficulty.Int64())
+		chain   = newTestChain(frontierConfig, genesis)
+	)
+	seal := func() []byte {
+		engine := NewPoW(WithThreads(1), WithNonceSource(rand.New(rand.NewSource(1)).Uint64))
+		header := sealHeader(t, engine, chain, genesis)
+		if err := engine.VerifyHeader(chain, header, true); err != nil {
+			t.Fatalf("sealed header failed verification: %v", err)
+		}
+		blob, err := rlp.EncodeToBytes(types.NewBlockWithHeader(header))
+		if err != nil {
+			t.Fatalf("failed to encode block: %v", err)
+		}
+		return blob
+	}
+	if first, second := seal(), seal(); !bytes.Equal(first, second) {
+		t.Errorf("sealed blocks differ:\nfirst:  %x\nsecond: %x", first, second)
+	}
+}
+
+// Tests that VerifyHeader only checks the seal when requested, delegating the
+// check to VerifySeal.
+func TestVerifyHeaderDelegatesSeal(t *testing.T) {
+	var (
+		engine  = NewPoW()
+		genesis = testGenesis(0)
+	)
+	// At a difficulty this high no nonce is a valid seal with any probability
+	genesis.Difficulty = new(big.Int).Lsh(common.Big1, 200)
+	chain := newTestChain(frontierConfig, genesis)
+
+	header := &types.Header{
+		ParentHash: genesis.Hash(),
+		UncleHash:  types.EmptyUncleHash,
+		Number:     big.NewInt(1),
+		GasLimit:   genesis.GasLimit,
+		Time:       1,
+	}
+	header.Difficulty = engine.CalcDifficulty(chain, header.Time, genesis)
+
+	if err := engine.VerifyHeader(chain, header, false); err != nil {
+		t.Errorf("unsealed verification failed: %v", err)
+	}
+	if err := engine.VerifyHeader(chain, header, true); !errors.Is(err, errInvalidPoW) {
+		t.Errorf("sealed verification error mismatch: have %v, want %v", err, errInvalidPoW)
+	}
+	if err := engine.VerifySeal(chain, header); !errors.Is(err, errInvalidPoW) {
+		t.Errorf("seal verification error mismatch: have %v, want %v", err, errInvalidPoW)
+	}
+}
+
+// Tests that the tester engine seals blocks of any difficulty within moments,
+// accepts them itself, yet still rejects nonces missing its capped target.
+func TestTesterSealVerify(t *testing.T) {
+	var (
+		engine  = NewTester()
+		genesis = testGenesis(0)
+	)
+	// No nonce meets a difficulty this high, unless it's capped
+	genesis.Difficulty = new(big.Int).Lsh(common.Big1, 200)
+	chain := newTestChain(frontierConfig, genesis)
+
+	start := time.Now()
+	header := sealHeader(t, engine, chain, genesis)
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("sealing took %v, want well under a second", elapsed)
+	}
+	if err := engine.VerifyHeader(chain, header, true); err != nil {
+		t.Fatalf("sealed header failed verification: %v", err)
+	}
+	if err := NewPoW().VerifySeal(chain, header); !errors.Is(err, errInvalidPoW) {
+		t.Errorf("uncapped seal error mismatch: have %v, want %v", err, errInvalidPoW)
+	}
+	// Find a garbage nonce missing the capped target
+	target := TargetFromDifficulty(testDifficulty)
+	for nonce := uint64(0); ; nonce++ {
+		if new(big.Int).SetBytes(powHash(Keccak256, engine.SealHash(header), nonce)).Cmp(target) > 0 {
+			header.Nonce = types.EncodeNonce(nonce)
+			break
+		}
+	}
+	if err := engine.VerifySeal(chain, header); !errors.Is(err, errInvalidPoW) {
+		t.Errorf("garbage nonce error mismatch: have %v, want %v", err, errInvalidPoW)
+	}
+}
+
+// Tests that headers not strictly after their parent, or too far ahead of the
+// local clock, are rejected.
+func TestVerifyHeaderTimestamp(t *testing.T) {
+	var (
+		now     = time.Unix(1_000_000, 0)
+		engine  = NewPoW(WithClock(func() time.Time { return now }))
+		genesis = testGenesis(params.MinimumDifficulty.Int64())
+	)
+	genesis.Time = uint64(now.Unix()) - 60
+	chain := newTestChain(frontierConfig, genesis)
+
+	tests := []struct {
+		name string
+		time uint64
+		want error
+	}{
+		{"after parent", genesis.Time + 1, nil},
+		{"equal to parent", genesis.Time, ErrTimestampTooOld},
+		{"older than parent", genesis.Time - 1, ErrTimestampTooOld},
+		{"at drift limit", uint64(now.Add(AllowedFutureBlockTime).Unix()), nil},
+		{"beyond drift limit", uint64(now.Add(AllowedFutureBlockTime).Unix()) + 1, ErrFutureBlock},
+		{"far in the future", uint64(now.Add(time.Hour).Unix()), ErrFutureBlock},
+	}
+	for _, tt := range tests {
+		header := &types.Header{
+			ParentHash: genesis.Hash(),
+			UncleHash:  types.EmptyUncleHash,
+			Number:     big.NewInt(1),
+			GasLimit:   genesis.GasLimit,
+			Time:       tt.time,
+			Difficulty: new(big.Int).Set(genesis.Difficulty),
+		}
+		if tt.time > genesis.Time {
+			header.Difficulty = engine.CalcDifficulty(chain, header.Time, genesis)
+		}
+		if err := engine.VerifyHeader(chain, header, false); !errors.Is(err, tt.want) {
+			t.Errorf("%s: error mismatch: have %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
+
+// Tests verifying headers directly against a parent, without any chain reader
+// to look it up from.
+func TestVerifyHeaderAgainst(t *testing.T) {
+	var (
+		engine  = NewTester()
+		genesis = testGenesis(params.MinimumDifficulty.Int64())
+		header  = sealHeader(t, engine, newTestChain(frontierConfig, genesis), genesis)
+	)
+	modify := func(fn func(*types.Header)) *types.Header {
+		header := types.CopyHeader(header)
+		fn(header)
+		return header
+	}
+	tests := []struct {
+		name   string
+		parent *types.Header
+		header *types.Header
+		want   error
+	}{
+		{"valid", genesis, header, nil},
+		{"wrong parent", header, header, ErrInvalidParentHash},
+		{"number gap", genesis, modify(func(h *types.Header) { h.Number = big.NewInt(2) }), ErrInvalidNumber},
+		{"older than parent", genesis, modify(func(h *types.Header) { h.Time = genesis.Time }), ErrTimestampTooOld},
+		{"wrong difficulty", genesis, modify(func(h *types.Header) { h.Difficulty = new(big.Int).Add(h.Difficulty, common.Big1) }), ErrInvalidDifficulty},
+		{"extra-data too long", genesis, modify(func(h *types.Header) { h.Extra = make([]byte, params.MaximumExtraDataSize+1) }), ErrExtraDataTooLong},
+	}
+	for _, tt := range tests {
+		if err := engine.VerifyHeaderAgainst(tt.parent, tt.header, false, frontierConfig); !errors.Is(err, tt.want) {
+			t.Errorf("%s: error mismatch: have %v, want %v", tt.name, err, tt.want)
+		}
+	}
+	// The seal is only checked on request
+	forged := modify(func(h *types.Header) {
+		target := TargetFromDifficulty(testDifficulty)
+		for nonce := uint64(0); ; nonce++ {
+			if new(big.Int).SetBytes(powHash(Keccak256, engine.SealHash(h), nonce)).Cmp(target) > 0 {
+				h.Nonce = types.EncodeNonce(nonce)
+				return
+			}
+		}
+	})
+	if err := engine.VerifyHeaderAgainst(genesis, forged, false, frontierConfig); err != nil {
+		t.Errorf("unsealed verification failed: %v", err)
+	}
+	if err := engine.VerifyHeaderAgainst(genesis, forged, true, frontierConfig); !errors.Is(err, errInvalidPoW) {
+		t.Errorf("sealed verification error mismatch: have %v, want %v", err, errInvalidPoW)
+	}
+	if err := engine.VerifyHeaderAgainst(genesis, header, true, frontierConfig); err != nil {
+		t.Errorf("sealed header failed verification: %v", err)
+	}
+	// A fake engine accepts anything, even without the right parent
+	if err := NewFaker().VerifyHeaderAgainst(header, forged, true, frontierConfig); err != nil {
+		t.Errorf("fake verification failed: %v", err)
+	}
+}
+
+// Tests that extra-data is accepted up to the configured limit and rejected
+// past it.
+func TestVerifyHeaderExtraData(t *testing.T) {
+	var (
+		genesis = testGenesis(params.MinimumDifficulty.Int64())
+		chain   = newTestChain(frontierConfig, genesis)
+	)
+	for _, limit := range []uint64{0, 64} {
+		engine, want := NewPoW(), uint64(params.MaximumExtraDataSize)
+		if limit > 0 {
+			engine, want = NewPoW(WithMaxExtraData(limit)), limit
+		}
+		header := makeHeaders(engine, newTestChain(frontierConfig, genesis), genesis, 1, 1)[0]
+
+		header.Extra = make([]byte, want)
+		if err := engine.VerifyHeader(chain, header, false); err != nil {
+			t.Errorf("limit %d: extra-data at the limit rejected: %v", want, err)
+		}
+		header.Extra = make([]byte, want+1)
+		if err := engine.VerifyHeader(chain, header, false); !errors.Is(err, ErrExtraDataTooLong) {
+			t.Errorf("limit %d: error mismatch: have %v, want %v", want, err, ErrExtraDataTooLong)
+		}
+	}
+}
+
+// Tests that importing a chain through the fake failer rejects exactly the
+// configured block, one header at a time as well as in a batch.
+func TestFakeFailer(t *testing.T) {
+	var (
+		engine  = NewFakeFailer(5)
+		genesis = testGenesis(params.MinimumDifficulty.Int64())
+		headers = makeHeaders(engine, newTestChain(frontierConfig, genesis), genesis, 10, 1)
+	)
+	// Import the headers in order, stopping at the first rejection
+	chain := newTestChain(frontierConfig, genesis)
+	for _, header := range headers {
+		if err := engine.VerifyHeader(chain, header, true); err != nil {
+			if number := header.Number.Uint64(); number != 5 {
+				t.Fatalf("block %d rejected: %v", number, err)
+			}
+			break
+		}
+		chain.insert(header)
+	}
+	if head := chain.CurrentHeader().Number.Uint64(); head != 4 {
+		t.Errorf("import head mismatch: have %d, want 4", head)
+	}
+	// Verify the whole batch, only block 5 may fail
+	chain = newTestChain(frontierConfig, genesis)
+	abort, results := engine.VerifyHeaders(chain, headers, make([]bool, len(headers)))
+	defer close(abort)
+
+	for _, header := range headers {
+		err := <-results
+		if failed, want := err != nil, header.Number.Uint64() == 5; failed != want {
+			t.Errorf("block %d: failure mismatch: have %v, want failure %v", header.Number, err, want)
+		}
+	}
+}
+
+// Tests that the seal hash ignores the seal fields, so that mining work stays
+// valid across nonce iterations, but covers every other header field.
+func TestSealHash(t *testing.T) {
+	var (
+		engine = NewPoW()
+		header = &types.Header{
+			ParentHash: common.Hash{0x01},
+			UncleHash:  types.EmptyUncleHash,
+			Number:     big.NewInt(1),
+			Difficulty: big.NewInt(131072),
+			GasLimit:   params.GenesisGasLimit,
+			Time:       1,
+		}
+		hash = engine.SealHash(header)
+	)
+	sealed := types.CopyHeader(header)
+	sealed.Nonce = types.EncodeNonce(0xdeadbeef)
+	sealed.MixDigest = common.Hash{0x02}
+	if have := engine.SealHash(sealed); have != hash {
+		t.Errorf("seal fields changed the seal hash: have %x, want %x", have, hash)
+	}
+	for name, mutate := range map[string]func(*types.Header){
+		"parent hash": func(h *types.Header) { h.ParentHash = common.Hash{0x03} },
+		"coinbase":    func(h *types.Header) { h.Coinbase = common.Address{0x04} },
+		"number":      func(h *types.Header) { h.Number = big.NewInt(2) },
+		"difficulty":  func(h *types.Header) { h.Difficulty = big.NewInt(131073) },
+		"time":        func(h *types.Header) { h.Time = 2 },
+		"extra-data":  func(h *types.Header) { h.Extra = []byte{0x05} },
+		"base fee":    func(h *types.Header) { h.BaseFee = big.NewInt(1) },
+	} {
+		changed := types.CopyHeader(header)
+		mutate(changed)
+		if engine.SealHash(changed) == hash {
+			t.Errorf("%s change left the seal hash unchanged", name)
+		}
+	}
+}
+
+// Tests that a seal verify fraction checks the seal of every Nth header of a
+// batch and always of its newest one, whatever the seals argument requests.
+func TestSealVerifyFraction(t *testing.T) {
+	var (
+		genesis = testGenesis(params.MinimumDifficulty.Int64())
+		headers = makeHeaders(NewPoW(), newTestChain(frontierConfig, genesis), genesis, 10, 1)
+	)
+	reversed := make([]*types.Header, len(headers))
+	for i, header := range headers {
+		reversed[len(headers)-1-i] = header
+	}
+	tests := []struct {
+		name    string
+		every   int
+		headers []*types.Header
+		checked map[int]bool // Indexes of the headers whose seal is verified
+	}{
+		{"disabled", 0, headers, map[int]bool{}},
+		{"every 4th", 4, headers, map[int]bool{3: true, 7: true, 9: true}},
+		{"every 5th", 5, headers, map[int]bool{4: true, 9: true}},
+		{"descending", 4, reversed, map[int]bool{0: true, 3: true, 7: true}},
+		{"every header", 1, headers, map[int]bool{0: true, 1: true, 2: true, 3: true, 4: true, 5: true, 6: true, 7: true, 8: true, 9: true}},
+	}
+	for _, tt := range tests {
+		// None of the headers is sealed, so every verified seal fails
+		engine := NewPoW(WithSealVerifyFraction(tt.every))
+		abort, results := engine.VerifyHeaders(newTestChain(frontierConfig, genesis), tt.headers, make([]bool, len(tt.headers)))
+		for i := range tt.headers {
+			err := <-results
+			if tt.checked[i] && !errors.Is(err, errInvalidPoW) {
+				t.Errorf("%s: header %d error mismatch: have %v, want %v", tt.name, i, err, errInvalidPoW)
+			}
+			if !tt.checked[i] && err != nil {
+				t.Errorf("%s: header %d seal verified: %v", tt.name, i, err)
+			}
+		}
+		close(abort)
+	}
+}
+
+// Tests that Prepare sets the configured etherbase as the coinbase, and leaves
+// the coinbase of the header alone without one.
+func TestPrepareEtherbase(t *testing.T) {
+	var (
+		engine  = NewPoW()
+		genesis = testGenesis(params.MinimumDifficulty.Int64())
+		chain   = newTestChain(frontierConfig, genesis)
+	)
+	prepare := func() common.Address {
+		header := &types.Header{
+			ParentHash: genesis.Hash(),
+			Coinbase:   common.Address{0x11},
+			Number:     big.NewInt(1),
+			Time:       genesis.Time + 1,
+		}
+		if err := engine.Prepare(chain, header); err != nil {
+			t.Fatalf("failed to prepare header: %v", err)
+		}
+		return header.Coinbase
+	}
+	if have := prepare(); have != (common.Address{0x11}) {
+		t.Errorf("coinbase without etherbase: have %x, want %x", have, common.Address{0x11})
+	}
+	engine.SetEtherbase(common.Address{0xaa})
+	if have := prepare(); have != (common.Address{0xaa}) {
+		t.Errorf("coinbase with etherbase: have %x, want %x", have, common.Address{0xaa})
+	}
+	engine.SetEtherbase(common.Address{})
+	if have := prepare(); have != (common.Address{0x11}) {
+		t.Errorf("coinbase with cleared etherbase: have %x, want %x", have, common.Address{0x11})
+	}
+}
+
+// Tests CalcDifficulty on known parent/child pairs on either side of the
+// Homestead and Byzantium forks, including parents right at the fork blocks,
+// and that VerifyHeader recomputes the difficulty to accept exactly the one
+// expected.
+func TestCalcDifficultyForks(t *testing.T) {
+	const (
+		diff = 1_000_000_000
+		step = diff / 2048
+	)
+	config := *frontierConfig
+	config.HomesteadBlock = big.NewInt(100)
+	config.ByzantiumBlock = big.NewInt(200)
+
+	tests := []struct {
+		number int64 // Number of the parent, timestamped 100
+		uncles bool  // Whether the parent has uncles
+		time   uint64
+		want   int64
+	}{
+		// Frontier: a step up below 13 seconds, a step down from there on
+		{50, false, 112, diff + step},
+		{50, false, 113, diff - step},
+		{98, false, 115, diff - step},
+
+		// Homestead from block 100: one step per 10 seconds, uncles ignored
+		{99, false, 109, diff + step},
+		{99, false, 115, diff},
+		{99, false, 130, diff - 2*step},
+		{100, false, 1100, diff - 99*step},
+		{150, true, 105, diff + step},
+		{198, true, 105, diff + step},
+
+		// Byzantium from block 200: one step per 9 seconds, another for uncles
+		{199, true, 105, diff + 2*step},
+		{199, false, 118, diff - step},
+		{199, true, 118, diff},
+		{200, false, 109, diff},
+		{200, true, 1009, diff - 99*step},
+	}
+	uncled := types.CalcUncleHash([]*types.Header{{Number: big.NewInt(1)}})
+	engine := NewPoW()
+
+	for _, tt := range tests {
+		parent := &types.Header{
+			UncleHash:  types.EmptyUncleHash,
+			Number:     big.NewInt(tt.number),
+			GasLimit:   params.GenesisGasLimit,
+			Time:       100,
+			Difficulty: big.NewInt(diff),
+		}
+		if tt.uncles {
+			parent.UncleHash = uncled
+		}
+		chain := newTestChain(&config, parent)
+
+		have := engine.CalcDifficulty(chain, tt.time, parent)
+		if have.Cmp(big.NewInt(tt.want)) != 0 {
+			t.Errorf("block %d at %d: difficulty mismatch: have %v, want %v", tt.number+1, tt.time, have, tt.want)
+			continue
+		}
+		header := &types.Header{
+			ParentHash: parent.Hash(),
+			UncleHash:  types.EmptyUncleHash,
+			Number:     big.NewInt(tt.number + 1),
+			GasLimit:   parent.GasLimit,
+			Time:       tt.time,
+			Difficulty: big.NewInt(tt.want),
+		}
+		if err := engine.VerifyHeader(chain, header, false); err != nil {
+			t.Errorf("block %d at %d: verification failed: %v", tt.number+1, tt.time, err)
+		}
+		header.Difficulty = big.NewInt(tt.want + 1)
+		if err := engine.VerifyHeader(chain, header, false); !errors.Is(err, ErrInvalidDifficulty) {
+			t.Errorf("block %d at %d: error mismatch: have %v, want %v", tt.number+1, tt.time, err, ErrInvalidDifficulty)
+		}
+	}
+}
+
+// Tests that EstimateSeal reports the difficulty CalcDifficulty computes for
+// the header, on either side of the forks, along with its target, the target
+// being capped in test mode, and that the expected seal time follows the
+// measured hashrate.
+func TestEstimateSeal(t *testing.T) {
+	config := *frontierConfig
+	config.HomesteadBlock = big.NewInt(100)
+	config.ByzantiumBlock = big.NewInt(200)
+
+	for _, number := range []int64{50, 99, 100, 199, 200} {
+		parent := &types.Header{
+			UncleHash:  types.EmptyUncleHash,
+			Number:     big.NewInt(number),
+			GasLimit:   params.GenesisGasLimit,
+			Time:       100,
+			Difficulty: big.NewInt(1_000_000_000),
+		}
+		var (
+			chain  = newTestChain(&config, parent)
+			header = &types.Header{ParentHash: parent.Hash(), Number: big.NewInt(number + 1), Time: 105}
+		)
+		for _, engine := range []*powEngine{NewPoW(), NewTester()} {
+			diff, target, expected, err := engine.EstimateSeal(chain, header)
+			if err != nil {
+				t.Fatalf("block %d: failed to estimate: %v", number+1, err)
+			}
+			if want := engine.CalcDifficulty(chain, header.Time, parent); diff.Cmp(want) != 0 {
+				t.Errorf("block %d: difficulty mismatch: have %v, want %v", number+1, diff, want)
+			}
+			if want := TargetFromDifficulty(engine.sealDifficulty(diff)); target.Cmp(want) != 0 {
+				t.Errorf("block %d: target mismatch: have %x, want %x", number+1, target, want)
+			}
+			if expected != 0 {
+				t.Errorf("block %d: expected seal time without hashrate: have %v, want 0", number+1, expected)
+			}
+			if header.Difficulty != nil || header.Nonce != (types.BlockNonce{}) {
+				t.Errorf("block %d: estimation modified the header", number+1)
+			}
+		}
+	}
+	// An unknown parent can't be estimated on
+	orphan := &types.Header{ParentHash: common.Hash{0x01}, Number: big.NewInt(1), Time: 105}
+	if _, _, _, err := NewPoW().EstimateSeal(newTestChain(frontierConfig), orphan); !errors.Is(err, ErrUnknownAncestor) {
+		t.Errorf("orphan error mismatch: have %v, want %v", err, ErrUnknownAncestor)
+	}
+}
+
+// Tests that the seal time EstimateSeal expects is the difficulty divided by
+// the hashrate measured while mining.
+func TestEstimateSealHashrate(t *testing.T) {
+	var (
+		engine   = NewPoW(WithThreads(1))
+		genesis  = testGenesis(1_000_000_000)
+		chain    = newTestChain(frontierConfig, genesis)
+		header   = &types.Header{ParentHash: genesis.Hash(), Number: big.NewInt(1), Time: genesis.Time + 5}
+		hopeless = &types.Header{Number: big.NewInt(1), Difficulty: new(big.Int).Lsh(common.Big1, 200)}
+	)
+	results, stop := make(chan *types.Block, 1), make(chan struct{})
+	if err := NewMiner(engine, 1).Seal(types.NewBlockWithHeader(hopeless), results, stop); err != nil {
+		t.Fatalf("failed to start sealing: %v", err)
+	}
+	time.Sleep(1500 * time.Millisecond)
+	close(stop)
+
+	diff, _, expected, err := engine.EstimateSeal(chain, header)
+	if err != nil {
+		t.Fatalf("failed to estimate: %v", err)
+	}
+	hashrate := engine.Hashrate()
+	if hashrate == 0 {
+		t.Fatalf("no hashrate measured")
+	}
+	want := time.Duration(float64(diff.Int64()) / hashrate * float64(time.Second))
+	if expected < want*9/10 || expected > want*11/10 {
+		t.Errorf("expected seal time mismatch: have %v, want about %v", expected, want)
+	}
+}
@@ -0,0 +1,111 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package consensus
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// progressInterval is the minimum time between two progress reports of a batch.
+const progressInterval = time.Second
+
+// ProgressFunc is called with the number of headers of a batch whose results
+// were delivered so far, out of the total number of headers in the batch.
+// ProgressFunc dipanggil dengan jumlah header dalam batch yang hasilnya sudah dikirim, dari total header batch.
+type ProgressFunc func(verified, total int)
+
+// batchProgress holds the progress reporting settings of an engine.
+type batchProgress struct {
+	every  int          // Minimum number of headers between two reports
+	report ProgressFunc // Callback receiving the reports (nil = no reporting)
+}
+
+// progressTracker reports the progress of a single batch verification from a
+// goroutine of its own, so the verification never waits for the callback.
+// Updates arriving while the callback is busy are coalesced into the latest.
+type progressTracker struct {
+	config batchProgress
+	total  int
+
+	latest atomic.Int64  // Number of headers verified so far
+	wake   chan struct{} // Signals the reporter that latest changed
+	done   chan struct{} // Closed when the batch finished or was aborted
+}
+
+// newProgressTracker starts reporting the progress of a batch of the given size,
+// or returns nil if no callback is configured.
+func newProgressTracker(config batchProgress, total int) *progressTracker {
+	if config.report == nil {
+		return nil
+	}
+	t := &progressTracker{
+		config: config,
+		total:  total,
+		wake:   make(chan struct{}, 1),
+		done:   make(chan struct{}),
+	}
+	go t.loop()
+	return t
+}
+
+// update records the number of headers verified so far without blocking.
+func (t *progressTracker) update(verified int) {
+	if t == nil {
+		return
+	}
+	t.latest.Store(int64(verified))
+	select {
+	case t.wake <- struct{}{}:
+	default:
+	}
+}
+
+// finish marks the batch done, making the reporter deliver the final count.
+func (t *progressTracker) finish() {
+	if t == nil {
+		return
+	}
+	close(t.done)
+}
+
+// loop invokes the callback whenever both the configured number of headers and
+// the minimum interval passed since the last report, whichever takes longer,
+// and once more with the final count when the batch is done.
+func (t *progressTracker) loop() {
+	var (
+		reported = 0
+		last     = time.Now()
+	)
+	for {
+		select {
+		case <-t.wake:
+			verified := int(t.latest.Load())
+			if verified-reported < t.config.every || time.Since(last) < progressInterval {
+				continue
+			}
+			t.config.report(verified, t.total)
+			reported, last = verified, time.Now()
+
+		case <-t.done:
+			if verified := int(t.latest.Load()); verified != reported || verified == 0 {
+				t.config.report(verified, t.total)
+			}
+			return
+		}
+	}
+}
@@ -0,0 +1,98 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package consensus
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// Tests that the progress of a batch is reported from a single goroutine with
+// growing counts, that a slow callback doesn't hold up the verification, and
+// that the final report covers the whole batch.
+func TestVerifyHeadersProgress(t *testing.T) {
+	var (
+		engine  = NewFakeDelayer(time.Millisecond)
+		genesis = testGenesis(1)
+		headers = makeHeaders(engine, newTestChain(frontierConfig, genesis), genesis, 1500, 1)
+
+		reports  = make(chan [2]int, 16)
+		inflight atomic.Int32
+		returned atomic.Bool
+	)
+	engine.workers = 1
+	engine.progress = batchProgress{every: 100, report: func(verified, total int) {
+		if inflight.Add(1) > 1 {
+			t.Errorf("progress reported concurrently")
+		}
+		defer inflight.Add(-1)
+
+		reports <- [2]int{verified, total}
+		if verified < total {
+			// Stall the first intermediate report past the end of the batch
+			time.Sleep(1500 * time.Millisecond)
+			returned.Store(true)
+		}
+	}}
+	quit, results := engine.VerifyHeaders(newTestChain(frontierConfig, genesis), headers, make([]bool, len(headers)))
+	defer close(quit)
+
+	for range results {
+	}
+	if returned.Load() {
+		t.Errorf("verification waited for the stalled progress callback")
+	}
+	var last, intermediate int
+	for {
+		select {
+		case report := <-reports:
+			verified, total := report[0], report[1]
+			if total != len(headers) {
+				t.Fatalf("total mismatch: have %d, want %d", total, len(headers))
+			}
+			if verified < last || verified-last < 100 && verified != total {
+				t.Errorf("report out of step: have %d after %d", verified, last)
+			}
+			if last = verified; verified == total {
+				// The batch outlasted the report interval, so it was reported midway too
+				if intermediate == 0 {
+					t.Errorf("no progress reported before the end of the batch")
+				}
+				return
+			}
+			intermediate++
+		case <-time.After(5 * time.Second):
+			t.Fatalf("final progress report missing, last at %d", last)
+		}
+	}
+}
+
+// Tests that the progress options of the engines configure the reporting.
+func TestProgressOptions(t *testing.T) {
+	report := func(verified, total int) {}
+
+	if progress := NewPoW(WithProgress(64, report)).progress; progress.every != 64 || progress.report == nil {
+		t.Errorf("pow progress mismatch: have %+v", progress)
+	}
+	if progress := NewPoA(testCliqueConfig, nil, WithPoAProgress(32, report)).progress; progress.every != 32 || progress.report == nil {
+		t.Errorf("poa progress mismatch: have %+v", progress)
+	}
+	if progress := newProgressTracker(batchProgress{every: 1}, 10); progress != nil {
+		t.Errorf("tracker started without a callback")
+	}
+}
@@ -0,0 +1,81 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package consensus
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/params"
+)
+
+var errMissingCliqueConfig = errors.New("chain config has no clique section")
+
+// EngineConstructor creates a consensus engine for the given chain config.
+// EngineConstructor adalah fungsi yang membuat consensus engine untuk konfigurasi chain tertentu.
+type EngineConstructor func(*params.ChainConfig) (Engine, error)
+
+var (
+	engines     = make(map[string]EngineConstructor)
+	enginesLock sync.RWMutex
+)
+
+func init() {
+	RegisterEngine("pow", func(*params.ChainConfig) (Engine, error) {
+		return NewPoW(), nil
+	})
+	RegisterEngine("poa", func(config *params.ChainConfig) (Engine, error) {
+		if config == nil || config.Clique == nil {
+			return nil, errMissingCliqueConfig
+		}
+		return NewPoA(config.Clique, nil), nil
+	})
+	RegisterEngine("dev", DevConstructor(0))
+	RegisterEngine("fake", func(*params.ChainConfig) (Engine, error) {
+		return NewFaker(), nil
+	})
+}
+
+// RegisterEngine makes a consensus engine constructible by name through
+// NewEngineByName. Registering the same name twice is an error.
+// metoda 'register engine' akan mendaftarkan consensus engine agar dapat dibuat berdasarkan nama.
+// Mendaftarkan nama yang sama dua kali akan menghasilkan error.
+func RegisterEngine(name string, constructor EngineConstructor) error {
+	enginesLock.Lock()
+	defer enginesLock.Unlock()
+
+	if _, ok := engines[name]; ok {
+		return fmt.Errorf("consensus engine %q already registered", name)
+	}
+	engines[name] = constructor
+	return nil
+}
+
+// NewEngineByName constructs the consensus engine registered under the given
+// name.
+// metoda 'new engine by name' akan membuat consensus engine yang terdaftar dengan nama tertentu.
+func NewEngineByName(name string, cfg *params.ChainConfig) (Engine, error) {
+	enginesLock.RLock()
+	constructor, ok := engines[name]
+	enginesLock.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("unknown consensus engine %q", name)
+	}
+	return constructor(cfg)
+}
@@ -0,0 +1,62 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package consensus
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that a custom engine can be registered and constructed by name, and
+// that names cannot be registered twice.
+func TestRegisterEngine(t *testing.T) {
+	var (
+		stub   = NewDev(0)
+		config *params.ChainConfig
+	)
+	err := RegisterEngine("registry-test-stub", func(cfg *params.ChainConfig) (Engine, error) {
+		config = cfg
+		return stub, nil
+	})
+	if err != nil {
+		t.Fatalf("failed to register engine: %v", err)
+	}
+	engine, err := NewEngineByName("registry-test-stub", frontierConfig)
+	if err != nil {
+		t.Fatalf("failed to construct engine: %v", err)
+	}
+	if engine != stub || config != frontierConfig {
+		t.Errorf("constructor not invoked with the config: engine %p, config %p", engine, config)
+	}
+	if err := RegisterEngine("registry-test-stub", nil); err == nil {
+		t.Errorf("duplicate registration accepted")
+	}
+	if _, err := NewEngineByName("registry-test-missing", frontierConfig); err == nil {
+		t.Errorf("unknown engine constructed")
+	}
+	// The built-in engines register themselves
+	for _, name := range []string{"pow", "dev", "fake"} {
+		if _, err := NewEngineByName(name, frontierConfig); err != nil {
+			t.Errorf("built-in engine %q: construction failed: %v", name, err)
+		}
+	}
+	if _, err := NewEngineByName("poa", frontierConfig); !errors.Is(err, errMissingCliqueConfig) {
+		t.Errorf("poa without clique error mismatch: have %v, want %v", err, errMissingCliqueConfig)
+	}
+}
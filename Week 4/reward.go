@@ -0,0 +1,102 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package consensus
+
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+var (
+	big8  = big.NewInt(8)
+	big32 = big.NewInt(32)
+)
+
+// RewardSchedule maps the block numbers from which a block reward applies to
+// the reward in wei. A block is paid the reward of the highest threshold not
+// above its number.
+// RewardSchedule memetakan nomor block awal berlakunya sebuah block reward ke besar reward dalam wei.
+type RewardSchedule map[uint64]*big.Int
+
+// DefaultRewardSchedule follows Ethereum's block reward reductions at the
+// Byzantium and Constantinople forks.
+// DefaultRewardSchedule mengikuti penurunan block reward Ethereum pada fork Byzantium dan Constantinople.
+var DefaultRewardSchedule = RewardSchedule{
+	0:       big.NewInt(5e+18), // Frontier
+	4370000: big.NewInt(3e+18), // Byzantium
+	7280000: big.NewInt(2e+18), // Constantinople
+}
+
+// Reward returns the block reward in wei for the block at the given number.
+// metoda 'reward' akan mengembalikan block reward dalam wei untuk block dengan nomor tertentu.
+func (s RewardSchedule) Reward(number uint64) *big.Int {
+	var (
+		from   uint64
+		reward *big.Int
+	)
+	for threshold, amount := range s {
+		if threshold <= number && (reward == nil || threshold > from) {
+			from, reward = threshold, amount
+		}
+	}
+	if reward == nil {
+		return new(big.Int)
+	}
+	return new(big.Int).Set(reward)
+}
+
+// RewardRecipient returns the account the rewards of the given block or uncle
+// header are paid to, e.g. a treasury or burn address instead of the coinbase.
+// RewardRecipient mengembalikan akun penerima reward dari header block atau uncle, misalnya treasury atau alamat
+// burn sebagai ganti coinbase.
+type RewardRecipient func(header *types.Header) common.Address
+
+// recipientOf returns the account the rewards of header are paid to, which is
+// the coinbase unless a recipient override is given.
+func recipientOf(recipient RewardRecipient, header *types.Header) common.Address {
+	if recipient == nil {
+		return header.Coinbase
+	}
+	return recipient(header)
+}
+
+// accumulateRewards credits the recipient of the given block with the mining
+// reward. The total reward consists of the static block reward and rewards for
+// included uncles. The recipient of each uncle is also rewarded, the closer the
+// uncle the higher its reward. Without a recipient override, rewards are paid
+// to the coinbase.
+func accumulateRewards(schedule RewardSchedule, recipient RewardRecipient, state *state.StateDB, header *types.Header, uncles []*types.Header) {
+	blockReward := schedule.Reward(header.Number.Uint64())
+
+	// Accumulate the rewards for the miner and any included uncles
+	reward := new(big.Int).Set(blockReward)
+	r := new(big.Int)
+	for _, uncle := range uncles {
+		r.Add(uncle.Number, big8)
+		r.Sub(r, header.Number)
+		r.Mul(r, blockReward)
+		r.Div(r, big8)
+		state.AddBalance(recipientOf(recipient, uncle), r)
+
+		r.Div(blockReward, big32)
+		reward.Add(reward, r)
+	}
+	state.AddBalance(recipientOf(recipient, header), reward)
+}
@@ -0,0 +1,158 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package consensus
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// newTestState creates an empty in-memory state database.
+func newTestState(t *testing.T) *state.StateDB {
+	t.Helper()
+
+	statedb, err := state.New(types.EmptyRootHash, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	if err != nil {
+		t.Fatalf("failed to create state: %v", err)
+	}
+	return statedb
+}
+
+// Tests that the reward schedule pays the reward of the highest threshold not
+// above the block number.
+func TestRewardSchedule(t *testing.T) {
+	tests := []struct {
+		number uint64
+		want   *big.Int
+	}{
+		{0, big.NewInt(5e+18)},
+		{4369999, big.NewInt(5e+18)},
+		{4370000, big.NewInt(3e+18)},
+		{7279999, big.NewInt(3e+18)},
+		{7280000, big.NewInt(2e+18)},
+		{100000000, big.NewInt(2e+18)},
+	}
+	for _, tt := range tests {
+		if have := DefaultRewardSchedule.Reward(tt.number); have.Cmp(tt.want) != 0 {
+			t.Errorf("block %d: reward mismatch: have %v, want %v", tt.number, have, tt.want)
+		}
+	}
+	if have := (RewardSchedule{10: big.NewInt(1)}).Reward(9); have.Sign() != 0 {
+		t.Errorf("reward before the first threshold: have %v, want 0", have)
+	}
+}
+
+// Tests the balances credited by Finalize for blocks with and without uncles.
+func TestFinalizeRewards(t *testing.T) {
+	var (
+		miner  = common.Address{0x01}
+		uncle1 = common.Address{0x02}
+		uncle2 = common.Address{0x03}
+		reward = big.NewInt(5e+18)
+	)
+	// A block without uncles only pays the block reward
+	statedb := newTestState(t)
+	header := &types.Header{Number: big.NewInt(10), Coinbase: miner}
+	NewPoW().Finalize(nil, header, statedb, nil, nil, nil)
+
+	if have := statedb.GetBalance(miner); have.Cmp(reward) != 0 {
+		t.Errorf("miner balance without uncles: have %v, want %v", have, reward)
+	}
+	// Uncles are paid by their distance, the miner 1/32 of the reward for each
+	statedb = newTestState(t)
+	uncles := []*types.Header{
+		{Number: big.NewInt(9), Coinbase: uncle1},
+		{Number: big.NewInt(8), Coinbase: uncle2},
+	}
+	NewPoW().Finalize(nil, header, statedb, nil, uncles, nil)
+
+	want := map[common.Address]*big.Int{
+		miner:  big.NewInt(5e+18 + 2*5e+18/32),
+		uncle1: big.NewInt(5e+18 * 7 / 8),
+		uncle2: big.NewInt(5e+18 * 6 / 8),
+	}
+	for addr, balance := range want {
+		if have := statedb.GetBalance(addr); have.Cmp(balance) != 0 {
+			t.Errorf("account %x balance with uncles: have %v, want %v", addr, have, balance)
+		}
+	}
+	// A custom schedule and recipient replace the defaults
+	statedb = newTestState(t)
+	treasury := common.Address{0xff}
+	engine := NewPoW(
+		WithRewardSchedule(RewardSchedule{0: big.NewInt(1000)}),
+		WithRewardRecipient(func(*types.Header) common.Address { return treasury }),
+	)
+	engine.Finalize(nil, header, statedb, nil, uncles[:1], nil)
+
+	if have, want := statedb.GetBalance(treasury), big.NewInt(1000+1000/32+1000*7/8); have.Cmp(want) != 0 {
+		t.Errorf("treasury balance: have %v, want %v", have, want)
+	}
+	if have := statedb.GetBalance(miner); have.Sign() != 0 {
+		t.Errorf("miner paid despite the recipient override: %v", have)
+	}
+}
+
+// Tests that a reward recipient redirects the rewards away from the coinbases,
+// and may pick a different account for every header it's asked about.
+func TestRewardRecipient(t *testing.T) {
+	var (
+		miner    = common.Address{0x01}
+		uncler   = common.Address{0x02}
+		treasury = common.Address{0xff}
+		header   = &types.Header{Number: big.NewInt(10), Coinbase: miner}
+		uncles   = []*types.Header{{Number: big.NewInt(9), Coinbase: uncler}}
+	)
+	// Redirect everything to the treasury
+	statedb := newTestState(t)
+	engine := NewPoW(WithRewardRecipient(func(*types.Header) common.Address { return treasury }))
+	engine.Finalize(nil, header, statedb, nil, uncles, nil)
+
+	if have, want := statedb.GetBalance(treasury), new(big.Int).Add(big.NewInt(5e+18+5e+18/32), big.NewInt(5e+18*7/8)); have.Cmp(want) != 0 {
+		t.Errorf("treasury balance: have %v, want %v", have, want)
+	}
+	for _, addr := range []common.Address{miner, uncler} {
+		if have := statedb.GetBalance(addr); have.Sign() != 0 {
+			t.Errorf("coinbase %x paid despite the recipient override: %v", addr, have)
+		}
+	}
+	// Split by header: block rewards to the treasury, uncle rewards as usual
+	statedb = newTestState(t)
+	engine = NewPoW(WithRewardRecipient(func(h *types.Header) common.Address {
+		if h == header {
+			return treasury
+		}
+		return h.Coinbase
+	}))
+	engine.Finalize(nil, header, statedb, nil, uncles, nil)
+
+	want := map[common.Address]*big.Int{
+		treasury: big.NewInt(5e+18 + 5e+18/32),
+		uncler:   big.NewInt(5e+18 * 7 / 8),
+		miner:    new(big.Int),
+	}
+	for addr, balance := range want {
+		if have := statedb.GetBalance(addr); have.Cmp(balance) != 0 {
+			t.Errorf("account %x balance with split recipient: have %v, want %v", addr, have, balance)
+		}
+	}
+}
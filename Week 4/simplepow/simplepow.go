@@ -0,0 +1,403 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package simplepow implements a minimal Keccak based proof-of-work engine.
+// pada package simplepow adalah implementasi sederhana consensus engine proof-of-work berbasis Keccak.
+package simplepow
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+var (
+	// two256 is a big integer representing 2^256.
+	two256 = new(big.Int).Exp(big.NewInt(2), big.NewInt(256), big.NewInt(0))
+
+	// minimumDifficulty is the lowest difficulty the engine will ever demand.
+	minimumDifficulty = big.NewInt(1024)
+
+	// difficultyBoundDivisor is the fraction of the parent difficulty that a
+	// single block may move the difficulty by.
+	difficultyBoundDivisor = big.NewInt(2048)
+)
+
+var (
+	errInvalidDifficulty = errors.New("invalid difficulty")
+	errInvalidPoW        = errors.New("invalid proof-of-work")
+	errUnclesNotAllowed  = errors.New("uncles not allowed")
+	errDescendingBatch   = errors.New("descending batches not supported")
+)
+
+// SimplePoW is a proof-of-work consensus engine searching for a nonce such that
+// keccak256(sealhash ++ nonce) falls below 2^256 / difficulty.
+// SimplePoW adalah consensus engine proof-of-work yang mencari nonce sehingga keccak256(sealhash ++ nonce) berada di bawah 2^256 / difficulty.
+type SimplePoW struct {
+	target uint64 // Block time in seconds the difficulty adjustment aims for
+
+	hashrate atomic.Uint64 // Hashes per second of the last sealing attempt (float64 bits)
+}
+
+// NewSimplePoW creates a proof-of-work engine whose difficulty adjusts towards
+// the given block time.
+// metoda 'new simple pow' akan membuat engine proof-of-work yang tingkat kesulitannya menyesuaikan ke waktu block yang diberikan.
+func NewSimplePoW(target time.Duration) *SimplePoW {
+	if target < time.Second {
+		target = time.Second
+	}
+	return &SimplePoW{target: uint64(target / time.Second)}
+}
+
+// Author implements consensus.Engine, returning the header's coinbase as the
+// proof-of-work verified author of the block.
+// metoda 'author' akan mengembalikan coinbase dari header sebagai pembuat block.
+func (pow *SimplePoW) Author(header *types.Header) (common.Address, error) {
+	return header.Coinbase, nil
+}
+
+// VerifyHeader checks whether a header conforms to the consensus rules of the
+// simple proof-of-work engine.
+// metoda 'verify header' akan mengecek apakah header sesuai dengan aturan consensus dari engine ini.
+func (pow *SimplePoW) VerifyHeader(chain consensus.ChainHeaderReader, header *types.Header, seal bool) error {
+	number := header.Number.Uint64()
+	if chain.GetHeader(header.Hash(), number) != nil {
+		return nil
+	}
+	// The genesis header has no parent to verify against
+	if number == 0 {
+		return wrapHeaderError(header, verifyGenesis(header))
+	}
+	parent := chain.GetHeader(header.ParentHash, number-1)
+	if parent == nil {
+		return wrapHeaderError(header, consensus.ErrUnknownAncestor)
+	}
+	return wrapHeaderError(header, pow.verifyHeader(chain, header, parent, seal))
+}
+
+// VerifyHeaders is similar to VerifyHeader, but verifies a batch of headers. The
+// headers are checked one after the other in a background goroutine. Malformed
+// batches are rejected with a single error before any header is checked. The
+// results channel is closed after the last result, or once aborted.
+// metoda 'verify headers' sama dengan 'verify header', namun memverifikasi header dalam batch di goroutine background.
+func (pow *SimplePoW) VerifyHeaders(chain consensus.ChainHeaderReader, headers []*types.Header, seals []bool) (chan<- struct{}, <-chan error) {
+	abort := make(chan struct{})
+
+	// Reject malformed batches as a whole, before any header is touched
+	descending, err := consensus.ValidateBatch(headers, seals)
+	if err == nil && descending {
+		err = errDescendingBatch
+	}
+	if err != nil {
+		results := make(chan error, 1)
+		results <- err
+		close(results)
+		return abort, results
+	}
+	results := make(chan error, len(headers))
+
+	go func() {
+		defer close(results)
+
+		for i := range headers {
+			err := pow.verifyBatchHeader(chain, headers, seals, i)
+			select {
+			case <-abort:
+				return
+			case results <- err:
+			}
+		}
+	}()
+	return abort, results
+}
+
+// verifyBatchHeader verifies the header at the given index of a batch against
+// its predecessor in the batch, or the chain for the first one. A panic during
+// verification is recovered and returned as the error of the header.
+func (pow *SimplePoW) verifyBatchHeader(chain consensus.ChainHeaderReader, headers []*types.Header, seals []bool, i int) (err error) {
+	header := headers[i]
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("verification panicked: %v", r)
+		}
+		if err != nil {
+			err = &consensus.HeaderVerifyError{
+				Index:  i,
+				Number: header.Number.Uint64(),
+				Hash:   header.Hash(),
+				Err:    wrapHeaderError(header, err),
+			}
+		}
+	}()
+	var parent *types.Header
+	switch {
+	case header.Number.Sign() == 0:
+		return verifyGenesis(header)
+	case i == 0:
+		parent = chain.GetHeader(header.ParentHash, header.Number.Uint64()-1)
+	case headers[i-1].Hash() == header.ParentHash:
+		parent = headers[i-1]
+	}
+	if parent == nil {
+		return consensus.ErrUnknownAncestor
+	}
+	return pow.verifyHeader(chain, header, parent, seals[i])
+}
+
+// wrapHeaderError wraps err, if any, with the number and hash of the header
+// failing verification.
+func wrapHeaderError(header *types.Header, err error) error {
+	if err == nil {
+		return nil
+	}
+	return fmt.Errorf("header %d (%s): %w", header.Number, header.Hash().TerminalString(), err)
+}
+
+// verifyGenesis checks a header at number 0, which has no parent to be
+// verified against.
+func verifyGenesis(header *types.Header) error {
+	if header.ParentHash != (common.Hash{}) {
+		return fmt.Errorf("%w: genesis with parent %x", consensus.ErrInvalidParentHash, header.ParentHash)
+	}
+	return nil
+}
+
+// verifyHeader checks a header against its parent and, optionally, its seal.
+func (pow *SimplePoW) verifyHeader(chain consensus.ChainHeaderReader, header, parent *types.Header, seal bool) error {
+	if hash := parent.Hash(); hash != header.ParentHash {
+		return fmt.Errorf("%w: have %x, want %x", consensus.ErrInvalidParentHash, header.ParentHash, hash)
+	}
+	if diff := new(big.Int).Sub(header.Number, parent.Number); diff.Cmp(common.Big1) != 0 {
+		return fmt.Errorf("%w: have %v, want %v", consensus.ErrInvalidNumber, header.Number, new(big.Int).Add(parent.Number, common.Big1))
+	}
+	if err := consensus.VerifyWithdrawals(chain.Config(), header); err != nil {
+		return err
+	}
+	if err := consensus.VerifyParentBeaconRoot(chain.Config(), header); err != nil {
+		return err
+	}
+	expected := pow.CalcDifficulty(chain, header.Time, parent)
+	if expected.Cmp(header.Difficulty) != 0 {
+		return errInvalidDifficulty
+	}
+	if seal {
+		return pow.VerifySeal(chain, header)
+	}
+	return nil
+}
+
+// VerifySeal implements consensus.Engine, checking whether the header's nonce
+// satisfies its difficulty.
+// metoda 'verify seal' akan mengecek apakah nonce pada header memenuhi tingkat kesulitannya.
+func (pow *SimplePoW) VerifySeal(chain consensus.ChainHeaderReader, header *types.Header) error {
+	if header.Difficulty.Sign() <= 0 {
+		return errInvalidDifficulty
+	}
+	target := new(big.Int).Div(two256, header.Difficulty)
+	digest := powHash(pow.SealHash(header), header.Nonce.Uint64())
+	if new(big.Int).SetBytes(digest).Cmp(target) > 0 {
+		return errInvalidPoW
+	}
+	return nil
+}
+
+// VerifyUncles implements consensus.Engine, rejecting any uncles since the simple
+// proof-of-work engine does not reward them.
+// metoda 'verify uncles' akan menolak semua uncle karena engine ini tidak mendukung uncle.
+func (pow *SimplePoW) VerifyUncles(chain consensus.ChainReader, block *types.Block) error {
+	if len(block.Uncles()) > 0 {
+		return errUnclesNotAllowed
+	}
+	return nil
+}
+
+// Prepare implements consensus.Engine, initializing the difficulty field of a
+// header to conform to the engine's retargeting rule.
+// metoda 'prepare' akan menginisialisasi field difficulty dari header sesuai aturan engine ini.
+func (pow *SimplePoW) Prepare(chain consensus.ChainHeaderReader, header *types.Header) error {
+	parent := chain.GetHeader(header.ParentHash, header.Number.Uint64()-1)
+	if parent == nil {
+		return consensus.ErrUnknownAncestor
+	}
+	header.Difficulty = pow.CalcDifficulty(chain, header.Time, parent)
+	return nil
+}
+
+// Finalize implements consensus.Engine. The simple proof-of-work engine pays no
+// block rewards, so only the withdrawals are credited.
+// metoda 'finalize' hanya mengkreditkan withdrawal karena engine ini tidak memberikan block rewards.
+func (pow *SimplePoW) Finalize(chain consensus.ChainHeaderReader, header *types.Header, state *state.StateDB, txs []*types.Transaction, uncles []*types.Header, withdrawals []*types.Withdrawal) {
+	consensus.ProcessWithdrawals(state, withdrawals)
+}
+
+// FinalizeAndAssemble implements consensus.Engine, setting the final state root
+// and assembling the block. Withdrawals are rejected before the Shanghai fork.
+// metoda 'finalize and assemble' akan mengatur state root akhir dan membangun block.
+func (pow *SimplePoW) FinalizeAndAssemble(chain consensus.ChainHeaderReader, header *types.Header, state *state.StateDB, txs []*types.Transaction, uncles []*types.Header, receipts []*types.Receipt, withdrawals []*types.Withdrawal) (*types.Block, error) {
+	if err := consensus.AssembleWithdrawals(chain.Config(), header, withdrawals); err != nil {
+		return nil, err
+	}
+	pow.Finalize(chain, header, state, txs, uncles, withdrawals)
+
+	header.Root = state.IntermediateRoot(true)
+	return types.NewBlockWithWithdrawals(header, txs, uncles, receipts, withdrawals, trie.NewStackTrie(nil)), nil
+}
+
+// Seal implements consensus.Engine, searching for a nonce that satisfies the
+// block's difficulty in a background goroutine.
+// metoda 'seal' akan mencari nonce yang memenuhi tingkat kesulitan block di goroutine background.
+func (pow *SimplePoW) Seal(chain consensus.ChainHeaderReader, block *types.Block, results chan<- *types.Block, stop <-chan struct{}) error {
+	header := block.Header()
+	if header.Difficulty == nil || header.Difficulty.Sign() <= 0 {
+		return errInvalidDifficulty
+	}
+	var (
+		hash   = pow.SealHash(header)
+		target = new(big.Int).Div(two256, header.Difficulty)
+	)
+	go func() {
+		var (
+			start    = time.Now()
+			attempts uint64
+		)
+		defer func() {
+			if elapsed := time.Since(start).Seconds(); elapsed > 0 {
+				pow.hashrate.Store(math.Float64bits(float64(attempts) / elapsed))
+			}
+		}()
+		for nonce := uint64(0); ; nonce++ {
+			// Check for abort requests every once in a while
+			if attempts%1024 == 0 {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+			}
+			attempts++
+			if new(big.Int).SetBytes(powHash(hash, nonce)).Cmp(target) <= 0 {
+				header.Nonce = types.EncodeNonce(nonce)
+				select {
+				case results <- block.WithSeal(header):
+				case <-stop:
+				}
+				return
+			}
+		}
+	}()
+	return nil
+}
+
+// SealHash returns the hash of a block prior to it being sealed. Besides the
+// legacy fields, it covers the base fee, withdrawals hash, blob gas fields and
+// parent beacon root whenever set by the later forks, so none of them can be
+// changed without invalidating the seal.
+// metoda 'seal hash' akan mengembalikan hash dari block sebelum dibungkus.
+func (pow *SimplePoW) SealHash(header *types.Header) (hash common.Hash) {
+	hasher := crypto.NewKeccakState()
+
+	enc := []interface{}{
+		header.ParentHash,
+		header.UncleHash,
+		header.Coinbase,
+		header.Root,
+		header.TxHash,
+		header.ReceiptHash,
+		header.Bloom,
+		header.Difficulty,
+		header.Number,
+		header.GasLimit,
+		header.GasUsed,
+		header.Time,
+		header.Extra,
+	}
+	if header.BaseFee != nil {
+		enc = append(enc, header.BaseFee)
+	}
+	if header.WithdrawalsHash != nil {
+		enc = append(enc, header.WithdrawalsHash)
+	}
+	if header.ExcessBlobGas != nil {
+		enc = append(enc, header.BlobGasUsed, header.ExcessBlobGas)
+	}
+	if header.ParentBeaconRoot != nil {
+		enc = append(enc, header.ParentBeaconRoot)
+	}
+	rlp.Encode(hasher, enc)
+	hasher.Sum(hash[:0])
+	return hash
+}
+
+// CalcDifficulty is the difficulty adjustment algorithm. Blocks arriving faster
+// than the target block time raise the difficulty by 1/2048 of the parent's,
+// slower blocks lower it by the same amount. A timestamp before the parent's
+// counts as a fast block.
+// metoda 'calc difficulty' akan menaikkan tingkat kesulitan jika block lebih cepat dari target, dan menurunkannya jika lebih lambat.
+func (pow *SimplePoW) CalcDifficulty(chain consensus.ChainHeaderReader, time uint64, parent *types.Header) *big.Int {
+	if parent.Difficulty == nil || parent.Difficulty.Cmp(minimumDifficulty) < 0 {
+		return new(big.Int).Set(minimumDifficulty)
+	}
+	adjust := new(big.Int).Div(parent.Difficulty, difficultyBoundDivisor)
+	diff := new(big.Int).Set(parent.Difficulty)
+	if time < parent.Time || time-parent.Time < pow.target {
+		diff.Add(diff, adjust)
+	} else {
+		diff.Sub(diff, adjust)
+	}
+	if diff.Cmp(minimumDifficulty) < 0 {
+		diff.Set(minimumDifficulty)
+	}
+	return diff
+}
+
+// APIs implements consensus.Engine, returning no RPC APIs.
+// metoda 'apis' tidak mengembalikan RPC API apapun.
+func (pow *SimplePoW) APIs(chain consensus.ChainHeaderReader) []rpc.API {
+	return nil
+}
+
+// Close implements consensus.Engine. There are no background threads to stop.
+// metoda 'close' tidak melakukan apa-apa karena tidak ada thread background.
+func (pow *SimplePoW) Close() error {
+	return nil
+}
+
+// Hashrate implements consensus.PoW, returning the hashes per second measured
+// during the most recent sealing attempt.
+// metoda 'hashrate' akan mengembalikan jumlah hash per detik dari percobaan seal terakhir.
+func (pow *SimplePoW) Hashrate() float64 {
+	return math.Float64frombits(pow.hashrate.Load())
+}
+
+// powHash computes keccak256(sealhash ++ nonce) with the nonce in big endian.
+func powHash(hash common.Hash, nonce uint64) []byte {
+	var enc [8]byte
+	binary.BigEndian.PutUint64(enc[:], nonce)
+	return crypto.Keccak256(hash[:], enc[:])
+}
@@ -0,0 +1,388 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package simplepow
+
+import (
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/consensus/consensustest"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// newTestChain creates a chain holding only a genesis header of the engine's
+// minimum difficulty, so sealing on top of it is quick.
+func newTestChain() (*consensustest.HeaderChain, *types.Header) {
+	genesis := &types.Header{
+		Number:     new(big.Int),
+		Difficulty: new(big.Int).Set(minimumDifficulty),
+		GasLimit:   params.GenesisGasLimit,
+		UncleHash:  types.EmptyUncleHash,
+	}
+	return consensustest.NewHeaderChain(params.TestChainConfig, genesis), genesis
+}
+
+// sealHeader prepares a child of the given parent and seals it.
+func sealHeader(t *testing.T, engine *SimplePoW, chain consensus.ChainHeaderReader, parent *types.Header) *types.Header {
+	t.Helper()
+
+	header := &types.Header{
+		ParentHash: parent.Hash(),
+		UncleHash:  types.EmptyUncleHash,
+		Number:     new(big.Int).Add(parent.Number, common.Big1),
+		GasLimit:   parent.GasLimit,
+		Time:       parent.Time + 1,
+	}
+	if err := engine.Prepare(chain, header); err != nil {
+		t.Fatalf("failed to prepare header: %v", err)
+	}
+	results, stop := make(chan *types.Block, 1), make(chan struct{})
+	defer close(stop)
+
+	if err := engine.Seal(chain, types.NewBlockWithHeader(header), results, stop); err != nil {
+		t.Fatalf("failed to seal block: %v", err)
+	}
+	select {
+	case block := <-results:
+		return block.Header()
+	case <-time.After(10 * time.Second):
+		t.Fatalf("sealing timed out")
+	}
+	return nil
+}
+
+// Tests that a header sealed by the engine passes its own verification, and
+// that a forged seal doesn't.
+func TestSealVerifyRoundTrip(t *testing.T) {
+	var (
+		engine         = NewSimplePoW(time.Second)
+		chain, genesis = newTestChain()
+	)
+	header := sealHeader(t, engine, chain, genesis)
+	if err := engine.VerifyHeader(chain, header, true); err != nil {
+		t.Fatalf("sealed header failed verification: %v", err)
+	}
+	if rate := engine.Hashrate(); rate <= 0 {
+		t.Errorf("hashrate not measured after sealing: %v", rate)
+	}
+	// The nonce only meets a target that low with negligible probability
+	forged := types.CopyHeader(header)
+	forged.Difficulty = new(big.Int).Lsh(common.Big1, 200)
+	if err := engine.VerifySeal(chain, forged); !errors.Is(err, errInvalidPoW) {
+		t.Errorf("forged seal error mismatch: have %v, want %v", err, errInvalidPoW)
+	}
+}
+
+// Tests that a genesis header is verified without looking up a parent, both
+// on its own and leading a batch.
+func TestVerifyGenesis(t *testing.T) {
+	var (
+		engine      = NewSimplePoW(time.Second)
+		chain, _    = newTestChain()
+		genesis     = &types.Header{Number: new(big.Int), Difficulty: big.NewInt(1), Time: 7}
+		bad         = &types.Header{Number: new(big.Int), Difficulty: big.NewInt(1), ParentHash: common.Hash{1}}
+		child       = sealHeader(t, engine, consensustest.NewHeaderChain(params.TestChainConfig, genesis), genesis)
+		headers     = []*types.Header{genesis, child}
+		seals       = []bool{true, true}
+		_, received = engine.VerifyHeaders(chain, headers, seals)
+	)
+	if err := engine.VerifyHeader(chain, genesis, true); err != nil {
+		t.Errorf("genesis header failed verification: %v", err)
+	}
+	if err := engine.VerifyHeader(chain, bad, true); !errors.Is(err, consensus.ErrInvalidParentHash) {
+		t.Errorf("genesis with parent error mismatch: have %v, want %v", err, consensus.ErrInvalidParentHash)
+	}
+	for i := range headers {
+		if err := <-received; err != nil {
+			t.Errorf("header %d: batch verification failed: %v", i, err)
+		}
+	}
+}
+
+// Tests that a timestamp before the parent's is treated as a fast block instead
+// of wrapping around into a slow one.
+func TestCalcDifficultyTimestampUnderflow(t *testing.T) {
+	var (
+		engine = NewSimplePoW(10 * time.Second)
+		parent = &types.Header{Number: big.NewInt(1), Difficulty: big.NewInt(2048000), Time: 100}
+	)
+	tests := []struct {
+		time uint64
+		want int64
+	}{
+		{105, 2049000}, // Faster than the target
+		{110, 2047000}, // Exactly the target
+		{100, 2049000}, // Same second as the parent
+		{50, 2049000},  // Before the parent
+	}
+	for i, tt := range tests {
+		if have := engine.CalcDifficulty(nil, tt.time, parent); have.Cmp(big.NewInt(tt.want)) != 0 {
+			t.Errorf("test %d: difficulty mismatch: have %v, want %d", i, have, tt.want)
+		}
+	}
+}
+
+// Tests that malformed batches fail with a single error on a closed results
+// channel, instead of panicking in the verifying goroutine.
+func TestVerifyHeadersMalformed(t *testing.T) {
+	var (
+		engine         = NewSimplePoW(time.Second)
+		chain, genesis = newTestChain()
+		first          = sealHeader(t, engine, chain, genesis)
+		second         = sealHeader(t, engine, consensustest.NewHeaderChain(params.TestChainConfig, genesis, first), first)
+	)
+	tests := []struct {
+		name    string
+		headers []*types.Header
+		seals   []bool
+	}{
+		{"short seals", []*types.Header{first, second}, []bool{true}},
+		{"nil header", []*types.Header{first, nil, second}, make([]bool, 3)},
+		{"descending", []*types.Header{second, first}, make([]bool, 2)},
+	}
+	for _, tt := range tests {
+		_, results := engine.VerifyHeaders(chain, tt.headers, tt.seals)
+		if err := <-results; err == nil {
+			t.Errorf("%s: malformed batch accepted", tt.name)
+		}
+		if _, ok := <-results; ok {
+			t.Errorf("%s: results not closed after the rejection", tt.name)
+		}
+	}
+}
+
+// panickingChain is a chain whose header lookups panic.
+type panickingChain struct {
+	*consensustest.HeaderChain
+}
+
+func (c panickingChain) GetHeader(hash common.Hash, number uint64) *types.Header {
+	panic("header lookup failed")
+}
+
+// Tests that a panic while verifying a header of a batch is recovered and
+// reported as the error of that header.
+func TestVerifyHeadersPanic(t *testing.T) {
+	var (
+		engine         = NewSimplePoW(time.Second)
+		chain, genesis = newTestChain()
+		header         = sealHeader(t, engine, chain, genesis)
+	)
+	abort, results := engine.VerifyHeaders(panickingChain{chain}, []*types.Header{header}, []bool{true})
+	defer close(abort)
+
+	var verr *consensus.HeaderVerifyError
+	if err := <-results; !errors.As(err, &verr) || verr.Index != 0 || !strings.Contains(err.Error(), "panicked") {
+		t.Errorf("panic not reported as the header's error: %v", err)
+	}
+}
+
+// Tests that the results channel of a batch is closed after the last result,
+// and soon after the batch is aborted at any point, so that ranging over it
+// always terminates. Meant to be run with -race.
+func TestVerifyHeadersClosed(t *testing.T) {
+	var (
+		engine         = NewSimplePoW(time.Second)
+		chain, genesis = newTestChain()
+		headers        []*types.Header
+		parent         = genesis
+	)
+	for i := 0; i < 8; i++ {
+		parent = sealHeader(t, engine, chain, parent)
+		chain.Insert(parent)
+		headers = append(headers, parent)
+	}
+	seals := make([]bool, len(headers))
+	for i := range seals {
+		seals[i] = true
+	}
+	// drain reads the results left until the channel is closed
+	drain := func(results <-chan error) int {
+		var count int
+		timeout := time.After(5 * time.Second)
+		for {
+			select {
+			case _, ok := <-results:
+				if !ok {
+					return count
+				}
+				count++
+			case <-timeout:
+				t.Fatalf("results not closed after %d results", count)
+			}
+		}
+	}
+	abort, results := engine.VerifyHeaders(chain, headers, seals)
+	if count := drain(results); count != len(headers) {
+		t.Errorf("result count mismatch: have %d, want %d", count, len(headers))
+	}
+	close(abort)
+
+	for i := 0; i < 100; i++ {
+		abort, results := engine.VerifyHeaders(chain, headers, seals)
+		for n := i % len(headers); n > 0; n-- {
+			<-results
+		}
+		close(abort)
+		drain(results)
+	}
+}
+
+// Tests that headers commit to their withdrawals exactly from the Shanghai fork
+// on, and that blocks may only be assembled with withdrawals from then on.
+func TestVerifyWithdrawals(t *testing.T) {
+	var (
+		engine   = NewSimplePoW(time.Second)
+		shanghai = uint64(10)
+		config   = &params.ChainConfig{ChainID: big.NewInt(1337), LondonBlock: new(big.Int), ShanghaiTime: &shanghai}
+		genesis  = &types.Header{Number: new(big.Int), Difficulty: new(big.Int).Set(minimumDifficulty), GasLimit: params.GenesisGasLimit, UncleHash: types.EmptyUncleHash}
+		chain    = consensustest.NewHeaderChain(config, genesis)
+		hash     = types.EmptyWithdrawalsHash
+	)
+	tests := []struct {
+		time     uint64
+		withHash bool
+		valid    bool
+	}{
+		{shanghai - 1, false, true},
+		{shanghai - 1, true, false},
+		{shanghai, false, false},
+		{shanghai, true, true},
+	}
+	for _, tt := range tests {
+		header := &types.Header{
+			ParentHash: genesis.Hash(),
+			UncleHash:  types.EmptyUncleHash,
+			Number:     big.NewInt(1),
+			GasLimit:   genesis.GasLimit,
+			Time:       tt.time,
+		}
+		if err := engine.Prepare(chain, header); err != nil {
+			t.Fatalf("failed to prepare header: %v", err)
+		}
+		if tt.withHash {
+			header.WithdrawalsHash = &hash
+		}
+		if err := engine.VerifyHeader(chain, header, false); (err == nil) != tt.valid {
+			t.Errorf("time %d, withdrawals hash %v: validity mismatch: have %v, want valid %v", tt.time, tt.withHash, err, tt.valid)
+		}
+	}
+	withdrawals := []*types.Withdrawal{{Index: 0, Validator: 1, Address: common.Address{0xa1}, Amount: 1}}
+	for _, time := range []uint64{shanghai - 1, shanghai} {
+		statedb, err := state.New(types.EmptyRootHash, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+		if err != nil {
+			t.Fatalf("failed to create state: %v", err)
+		}
+		header := &types.Header{Number: big.NewInt(1), Difficulty: new(big.Int).Set(minimumDifficulty), Time: time, GasLimit: params.GenesisGasLimit}
+
+		block, err := engine.FinalizeAndAssemble(chain, header, statedb, nil, nil, nil, withdrawals)
+		switch {
+		case time < shanghai && err == nil:
+			t.Errorf("time %d: withdrawals assembled before Shanghai", time)
+		case time >= shanghai && err != nil:
+			t.Errorf("time %d: failed to assemble block: %v", time, err)
+		case time >= shanghai && block.Header().WithdrawalsHash == nil:
+			t.Errorf("time %d: assembled block lacks the withdrawals hash", time)
+		}
+	}
+}
+
+// Tests that the seal hash covers the fields added by the later forks, so none
+// of them can be set or changed without invalidating the seal.
+func TestSealHashForkFields(t *testing.T) {
+	var (
+		engine = NewSimplePoW(time.Second)
+		header = &types.Header{Number: big.NewInt(1), Difficulty: big.NewInt(1), GasLimit: params.GenesisGasLimit}
+	)
+	tests := []struct {
+		name string
+		set  func(h *types.Header, v uint64)
+	}{
+		{"base fee", func(h *types.Header, v uint64) { h.BaseFee = new(big.Int).SetUint64(v) }},
+		{"withdrawals hash", func(h *types.Header, v uint64) { h.WithdrawalsHash = &common.Hash{byte(v)} }},
+		{"blob gas used", func(h *types.Header, v uint64) { h.BlobGasUsed, h.ExcessBlobGas = &v, new(uint64) }},
+		{"excess blob gas", func(h *types.Header, v uint64) { h.BlobGasUsed, h.ExcessBlobGas = new(uint64), &v }},
+		{"parent beacon root", func(h *types.Header, v uint64) { h.ParentBeaconRoot = &common.Hash{byte(v)} }},
+	}
+	for _, tt := range tests {
+		first, second := types.CopyHeader(header), types.CopyHeader(header)
+		tt.set(first, 1)
+		tt.set(second, 2)
+
+		if engine.SealHash(first) == engine.SealHash(header) {
+			t.Errorf("%s: seal hash unchanged by setting the field", tt.name)
+		}
+		if engine.SealHash(first) == engine.SealHash(second) {
+			t.Errorf("%s: seal hash unchanged by changing the field", tt.name)
+		}
+	}
+}
+
+// Tests that headers carry the parent beacon root exactly from the Cancun fork
+// on.
+func TestVerifyParentBeaconRoot(t *testing.T) {
+	var (
+		engine  = NewSimplePoW(time.Second)
+		cancun  = uint64(10)
+		config  = &params.ChainConfig{ChainID: big.NewInt(1337), LondonBlock: new(big.Int), ShanghaiTime: new(uint64), CancunTime: &cancun}
+		genesis = &types.Header{Number: new(big.Int), Difficulty: new(big.Int).Set(minimumDifficulty), GasLimit: params.GenesisGasLimit, UncleHash: types.EmptyUncleHash}
+		chain   = consensustest.NewHeaderChain(config, genesis)
+		root    = common.Hash{0x01}
+	)
+	tests := []struct {
+		time     uint64
+		withRoot bool
+		valid    bool
+	}{
+		{cancun - 1, false, true},
+		{cancun - 1, true, false},
+		{cancun, false, false},
+		{cancun, true, true},
+	}
+	for _, tt := range tests {
+		header := &types.Header{
+			ParentHash:      genesis.Hash(),
+			UncleHash:       types.EmptyUncleHash,
+			Number:          big.NewInt(1),
+			GasLimit:        genesis.GasLimit,
+			Time:            tt.time,
+			WithdrawalsHash: &types.EmptyWithdrawalsHash,
+		}
+		if err := engine.Prepare(chain, header); err != nil {
+			t.Fatalf("failed to prepare header: %v", err)
+		}
+		if tt.withRoot {
+			header.ParentBeaconRoot = &root
+		}
+		if err := engine.VerifyHeader(chain, header, false); (err == nil) != tt.valid {
+			t.Errorf("time %d, beacon root %v: validity mismatch: have %v, want valid %v", tt.time, tt.withRoot, err, tt.valid)
+		}
+	}
+}
+
+func TestConformance(t *testing.T) {
+	chain, _ := newTestChain()
+	consensustest.Run(t, NewSimplePoW(time.Second), chain)
+}
@@ -0,0 +1,179 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package consensus
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"math/big"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/lru"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// testVote is a block signed by one of the test signers, casting a vote.
+type testVote struct {
+	signer    int            // Index of the signer sealing the block
+	candidate common.Address // Account voted on, zero for no vote
+	authorize bool           // Whether to vote for adding or removing the candidate
+}
+
+// makeVotingChain creates a proof-of-authority chain whose genesis checkpoint
+// authorizes the first few of the keys, followed by a block for every vote.
+func makeVotingChain(t *testing.T, keys []*ecdsa.PrivateKey, authorized int, votes []testVote) (*testChain, []*types.Header) {
+	t.Helper()
+
+	genesis := testGenesis(1)
+	genesis.Extra = make([]byte, extraVanity, extraVanity+authorized*common.AddressLength+extraSeal)
+	for _, key := range keys[:authorized] {
+		addr := crypto.PubkeyToAddress(key.PublicKey)
+		genesis.Extra = append(genesis.Extra, addr[:]...)
+	}
+	genesis.Extra = append(genesis.Extra, make([]byte, extraSeal)...)
+	chain := newTestChain(frontierConfig, genesis)
+
+	headers := make([]*types.Header, len(votes))
+	parent := genesis
+	for i, vote := range votes {
+		header := &types.Header{
+			ParentHash: parent.Hash(),
+			UncleHash:  types.EmptyUncleHash,
+			Coinbase:   vote.candidate,
+			Number:     big.NewInt(int64(i + 1)),
+			GasLimit:   parent.GasLimit,
+			Time:       parent.Time + testCliqueConfig.Period,
+			Difficulty: diffNoTurn,
+		}
+		if vote.authorize {
+			header.Nonce = nonceAuthVote
+		}
+		signHeader(t, header, keys[vote.signer])
+		chain.insert(header)
+
+		headers[i], parent = header, header
+	}
+	return chain, headers
+}
+
+// sortedAddresses returns the addresses in ascending order.
+func sortedAddresses(addrs ...common.Address) []common.Address {
+	sort.Slice(addrs, func(i, j int) bool { return bytes.Compare(addrs[i][:], addrs[j][:]) < 0 })
+	return addrs
+}
+
+// Tests that a snapshot replayed from the genesis checkpoint admits a signer
+// once a majority voted for it, and drops one once a majority voted against.
+func TestSnapshotVoting(t *testing.T) {
+	keys := make([]*ecdsa.PrivateKey, 4)
+	addrs := make([]common.Address, 4)
+	for i := range keys {
+		keys[i], addrs[i] = newTestKey(t)
+	}
+	votes := []testVote{
+		{signer: 0, candidate: addrs[3], authorize: true},
+		{signer: 1, candidate: addrs[3], authorize: true}, // 2 of 3 votes, signer 3 admitted
+		{signer: 2},
+		{signer: 0, candidate: addrs[2]},
+		{signer: 1, candidate: addrs[2]},
+		{signer: 3, candidate: addrs[2]}, // 3 of 4 votes, signer 2 dropped
+	}
+	chain, headers := makeVotingChain(t, keys, 3, votes)
+	genesis := chain.GetHeaderByNumber(0)
+	sigcache := lru.NewCache[common.Hash, common.Address](inmemorySignatures)
+
+	tests := []struct {
+		number  int
+		signers []common.Address
+	}{
+		{0, sortedAddresses(addrs[0], addrs[1], addrs[2])},
+		{1, sortedAddresses(addrs[0], addrs[1], addrs[2])},
+		{2, sortedAddresses(addrs[0], addrs[1], addrs[2], addrs[3])},
+		{5, sortedAddresses(addrs[0], addrs[1], addrs[2], addrs[3])},
+		{6, sortedAddresses(addrs[0], addrs[1], addrs[3])},
+	}
+	for _, tt := range tests {
+		// Replay the votes directly on top of the genesis snapshot
+		snap := newSnapshot(testCliqueConfig, sigcache, 0, genesis.Hash(), tests[0].signers)
+		snap, err := snap.apply(headers[:tt.number])
+		if err != nil {
+			t.Fatalf("block %d: failed to apply votes: %v", tt.number, err)
+		}
+		if have := snap.signers(); !reflect.DeepEqual(have, tt.signers) {
+			t.Errorf("block %d: signers mismatch: have %x, want %x", tt.number, have, tt.signers)
+		}
+		// Derive the same snapshot through the engine, starting at the checkpoint
+		engine := NewPoA(testCliqueConfig, nil)
+		hash := genesis.Hash()
+		if tt.number > 0 {
+			hash = headers[tt.number-1].Hash()
+		}
+		derived, err := engine.snapshot(chain, uint64(tt.number), hash, nil)
+		if err != nil {
+			t.Fatalf("block %d: failed to derive snapshot: %v", tt.number, err)
+		}
+		if have := derived.signers(); !reflect.DeepEqual(have, tt.signers) {
+			t.Errorf("block %d: derived signers mismatch: have %x, want %x", tt.number, have, tt.signers)
+		}
+		if !engine.recents.Contains(hash) {
+			t.Errorf("block %d: derived snapshot not cached", tt.number)
+		}
+	}
+	// Votes around changed accounts are discarded once they pass
+	snap, _ := newSnapshot(testCliqueConfig, sigcache, 0, genesis.Hash(), tests[0].signers).apply(headers)
+	if len(snap.Votes) != 0 || len(snap.Tally) != 0 {
+		t.Errorf("leftover votes after passing: %d votes, %d tallies", len(snap.Votes), len(snap.Tally))
+	}
+}
+
+// Tests that a snapshot is served from the cache once derived, without
+// walking the headers again, and that it survives a round trip to disk.
+func TestSnapshotCache(t *testing.T) {
+	key, _ := newTestKey(t)
+	votes := make([]testVote, 8)
+	chain, headers := makeVotingChain(t, []*ecdsa.PrivateKey{key}, 1, votes)
+
+	engine := NewPoA(testCliqueConfig, rawdb.NewMemoryDatabase())
+	head := headers[len(headers)-1]
+	snap, err := engine.snapshot(chain, head.Number.Uint64(), head.Hash(), nil)
+	if err != nil {
+		t.Fatalf("failed to derive snapshot: %v", err)
+	}
+	// With the headers gone, only the cache can serve the snapshot
+	cached, err := engine.snapshot(newTestChain(frontierConfig), head.Number.Uint64(), head.Hash(), nil)
+	if err != nil {
+		t.Fatalf("failed to retrieve cached snapshot: %v", err)
+	}
+	if cached != snap {
+		t.Errorf("snapshot not served from the cache")
+	}
+	if err := snap.store(engine.db); err != nil {
+		t.Fatalf("failed to store snapshot: %v", err)
+	}
+	loaded, err := loadSnapshot(testCliqueConfig, nil, engine.db, snap.Hash)
+	if err != nil {
+		t.Fatalf("failed to load snapshot: %v", err)
+	}
+	if loaded.Number != snap.Number || !reflect.DeepEqual(loaded.signers(), snap.signers()) || !reflect.DeepEqual(loaded.Recents, snap.Recents) {
+		t.Errorf("loaded snapshot mismatch: have %+v, want %+v", loaded, snap)
+	}
+}
@@ -0,0 +1,83 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package consensus
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/lru"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// StatelessVerifier verifies the seals of headers given nothing but the header
+// itself and the chain config, without access to the chain, as needed by light
+// clients. Only the seal is checked, none of the parent-relative rules.
+// StatelessVerifier memverifikasi segel header hanya berdasarkan header dan konfigurasi chain, tanpa akses ke chain.
+type StatelessVerifier struct {
+	config     *params.ChainConfig // Chain config, telling whether sealing ended with the merge
+	pow        *powEngine          // Proof-of-work engine checking nonces with the configured hash
+	signatures *sigLRU             // Signatures of recent blocks to speed up signer recovery
+}
+
+// NewStatelessVerifier creates a stateless seal verifier for the chain config,
+// checking proof-of-work seals with the given hash function.
+// metoda 'new stateless verifier' akan membuat verifier segel tanpa state untuk konfigurasi chain tertentu.
+func NewStatelessVerifier(config *params.ChainConfig, algo HashAlgo) *StatelessVerifier {
+	return &StatelessVerifier{
+		config:     config,
+		pow:        NewPoW(WithHashAlgo(algo)),
+		signatures: lru.NewCache[common.Hash, common.Address](inmemorySignatures),
+	}
+}
+
+// VerifyPoWSeal checks whether the header's nonce satisfies the proof-of-work
+// target derived from the header's own difficulty. Past the merge, recognized
+// by a zero difficulty on a chain with a terminal total difficulty, there is no
+// seal and the zeroed out fields are checked instead.
+// metoda 'verify pow seal' akan mengecek apakah nonce header memenuhi target proof-of-work dari tingkat kesulitan
+// header itu sendiri.
+func (v *StatelessVerifier) VerifyPoWSeal(header *types.Header) error {
+	if v.postMerge(header) {
+		return verifyPostMerge(header)
+	}
+	return v.pow.VerifySeal(nil, header)
+}
+
+// VerifyPoASeal checks whether the header is signed by one of the given signers,
+// recovering the signer from the signature in the header's extra-data.
+// metoda 'verify poa seal' akan mengecek apakah header ditandatangani oleh salah satu signer yang diberikan.
+func (v *StatelessVerifier) VerifyPoASeal(header *types.Header, signers []common.Address) error {
+	signer, err := ecrecover(header, v.signatures)
+	if err != nil {
+		return err
+	}
+	for _, authorized := range signers {
+		if signer == authorized {
+			return nil
+		}
+	}
+	return errUnauthorizedSigner
+}
+
+// postMerge reports whether the header is a proof-of-stake header, which is
+// the case for a header without difficulty on a chain configured to merge.
+func (v *StatelessVerifier) postMerge(header *types.Header) bool {
+	if v.config == nil || v.config.TerminalTotalDifficulty == nil {
+		return false
+	}
+	return header.Difficulty != nil && header.Difficulty.Sign() == 0
+}
@@ -0,0 +1,83 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package consensus
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that the stateless verifier accepts a sealed proof-of-work header from
+// nothing but the header, and rejects it once its nonce is tampered with.
+func TestStatelessVerifierPoW(t *testing.T) {
+	var (
+		verifier = NewStatelessVerifier(frontierConfig, Keccak256)
+		genesis  = testGenesis(params.MinimumDifficulty.Int64())
+		header   = sealHeader(t, NewPoW(), newTestChain(frontierConfig, genesis), genesis)
+	)
+	if err := verifier.VerifyPoWSeal(header); err != nil {
+		t.Fatalf("sealed header failed verification: %v", err)
+	}
+	// Find a nonce missing the target of the header's difficulty
+	tampered := types.CopyHeader(header)
+	target := TargetFromDifficulty(tampered.Difficulty)
+	for nonce := tampered.Nonce.Uint64() + 1; ; nonce++ {
+		if new(big.Int).SetBytes(powHash(Keccak256, sealHash(tampered), nonce)).Cmp(target) > 0 {
+			tampered.Nonce = types.EncodeNonce(nonce)
+			break
+		}
+	}
+	if err := verifier.VerifyPoWSeal(tampered); !errors.Is(err, errInvalidPoW) {
+		t.Errorf("tampered nonce error mismatch: have %v, want %v", err, errInvalidPoW)
+	}
+	// Past the merge, only the zeroed out seal fields are checked
+	merged := NewStatelessVerifier(&params.ChainConfig{ChainID: big.NewInt(1), TerminalTotalDifficulty: big.NewInt(0)}, Keccak256)
+	pos := &types.Header{Number: big.NewInt(1), Difficulty: new(big.Int), UncleHash: types.EmptyUncleHash}
+	if err := merged.VerifyPoWSeal(pos); err != nil {
+		t.Errorf("proof-of-stake header failed verification: %v", err)
+	}
+	pos.Nonce = types.EncodeNonce(1)
+	if err := merged.VerifyPoWSeal(pos); !errors.Is(err, errInvalidPoSNonce) {
+		t.Errorf("proof-of-stake nonce error mismatch: have %v, want %v", err, errInvalidPoSNonce)
+	}
+}
+
+// Tests that the stateless verifier accepts a proof-of-authority header signed
+// by one of the given signers only.
+func TestStatelessVerifierPoA(t *testing.T) {
+	var (
+		verifier    = NewStatelessVerifier(frontierConfig, Keccak256)
+		key, signer = newTestKey(t)
+		_, other    = newTestKey(t)
+		header      = makeSignedHeaders(t, key, 1)[0]
+	)
+	if err := verifier.VerifyPoASeal(header, []common.Address{other, signer}); err != nil {
+		t.Errorf("authorized signer rejected: %v", err)
+	}
+	if err := verifier.VerifyPoASeal(header, []common.Address{other}); !errors.Is(err, errUnauthorizedSigner) {
+		t.Errorf("unauthorized signer error mismatch: have %v, want %v", err, errUnauthorizedSigner)
+	}
+	unsigned := &types.Header{Number: big.NewInt(1), Extra: make([]byte, extraSeal-1)}
+	if err := verifier.VerifyPoASeal(unsigned, []common.Address{signer}); !errors.Is(err, errMissingSignature) {
+		t.Errorf("missing signature error mismatch: have %v, want %v", err, errMissingSignature)
+	}
+}
@@ -0,0 +1,274 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package consensus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+var errNoEngines = errors.New("no consensus engines to switch between")
+
+// EngineSwitch activates a consensus engine from the given block number on.
+// EngineSwitch mengaktifkan sebuah consensus engine mulai dari nomor block tertentu.
+type EngineSwitch struct {
+	Block  uint64 // First block number handled by the engine
+	Engine Engine // Consensus engine active from Block onwards
+}
+
+// switchEngine is a consensus engine dispatching every call to the engine that
+// is active at the block number in question, allowing a chain to change its
+// consensus algorithm at configured heights.
+// switchEngine adalah consensus engine yang meneruskan setiap pemanggilan ke engine yang aktif pada nomor block
+// terkait, sehingga chain dapat berganti algoritma consensus pada ketinggian tertentu.
+type switchEngine struct {
+	engines []EngineSwitch // Engines ordered by ascending activation block
+}
+
+// NewSwitchEngine creates a consensus engine switching between the given
+// engines at their activation blocks. The switches must be ordered by strictly
+// ascending activation block, starting at the genesis block.
+// metoda 'new switch engine' akan membuat consensus engine yang berganti engine pada blok aktivasinya. Urutan harus
+// naik dan dimulai dari block genesis.
+func NewSwitchEngine(engines ...EngineSwitch) (*switchEngine, error) {
+	if len(engines) == 0 {
+		return nil, errNoEngines
+	}
+	if engines[0].Block != 0 {
+		return nil, fmt.Errorf("first consensus engine activates at block %d, not genesis", engines[0].Block)
+	}
+	for i := 1; i < len(engines); i++ {
+		if engines[i].Block <= engines[i-1].Block {
+			return nil, fmt.Errorf("consensus engine switch at block %d not after block %d", engines[i].Block, engines[i-1].Block)
+		}
+	}
+	return &switchEngine{engines: append([]EngineSwitch(nil), engines...)}, nil
+}
+
+// engineAt returns the consensus engine active at the given block number.
+func (s *switchEngine) engineAt(number *big.Int) Engine {
+	engine := s.engines[0].Engine
+	for _, sw := range s.engines[1:] {
+		if !number.IsUint64() || number.Uint64() >= sw.Block {
+			engine = sw.Engine
+		}
+	}
+	return engine
+}
+
+// Author implements Engine, delegating to the engine active at the header.
+// metoda 'author' akan meneruskan ke engine yang aktif pada header.
+func (s *switchEngine) Author(header *types.Header) (common.Address, error) {
+	return s.engineAt(header.Number).Author(header)
+}
+
+// VerifyHeader implements Engine, delegating to the engine active at the header.
+// metoda 'verify header' akan meneruskan ke engine yang aktif pada header.
+func (s *switchEngine) VerifyHeader(chain ChainHeaderReader, header *types.Header, seal bool) error {
+	return s.engineAt(header.Number).VerifyHeader(chain, header, seal)
+}
+
+// VerifyHeaders implements Engine, splitting the batch into runs of headers
+// handled by the same engine. Each run is verified by its engine, with the
+// headers of earlier runs visible as if already in the chain, and the results
+// are delivered in input order. The runs of a descending batch are verified
+// oldest first, so their results are held back until all runs are done.
+// metoda 'verify headers' akan membagi batch menjadi bagian-bagian yang ditangani oleh engine yang sama, lalu
+// mengirim hasilnya sesuai urutan input.
+func (s *switchEngine) VerifyHeaders(chain ChainHeaderReader, headers []*types.Header, seals []bool) (chan<- struct{}, <-chan error) {
+	descending, err := validateBatch(headers, seals)
+	if err != nil {
+		return failedBatch(err)
+	}
+	var (
+		abort   = make(chan struct{})
+		results = make(chan error, len(headers))
+		batch   = newBatchChain(chain)
+		runs    = s.splitRuns(headers)
+	)
+	// A run can only be verified once the run holding its parent is known, which
+	// for a descending batch is the one following it
+	var held []error
+	if descending {
+		for i, j := 0, len(runs)-1; i < j; i, j = i+1, j-1 {
+			runs[i], runs[j] = runs[j], runs[i]
+		}
+		held = make([]error, len(headers))
+	}
+	deliver := func(index int, err error) {
+		if held != nil {
+			held[index] = err
+		} else {
+			results <- err
+		}
+	}
+	go func() {
+		defer close(results)
+
+	verify:
+		for r, run := range runs {
+			start, end := run[0], run[1]
+			engine := s.engineAt(headers[start].Number)
+
+			subAbort, subResults := engine.VerifyHeaders(batch, headers[start:end], seals[start:end])
+			for i := start; i < end; i++ {
+				select {
+				case err, ok := <-subResults:
+					if !ok {
+						// The run stopped early, none of the headers left can be verified
+						close(subAbort)
+						if held == nil {
+							failRemaining(results, headers, i)
+							return
+						}
+						for _, run := range append([][2]int{{i, end}}, runs[r+1:]...) {
+							for j := run[0]; j < run[1]; j++ {
+								held[j] = headerVerifyError(headers[j], j, ErrUnknownAncestor)
+							}
+						}
+						break verify
+					}
+					// Translate the failing index from the run to the whole batch
+					var verr *HeaderVerifyError
+					if errors.As(err, &verr) {
+						err = &HeaderVerifyError{Index: verr.Index + start, Number: verr.Number, Hash: verr.Hash, Err: verr.Err}
+					}
+					deliver(i, err)
+				case <-abort:
+					close(subAbort)
+					return
+				}
+			}
+			batch.add(headers[start:end])
+		}
+		for _, err := range held {
+			results <- err
+		}
+	}()
+	return abort, results
+}
+
+// splitRuns splits a batch into runs of consecutive headers handled by the same
+// engine, returned in input order as their start and end indexes.
+func (s *switchEngine) splitRuns(headers []*types.Header) [][2]int {
+	var runs [][2]int
+	for start := 0; start < len(headers); {
+		engine := s.engineAt(headers[start].Number)
+		end := start + 1
+		for end < len(headers) && s.engineAt(headers[end].Number) == engine {
+			end++
+		}
+		runs = append(runs, [2]int{start, end})
+		start = end
+	}
+	return runs
+}
+
+// VerifyHeadersContext is similar to VerifyHeaders, but aborts verification
+// once the context is cancelled or its deadline elapses. Results are still
+// delivered in input order, headers left unverified report the context error.
+// metoda 'verify headers context' sama dengan 'verify headers', namun verifikasi dibatalkan ketika context dibatalkan atau melewati tenggat waktu.
+func (s *switchEngine) VerifyHeadersContext(ctx context.Context, chain ChainHeaderReader, headers []*types.Header, seals []bool) <-chan error {
+	return verifyHeadersContext(ctx, s, chain, headers, seals)
+}
+
+// VerifyHeadersWithContext is VerifyHeadersContext, under the name callers built
+// around contexts look for.
+// metoda 'verify headers with context' sama dengan 'verify headers context'.
+func (s *switchEngine) VerifyHeadersWithContext(ctx context.Context, chain ChainHeaderReader, headers []*types.Header, seals []bool) <-chan error {
+	return verifyHeadersContext(ctx, s, chain, headers, seals)
+}
+
+// VerifyUncles implements Engine, delegating to the engine active at the block.
+// metoda 'verify uncles' akan meneruskan ke engine yang aktif pada block.
+func (s *switchEngine) VerifyUncles(chain ChainReader, block *types.Block) error {
+	return s.engineAt(block.Number()).VerifyUncles(chain, block)
+}
+
+// VerifySeal implements Engine, delegating to the engine active at the header.
+// metoda 'verify seal' akan meneruskan ke engine yang aktif pada header.
+func (s *switchEngine) VerifySeal(chain ChainHeaderReader, header *types.Header) error {
+	return s.engineAt(header.Number).VerifySeal(chain, header)
+}
+
+// Prepare implements Engine, delegating to the engine active at the header.
+// metoda 'prepare' akan meneruskan ke engine yang aktif pada header.
+func (s *switchEngine) Prepare(chain ChainHeaderReader, header *types.Header) error {
+	return s.engineAt(header.Number).Prepare(chain, header)
+}
+
+// Finalize implements Engine, delegating to the engine active at the header.
+// metoda 'finalize' akan meneruskan ke engine yang aktif pada header.
+func (s *switchEngine) Finalize(chain ChainHeaderReader, header *types.Header, state *state.StateDB, txs []*types.Transaction, uncles []*types.Header, withdrawals []*types.Withdrawal) {
+	s.engineAt(header.Number).Finalize(chain, header, state, txs, uncles, withdrawals)
+}
+
+// FinalizeAndAssemble implements Engine, delegating to the engine active at the
+// header.
+// metoda 'finalize and assemble' akan meneruskan ke engine yang aktif pada header.
+func (s *switchEngine) FinalizeAndAssemble(chain ChainHeaderReader, header *types.Header, state *state.StateDB, txs []*types.Transaction, uncles []*types.Header, receipts []*types.Receipt, withdrawals []*types.Withdrawal) (*types.Block, error) {
+	return s.engineAt(header.Number).FinalizeAndAssemble(chain, header, state, txs, uncles, receipts, withdrawals)
+}
+
+// Seal implements Engine, delegating to the engine active at the block.
+// metoda 'seal' akan meneruskan ke engine yang aktif pada block.
+func (s *switchEngine) Seal(chain ChainHeaderReader, block *types.Block, results chan<- *types.Block, stop <-chan struct{}) error {
+	return s.engineAt(block.Number()).Seal(chain, block, results, stop)
+}
+
+// SealHash implements Engine, delegating to the engine active at the header.
+// metoda 'seal hash' akan meneruskan ke engine yang aktif pada header.
+func (s *switchEngine) SealHash(header *types.Header) common.Hash {
+	return s.engineAt(header.Number).SealHash(header)
+}
+
+// CalcDifficulty implements Engine, delegating to the engine active at the
+// block following parent. At a switch the new engine thus computes the
+// difficulty from a parent created by the old one.
+// metoda 'calc difficulty' akan meneruskan ke engine yang aktif pada block setelah parent.
+func (s *switchEngine) CalcDifficulty(chain ChainHeaderReader, time uint64, parent *types.Header) *big.Int {
+	return s.engineAt(new(big.Int).Add(parent.Number, common.Big1)).CalcDifficulty(chain, time, parent)
+}
+
+// APIs implements Engine, returning the RPC APIs of every engine.
+// metoda 'apis' akan mengembalikan RPC API dari semua engine.
+func (s *switchEngine) APIs(chain ChainHeaderReader) []rpc.API {
+	var apis []rpc.API
+	for _, sw := range s.engines {
+		apis = append(apis, sw.Engine.APIs(chain)...)
+	}
+	return apis
+}
+
+// Close implements Engine, closing every engine and returning the first error.
+// metoda 'close' akan menutup semua engine dan mengembalikan error pertama.
+func (s *switchEngine) Close() error {
+	var err error
+	for _, sw := range s.engines {
+		if cerr := sw.Engine.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
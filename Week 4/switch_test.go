@@ -0,0 +1,163 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package consensus
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// truncatingEngine is an engine rejecting every batch as a whole, delivering a
+// single error before closing the results channel.
+type truncatingEngine struct {
+	Engine
+}
+
+func (e truncatingEngine) VerifyHeaders(chain ChainHeaderReader, headers []*types.Header, seals []bool) (chan<- struct{}, <-chan error) {
+	return failedBatch(errInvalidPoW)
+}
+
+// Tests that the switch engine validates the order of its switches.
+func TestNewSwitchEngine(t *testing.T) {
+	tests := [][]EngineSwitch{
+		nil,
+		{{Block: 1, Engine: NewFaker()}},
+		{{Block: 0, Engine: NewFaker()}, {Block: 5, Engine: NewFaker()}, {Block: 5, Engine: NewFaker()}},
+	}
+	for i, switches := range tests {
+		if _, err := NewSwitchEngine(switches...); err == nil {
+			t.Errorf("test %d: invalid switches accepted", i)
+		}
+	}
+}
+
+// Tests that a batch crossing the switch height has every header verified by
+// the engine active at its number, failures reported at their batch index.
+func TestSwitchEngineVerifyHeaders(t *testing.T) {
+	genesis := testGenesis(100)
+	headers := makeHeaders(NewFaker(), newTestChain(frontierConfig, genesis), genesis, 10, 1)
+
+	tests := []struct {
+		before, after Engine
+		want          map[int]error
+	}{
+		{NewFaker(), NewFaker(), nil},
+		{NewFaker(), NewFakeFailer(7), map[int]error{6: errInvalidPoW}},
+		{NewFakeFailer(3), NewFaker(), map[int]error{2: errInvalidPoW}},
+		{NewFakeFailer(7), NewFaker(), nil},
+		{NewFaker(), truncatingEngine{NewFaker()}, map[int]error{
+			5: errInvalidPoW, 6: ErrUnknownAncestor, 7: ErrUnknownAncestor, 8: ErrUnknownAncestor, 9: ErrUnknownAncestor,
+		}},
+	}
+	for i, tt := range tests {
+		engine, err := NewSwitchEngine(EngineSwitch{Block: 0, Engine: tt.before}, EngineSwitch{Block: 6, Engine: tt.after})
+		if err != nil {
+			t.Fatalf("test %d: failed to create engine: %v", i, err)
+		}
+		_, results := engine.VerifyHeaders(newTestChain(frontierConfig, genesis), headers, make([]bool, len(headers)))
+
+		var index int
+		for err := range results {
+			if want := tt.want[index]; !errors.Is(err, want) {
+				t.Errorf("test %d, header %d: error mismatch: have %v, want %v", i, index, err, want)
+			}
+			var verr *HeaderVerifyError
+			if errors.As(err, &verr) && verr.Index != index {
+				t.Errorf("test %d, header %d: index mismatch: have %d", i, index, verr.Index)
+			}
+			index++
+		}
+		if index != len(headers) {
+			t.Errorf("test %d: result count mismatch: have %d, want %d", i, index, len(headers))
+		}
+	}
+}
+
+// Tests that a descending batch crossing the switch height, as handed out by a
+// backwards sync, has its oldest run verified first so that the newer run finds
+// its parent, the results still delivered in input order.
+func TestSwitchEngineVerifyHeadersDescending(t *testing.T) {
+	genesis := testGenesis(params.MinimumDifficulty.Int64())
+	ascending := makeHeaders(NewPoW(), newTestChain(frontierConfig, genesis), genesis, 10, 10)
+
+	headers := make([]*types.Header, len(ascending))
+	for i, header := range ascending {
+		headers[len(headers)-1-i] = header
+	}
+	tests := []struct {
+		before, after Engine
+		want          map[int]error
+	}{
+		{NewPoW(), NewPoW(), nil},
+		{NewPoW(), truncatingEngine{NewPoW()}, map[int]error{
+			0: errInvalidPoW, 1: ErrUnknownAncestor, 2: ErrUnknownAncestor, 3: ErrUnknownAncestor, 4: ErrUnknownAncestor,
+		}},
+		{truncatingEngine{NewPoW()}, NewPoW(), map[int]error{
+			0: ErrUnknownAncestor, 1: ErrUnknownAncestor, 2: ErrUnknownAncestor, 3: ErrUnknownAncestor, 4: ErrUnknownAncestor,
+			5: errInvalidPoW, 6: ErrUnknownAncestor, 7: ErrUnknownAncestor, 8: ErrUnknownAncestor, 9: ErrUnknownAncestor,
+		}},
+	}
+	for i, tt := range tests {
+		engine, err := NewSwitchEngine(EngineSwitch{Block: 0, Engine: tt.before}, EngineSwitch{Block: 6, Engine: tt.after})
+		if err != nil {
+			t.Fatalf("test %d: failed to create engine: %v", i, err)
+		}
+		_, results := engine.VerifyHeaders(newTestChain(frontierConfig, genesis), headers, make([]bool, len(headers)))
+
+		var index int
+		for err := range results {
+			if want := tt.want[index]; !errors.Is(err, want) {
+				t.Errorf("test %d, header %d: error mismatch: have %v, want %v", i, index, err, want)
+			}
+			var verr *HeaderVerifyError
+			if errors.As(err, &verr) && verr.Index != index {
+				t.Errorf("test %d, header %d: index mismatch: have %d", i, index, verr.Index)
+			}
+			index++
+		}
+		if index != len(headers) {
+			t.Errorf("test %d: result count mismatch: have %d, want %d", i, index, len(headers))
+		}
+	}
+}
+
+// Tests that the difficulty of the first block after the switch is calculated
+// by the new engine, from the parent handled by the old one.
+func TestSwitchEngineCalcDifficulty(t *testing.T) {
+	engine, err := NewSwitchEngine(EngineSwitch{Block: 0, Engine: NewFaker()}, EngineSwitch{Block: 5, Engine: NewDev(0)})
+	if err != nil {
+		t.Fatalf("failed to create engine: %v", err)
+	}
+	tests := []struct {
+		parent uint64
+		want   int64
+	}{
+		{3, 100}, // Fake engine keeps the parent difficulty
+		{4, 1},   // Developer engine at the switch
+		{5, 1},   // Developer engine past the switch
+	}
+	for _, tt := range tests {
+		parent := &types.Header{Number: new(big.Int).SetUint64(tt.parent), Difficulty: big.NewInt(100)}
+		if have := engine.CalcDifficulty(nil, 1, parent); have.Cmp(big.NewInt(tt.want)) != 0 {
+			t.Errorf("parent %d: difficulty mismatch: have %v, want %d", tt.parent, have, tt.want)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package consensus
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// uncleAncestor is a recent ancestor of a block along with the hashes of the
+// uncles it already included.
+type uncleAncestor struct {
+	header *types.Header
+	uncles []common.Hash
+}
+
+// uncleWindow is the set of recent ancestors the uncles of a block are checked
+// against, newest first. Consecutive blocks share most of their window, so it
+// can be slid forward instead of being gathered from the chain again.
+type uncleWindow struct {
+	ancestors []uncleAncestor
+}
+
+// newUncleWindow gathers the last maxUncleDepth ancestors of the block from the
+// chain, stopping early at genesis or at the first unknown ancestor.
+func newUncleWindow(chain ChainReader, block *types.Block) (*uncleWindow, error) {
+	window := new(uncleWindow)
+
+	number, parent := block.NumberU64()-1, block.ParentHash()
+	for i := 0; i < maxUncleDepth; i++ {
+		header := chain.GetHeader(parent, number)
+		if header == nil {
+			break
+		}
+		ancestor := uncleAncestor{header: header}
+
+		// If the ancestor doesn't have any uncles, we don't have to iterate them
+		if header.UncleHash != types.EmptyUncleHash {
+			// Need to add those uncles to the banned list too, skipping them
+			// could let an already rewarded uncle be paid twice
+			body := chain.GetBlock(parent, number)
+			if body == nil {
+				return nil, ErrUnknownAncestor
+			}
+			for _, uncle := range body.Uncles() {
+				ancestor.uncles = append(ancestor.uncles, uncle.Hash())
+			}
+		}
+		window.ancestors = append(window.ancestors, ancestor)
+
+		// Stop at genesis, there are no ancestors left to walk
+		if number == 0 {
+			break
+		}
+		parent, number = header.ParentHash, number-1
+	}
+	return window, nil
+}
+
+// slide moves the window forward onto the child of the given block, making the
+// block the newest ancestor and dropping the oldest one beyond maxUncleDepth.
+func (w *uncleWindow) slide(block *types.Block) {
+	ancestor := uncleAncestor{header: block.Header()}
+	for _, uncle := range block.Uncles() {
+		ancestor.uncles = append(ancestor.uncles, uncle.Hash())
+	}
+	w.ancestors = append([]uncleAncestor{ancestor}, w.ancestors...)
+	if len(w.ancestors) > maxUncleDepth {
+		w.ancestors = w.ancestors[:maxUncleDepth]
+	}
+}
+
+// sets returns the hashes the uncles of the block may not match, every uncle
+// already included and the block itself, and the headers of the ancestors by
+// hash, the block itself included.
+func (w *uncleWindow) sets(block *types.Block) (map[common.Hash]struct{}, map[common.Hash]*types.Header) {
+	uncles, ancestors := make(map[common.Hash]struct{}), make(map[common.Hash]*types.Header)
+	for _, ancestor := range w.ancestors {
+		ancestors[ancestor.header.Hash()] = ancestor.header
+		for _, uncle := range ancestor.uncles {
+			uncles[uncle] = struct{}{}
+		}
+	}
+	ancestors[block.Hash()] = block.Header()
+	uncles[block.Hash()] = struct{}{}
+	return uncles, ancestors
+}
+
+// VerifyUnclesBatch verifies the uncles of a batch of blocks, like a call to
+// VerifyUncles for each of them. The window of recent ancestors is gathered
+// from the chain once and slid forward across consecutive blocks of the batch,
+// falling back to a fresh walk whenever a block is not the child of the one
+// before it. The results are delivered in input order on the returned error
+// channel, and the quit channel aborts the operation.
+// metoda 'verify uncles batch' akan memverifikasi uncle dari sekumpulan block sekaligus. Jendela ancestor terbaru
+// hanya dikumpulkan sekali lalu digeser untuk block yang berurutan.
+func (pow *powEngine) VerifyUnclesBatch(chain ChainReader, blocks []*types.Block) (chan<- struct{}, <-chan error) {
+	var (
+		abort   = make(chan struct{})
+		results = make(chan error, len(blocks))
+	)
+	go func() {
+		defer close(results)
+
+		var window *uncleWindow
+		for i, block := range blocks {
+			select {
+			case <-abort:
+				return
+			default:
+			}
+			// Slide the window onto the block if it extends the previous one,
+			// otherwise it has to be gathered from the chain again
+			if window != nil {
+				if i > 0 && block.ParentHash() == blocks[i-1].Hash() {
+					window.slide(blocks[i-1])
+				} else {
+					window = nil
+				}
+			}
+			var err error
+			window, err = pow.verifyUncles(chain, block, window)
+			results <- err
+		}
+	}()
+	return abort, results
+}
@@ -0,0 +1,445 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package consensus
+
+import (
+	"errors"
+	"math/big"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+// Tests the uncle rules against valid and invalid uncle sets of a block on top
+// of a sealed chain.
+func TestVerifyUncles(t *testing.T) {
+	var (
+		engine  = NewTester()
+		uncler  = NewTester()
+		genesis = testGenesis(params.MinimumDifficulty.Int64())
+		chain   = newTestChain(frontierConfig, genesis)
+		blocks  = []*types.Header{genesis}
+	)
+	// Seal the canonical chain, and let a second miner seal the side blocks
+	for i := 0; i < 10; i++ {
+		header := sealHeader(t, engine, chain, blocks[i])
+		chain.insert(header)
+		blocks = append(blocks, header)
+	}
+	uncler.SetEtherbase(common.Address{0xaa})
+	sibling := func(number int) *types.Header {
+		return sealHeader(t, uncler, chain, blocks[number-1])
+	}
+	var (
+		uncle9  = sibling(9)
+		uncle8  = sibling(8)
+		uncle7  = sibling(7)
+		uncle2  = sibling(2)
+		uncle11 = sibling(11)
+	)
+	tests := []struct {
+		uncles []*types.Header
+		want   error
+	}{
+		{nil, nil},
+		{[]*types.Header{uncle9}, nil},
+		{[]*types.Header{uncle9, uncle8}, nil},
+		{[]*types.Header{uncle9, uncle8, uncle7}, ErrTooManyUncles},
+		{[]*types.Header{uncle9, uncle9}, ErrDuplicateUncle},
+		{[]*types.Header{blocks[8]}, ErrUncleIsAncestor},
+		{[]*types.Header{uncle2}, ErrUncleTooDeep},
+		{[]*types.Header{uncle11}, ErrDanglingUncle},
+	}
+	for i, tt := range tests {
+		header := &types.Header{
+			ParentHash: blocks[10].Hash(),
+			Number:     big.NewInt(11),
+			Time:       blocks[10].Time + 1,
+		}
+		block := types.NewBlock(header, nil, tt.uncles, nil, trie.NewStackTrie(nil))
+		if err := engine.VerifyUncles(chain, block); !errors.Is(err, tt.want) {
+			t.Errorf("test %d: error mismatch: have %v, want %v", i, err, tt.want)
+		}
+	}
+	// An uncle set disagreeing with the header's uncle hash is rejected outright
+	header := &types.Header{ParentHash: blocks[10].Hash(), Number: big.NewInt(11), UncleHash: types.EmptyUncleHash}
+	block := types.NewBlockWithHeader(header).WithBody(nil, []*types.Header{uncle9})
+	if err := engine.VerifyUncles(chain, block); !errors.Is(err, ErrInvalidUncleHash) {
+		t.Errorf("uncle hash mismatch error: have %v, want %v", err, ErrInvalidUncleHash)
+	}
+}
+
+// unreachableChain is a chain whose header and block lookups panic, proving that
+// a check never walks the ancestry.
+type unreachableChain struct {
+	*testChain
+}
+
+func (c unreachableChain) GetHeader(hash common.Hash, number uint64) *types.Header {
+	panic("header lookup")
+}
+
+func (c unreachableChain) GetBlock(hash common.Hash, number uint64) *types.Block {
+	panic("block lookup")
+}
+
+// Tests that blocks without uncles are accepted without walking the ancestry,
+// and that an uncle hash disagreeing with the uncle set is rejected either way.
+func TestVerifyUnclesEmpty(t *testing.T) {
+	var (
+		engine = NewPoW()
+		chain  = unreachableChain{newTestChain(frontierConfig, testGenesis(params.MinimumDifficulty.Int64()))}
+		uncle  = &types.Header{Number: big.NewInt(9), Difficulty: common.Big1}
+	)
+	tests := []struct {
+		hash   common.Hash
+		uncles []*types.Header
+		want   error
+	}{
+		{types.EmptyUncleHash, nil, nil},
+		{types.EmptyUncleHash, []*types.Header{uncle}, ErrInvalidUncleHash},
+		{types.CalcUncleHash([]*types.Header{uncle}), nil, ErrInvalidUncleHash},
+	}
+	for i, tt := range tests {
+		header := &types.Header{ParentHash: common.Hash{0x01}, Number: big.NewInt(11), UncleHash: tt.hash}
+		block := types.NewBlockWithHeader(header).WithBody(nil, tt.uncles)
+		if err := engine.VerifyUncles(chain, block); !errors.Is(err, tt.want) {
+			t.Errorf("test %d: error mismatch: have %v, want %v", i, err, tt.want)
+		}
+	}
+}
+
+// Tests the uncle depth limit on its exact boundary: an uncle six generations
+// behind the including block is accepted, one seven generations behind is not,
+// and neither is one whose parent isn't an ancestor.
+func TestVerifyUnclesDepth(t *testing.T) {
+	var (
+		engine  = NewTester()
+		uncler  = NewTester()
+		genesis = testGenesis(params.MinimumDifficulty.Int64())
+		chain   = newTestChain(frontierConfig, genesis)
+		blocks  = []*types.Header{genesis}
+	)
+	for i := 0; i < 10; i++ {
+		header := sealHeader(t, engine, chain, blocks[i])
+		chain.insert(header)
+		blocks = append(blocks, header)
+	}
+	uncler.SetEtherbase(common.Address{0xaa})
+
+	// Uncles of block 11 must descend from one of blocks 4 to 10
+	deepest := sealHeader(t, uncler, chain, blocks[4]) // Uncle #5, 6 generations behind
+	tooDeep := sealHeader(t, uncler, chain, blocks[3]) // Uncle #4, 7 generations behind
+	side := newTestChain(frontierConfig, append(append([]*types.Header{}, blocks[:4]...), tooDeep)...)
+	orphan := sealHeader(t, uncler, side, tooDeep) // Uncle #5 on a side chain
+
+	tests := []struct {
+		name  string
+		uncle *types.Header
+		want  error
+	}{
+		{"depth 6", deepest, nil},
+		{"depth 7", tooDeep, ErrUncleTooDeep},
+		{"foreign parent", orphan, ErrDanglingUncle},
+	}
+	for _, tt := range tests {
+		header := &types.Header{
+			ParentHash: blocks[10].Hash(),
+			Number:     big.NewInt(11),
+			Time:       blocks[10].Time + 1,
+		}
+		block := types.NewBlock(header, nil, []*types.Header{tt.uncle}, nil, trie.NewStackTrie(nil))
+		if err := engine.VerifyUncles(chain, block); !errors.Is(err, tt.want) {
+			t.Errorf("%s: error mismatch: have %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
+
+// Tests that an uncle already included by a recent ancestor is rejected when
+// included again, that the ancestor bodies are required for the check, and that
+// the ancestor walk stops at genesis.
+func TestVerifyUnclesIncludedByAncestor(t *testing.T) {
+	var (
+		engine  = NewTester()
+		uncler  = NewTester()
+		genesis = testGenesis(params.MinimumDifficulty.Int64())
+		chain   = newTestChain(frontierConfig, genesis)
+		blocks  = []*types.Header{genesis}
+	)
+	uncler.SetEtherbase(common.Address{0xaa})
+
+	// Block 2 may include a sibling of block 1, the walk ending at genesis
+	blocks = append(blocks, sealHeader(t, engine, chain, genesis))
+	chain.insert(blocks[1])
+	uncle1 := sealHeader(t, uncler, chain, genesis)
+
+	child := func(parent *types.Header, uncles ...*types.Header) *types.Block {
+		header := &types.Header{
+			ParentHash: parent.Hash(),
+			Number:     new(big.Int).Add(parent.Number, common.Big1),
+			Time:       parent.Time + 1,
+		}
+		return types.NewBlock(header, nil, uncles, nil, trie.NewStackTrie(nil))
+	}
+	if err := engine.VerifyUncles(chain, child(blocks[1], uncle1)); err != nil {
+		t.Fatalf("uncle next to genesis rejected: %v", err)
+	}
+	for i := 1; i < 5; i++ {
+		header := sealHeader(t, engine, chain, blocks[i])
+		chain.insert(header)
+		blocks = append(blocks, header)
+	}
+	// Block 6 includes a sibling of block 4, block 7 tries to include it again
+	uncle4 := sealHeader(t, uncler, chain, blocks[3])
+	block6 := child(blocks[5], uncle4)
+	if err := engine.VerifyUncles(chain, block6); err != nil {
+		t.Fatalf("first inclusion rejected: %v", err)
+	}
+	block7 := child(block6.Header(), uncle4)
+
+	// Without the body of block 6 its uncles can't be checked
+	bodiless := newTestChain(frontierConfig, append(append([]*types.Header{}, blocks...), block6.Header())...)
+	if err := engine.VerifyUncles(bodiless, block7); !errors.Is(err, ErrUnknownAncestor) {
+		t.Errorf("missing body error mismatch: have %v, want %v", err, ErrUnknownAncestor)
+	}
+	chain.insertBlock(block6)
+	if err := engine.VerifyUncles(chain, block7); !errors.Is(err, ErrDuplicateUncle) {
+		t.Errorf("second inclusion error mismatch: have %v, want %v", err, ErrDuplicateUncle)
+	}
+	// The batch slides its window over block 6 instead of reading its body
+	abort, results := engine.VerifyUnclesBatch(bodiless, []*types.Block{block6, block7})
+	defer close(abort)
+
+	if err := <-results; err != nil {
+		t.Errorf("batched first inclusion rejected: %v", err)
+	}
+	if err := <-results; !errors.Is(err, ErrDuplicateUncle) {
+		t.Errorf("batched second inclusion error mismatch: have %v, want %v", err, ErrDuplicateUncle)
+	}
+}
+
+// Tests that the parent's sibling is a valid uncle, the parent itself isn't,
+// and neither is a canonical block included by a block on a side chain, where
+// it's no ancestor.
+func TestVerifyUnclesCanonical(t *testing.T) {
+	var (
+		engine  = NewTester()
+		uncler  = NewTester()
+		genesis = testGenesis(params.MinimumDifficulty.Int64())
+		chain   = newTestChain(frontierConfig, genesis)
+		blocks  = []*types.Header{genesis}
+	)
+	for i := 0; i < 10; i++ {
+		header := sealHeader(t, engine, chain, blocks[i])
+		chain.insert(header)
+		blocks = append(blocks, header)
+	}
+	uncler.SetEtherbase(common.Address{0xaa})
+	sibling := sealHeader(t, uncler, chain, blocks[9])
+
+	// Make the sibling known without making it canonical
+	chain.headers[sibling.Hash()] = sibling
+
+	child := func(parent *types.Header, uncle *types.Header) *types.Block {
+		header := &types.Header{
+			ParentHash: parent.Hash(),
+			Number:     new(big.Int).Add(parent.Number, common.Big1),
+			Time:       parent.Time + 1,
+		}
+		return types.NewBlock(header, nil, []*types.Header{uncle}, nil, trie.NewStackTrie(nil))
+	}
+	tests := []struct {
+		name  string
+		block *types.Block
+		want  error
+	}{
+		{"parent's sibling", child(blocks[10], sibling), nil},
+		{"parent", child(blocks[10], blocks[10]), ErrUncleIsAncestor},
+		{"canonical on side chain", child(sibling, blocks[10]), ErrUncleIsCanonical},
+	}
+	for _, tt := range tests {
+		if err := engine.VerifyUncles(chain, tt.block); !errors.Is(err, tt.want) {
+			t.Errorf("%s: error mismatch: have %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
+
+// Tests that of several uncles failing their header verification concurrently,
+// the lowest index is always reported, and that no verifier outlives the call.
+func TestVerifyUnclesFirstError(t *testing.T) {
+	var (
+		engine  = NewPoW()
+		genesis = testGenesis(params.MinimumDifficulty.Int64())
+		chain   = newTestChain(frontierConfig, genesis)
+		blocks  = makeHeaders(engine, chain, genesis, 3, 1)
+	)
+	// Two unsealed siblings of block 2, with nonces missing the target for sure
+	uncles := make([]*types.Header, 2)
+	for i := range uncles {
+		uncle := types.CopyHeader(blocks[1])
+		uncle.Coinbase = common.Address{byte(i + 1)}
+
+		target := TargetFromDifficulty(uncle.Difficulty)
+		for nonce := uint64(0); ; nonce++ {
+			if new(big.Int).SetBytes(powHash(Keccak256, engine.SealHash(uncle), nonce)).Cmp(target) > 0 {
+				uncle.Nonce = types.EncodeNonce(nonce)
+				break
+			}
+		}
+		uncles[i] = uncle
+	}
+	header := &types.Header{
+		ParentHash: blocks[2].Hash(),
+		Number:     big.NewInt(4),
+		Time:       blocks[2].Time + 1,
+	}
+	block := types.NewBlock(header, nil, uncles, nil, trie.NewStackTrie(nil))
+
+	goroutines := runtime.NumGoroutine()
+	for i := 0; i < 100; i++ {
+		err := engine.VerifyUncles(chain, block)
+		if !errors.Is(err, errInvalidPoW) {
+			t.Fatalf("run %d: error mismatch: have %v, want %v", i, err, errInvalidPoW)
+		}
+		if !strings.Contains(err.Error(), uncles[0].Hash().TerminalString()) {
+			t.Fatalf("run %d: error not about the first uncle: %v", i, err)
+		}
+	}
+	waitGoroutines(t, goroutines)
+}
+
+// slowAlgo is Keccak-256 iterated a number of rounds, modelling a proof-of-work
+// function whose seals are costly to verify.
+type slowAlgo int
+
+func (rounds slowAlgo) Sum(data []byte) []byte {
+	for i := 0; i < int(rounds); i++ {
+		data = crypto.Keccak256(data)
+	}
+	return data
+}
+
+// Benchmarks the verification of a block with two uncles whose seals are costly
+// to verify, with the uncle headers verified one at a time and concurrently. The
+// speedup is the ratio of the two, close to 2x on multiple cores.
+func BenchmarkVerifyUncles(b *testing.B) {
+	var (
+		algo    = slowAlgo(5_000)
+		engine  = NewTester(WithHashAlgo(algo))
+		uncler  = NewTester(WithHashAlgo(algo))
+		genesis = testGenesis(params.MinimumDifficulty.Int64())
+		chain   = newTestChain(frontierConfig, genesis)
+		blocks  = []*types.Header{genesis}
+	)
+	for i := 0; i < 3; i++ {
+		header := sealHeader(b, engine, chain, blocks[i])
+		chain.insert(header)
+		blocks = append(blocks, header)
+	}
+	uncler.SetEtherbase(common.Address{0xaa})
+	uncles := []*types.Header{sealHeader(b, uncler, chain, blocks[1])}
+	uncler.SetEtherbase(common.Address{0xbb})
+	uncles = append(uncles, sealHeader(b, uncler, chain, blocks[1]))
+
+	header := &types.Header{
+		ParentHash: blocks[3].Hash(),
+		Number:     big.NewInt(4),
+		Time:       blocks[3].Time + 1,
+	}
+	block := types.NewBlock(header, nil, uncles, nil, trie.NewStackTrie(nil))
+
+	for _, bb := range []struct {
+		name  string
+		procs int
+	}{
+		{"serial", 1},
+		{"concurrent", runtime.GOMAXPROCS(0)},
+	} {
+		b.Run(bb.name, func(b *testing.B) {
+			defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(bb.procs))
+			for i := 0; i < b.N; i++ {
+				if err := engine.VerifyUncles(chain, block); err != nil {
+					b.Fatalf("uncle verification failed: %v", err)
+				}
+			}
+		})
+	}
+}
+
+// Benchmarks verifying the uncles of a run of consecutive blocks, each including
+// an uncle, with a call to VerifyUncles per block and with a single batch whose
+// ancestor window slides across the blocks.
+func BenchmarkVerifyUnclesBatch(b *testing.B) {
+	var (
+		engine  = NewTester()
+		uncler  = NewTester()
+		genesis = testGenesis(params.MinimumDifficulty.Int64())
+		chain   = newTestChain(frontierConfig, genesis)
+		headers = []*types.Header{genesis}
+		blocks  []*types.Block
+	)
+	uncler.SetEtherbase(common.Address{0xaa})
+	for i := 1; i <= 64; i++ {
+		header := &types.Header{
+			ParentHash: headers[i-1].Hash(),
+			Number:     big.NewInt(int64(i)),
+			GasLimit:   headers[i-1].GasLimit,
+			Time:       headers[i-1].Time + 1,
+		}
+		if err := engine.Prepare(chain, header); err != nil {
+			b.Fatalf("failed to prepare header: %v", err)
+		}
+		// Include a sibling of the parent, anything older would need the
+		// ancestry to be deeper
+		var uncles []*types.Header
+		if i > 1 {
+			uncles = append(uncles, sealHeader(b, uncler, chain, headers[i-2]))
+		}
+		block := types.NewBlock(header, nil, uncles, nil, trie.NewStackTrie(nil))
+		chain.insertBlock(block)
+
+		headers = append(headers, block.Header())
+		blocks = append(blocks, block)
+	}
+	blocks = blocks[maxUncleDepth:]
+
+	b.Run("single", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			for _, block := range blocks {
+				if err := engine.VerifyUncles(chain, block); err != nil {
+					b.Fatalf("block %d: uncle verification failed: %v", block.NumberU64(), err)
+				}
+			}
+		}
+	})
+	b.Run("batch", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			_, results := engine.VerifyUnclesBatch(chain, blocks)
+			for j := range blocks {
+				if err := <-results; err != nil {
+					b.Fatalf("block %d: uncle verification failed: %v", blocks[j].NumberU64(), err)
+				}
+			}
+		}
+	})
+}
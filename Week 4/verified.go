@@ -0,0 +1,81 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package consensus
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/lru"
+)
+
+// inmemoryVerified is the default number of verified header hashes to remember.
+const inmemoryVerified = 4096
+
+// verifiedCache remembers the hashes of the headers that recently passed
+// verification, so headers seen again on reorgs or re-broadcasts by peers skip
+// the checks. Every entry records whether the seal was verified along, as a
+// header verified without its seal doesn't vouch for one.
+type verifiedCache struct {
+	headers *lru.Cache[common.Hash, bool] // Whether the seal was checked, by header hash
+}
+
+// newVerifiedCache creates a cache of the given number of verified headers,
+// inmemoryVerified if not positive.
+func newVerifiedCache(size int) *verifiedCache {
+	if size <= 0 {
+		size = inmemoryVerified
+	}
+	return &verifiedCache{headers: lru.NewCache[common.Hash, bool](size)}
+}
+
+// known returns whether the header with the given hash passed verification
+// before, including the seal if requested.
+func (c *verifiedCache) known(hash common.Hash, seal bool) bool {
+	if c == nil {
+		return false
+	}
+	sealed, ok := c.headers.Get(hash)
+	return ok && (sealed || !seal)
+}
+
+// add records that the header with the given hash passed verification, never
+// downgrading an entry whose seal was already checked.
+func (c *verifiedCache) add(hash common.Hash, seal bool) {
+	if c == nil || c.known(hash, true) {
+		return
+	}
+	c.headers.Add(hash, seal)
+}
+
+// purge forgets every verified header.
+func (c *verifiedCache) purge() {
+	if c != nil {
+		c.headers.Purge()
+	}
+}
+
+// verifyCached runs verify for the header with the given hash unless it passed
+// verification before, remembering the header if it passes now.
+func (c *verifiedCache) verifyCached(hash common.Hash, seal bool, verify func() error) error {
+	if c.known(hash, seal) {
+		return nil
+	}
+	if err := verify(); err != nil {
+		return err
+	}
+	c.add(hash, seal)
+	return nil
+}
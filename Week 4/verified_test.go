@@ -0,0 +1,104 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package consensus
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that the verified cache only vouches for a seal it saw checked, never
+// downgrades an entry, and stays within its configured size.
+func TestVerifiedCache(t *testing.T) {
+	cache := newVerifiedCache(2)
+
+	cache.add(common.Hash{0x01}, false)
+	if !cache.known(common.Hash{0x01}, false) {
+		t.Errorf("unsealed entry not known without the seal")
+	}
+	if cache.known(common.Hash{0x01}, true) {
+		t.Errorf("unsealed entry vouched for the seal")
+	}
+	cache.add(common.Hash{0x02}, true)
+	cache.add(common.Hash{0x02}, false)
+	if !cache.known(common.Hash{0x02}, true) {
+		t.Errorf("sealed entry downgraded")
+	}
+	cache.add(common.Hash{0x03}, true)
+	if cache.known(common.Hash{0x01}, false) {
+		t.Errorf("oldest entry not evicted")
+	}
+	// Unsized caches hold the default number of headers
+	cache = newVerifiedCache(0)
+	for i := 0; i < inmemoryVerified+100; i++ {
+		cache.add(common.BigToHash(big.NewInt(int64(i))), true)
+	}
+	if size := cache.headers.Len(); size != inmemoryVerified {
+		t.Errorf("default cache size mismatch: have %d, want %d", size, inmemoryVerified)
+	}
+	var nilCache *verifiedCache
+	if nilCache.known(common.Hash{0x02}, false) {
+		t.Errorf("disabled cache knows a header")
+	}
+}
+
+// Tests that a header verified without its seal is accepted again from the
+// cache, but still has its seal checked when requested.
+func TestVerifiedCacheEngine(t *testing.T) {
+	var (
+		engine  = NewPoW()
+		genesis = testGenesis(params.MinimumDifficulty.Int64())
+		chain   = newTestChain(frontierConfig, genesis)
+		header  = makeHeaders(engine, newTestChain(frontierConfig, genesis), genesis, 1, 1)[0]
+	)
+	if err := engine.VerifyHeader(chain, header, false); err != nil {
+		t.Fatalf("failed to verify header: %v", err)
+	}
+	if !engine.verified.known(header.Hash(), false) {
+		t.Fatalf("verified header not cached")
+	}
+	if err := engine.VerifyHeader(chain, header, false); err != nil {
+		t.Errorf("cached header not accepted: %v", err)
+	}
+	// The unsealed header mustn't pass a seal check through the cache
+	if err := engine.VerifyHeader(chain, header, true); !errors.Is(err, errInvalidPoW) {
+		t.Errorf("seal check error mismatch: have %v, want %v", err, errInvalidPoW)
+	}
+	// Closing the engine forgets every verified header
+	if err := engine.Close(); err != nil {
+		t.Fatalf("failed to close engine: %v", err)
+	}
+	if engine.verified.known(header.Hash(), false) {
+		t.Errorf("verified header still cached after close")
+	}
+	// The option sizes the cache of both engines
+	for name, cache := range map[string]*verifiedCache{
+		"pow": NewPoW(WithVerifiedCache(16)).verified,
+		"poa": NewPoA(testCliqueConfig, nil, WithPoAVerifiedCache(16)).verified,
+	} {
+		for i := 0; i < 32; i++ {
+			cache.add(common.Hash{byte(i)}, true)
+		}
+		if size := cache.headers.Len(); size != 16 {
+			t.Errorf("%s: cache size mismatch: have %d, want 16", name, size)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package consensus
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// errWithdrawalsBeforeShanghai is returned if a block is assembled with
+// withdrawals before the Shanghai fork activated them.
+var errWithdrawalsBeforeShanghai = errors.New("withdrawals set before Shanghai activation")
+
+// ProcessWithdrawals credits the recipient of every withdrawal with its amount,
+// which is denominated in gwei. Withdrawals are not transactions and use no gas.
+// metoda 'process withdrawals' akan menambahkan saldo penerima setiap withdrawal sebesar jumlahnya (dalam gwei).
+func ProcessWithdrawals(state *state.StateDB, withdrawals []*types.Withdrawal) {
+	for _, w := range withdrawals {
+		amount := new(big.Int).SetUint64(w.Amount)
+		amount.Mul(amount, big.NewInt(params.GWei))
+		state.AddBalance(w.Address, amount)
+	}
+}
+
+// verifyWithdrawals checks that a header commits to its withdrawals exactly
+// from the Shanghai fork on. Chains without a config skip the check.
+func verifyWithdrawals(config *params.ChainConfig, header *types.Header) error {
+	if config == nil {
+		return nil
+	}
+	shanghai := config.IsShanghai(header.Number, header.Time)
+	if !shanghai && header.WithdrawalsHash != nil {
+		return fmt.Errorf("invalid withdrawalsHash: have %x, expected nil", header.WithdrawalsHash)
+	}
+	if shanghai && header.WithdrawalsHash == nil {
+		return errors.New("missing withdrawalsHash")
+	}
+	return nil
+}
+
+// assembleWithdrawals checks that withdrawals may be included in a block being
+// assembled from the given header.
+func assembleWithdrawals(config *params.ChainConfig, header *types.Header, withdrawals []*types.Withdrawal) error {
+	if config != nil && !config.IsShanghai(header.Number, header.Time) && len(withdrawals) > 0 {
+		return errWithdrawalsBeforeShanghai
+	}
+	return nil
+}
+
+// VerifyWithdrawals checks that a header commits to its withdrawals exactly from
+// the Shanghai fork on, so engines outside this package apply the same rule.
+// Chains without a config skip the check.
+// metoda 'verify withdrawals' akan mengecek bahwa header memiliki withdrawals hash tepat sejak fork Shanghai.
+func VerifyWithdrawals(config *params.ChainConfig, header *types.Header) error {
+	return verifyWithdrawals(config, header)
+}
+
+// AssembleWithdrawals checks that withdrawals may be included in a block being
+// assembled from the given header, rejecting them before the Shanghai fork.
+// metoda 'assemble withdrawals' akan mengecek apakah withdrawal boleh dimasukkan ke block yang sedang dibangun.
+func AssembleWithdrawals(config *params.ChainConfig, header *types.Header, withdrawals []*types.Withdrawal) error {
+	return assembleWithdrawals(config, header, withdrawals)
+}
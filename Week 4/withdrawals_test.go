@@ -0,0 +1,96 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package consensus
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+// Tests that assembling a block credits every withdrawal in gwei, commits to
+// the withdrawals and the resulting state in the header, and that withdrawals
+// are refused before Shanghai.
+func TestFinalizeWithdrawals(t *testing.T) {
+	shanghai := *londonConfig
+	shanghai.ShanghaiTime = new(uint64)
+
+	var (
+		alice       = common.Address{0xa1}
+		bob         = common.Address{0xb0}
+		withdrawals = []*types.Withdrawal{
+			{Index: 0, Validator: 1, Address: alice, Amount: 1},
+			{Index: 1, Validator: 2, Address: bob, Amount: 32_000_000_000},
+			{Index: 2, Validator: 3, Address: alice, Amount: 2},
+		}
+		chain   = newTestChain(&shanghai, testGenesis(0))
+		engine  = NewBeacon(NewPoW())
+		statedb = newTestState(t)
+		header  = &types.Header{Number: big.NewInt(1), Difficulty: new(big.Int), Time: 1, GasLimit: params.GenesisGasLimit}
+	)
+	block, err := engine.FinalizeAndAssemble(chain, header, statedb, nil, nil, nil, withdrawals)
+	if err != nil {
+		t.Fatalf("failed to assemble block: %v", err)
+	}
+	want := map[common.Address]*big.Int{
+		alice: big.NewInt(3 * params.GWei),
+		bob:   new(big.Int).Mul(big.NewInt(32_000_000_000), big.NewInt(params.GWei)),
+	}
+	for addr, balance := range want {
+		if have := statedb.GetBalance(addr); have.Cmp(balance) != 0 {
+			t.Errorf("account %x balance mismatch: have %v, want %v", addr, have, balance)
+		}
+	}
+	assembled := block.Header()
+	if root := statedb.IntermediateRoot(true); assembled.Root != root {
+		t.Errorf("state root mismatch: have %x, want %x", assembled.Root, root)
+	}
+	wantHash := types.DeriveSha(types.Withdrawals(withdrawals), trie.NewStackTrie(nil))
+	if assembled.WithdrawalsHash == nil || *assembled.WithdrawalsHash != wantHash {
+		t.Errorf("withdrawals hash mismatch: have %v, want %x", assembled.WithdrawalsHash, wantHash)
+	}
+	if block.Hash() != assembled.Hash() {
+		t.Errorf("block hash mismatch: have %x, want %x", block.Hash(), assembled.Hash())
+	}
+	// Finalizing alone credits the same balances
+	finalized := newTestState(t)
+	engine.Finalize(chain, types.CopyHeader(header), finalized, nil, nil, withdrawals)
+	if root := finalized.IntermediateRoot(true); root != assembled.Root {
+		t.Errorf("finalized state root mismatch: have %x, want %x", root, assembled.Root)
+	}
+	// Headers commit to withdrawals exactly from Shanghai on
+	if err := verifyWithdrawals(&shanghai, assembled); err != nil {
+		t.Errorf("assembled header failed verification: %v", err)
+	}
+	if err := verifyWithdrawals(&shanghai, header); err == nil {
+		t.Errorf("missing withdrawals hash accepted after Shanghai")
+	}
+	if err := verifyWithdrawals(londonConfig, assembled); err == nil {
+		t.Errorf("withdrawals hash accepted before Shanghai")
+	}
+	// Before Shanghai no withdrawals may be included
+	chain = newTestChain(londonConfig, testGenesis(0))
+	header = &types.Header{Number: big.NewInt(1), Difficulty: new(big.Int), Time: 1, GasLimit: params.GenesisGasLimit}
+	if _, err := engine.FinalizeAndAssemble(chain, header, newTestState(t), nil, nil, nil, withdrawals); !errors.Is(err, errWithdrawalsBeforeShanghai) {
+		t.Errorf("pre-Shanghai error mismatch: have %v, want %v", err, errWithdrawalsBeforeShanghai)
+	}
+}